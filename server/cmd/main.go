@@ -13,7 +13,7 @@ import (
 	"time"
 
 	"cassiopeia/internal/config"
-	_ "cassiopeia/internal/handlers"
+	"cassiopeia/internal/handlers"
 	"cassiopeia/internal/middleware"
 	"cassiopeia/internal/repository"
 	"cassiopeia/internal/service"
@@ -65,20 +65,68 @@ func main() {
 	issRepo := repository.NewISSRepository(db)
 	osdrRepo := repository.NewOSDRRepository(db)
 	telemetryRepo := repository.NewTelemetryRepository(db)
+	telemetryChannelRepo := repository.NewTelemetryChannelRepository(db)
+	telemetryAnomalyRepo := repository.NewTelemetryAnomalyRepository(db)
+	telemetryAlertRepo := repository.NewTelemetryAlertRepository(db)
+	telemetryRollupRepo := repository.NewTelemetryRollupRepository(db)
+	retentionRepo := repository.NewRetentionRepository(db)
 	spaceCacheRepo := repository.NewSpaceCacheRepository(db)
+	spaceWeatherRepo := repository.NewSpaceWeatherRepository(db)
+	jwstImageRepo := repository.NewJWSTImageRepository(db)
+	collectionRepo := repository.NewCollectionRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
 	cacheRepo := repository.NewCacheRepository(redisClient)
 
-	issClient := clients.NewISSClient(cfg.ISS.URL)
+	issClient := clients.NewISSClient(cfg.ISS.URL, cfg.ISS.TLEURL)
 	nasaClient := clients.NewNASAClient(cfg.NASA)
 	jwstClient := clients.NewJWSTClient(cfg.JWST)
 	astroClient := clients.NewAstroClient(cfg.Astro)
 
 	// Инициализация сервисов
 	issService := service.NewISSService(issRepo, cacheRepo, issClient, cfg.ISS)
-	nasaService := service.NewNASAService(osdrRepo, spaceCacheRepo, cacheRepo, nasaClient)
-	jwstService := service.NewJWSTService(cacheRepo, jwstClient)
+	nasaService := service.NewNASAService(osdrRepo, spaceCacheRepo, spaceWeatherRepo, cacheRepo, nasaClient)
+	fitsService, err := service.NewFITSService(cfg.FITS)
+	if err != nil {
+		log.Fatal("Failed to initialize FITS header cache:", err)
+	}
+	jwstService := service.NewJWSTService(cacheRepo, jwstImageRepo, jwstClient, fitsService, cfg.JWSTCatalog)
 	astroService := service.NewAstroService(cacheRepo, astroClient)
-	telemetryService := service.NewTelemetryService(telemetryRepo, cfg.Telemetry.OutputDir)
+	locationService := service.NewLocationService(locationRepo)
+	calendarService := service.NewCalendarService(astroService, issService, nasaService)
+	observingService := service.NewObservingService(issService)
+	telemetryChannelService := service.NewTelemetryChannelService(telemetryChannelRepo)
+	telemetryAnomalyService := service.NewTelemetryAnomalyService(telemetryAnomalyRepo, telemetryRepo, telemetryChannelService, cfg.TelemetryAnomaly)
+	telemetryService := service.NewTelemetryService(telemetryRepo, telemetryChannelService, telemetryAnomalyService, cfg.Telemetry.OutputDir)
+	telemetryStatsService := service.NewTelemetryStatsService(telemetryRepo, cacheRepo)
+	telemetryRollupService := service.NewTelemetryRollupService(telemetryRollupRepo, telemetryChannelService, cfg.TelemetryRollup)
+	telemetryAlertService := service.NewTelemetryAlertService(telemetryAlertRepo, telemetryRepo, telemetryChannelService, cfg.TelemetryAlert)
+	telemetryIngestService := service.NewTelemetryIngestService(telemetryRepo, telemetryChannelService, telemetryAnomalyService,
+		telemetryAlertService, cfg.TelemetryIngest)
+	retentionService := service.NewRetentionService(retentionRepo, cfg.Retention)
+	spaceWeatherService := service.NewSpaceWeatherService(spaceWeatherRepo, cacheRepo)
+	mediaService, err := service.NewMediaService(cfg.Media)
+	if err != nil {
+		log.Fatal("Failed to initialize media cache:", err)
+	}
+	collectionService := service.NewCollectionService(collectionRepo, jwstService, nasaService, mediaService)
+
+	// Инициализация обработчиков
+	spaceWeatherHandler := handlers.NewSpaceWeatherHandler(spaceWeatherService)
+	mediaHandler := handlers.NewMediaHandler(mediaService)
+	jwstHandler := handlers.NewJWSTHandler(jwstService)
+	collectionHandler := handlers.NewCollectionHandler(collectionService)
+	astroHandler := handlers.NewAstroHandler(astroService, locationService)
+	locationHandler := handlers.NewLocationHandler(locationService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService, locationService)
+	observingHandler := handlers.NewObservingHandler(observingService, locationService)
+	telemetryHandler := handlers.NewTelemetryHandler(telemetryService)
+	telemetryIngestHandler := handlers.NewTelemetryIngestHandler(telemetryIngestService)
+	telemetryChannelHandler := handlers.NewTelemetryChannelHandler(telemetryChannelService)
+	telemetryAnomalyHandler := handlers.NewTelemetryAnomalyHandler(telemetryAnomalyService)
+	telemetryAlertHandler := handlers.NewTelemetryAlertHandler(telemetryAlertService)
+	telemetryRollupHandler := handlers.NewTelemetryRollupHandler(telemetryRollupService)
+	telemetryStatsHandler := handlers.NewTelemetryStatsHandler(telemetryStatsService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
 
 	// Инициализация воркеров (фоновые задачи)
 	scheduler := worker.NewScheduler()
@@ -99,6 +147,23 @@ func main() {
 		log.Printf("Telemetry Worker enabled (interval: %v)", cfg.Workers.TelemetryInterval)
 	}
 
+	// Очередь приема телеметрии нужна всегда, пока открыт /telemetry/ingest
+	scheduler.AddWorker(worker.NewTelemetryIngestWorker(telemetryIngestService))
+	scheduler.AddWorker(worker.NewTelemetryAlertWorker(telemetryAlertService))
+	log.Printf("Telemetry Alert Worker enabled (interval: %v)", cfg.TelemetryAlert.Interval)
+	scheduler.AddWorker(worker.NewTelemetryRollupWorker(telemetryRollupService, cfg.Workers.RollupInterval))
+	log.Printf("Telemetry Rollup Worker enabled (interval: %v)", cfg.Workers.RollupInterval)
+
+	if cfg.Workers.RetentionEnabled {
+		scheduler.AddWorker(worker.NewRetentionWorker(retentionService, cfg.Workers.RetentionInterval))
+		log.Printf("Retention Worker enabled (interval: %v, dry run: %v)", cfg.Workers.RetentionInterval, cfg.Retention.DryRun)
+	}
+
+	if cfg.Workers.JWSTEnabled {
+		scheduler.AddWorker(worker.NewJWSTWorker(jwstService, cfg.Workers.JWSTInterval))
+		log.Printf("JWST Worker enabled (interval: %v)", cfg.Workers.JWSTInterval)
+	}
+
 	// Запускаем воркеры в фоне
 	go scheduler.Start()
 	defer scheduler.Stop()
@@ -116,8 +181,8 @@ func main() {
 	// CORS для React фронтенда
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", cfg.App.FrontendURL},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Content-Encoding", "Authorization", "X-Telemetry-Token", "X-Admin-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -156,6 +221,25 @@ func main() {
 		c.JSON(200, trend)
 	})
 
+	// 1.1. Видимые пролеты МКС над местом наблюдения (location_id или lat/lon)
+	api.GET("/iss/passes", func(c *gin.Context) {
+		observer, ok := handlers.ResolveObserver(c, locationService)
+		if !ok {
+			return
+		}
+		days, _ := strconv.Atoi(c.DefaultQuery("days", "3"))
+
+		passes, err := issService.GetPasses(c.Request.Context(), *observer, days)
+		if err != nil {
+			c.JSON(502, gin.H{
+				"error":   "failed to predict ISS passes",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(200, gin.H{"success": true, "data": passes})
+	})
+
 	// 2. OSDR данные (как rust_iss /osdr/list)
 	api.GET("/osdr/list", func(c *gin.Context) {
 		ctx := c.Request.Context()
@@ -172,101 +256,110 @@ func main() {
 	})
 
 	// 3. JWST галерея (как php-web /api/jwst/feed)
-	api.GET("/jwst/feed", func(c *gin.Context) {
-		ctx := c.Request.Context()
+	api.GET("/jwst/feed", jwstHandler.GetJWSTFeed)
 
-		source := c.DefaultQuery("source", "jpg")
-		suffix := c.Query("suffix")
-		program := c.Query("program")
-		instrument := c.Query("instrument")
-		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-		perPage, _ := strconv.Atoi(c.DefaultQuery("perPage", "24"))
-
-		images, err := jwstService.GetFeed(ctx, source, suffix, program, instrument, page, perPage)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to get JWST feed"})
-			return
-		}
-
-		c.JSON(200, gin.H{
-			"source": source,
-			"count":  len(images),
-			"items":  images,
-		})
-	})
+	// 3.1. Поиск JWST, наблюдения и сводки по программам
+	api.GET("/jwst/search", jwstHandler.SearchJWST)
+	api.GET("/jwst/observations/:id", jwstHandler.GetJWSTObservation)
+	api.GET("/jwst/programs/:id/summary", jwstHandler.GetJWSTProgramSummary)
 
 	// 4. AstronomyAPI события (как php-web /api/astro/events)
 	api.GET("/astro/events", func(c *gin.Context) {
 		ctx := c.Request.Context()
 
-		lat, _ := strconv.ParseFloat(c.DefaultQuery("lat", "55.7558"), 64)
-		lon, _ := strconv.ParseFloat(c.DefaultQuery("lon", "37.6176"), 64)
+		observer, ok := handlers.ResolveObserver(c, locationService)
+		if !ok {
+			return
+		}
 		days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
-		events, err := astroService.GetEvents(ctx, lat, lon, days)
+		events, err := astroService.GetEvents(ctx, *observer, days)
 		if err != nil {
-			// Вместо 500 возвращаем 200 с сообщением
-			log.Printf("Astro service error (but returning stub): %v", err)
-
-			c.JSON(200, gin.H{
-				"events": []map[string]interface{}{
-					{
-						"name":    "Service Unavailable",
-						"type":    "info",
-						"when":    time.Now().Format(time.RFC3339),
-						"details": "AstronomyAPI requires API key. Using stub data.",
-					},
-				},
-				"location": gin.H{"lat": lat, "lon": lon},
-				"days":     days,
-				"note":     "This is stub data. Add ASTRO_APP_ID and ASTRO_APP_SECRET to .env for real data.",
+			c.JSON(500, gin.H{
+				"error":   "failed to get astronomy events",
+				"message": err.Error(),
 			})
 			return
 		}
 
 		c.JSON(200, gin.H{
 			"events":   events,
-			"location": gin.H{"lat": lat, "lon": lon},
+			"location": observer,
 			"days":     days,
 		})
 	})
 
-	// 5. Телеметрия CSV экспорт (заменяет Pascal)
-	api.GET("/telemetry/export", func(c *gin.Context) {
-		ctx := c.Request.Context()
-
-		format := c.DefaultQuery("format", "csv")
-		fromStr := c.Query("from")
-		toStr := c.Query("to")
-
-		var from, to time.Time
-		var err error
-
-		if fromStr != "" {
-			from, err = time.Parse("2006-01-02", fromStr)
-			if err != nil {
-				c.JSON(400, gin.H{"error": "Invalid from date format"})
-				return
-			}
-		}
-
-		if toStr != "" {
-			to, err = time.Parse("2006-01-02", toStr)
-			if err != nil {
-				c.JSON(400, gin.H{"error": "Invalid to date format"})
-				return
-			}
-		}
-
-		filepath, err := telemetryService.ExportTelemetry(ctx, format, from, to)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "Failed to export telemetry"})
-			return
-		}
-
-		// Отправляем файл
-		c.File(filepath)
-	})
+	// 4.1. Локальный расчет: фаза Луны, восходы/заходы, сумерки и положения планет
+	api.GET("/astro/moon-phase", astroHandler.GetMoonPhase)
+	api.GET("/astro/sun-moon", astroHandler.GetSkyTimes)
+	api.GET("/astro/positions", astroHandler.GetPositions)
+	// Затмения, сезоны и суперлуния: year и необязательный to_year (до 10 лет)
+	api.GET("/astro/almanac", astroHandler.GetAlmanac)
+	// Оценка ночи 0-100: темнота, Луна, пролеты МКС
+	api.GET("/astro/observing-window", observingHandler.GetObservingWindow)
+	// Подписка iCalendar: include=astro|sun|moon|phases|other|iss|neo через запятую
+	api.GET("/astro/events.ics", calendarHandler.GetAstroCalendar)
+
+	// 4.1.1. Сохраненные места наблюдения (location_id для астрономии и дашборда)
+	api.GET("/locations", locationHandler.ListLocations)
+	api.POST("/locations", locationHandler.CreateLocation)
+	// Подсказка пояса по координатам: timezone при создании места обязателен
+	api.GET("/locations/timezone", locationHandler.SuggestTimezone)
+	api.GET("/locations/:id", locationHandler.GetLocation)
+	api.PUT("/locations/:id", locationHandler.UpdateLocation)
+	api.DELETE("/locations/:id", locationHandler.DeleteLocation)
+
+	// 4.2. Сводка космической погоды по сохраненным событиям DONKI
+	api.GET("/space-weather/summary", spaceWeatherHandler.GetSummary)
+
+	// 4.3. Прокси и кэш миниатюр для изображений APOD и JWST
+	api.GET("/media/proxy", mediaHandler.Proxy)
+
+	// 4.4. Подборки изображений JWST и APOD с выгрузкой в ZIP
+	api.GET("/collections", collectionHandler.ListCollections)
+	api.POST("/collections", collectionHandler.CreateCollection)
+	api.GET("/collections/:id", collectionHandler.GetCollection)
+	api.PUT("/collections/:id", collectionHandler.UpdateCollection)
+	api.DELETE("/collections/:id", collectionHandler.DeleteCollection)
+	api.POST("/collections/:id/items", collectionHandler.AddItem)
+	api.PATCH("/collections/:id/items/:item_id", collectionHandler.UpdateItem)
+	api.DELETE("/collections/:id/items/:item_id", collectionHandler.RemoveItem)
+	api.PUT("/collections/:id/order", collectionHandler.ReorderItems)
+	api.GET("/collections/:id/export", collectionHandler.ExportCollection)
+
+	// 5. Телеметрия: потоковая выгрузка CSV/NDJSON/JSON/Excel и история по выбранным каналам (channels=voltage,temperature)
+	api.GET("/telemetry/export", telemetryHandler.ExportTelemetry)
+	api.GET("/telemetry/history", telemetryHandler.GetTelemetryHistory)
+
+	// 5.1. Импорт реальных показаний из CSV/XLSX/JSON/NDJSON с отчетом по строкам
+	api.POST("/telemetry/import", telemetryHandler.ImportTelemetry)
+
+	// 5.2. Прием показаний от станций (JSON, NDJSON, line protocol) по токену источника
+	api.POST("/telemetry/ingest", telemetryIngestHandler.Ingest)
+
+	// 5.3. Реестр каналов телеметрии: имя, единица, тип, диапазон, точность
+	api.GET("/telemetry/channels", telemetryChannelHandler.ListChannels)
+	api.POST("/telemetry/channels", telemetryChannelHandler.CreateChannel)
+	api.GET("/telemetry/channels/:name", telemetryChannelHandler.GetChannel)
+	api.PUT("/telemetry/channels/:name", telemetryChannelHandler.UpdateChannel)
+
+	// 5.4. Аномалии телеметрии: пороги, z-score, EWMA, скорость изменения
+	api.GET("/telemetry/anomalies", telemetryAnomalyHandler.ListAnomalies)
+
+	// 5.5. Правила тревоги (above/below за время, rise/fall за окно) и история срабатываний
+	api.GET("/telemetry/alert-rules", telemetryAlertHandler.ListRules)
+	api.POST("/telemetry/alert-rules", telemetryAlertHandler.CreateRule)
+	api.GET("/telemetry/alert-rules/:id", telemetryAlertHandler.GetRule)
+	api.PUT("/telemetry/alert-rules/:id", telemetryAlertHandler.UpdateRule)
+	api.DELETE("/telemetry/alert-rules/:id", telemetryAlertHandler.DeleteRule)
+	api.POST("/telemetry/alert-rules/:id/test", telemetryAlertHandler.TestRule)
+	api.GET("/telemetry/alerts", telemetryAlertHandler.ListAlerts)
+
+	// 5.6. Сводки min/max/avg/count/stddev по минутам, часам и суткам; разрешение - по длине периода
+	api.GET("/telemetry/rollups", telemetryRollupHandler.GetRollups)
+
+	// 5.7. Статистика с процентилями p50/p95/p99 по часам, суткам или источникам (кэшируется)
+	api.GET("/telemetry/stats", telemetryStatsHandler.GetStats)
 
 	// 6. Health check
 	api.GET("/health", func(c *gin.Context) {
@@ -294,12 +387,14 @@ func main() {
 		// Статистика из БД
 		issCount, _ := issRepo.Count(ctx)
 		osdrCount, _ := osdrRepo.Count(ctx)
+		jwstCount, _ := jwstImageRepo.Count(ctx)
 		//telemetryCount, _ := telemetryRepo.Count(ctx)
 
 		c.JSON(200, gin.H{
 			"database": gin.H{
-				"iss_logs":   issCount,
-				"osdr_items": osdrCount,
+				"iss_logs":    issCount,
+				"osdr_items":  osdrCount,
+				"jwst_images": jwstCount,
 				//"telemetry":  telemetryCount,
 			},
 			"redis": redisStats,
@@ -307,10 +402,17 @@ func main() {
 				"iss_enabled":       cfg.Workers.ISSEnabled,
 				"nasa_enabled":      cfg.Workers.NASAEnabled,
 				"telemetry_enabled": cfg.Workers.TelemetryEnabled,
+				"jwst_enabled":      cfg.Workers.JWSTEnabled,
+				"retention_enabled": cfg.Workers.RetentionEnabled,
 			},
 		})
 	})
 
+	// 7.1. Сроки хранения: правила по таблицам, ручной запуск (dry_run; удаление - только с RETENTION_ADMIN_TOKEN) и манифест архивов
+	api.GET("/system/retention/policies", retentionHandler.GetPolicies)
+	api.POST("/system/retention/run", retentionHandler.Run)
+	api.GET("/system/retention/archives", retentionHandler.ListArchives)
+
 	// 8. Force refresh endpoints (для дебага)
 	if cfg.App.Debug {
 		api.POST("/refresh/iss", func(c *gin.Context) {
@@ -345,6 +447,11 @@ func main() {
 	api.GET("/dashboard", func(c *gin.Context) {
 		ctx := c.Request.Context()
 
+		observer, ok := handlers.ResolveObserver(c, locationService)
+		if !ok {
+			return
+		}
+
 		// Собираем все данные параллельно
 		type DashboardData struct {
 			ISS       interface{} `json:"iss"`
@@ -367,18 +474,19 @@ func main() {
 		}
 
 		// JWST изображения
-		if jwst, err := jwstService.GetFeed(ctx, "jpg", "", "", "", 1, 12); err == nil {
-			data.JWST = jwst
+		if jwst, err := jwstService.GetFeed(ctx, service.JWSTFeedFilter{Source: "jpg", Page: 1, PerPage: 12}); err == nil {
+			data.JWST = jwst.Images
 		}
 
 		// Астрономические события
-		if astro, err := astroService.GetEvents(ctx, 55.7558, 37.6176, 7); err == nil {
+		if astro, err := astroService.GetEvents(ctx, *observer, 7); err == nil {
 			data.Astro = astro
 		}
 
 		// Телеметрия (последние 50 записей)
-		if telemetry, err := telemetryService.GetTelemetryHistory(ctx,
-			time.Now().Add(-24*time.Hour), time.Now()); err == nil {
+		if history, err := telemetryService.GetTelemetryHistory(ctx,
+			time.Now().Add(-24*time.Hour), time.Now(), nil); err == nil {
+			telemetry := history.Records
 			if len(telemetry) > 50 {
 				telemetry = telemetry[:50]
 			}
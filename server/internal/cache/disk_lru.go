@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"container/list"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiskLRU отслеживает файлы в каталоге кэша и удаляет самые давно
+// использованные, когда суммарный размер превышает лимит.
+// Время последнего доступа хранится в mtime, поэтому порядок переживает рестарт.
+type DiskLRU struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	size    int64
+}
+
+type diskEntry struct {
+	path string
+	size int64
+}
+
+func NewDiskLRU(dir string, maxBytes int64) (*DiskLRU, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	lru := &DiskLRU{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	if err := lru.load(); err != nil {
+		return nil, err
+	}
+
+	return lru, nil
+}
+
+// load восстанавливает индекс по файлам на диске (от старых к новым)
+func (l *DiskLRU) load() error {
+	type fileInfo struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []fileInfo
+
+	err := filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) == ".tmp" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, fileInfo{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, f := range files {
+		l.entries[f.path] = l.order.PushFront(&diskEntry{path: f.path, size: f.size})
+		l.size += f.size
+	}
+	l.evictLocked()
+
+	return nil
+}
+
+// Add регистрирует только что записанный файл и при необходимости освобождает место
+func (l *DiskLRU) Add(path string, size int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[path]; ok {
+		entry := elem.Value.(*diskEntry)
+		l.size += size - entry.size
+		entry.size = size
+		l.order.MoveToFront(elem)
+	} else {
+		l.entries[path] = l.order.PushFront(&diskEntry{path: path, size: size})
+		l.size += size
+	}
+
+	l.evictLocked()
+}
+
+// Touch отмечает файл как использованный. Возвращает false, если файла нет в кэше.
+func (l *DiskLRU) Touch(path string) bool {
+	l.mu.Lock()
+	elem, ok := l.entries[path]
+	if ok {
+		l.order.MoveToFront(elem)
+	}
+	l.mu.Unlock()
+
+	if ok {
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			log.Printf("Failed to touch cached file %s: %v", path, err)
+		}
+	}
+	return ok
+}
+
+// Size возвращает текущий суммарный размер кэша в байтах
+func (l *DiskLRU) Size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+func (l *DiskLRU) evictLocked() {
+	// Самый свежий файл не удаляем, даже если он один больше лимита
+	for l.maxBytes > 0 && l.size > l.maxBytes && l.order.Len() > 1 {
+		elem := l.order.Back()
+		entry := elem.Value.(*diskEntry)
+
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to evict cached file %s: %v", entry.path, err)
+		}
+
+		l.order.Remove(elem)
+		delete(l.entries, entry.path)
+		l.size -= entry.size
+	}
+}
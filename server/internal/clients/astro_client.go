@@ -1,19 +1,29 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+// ErrAstroNotConfigured возвращается без запроса к API, если не заданы ASTRO_APP_ID/ASTRO_APP_SECRET
+var ErrAstroNotConfigured = errors.New("AstronomyAPI credentials are not configured")
+
 type AstroClient interface {
-	GetEvents(ctx context.Context, lat, lon float64, days int) (map[string]interface{}, error)
+	// GetEvents события тела (sun или moon) в интервале дат: затмения с восходом/заходом
+	GetEvents(ctx context.Context, body string, lat, lon, elevation float64, from, to time.Time) (*AstroEventsResponse, error)
+	// GetPositions положения всех тел на момент at
+	GetPositions(ctx context.Context, lat, lon, elevation float64, at time.Time) (*AstroPositionsResponse, error)
 	GetBodies(ctx context.Context) (map[string]interface{}, error)
-	GetMoonPhase(ctx context.Context, date time.Time) (map[string]interface{}, error)
+	// GetMoonPhase изображение фазы Луны для наблюдателя (Studio API)
+	GetMoonPhase(ctx context.Context, lat, lon float64, date time.Time) (*AstroMoonPhaseResponse, error)
 }
 
 type astroClient struct {
@@ -40,109 +50,116 @@ func NewAstroClient(config AstroConfig) AstroClient {
 	}
 }
 
-func (c *astroClient) GetEvents(ctx context.Context, lat, lon float64, days int) (map[string]interface{}, error) {
-	from := time.Now().UTC().Format("2006-01-02")
-	to := time.Now().UTC().AddDate(0, 0, days).Format("2006-01-02")
-
-	reqURL := fmt.Sprintf("%s/bodies/events", c.baseURL)
-
-	params := url.Values{}
-	params.Add("latitude", fmt.Sprintf("%f", lat))
-	params.Add("longitude", fmt.Sprintf("%f", lon))
-	params.Add("from", from)
-	params.Add("to", to)
-
-	reqURL += "?" + params.Encode()
+func (c *astroClient) GetEvents(ctx context.Context, body string, lat, lon, elevation float64, from, to time.Time) (*AstroEventsResponse, error) {
+	params := observerParams(lat, lon, elevation)
+	params.Set("from_date", from.UTC().Format("2006-01-02"))
+	params.Set("to_date", to.UTC().Format("2006-01-02"))
+	params.Set("time", from.UTC().Format("15:04:05"))
+	params.Set("output", "rows")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+	var resp AstroEventsResponse
+	if err := c.do(ctx, http.MethodGet, "/bodies/events/"+url.PathEscape(body), params, nil, &resp); err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
-	// Базовая авторизация
-	auth := base64.StdEncoding.EncodeToString([]byte(c.appID + ":" + c.secret))
-	req.Header.Set("Authorization", "Basic "+auth)
-	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
+func (c *astroClient) GetPositions(ctx context.Context, lat, lon, elevation float64, at time.Time) (*AstroPositionsResponse, error) {
+	params := observerParams(lat, lon, elevation)
+	date := at.UTC().Format("2006-01-02")
+	params.Set("from_date", date)
+	params.Set("to_date", date)
+	params.Set("time", at.UTC().Format("15:04:05"))
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("AstronomyAPI returned status %d", resp.StatusCode)
+	var resp AstroPositionsResponse
+	if err := c.do(ctx, http.MethodGet, "/bodies/positions", params, nil, &resp); err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
+func (c *astroClient) GetBodies(ctx context.Context) (map[string]interface{}, error) {
 	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("decode JSON: %w", err)
+	if err := c.do(ctx, http.MethodGet, "/bodies", nil, nil, &data); err != nil {
+		return nil, err
 	}
-
 	return data, nil
 }
 
-func (c *astroClient) GetBodies(ctx context.Context) (map[string]interface{}, error) {
-	reqURL := fmt.Sprintf("%s/bodies", c.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+func (c *astroClient) GetMoonPhase(ctx context.Context, lat, lon float64, date time.Time) (*AstroMoonPhaseResponse, error) {
+	var req AstroMoonPhaseRequest
+	req.Format = "png"
+	req.Style.MoonStyle = "default"
+	req.Style.BackgroundStyle = "stars"
+	req.Style.BackgroundColor = "#000000"
+	req.Style.HeadingColor = "#ffffff"
+	req.Style.TextColor = "#ffffff"
+	req.Observer.Latitude = lat
+	req.Observer.Longitude = lon
+	req.Observer.Date = date.Format("2006-01-02")
+	req.View.Type = "portrait-simple"
+
+	var resp AstroMoonPhaseResponse
+	if err := c.do(ctx, http.MethodPost, "/studio/moon-phase", nil, req, &resp); err != nil {
+		return nil, err
 	}
+	return &resp, nil
+}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(c.appID + ":" + c.secret))
-	req.Header.Set("Authorization", "Basic "+auth)
-	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+// do выполняет запрос с базовой авторизацией и декодирует JSON-ответ в out
+func (c *astroClient) do(ctx context.Context, method, path string, params url.Values, body, out interface{}) error {
+	if c.appID == "" || c.secret == "" {
+		return ErrAstroNotConfigured
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("AstronomyAPI returned status %d", resp.StatusCode)
+	reqURL := c.baseURL + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
 	}
 
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("decode JSON: %w", err)
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
 	}
 
-	return data, nil
-}
-
-func (c *astroClient) GetMoonPhase(ctx context.Context, date time.Time) (map[string]interface{}, error) {
-	dateStr := date.Format("2006-01-02")
-	reqURL := fmt.Sprintf("%s/moon-phase?date=%s", c.baseURL, dateStr)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reader)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return fmt.Errorf("create request: %w", err)
 	}
 
+	// Базовая авторизация
 	auth := base64.StdEncoding.EncodeToString([]byte(c.appID + ":" + c.secret))
 	req.Header.Set("Authorization", "Basic "+auth)
 	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
 	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+		return fmt.Errorf("execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("AstronomyAPI returned status %d", resp.StatusCode)
+		return fmt.Errorf("AstronomyAPI returned status %d", resp.StatusCode)
 	}
 
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("decode JSON: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode JSON: %w", err)
 	}
+	return nil
+}
 
-	return data, nil
+func observerParams(lat, lon, elevation float64) url.Values {
+	params := url.Values{}
+	params.Set("latitude", fmt.Sprintf("%f", lat))
+	params.Set("longitude", fmt.Sprintf("%f", lon))
+	params.Set("elevation", fmt.Sprintf("%.0f", elevation))
+	return params
 }
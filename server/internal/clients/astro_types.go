@@ -0,0 +1,180 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Модели ответов AstronomyAPI v2. Поля, которые API может не прислать или прислать
+// как null, объявлены указателями, чтобы отличать отсутствие значения от нуля.
+
+// APIFloat число, которое AstronomyAPI отдает то числом, то строкой ("-24.51")
+type APIFloat float64
+
+func (f *APIFloat) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		*f = APIFloat(v)
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = APIFloat(v)
+	return nil
+}
+
+// AstroEntry тело, к которому относится строка таблицы
+type AstroEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type AstroObserver struct {
+	Location struct {
+		Latitude  *APIFloat `json:"latitude"`
+		Longitude *APIFloat `json:"longitude"`
+		Elevation *APIFloat `json:"elevation"`
+	} `json:"location"`
+}
+
+type AstroDates struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AstroEventsResponse ответ GET /bodies/events/{body}
+type AstroEventsResponse struct {
+	Data struct {
+		Dates    AstroDates    `json:"dates"`
+		Observer AstroObserver `json:"observer"`
+		Table    *struct {
+			Header []string         `json:"header"`
+			Rows   []AstroEventsRow `json:"rows"`
+		} `json:"table"`
+	} `json:"data"`
+}
+
+type AstroEventsRow struct {
+	Entry AstroEntry       `json:"entry"`
+	Cells []AstroEventCell `json:"cells"`
+}
+
+// AstroEventCell событие: тип (total_lunar_eclipse, partial_solar_eclipse, ...),
+// ключевые моменты и восход/заход тела в день события
+type AstroEventCell struct {
+	Type            string                     `json:"type"`
+	EventHighlights map[string]*AstroHighlight `json:"eventHighlights"`
+	Rise            *string                    `json:"rise"`
+	Set             *string                    `json:"set"`
+	ExtraInfo       struct {
+		Obscuration *APIFloat `json:"obscuration"`
+	} `json:"extraInfo"`
+}
+
+// AstroHighlight момент фазы события (partialStart, peak, totalEnd, ...)
+type AstroHighlight struct {
+	Date     string    `json:"date"`
+	Altitude *APIFloat `json:"altitude"`
+}
+
+// AstroPositionsResponse ответ GET /bodies/positions
+type AstroPositionsResponse struct {
+	Data struct {
+		Dates    AstroDates    `json:"dates"`
+		Observer AstroObserver `json:"observer"`
+		Table    *struct {
+			Header []string            `json:"header"`
+			Rows   []AstroPositionsRow `json:"rows"`
+		} `json:"table"`
+	} `json:"data"`
+}
+
+type AstroPositionsRow struct {
+	Entry AstroEntry          `json:"entry"`
+	Cells []AstroPositionCell `json:"cells"`
+}
+
+type AstroPositionCell struct {
+	Date     string `json:"date"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Distance struct {
+		FromEarth struct {
+			AU *APIFloat `json:"au"`
+			Km *APIFloat `json:"km"`
+		} `json:"fromEarth"`
+	} `json:"distance"`
+	Position struct {
+		Horizontal struct {
+			Altitude AstroAngle `json:"altitude"`
+			Azimuth  AstroAngle `json:"azimuth"`
+		} `json:"horizontal"`
+		Equatorial struct {
+			RightAscension struct {
+				Hours  *APIFloat `json:"hours"`
+				String string    `json:"string"`
+			} `json:"rightAscension"`
+			Declination AstroAngle `json:"declination"`
+		} `json:"equatorial"`
+		Constellation struct {
+			ID    string `json:"id"`
+			Short string `json:"short"`
+			Name  string `json:"name"`
+		} `json:"constellation"`
+	} `json:"position"`
+	ExtraInfo struct {
+		Elongation *APIFloat `json:"elongation"`
+		Magnitude  *APIFloat `json:"magnitude"`
+		Phase      *struct {
+			// Так в API: "angel" вместо "angle"
+			Angle    *APIFloat `json:"angel"`
+			Fraction *APIFloat `json:"fraction"`
+			String   string    `json:"string"`
+		} `json:"phase"`
+	} `json:"extraInfo"`
+}
+
+type AstroAngle struct {
+	Degrees *APIFloat `json:"degrees"`
+	String  string    `json:"string"`
+}
+
+// AstroMoonPhaseRequest тело POST /studio/moon-phase
+type AstroMoonPhaseRequest struct {
+	Format string `json:"format"`
+	Style  struct {
+		MoonStyle       string `json:"moonStyle"`
+		BackgroundStyle string `json:"backgroundStyle"`
+		BackgroundColor string `json:"backgroundColor"`
+		HeadingColor    string `json:"headingColor"`
+		TextColor       string `json:"textColor"`
+	} `json:"style"`
+	Observer struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Date      string  `json:"date"`
+	} `json:"observer"`
+	View struct {
+		Type string `json:"type"`
+	} `json:"view"`
+}
+
+// AstroMoonPhaseResponse ответ /studio/moon-phase: ссылка на сгенерированное изображение
+type AstroMoonPhaseResponse struct {
+	Data struct {
+		ImageURL string `json:"imageUrl"`
+	} `json:"data"`
+}
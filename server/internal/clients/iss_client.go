@@ -11,16 +11,20 @@ import (
 
 type ISSClient interface {
 	GetCurrentPosition(ctx context.Context) (map[string]interface{}, error)
+	// GetTLE возвращает текущие элементы орбиты в текстовом формате TLE
+	GetTLE(ctx context.Context) (string, error)
 }
 
 type issClient struct {
 	baseURL    string
+	tleURL     string
 	httpClient *http.Client
 }
 
-func NewISSClient(baseURL string) ISSClient {
+func NewISSClient(baseURL, tleURL string) ISSClient {
 	return &issClient{
 		baseURL: baseURL,
+		tleURL:  tleURL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
@@ -57,3 +61,31 @@ func (c *issClient) GetCurrentPosition(ctx context.Context) (map[string]interfac
 
 	return data, nil
 }
+
+func (c *issClient) GetTLE(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.tleURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// TLE - три короткие строки, больше 4 КБ быть не может
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to read TLE: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("TLE API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return string(body), nil
+}
@@ -13,6 +13,8 @@ type NASAClient interface {
 	FetchOSDR(ctx context.Context) ([]map[string]interface{}, error)
 	FetchAPOD(ctx context.Context, date string) (map[string]interface{}, error)
 	FetchNEOFeed(ctx context.Context, days int) (map[string]interface{}, error)
+	// FetchNEOFeedRange лента NeoWs за произвольные даты (API допускает не больше 7 суток)
+	FetchNEOFeedRange(ctx context.Context, start, end time.Time) (map[string]interface{}, error)
 	FetchDONKI(ctx context.Context, eventType string, days int) ([]map[string]interface{}, error)
 }
 
@@ -151,13 +153,22 @@ func (c *nasaClient) FetchNEOFeed(ctx context.Context, days int) (map[string]int
 		days = 7
 	}
 
-	endDate := time.Now().UTC().Format("2006-01-02")
-	startDate := time.Now().UTC().AddDate(0, 0, -days).Format("2006-01-02")
+	now := time.Now().UTC()
+	return c.FetchNEOFeedRange(ctx, now.AddDate(0, 0, -days), now)
+}
+
+func (c *nasaClient) FetchNEOFeedRange(ctx context.Context, start, end time.Time) (map[string]interface{}, error) {
+	if end.Before(start) {
+		return nil, fmt.Errorf("invalid NEO range: %s > %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	if end.Sub(start) > 7*24*time.Hour {
+		end = start.AddDate(0, 0, 7)
+	}
 
 	reqURL := c.neoURL
 	params := url.Values{}
-	params.Add("start_date", startDate)
-	params.Add("end_date", endDate)
+	params.Add("start_date", start.UTC().Format("2006-01-02"))
+	params.Add("end_date", end.UTC().Format("2006-01-02"))
 	if c.apiKey != "" {
 		params.Add("api_key", c.apiKey)
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,6 +29,7 @@ type Config struct {
 	}
 	ISS struct {
 		URL      string
+		TLEURL   string
 		Interval time.Duration
 	}
 	NASA struct {
@@ -47,13 +49,31 @@ type Config struct {
 		Secret  string
 		BaseURL string
 	}
+	JWSTCatalog struct {
+		PagesPerRun int
+		PerPage     int
+		FITSPerRun  int
+	}
+	FITS struct {
+		CacheDir       string
+		ProductURL     string
+		MaxHeaderBytes int
+		ChunkSize      int
+	}
 	Workers struct {
 		ISSEnabled        bool
 		NASAEnabled       bool
 		TelemetryEnabled  bool
+		JWSTEnabled       bool
 		ISSInterval       time.Duration
 		NASAInterval      time.Duration
 		TelemetryInterval time.Duration
+		JWSTInterval      time.Duration
+		// RollupInterval период досчета сводок телеметрии
+		RollupInterval time.Duration
+		// RetentionEnabled архивация и удаление данных старше сроков хранения
+		RetentionEnabled  bool
+		RetentionInterval time.Duration
 	}
 	RateLimit struct {
 		RequestsPerSecond int
@@ -62,6 +82,63 @@ type Config struct {
 	Telemetry struct {
 		OutputDir string
 	}
+	TelemetryIngest struct {
+		// Tokens токен доступа по имени источника (наземной станции)
+		Tokens         map[string]string
+		BatchSize      int
+		FlushInterval  time.Duration
+		QueueSize      int
+		MaxBodyBytes   int64
+		EnqueueTimeout time.Duration
+		DeadLetterDir  string
+	}
+	TelemetryAnomaly struct {
+		// Window число предыдущих значений канала для скользящего z-score
+		Window        int
+		MinSamples    int
+		WarnSigma     float64
+		CriticalSigma float64
+		EWMAAlpha     float64
+		// MaxGap после такого перерыва в данных статистика канала набирается заново
+		MaxGap time.Duration
+	}
+	TelemetryAlert struct {
+		// Interval период плановой проверки правил по данным в базе
+		Interval      time.Duration
+		NotifyTimeout time.Duration
+		WebhookURL    string
+		WebhookSecret string
+		SMTPHost      string
+		SMTPPort      int
+		SMTPUsername  string
+		SMTPPassword  string
+		SMTPFrom      string
+		SMTPTo        []string
+	}
+	TelemetryRollup struct {
+		BatchSize int
+	}
+	Retention struct {
+		ArchiveDir    string
+		DryRun        bool
+		MaxDaysPerRun int
+		// Сроки хранения в сутках; 0 - таблица не очищается
+		TelemetryDays  int
+		AnomalyDays    int
+		ISSLogDays     int
+		SpaceCacheDays int
+		ExportDays     int
+		ExportDir      string
+		AdminToken     string
+	}
+	Media struct {
+		CacheDir      string
+		AllowedHosts  []string
+		ThumbWidths   []int
+		MaxCacheBytes int64
+		MaxSourceSize int64
+		MaxPixels     int64
+	}
 }
 
 func Load() *Config {
@@ -69,6 +146,51 @@ func Load() *Config {
 
 	cfg.Telemetry.OutputDir = getEnv("TELEMETRY_OUTPUT_DIR", "./data/telemetry")
 
+	// Прием показаний по HTTP: TELEMETRY_INGEST_TOKENS="station-1:token1,station-2:token2"
+	cfg.TelemetryIngest.Tokens = getEnvAsMap("TELEMETRY_INGEST_TOKENS")
+	cfg.TelemetryIngest.BatchSize = getEnvAsInt("TELEMETRY_INGEST_BATCH_SIZE", 500)
+	cfg.TelemetryIngest.FlushInterval = getEnvAsDuration("TELEMETRY_INGEST_FLUSH_INTERVAL", time.Second)
+	cfg.TelemetryIngest.QueueSize = getEnvAsInt("TELEMETRY_INGEST_QUEUE_SIZE", 50000)
+	cfg.TelemetryIngest.MaxBodyBytes = int64(getEnvAsInt("TELEMETRY_INGEST_MAX_BODY_MB", 10)) << 20
+	cfg.TelemetryIngest.EnqueueTimeout = getEnvAsDuration("TELEMETRY_INGEST_ENQUEUE_TIMEOUT", 2*time.Second)
+	cfg.TelemetryIngest.DeadLetterDir = getEnv("TELEMETRY_INGEST_DEAD_LETTER_DIR", "./data/ingest-deadletter")
+
+	// Детекторы аномалий телеметрии
+	cfg.TelemetryAnomaly.Window = getEnvAsInt("TELEMETRY_ANOMALY_WINDOW", 60)
+	cfg.TelemetryAnomaly.MinSamples = getEnvAsInt("TELEMETRY_ANOMALY_MIN_SAMPLES", 10)
+	cfg.TelemetryAnomaly.WarnSigma = getEnvAsFloat("TELEMETRY_ANOMALY_WARN_SIGMA", 3)
+	cfg.TelemetryAnomaly.CriticalSigma = getEnvAsFloat("TELEMETRY_ANOMALY_CRITICAL_SIGMA", 5)
+	cfg.TelemetryAnomaly.EWMAAlpha = getEnvAsFloat("TELEMETRY_ANOMALY_EWMA_ALPHA", 0.3)
+	cfg.TelemetryAnomaly.MaxGap = getEnvAsDuration("TELEMETRY_ANOMALY_MAX_GAP", time.Hour)
+
+	// Правила тревоги: канал уведомлений включается заданием адреса (webhook URL, SMTP host)
+	cfg.TelemetryAlert.Interval = getEnvAsDuration("TELEMETRY_ALERT_INTERVAL", time.Minute)
+	cfg.TelemetryAlert.NotifyTimeout = getEnvAsDuration("TELEMETRY_ALERT_NOTIFY_TIMEOUT", 10*time.Second)
+	cfg.TelemetryAlert.WebhookURL = getEnv("TELEMETRY_ALERT_WEBHOOK_URL", "")
+	cfg.TelemetryAlert.WebhookSecret = getEnv("TELEMETRY_ALERT_WEBHOOK_SECRET", "")
+	cfg.TelemetryAlert.SMTPHost = getEnv("TELEMETRY_ALERT_SMTP_HOST", "")
+	cfg.TelemetryAlert.SMTPPort = getEnvAsInt("TELEMETRY_ALERT_SMTP_PORT", 587)
+	cfg.TelemetryAlert.SMTPUsername = getEnv("TELEMETRY_ALERT_SMTP_USERNAME", "")
+	cfg.TelemetryAlert.SMTPPassword = getEnv("TELEMETRY_ALERT_SMTP_PASSWORD", "")
+	cfg.TelemetryAlert.SMTPFrom = getEnv("TELEMETRY_ALERT_SMTP_FROM", "")
+	cfg.TelemetryAlert.SMTPTo = getEnvAsSlice("TELEMETRY_ALERT_SMTP_TO", nil)
+
+	// Сводки телеметрии 1m/1h/1d
+	cfg.TelemetryRollup.BatchSize = getEnvAsInt("TELEMETRY_ROLLUP_BATCH_SIZE", 50000)
+
+	// Сроки хранения: строки старше срока выгружаются в <archive>/<table>/YYYY/MM/*.csv.gz и удаляются
+	cfg.Retention.ArchiveDir = getEnv("RETENTION_ARCHIVE_DIR", "./data/archive")
+	cfg.Retention.DryRun = getEnvAsBool("RETENTION_DRY_RUN", false)
+	cfg.Retention.MaxDaysPerRun = getEnvAsInt("RETENTION_MAX_DAYS_PER_RUN", 31)
+	cfg.Retention.TelemetryDays = getEnvAsInt("RETENTION_TELEMETRY_DAYS", 90)
+	cfg.Retention.AnomalyDays = getEnvAsInt("RETENTION_TELEMETRY_ANOMALY_DAYS", 365)
+	cfg.Retention.ISSLogDays = getEnvAsInt("RETENTION_ISS_LOG_DAYS", 30)
+	cfg.Retention.SpaceCacheDays = getEnvAsInt("RETENTION_SPACE_CACHE_DAYS", 7)
+	cfg.Retention.ExportDays = getEnvAsInt("RETENTION_TELEMETRY_EXPORT_DAYS", 14)
+	cfg.Retention.ExportDir = cfg.Telemetry.OutputDir
+	// Без токена запуск с удалением по HTTP запрещен - его выполняет только воркер
+	cfg.Retention.AdminToken = getEnv("RETENTION_ADMIN_TOKEN", "")
+
 	// App
 	cfg.App.Port = getEnv("PORT", "8080")
 	cfg.App.Debug = getEnvAsBool("DEBUG", false)
@@ -90,6 +212,7 @@ func Load() *Config {
 
 	// ISS
 	cfg.ISS.URL = getEnv("ISS_URL", "https://api.wheretheiss.at/v1/satellites/25544")
+	cfg.ISS.TLEURL = getEnv("ISS_TLE_URL", "https://celestrak.org/NORAD/elements/gp.php?CATNR=25544&FORMAT=TLE")
 	cfg.ISS.Interval = getEnvAsDuration("ISS_INTERVAL", 120*time.Second)
 
 	// NASA
@@ -104,6 +227,18 @@ func Load() *Config {
 	cfg.JWST.APIKey = getEnv("JWST_API_KEY", "")
 	cfg.JWST.Email = getEnv("JWST_EMAIL", "")
 
+	// JWST каталог
+	cfg.JWSTCatalog.PagesPerRun = getEnvAsInt("JWST_CATALOG_PAGES_PER_RUN", 5)
+	cfg.JWSTCatalog.PerPage = getEnvAsInt("JWST_CATALOG_PER_PAGE", 100)
+	cfg.JWSTCatalog.FITSPerRun = getEnvAsInt("JWST_CATALOG_FITS_PER_RUN", 20)
+
+	// Заголовки FITS (%s в ProductURL заменяется именем файла продукта)
+	cfg.FITS.CacheDir = getEnv("FITS_CACHE_DIR", "./data/fits")
+	cfg.FITS.ProductURL = getEnv("FITS_PRODUCT_URL",
+		"https://mast.stsci.edu/api/v0.1/Download/file?uri=mast:JWST/product/%s.fits")
+	cfg.FITS.MaxHeaderBytes = getEnvAsInt("FITS_MAX_HEADER_KB", 1024) << 10
+	cfg.FITS.ChunkSize = getEnvAsInt("FITS_RANGE_CHUNK_KB", 56) << 10
+
 	// Astro
 	cfg.Astro.AppID = getEnv("ASTRO_APP_ID", "")
 	cfg.Astro.Secret = getEnv("ASTRO_APP_SECRET", "")
@@ -113,14 +248,28 @@ func Load() *Config {
 	cfg.Workers.ISSEnabled = getEnvAsBool("ISS_ENABLED", true)
 	cfg.Workers.NASAEnabled = getEnvAsBool("NASA_ENABLED", true)
 	cfg.Workers.TelemetryEnabled = getEnvAsBool("TELEMETRY_ENABLED", true)
+	cfg.Workers.JWSTEnabled = getEnvAsBool("JWST_ENABLED", true)
 	cfg.Workers.ISSInterval = getEnvAsDuration("WORKER_ISS_INTERVAL", 120*time.Second)
 	cfg.Workers.NASAInterval = getEnvAsDuration("WORKER_NASA_INTERVAL", 3600*time.Second)
 	cfg.Workers.TelemetryInterval = getEnvAsDuration("WORKER_TELEMETRY_INTERVAL", 300*time.Second)
+	cfg.Workers.JWSTInterval = getEnvAsDuration("WORKER_JWST_INTERVAL", 1800*time.Second)
+	cfg.Workers.RollupInterval = getEnvAsDuration("WORKER_TELEMETRY_ROLLUP_INTERVAL", 60*time.Second)
+	cfg.Workers.RetentionEnabled = getEnvAsBool("RETENTION_ENABLED", true)
+	cfg.Workers.RetentionInterval = getEnvAsDuration("WORKER_RETENTION_INTERVAL", 6*time.Hour)
 
 	// Rate Limit
 	cfg.RateLimit.RequestsPerSecond = getEnvAsInt("RATE_LIMIT_RPS", 10)
 	cfg.RateLimit.Burst = getEnvAsInt("RATE_LIMIT_BURST", 20)
 
+	// Media proxy
+	cfg.Media.CacheDir = getEnv("MEDIA_CACHE_DIR", "./data/media")
+	cfg.Media.AllowedHosts = getEnvAsSlice("MEDIA_ALLOWED_HOSTS",
+		[]string{"apod.nasa.gov", "stsci-opo.org", "mast.stsci.edu", "img.youtube.com"})
+	cfg.Media.ThumbWidths = getEnvAsIntSlice("MEDIA_THUMB_WIDTHS", []int{320, 640, 1280})
+	cfg.Media.MaxCacheBytes = int64(getEnvAsInt("MEDIA_CACHE_MAX_MB", 1024)) << 20
+	cfg.Media.MaxSourceSize = int64(getEnvAsInt("MEDIA_MAX_SOURCE_MB", 50)) << 20
+	cfg.Media.MaxPixels = int64(getEnvAsInt("MEDIA_MAX_MEGAPIXELS", 100)) * 1000000
+
 	return cfg
 }
 
@@ -140,6 +289,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -157,3 +315,45 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			return items
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsIntSlice(key string, defaultValue []int) []int {
+	if value := os.Getenv(key); value != "" {
+		var items []int
+		for _, item := range strings.Split(value, ",") {
+			if intValue, err := strconv.Atoi(strings.TrimSpace(item)); err == nil && intValue > 0 {
+				items = append(items, intValue)
+			}
+		}
+		if len(items) > 0 {
+			return items
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMap разбирает пары "ключ:значение" через запятую
+func getEnvAsMap(key string) map[string]string {
+	items := make(map[string]string)
+	for _, pair := range getEnvAsSlice(key, nil) {
+		k, v, ok := strings.Cut(pair, ":")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); ok && k != "" && v != "" {
+			items[k] = v
+		}
+	}
+	return items
+}
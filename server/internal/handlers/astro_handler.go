@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,21 +12,24 @@ import (
 )
 
 type AstroHandler struct {
-	service service.AstroService
+	service   service.AstroService
+	locations service.LocationService
 }
 
-func NewAstroHandler(service service.AstroService) *AstroHandler {
-	return &AstroHandler{service: service}
+func NewAstroHandler(service service.AstroService, locations service.LocationService) *AstroHandler {
+	return &AstroHandler{service: service, locations: locations}
 }
 
 func (h *AstroHandler) GetAstroEvents(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	lat, _ := strconv.ParseFloat(c.DefaultQuery("lat", "55.7558"), 64)
-	lon, _ := strconv.ParseFloat(c.DefaultQuery("lon", "37.6176"), 64)
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
-	events, err := h.service.GetEvents(ctx, lat, lon, days)
+	events, err := h.service.GetEvents(ctx, *observer, days)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get astronomy events",
@@ -37,13 +41,10 @@ func (h *AstroHandler) GetAstroEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"events": events,
-			"count":  len(events),
-			"location": gin.H{
-				"lat": lat,
-				"lon": lon,
-			},
-			"days": days,
+			"events":   events,
+			"count":    len(events),
+			"location": observer,
+			"days":     days,
 		},
 	})
 }
@@ -70,6 +71,11 @@ func (h *AstroHandler) GetCelestialBodies(c *gin.Context) {
 func (h *AstroHandler) GetMoonPhase(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
 	dateStr := c.Query("date")
 	var date time.Time
 	var err error
@@ -86,7 +92,7 @@ func (h *AstroHandler) GetMoonPhase(c *gin.Context) {
 		date = time.Now()
 	}
 
-	phase, err := h.service.GetMoonPhase(ctx, date)
+	phase, err := h.service.GetMoonPhase(ctx, *observer, date)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get moon phase",
@@ -101,3 +107,124 @@ func (h *AstroHandler) GetMoonPhase(c *gin.Context) {
 		"date":    date.Format("2006-01-02"),
 	})
 }
+
+func (h *AstroHandler) GetSkyTimes(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
+	var date time.Time
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+		date = parsed
+	}
+
+	times, err := h.service.GetSkyTimes(ctx, *observer, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to compute sun and moon times",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    times,
+	})
+}
+
+func (h *AstroHandler) GetPositions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
+	var at time.Time
+	if timeStr := c.Query("time"); timeStr != "" {
+		parsed, err := time.Parse(time.RFC3339, timeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid time format, use RFC 3339 (2006-01-02T15:04:05Z)",
+			})
+			return
+		}
+		at = parsed
+	}
+
+	positions, err := h.service.GetPositions(ctx, *observer, at)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to compute positions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    positions,
+	})
+}
+
+func (h *AstroHandler) GetAlmanac(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
+	year := time.Now().Year()
+	if yearStr := c.Query("year"); yearStr != "" {
+		parsed, err := strconv.Atoi(yearStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid year",
+			})
+			return
+		}
+		year = parsed
+	}
+
+	toYear := 0
+	if toYearStr := c.Query("to_year"); toYearStr != "" {
+		parsed, err := strconv.Atoi(toYearStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid to_year",
+			})
+			return
+		}
+		toYear = parsed
+	}
+
+	almanac, err := h.service.GetAlmanac(ctx, *observer, year, toYear)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAlmanacInvalid) || errors.Is(err, service.ErrLocationInvalid) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "failed to compute almanac",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    almanac,
+	})
+}
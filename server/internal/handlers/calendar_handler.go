@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	service   service.CalendarService
+	locations service.LocationService
+}
+
+func NewCalendarHandler(service service.CalendarService, locations service.LocationService) *CalendarHandler {
+	return &CalendarHandler{service: service, locations: locations}
+}
+
+// GetAstroCalendar отдает события в формате iCalendar для подписки:
+// /astro/events.ics?location_id=1&days=14&include=astro,iss,neo
+func (h *CalendarHandler) GetAstroCalendar(c *gin.Context) {
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	include, err := service.ParseCalendarInclude(c.Query("include"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	calendar, err := h.service.AstroCalendar(c.Request.Context(), *observer, service.CalendarOptions{
+		Days:    days,
+		Include: include,
+	})
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, service.ErrLocationInvalid) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "failed to build calendar",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="astro-events.ics"`)
+	c.Header("Cache-Control", "public, max-age=1800")
+	c.Status(http.StatusOK)
+	if err := calendar.Encode(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
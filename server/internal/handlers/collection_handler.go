@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// collectionExportWriteTimeout срок на отправку ZIP коллекции вместе со скачиванием изображений
+const collectionExportWriteTimeout = time.Hour
+
+type CollectionHandler struct {
+	service service.CollectionService
+}
+
+func NewCollectionHandler(service service.CollectionService) *CollectionHandler {
+	return &CollectionHandler{service: service}
+}
+
+func (h *CollectionHandler) ListCollections(c *gin.Context) {
+	collections, err := h.service.List(c.Request.Context())
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collections,
+	})
+}
+
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	var input service.CollectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.service.Create(c.Request.Context(), input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	collection, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input service.CollectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.service.Update(c.Request.Context(), id, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (h *CollectionHandler) AddItem(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input service.CollectionItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	item, err := h.service.AddItem(c.Request.Context(), id, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    item,
+	})
+}
+
+func (h *CollectionHandler) UpdateItem(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseIDParam(c, "item_id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	item, err := h.service.UpdateItem(c.Request.Context(), id, itemID, input.Note)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    item,
+	})
+}
+
+func (h *CollectionHandler) RemoveItem(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := parseIDParam(c, "item_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveItem(c.Request.Context(), id, itemID); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+func (h *CollectionHandler) ReorderItems(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ItemIDs []uint `json:"item_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	collection, err := h.service.Reorder(c.Request.Context(), id, input.ItemIDs)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    collection,
+	})
+}
+
+func (h *CollectionHandler) ExportCollection(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := h.service.Export(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename()))
+	// Архив собирается по ходу скачивания изображений - общий WriteTimeout сервера тут мал
+	extendDeadlines(c, 0, collectionExportWriteTimeout)
+	c.Status(http.StatusOK)
+
+	if err := export.WriteZip(c.Request.Context(), c.Writer); err != nil {
+		// Заголовки уже отправлены: обрываем соединение, чтобы архив без оглавления не выглядел целым
+		log.Printf("Failed to stream collection %d export: %v", id, err)
+		abortStream(c)
+	}
+}
+
+func (h *CollectionHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCollectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "collection not found",
+		})
+	case errors.Is(err, service.ErrCollectionItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "collection item not found",
+		})
+	case errors.Is(err, service.ErrCollectionItemExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "item is already in the collection",
+		})
+	case errors.Is(err, service.ErrCollectionInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "collection request failed",
+			"message": err.Error(),
+		})
+	}
+}
+
+// parseIDParam разбирает числовой параметр пути; при ошибке сам отвечает 400
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid " + name,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -15,6 +15,7 @@ type DashboardHandler struct {
 	jwstService      service.JWSTService
 	astroService     service.AstroService
 	telemetryService service.TelemetryService
+	locationService  service.LocationService
 }
 
 func NewDashboardHandler(
@@ -23,6 +24,7 @@ func NewDashboardHandler(
 	jwstService service.JWSTService,
 	astroService service.AstroService,
 	telemetryService service.TelemetryService,
+	locationService service.LocationService,
 ) *DashboardHandler {
 	return &DashboardHandler{
 		issService:       issService,
@@ -30,6 +32,7 @@ func NewDashboardHandler(
 		jwstService:      jwstService,
 		astroService:     astroService,
 		telemetryService: telemetryService,
+		locationService:  locationService,
 	}
 }
 
@@ -38,12 +41,18 @@ func NewDashboardHandler(
 // @Description Возвращает все данные для главного дашборда в одном запросе
 // @Tags Dashboard
 // @Produce json
+// @Param location_id query int false "Сохраненное место наблюдения для астрономических событий"
 // @Success 200 {object} DashboardResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /dashboard [get]
 func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	observer, ok := ResolveObserver(c, h.locationService)
+	if !ok {
+		return
+	}
+
 	// Собираем данные параллельно
 	type dashboardData struct {
 		ISS       interface{} `json:"iss,omitempty"`
@@ -89,15 +98,15 @@ func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
 	}
 
 	// 4. JWST изображения (первые 12)
-	jwstImages, err := h.jwstService.GetFeed(ctx, "jpg", "", "", "", 1, 12)
+	jwstFeed, err := h.jwstService.GetFeed(ctx, service.JWSTFeedFilter{Source: "jpg", Page: 1, PerPage: 12})
 	if err != nil {
 		errors = append(errors, "JWST: "+err.Error())
 	} else {
-		data.JWST = jwstImages
+		data.JWST = jwstFeed.Images
 	}
 
 	// 5. Астрономические события
-	astroEvents, err := h.astroService.GetEvents(ctx, 55.7558, 37.6176, 7)
+	astroEvents, err := h.astroService.GetEvents(ctx, *observer, 7)
 	if err != nil {
 		errors = append(errors, "Astronomy: "+err.Error())
 	} else {
@@ -105,11 +114,11 @@ func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
 	}
 
 	// 6. Последние данные телеметрии
-	telemetry, err := h.telemetryService.GetTelemetryHistory(ctx, time.Now().Add(-24*time.Hour), time.Now())
+	telemetry, err := h.telemetryService.GetTelemetryHistory(ctx, time.Now().Add(-24*time.Hour), time.Now(), nil)
 	if err != nil {
 		errors = append(errors, "Telemetry: "+err.Error())
 	} else {
-		data.Telemetry = telemetry
+		data.Telemetry = telemetry.Records
 	}
 
 	// 8. Сводная статистика
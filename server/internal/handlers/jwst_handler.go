@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"cassiopeia/internal/service"
 
@@ -27,8 +30,32 @@ func (h *JWSTHandler) GetJWSTFeed(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	perPage, _ := strconv.Atoi(c.DefaultQuery("perPage", "24"))
 
-	images, err := h.service.GetFeed(ctx, source, suffix, program, instrument, page, perPage)
+	observedFrom, observedTo, err := parseObservedRange(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	feed, err := h.service.GetFeed(ctx, service.JWSTFeedFilter{
+		Source:       source,
+		Suffix:       suffix,
+		Program:      program,
+		Instrument:   instrument,
+		ObservedFrom: observedFrom,
+		ObservedTo:   observedTo,
+		Page:         page,
+		PerPage:      perPage,
+		Cursor:       c.Query("cursor"),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrJWSTInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid cursor",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get JWST feed",
 			"message": err.Error(),
@@ -39,11 +66,47 @@ func (h *JWSTHandler) GetJWSTFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"images":  images,
-			"count":   len(images),
+			"images":  feed.Images,
+			"count":   len(feed.Images),
 			"page":    page,
 			"perPage": perPage,
 			"source":  source,
+			"next":    feed.Next,
+		},
+	})
+}
+
+func (h *JWSTHandler) SearchJWST(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := c.Query("q")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("perPage", "24"))
+
+	result, err := h.service.Search(ctx, query, page, perPage)
+	if err != nil {
+		if errors.Is(err, service.ErrJWSTEmptyQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "q parameter is required",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to search JWST images",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"query":   result.Query,
+			"images":  result.Images,
+			"count":   len(result.Images),
+			"total":   result.Total,
+			"page":    result.Page,
+			"perPage": result.PerPage,
 		},
 	})
 }
@@ -61,8 +124,14 @@ func (h *JWSTHandler) GetJWSTObservation(c *gin.Context) {
 
 	observation, err := h.service.GetObservation(ctx, observationID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "observation not found",
+		if errors.Is(err, service.ErrJWSTNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "observation not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get observation",
 			"message": err.Error(),
 		})
 		return
@@ -74,6 +143,38 @@ func (h *JWSTHandler) GetJWSTObservation(c *gin.Context) {
 	})
 }
 
+func (h *JWSTHandler) GetJWSTProgramSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	programID := c.Param("id")
+	if programID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "program ID is required",
+		})
+		return
+	}
+
+	summary, err := h.service.GetProgramSummary(ctx, programID)
+	if err != nil {
+		if errors.Is(err, service.ErrJWSTNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "program not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get program summary",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+	})
+}
+
 func (h *JWSTHandler) GetJWSTProgram(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -108,3 +209,28 @@ func (h *JWSTHandler) GetJWSTProgram(c *gin.Context) {
 		},
 	})
 }
+
+// parseObservedRange разбирает observed_from/observed_to в формате YYYY-MM-DD
+func parseObservedRange(c *gin.Context) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	if fromStr := c.Query("observed_from"); fromStr != "" {
+		t, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid observed_from date format, use YYYY-MM-DD")
+		}
+		from = &t
+	}
+
+	if toStr := c.Query("observed_to"); toStr != "" {
+		t, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid observed_to date format, use YYYY-MM-DD")
+		}
+		// Включаем весь последний день
+		t = t.Add(24*time.Hour - time.Nanosecond)
+		to = &t
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Точка наблюдения по умолчанию - Москва
+const (
+	defaultObserverLat      = "55.7558"
+	defaultObserverLon      = "37.6176"
+	defaultObserverTimezone = "Europe/Moscow"
+)
+
+type LocationHandler struct {
+	service service.LocationService
+}
+
+func NewLocationHandler(service service.LocationService) *LocationHandler {
+	return &LocationHandler{service: service}
+}
+
+func (h *LocationHandler) ListLocations(c *gin.Context) {
+	locations, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    locations,
+	})
+}
+
+func (h *LocationHandler) CreateLocation(c *gin.Context) {
+	var input service.LocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	location, err := h.service.Create(c.Request.Context(), input)
+	if err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    location,
+	})
+}
+
+func (h *LocationHandler) GetLocation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	location, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    location,
+	})
+}
+
+func (h *LocationHandler) UpdateLocation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input service.LocationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	location, err := h.service.Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    location,
+	})
+}
+
+func (h *LocationHandler) DeleteLocation(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// ResolveObserver определяет наблюдателя по location_id или lat/lon (по умолчанию - Москва)
+// и необязательному timezone; при ошибке сам отвечает клиенту
+func ResolveObserver(c *gin.Context, locations service.LocationService) (*service.Observer, bool) {
+	if idStr := c.Query("location_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid location_id",
+			})
+			return nil, false
+		}
+
+		observer, err := locations.Observer(c.Request.Context(), uint(id))
+		if err != nil {
+			respondLocationError(c, err)
+			return nil, false
+		}
+		return observer, true
+	}
+
+	lat, errLat := strconv.ParseFloat(c.DefaultQuery("lat", defaultObserverLat), 64)
+	lon, errLon := strconv.ParseFloat(c.DefaultQuery("lon", defaultObserverLon), 64)
+	if errLat != nil || errLon != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid lat/lon",
+		})
+		return nil, false
+	}
+
+	timezone := c.Query("timezone")
+	if timezone == "" && c.Query("lat") == "" && c.Query("lon") == "" {
+		timezone = defaultObserverTimezone
+	}
+	observer, err := locations.ObserverAt(lat, lon, timezone)
+	if err != nil {
+		respondLocationError(c, err)
+		return nil, false
+	}
+	return observer, true
+}
+
+// SuggestTimezone пояса-кандидаты по координатам для формы места: пользователь выбирает
+// один из них, и он сохраняется явно
+func (h *LocationHandler) SuggestTimezone(c *gin.Context) {
+	lat, errLat := strconv.ParseFloat(c.Query("lat"), 64)
+	lon, errLon := strconv.ParseFloat(c.Query("lon"), 64)
+	if errLat != nil || errLon != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid lat/lon",
+		})
+		return
+	}
+
+	suggestions, err := h.service.SuggestTimezone(lat, lon)
+	if err != nil {
+		respondLocationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    suggestions,
+	})
+}
+
+func respondLocationError(c *gin.Context, err error) {
+	var timezoneErr *service.TimezoneError
+	switch {
+	case errors.As(err, &timezoneErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":       err.Error(),
+			"suggestions": timezoneErr.Suggestions,
+		})
+	case errors.Is(err, service.ErrLocationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "location not found",
+		})
+	case errors.Is(err, service.ErrLocationInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "location request failed",
+			"message": err.Error(),
+		})
+	}
+}
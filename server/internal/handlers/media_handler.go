@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaHandler struct {
+	service service.MediaService
+}
+
+func NewMediaHandler(service service.MediaService) *MediaHandler {
+	return &MediaHandler{service: service}
+}
+
+func (h *MediaHandler) Proxy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	src := c.Query("src")
+	if src == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "src parameter is required",
+		})
+		return
+	}
+
+	width := 0
+	if widthStr := c.Query("w"); widthStr != "" {
+		w, err := strconv.Atoi(widthStr)
+		if err != nil || w < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid width",
+			})
+			return
+		}
+		width = w
+	}
+
+	// Формат миниатюры: явно из запроса или по заголовку Accept
+	format := c.Query("format")
+	if format == "" && width > 0 && strings.Contains(c.GetHeader("Accept"), "image/webp") {
+		format = "webp"
+	}
+
+	media, err := h.service.Fetch(ctx, src, width, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrMediaHostNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "media host is not allowed",
+			})
+		case errors.Is(err, service.ErrMediaInvalidSource):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid src URL",
+			})
+		case errors.Is(err, service.ErrMediaWidthInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "unsupported width",
+				"widths": h.service.ThumbWidths(),
+			})
+		case errors.Is(err, service.ErrMediaFormatInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "unsupported format, use 'jpeg' or 'webp'",
+			})
+		case errors.Is(err, service.ErrMediaTooLarge):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "image is too large for a thumbnail",
+				"message": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "failed to fetch media",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	file, err := os.Open(media.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to open cached media",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", media.ContentType)
+	c.Header("ETag", media.ETag)
+	c.Header("Cache-Control", "public, max-age=604800")
+	c.Header("Vary", "Accept")
+
+	// ServeContent сам обрабатывает If-None-Match и Range
+	http.ServeContent(c.Writer, c.Request, filepath.Base(media.Path), media.ModTime, file)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ObservingHandler struct {
+	service   service.ObservingService
+	locations service.LocationService
+}
+
+func NewObservingHandler(service service.ObservingService, locations service.LocationService) *ObservingHandler {
+	return &ObservingHandler{service: service, locations: locations}
+}
+
+// GetObservingWindow оценка ночи для наблюдений:
+// /astro/observing-window?location_id=1&date=2025-01-15
+func (h *ObservingHandler) GetObservingWindow(c *gin.Context) {
+	observer, ok := ResolveObserver(c, h.locations)
+	if !ok {
+		return
+	}
+
+	var date time.Time
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid date format, use YYYY-MM-DD",
+			})
+			return
+		}
+		date = parsed
+	}
+
+	window, err := h.service.GetObservingWindow(c.Request.Context(), *observer, date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to evaluate observing conditions",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    window,
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	service service.RetentionService
+}
+
+func NewRetentionHandler(service service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{service: service}
+}
+
+func (h *RetentionHandler) GetPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.service.Policies(),
+	})
+}
+
+// Run ручной запуск очистки; по умолчанию dry_run=true - только отчет без удаления.
+// Запуск с удалением требует токен RETENTION_ADMIN_TOKEN в Authorization: Bearer или X-Admin-Token.
+func (h *RetentionHandler) Run(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "true"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid dry_run, use true or false",
+		})
+		return
+	}
+
+	if !dryRun {
+		token := strings.TrimSpace(c.GetHeader("X-Admin-Token"))
+		if auth := c.GetHeader("Authorization"); token == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			token = strings.TrimSpace(auth[7:])
+		}
+
+		if err := h.service.AuthorizeRun(token); err != nil {
+			if errors.Is(err, service.ErrRetentionRunDisabled) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "retention runs with deletion are applied by the scheduled worker only",
+					"message": err.Error(),
+				})
+				return
+			}
+			c.Header("WWW-Authenticate", `Bearer realm="retention"`)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid or missing admin token",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := h.service.Run(c.Request.Context(), dryRun)
+	if err != nil {
+		if errors.Is(err, service.ErrRetentionBusy) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "retention run failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// ListArchives манифест архивов с фильтром table
+func (h *RetentionHandler) ListArchives(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "50"))
+
+	result, err := h.service.ListArchives(c.Request.Context(), c.Query("table"), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to list retention archives",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SpaceWeatherHandler struct {
+	service service.SpaceWeatherService
+}
+
+func NewSpaceWeatherHandler(service service.SpaceWeatherService) *SpaceWeatherHandler {
+	return &SpaceWeatherHandler{service: service}
+}
+
+func (h *SpaceWeatherHandler) GetSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	summary, err := h.service.GetSummary(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get space weather summary",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+	})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryAlertHandler struct {
+	service service.TelemetryAlertService
+}
+
+func NewTelemetryAlertHandler(service service.TelemetryAlertService) *TelemetryAlertHandler {
+	return &TelemetryAlertHandler{service: service}
+}
+
+func (h *TelemetryAlertHandler) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+	})
+}
+
+func (h *TelemetryAlertHandler) CreateRule(c *gin.Context) {
+	var input service.TelemetryAlertRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), input)
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+func (h *TelemetryAlertHandler) GetRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	rule, err := h.service.GetRule(c.Request.Context(), id)
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+func (h *TelemetryAlertHandler) UpdateRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input service.TelemetryAlertRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), id, input)
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+func (h *TelemetryAlertHandler) DeleteRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), id); err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
+}
+
+// TestRule пробное уведомление по каналам правила; недоставка - не ошибка запроса
+func (h *TelemetryAlertHandler) TestRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.TestRule(c.Request.Context(), id)
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    deliveries,
+	})
+}
+
+// ListAlerts история тревог с фильтрами rule_id, state, source и периодом from/to
+func (h *TelemetryAlertHandler) ListAlerts(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var ruleID uint
+	if value := c.Query("rule_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid rule_id",
+			})
+			return
+		}
+		ruleID = uint(id)
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "100"))
+
+	result, err := h.service.ListAlerts(c.Request.Context(), service.TelemetryAlertQuery{
+		RuleID:  ruleID,
+		State:   c.Query("state"),
+		Source:  c.Query("source"),
+		From:    from,
+		To:      to,
+		Page:    page,
+		PerPage: perPage,
+	})
+	if err != nil {
+		respondTelemetryAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+func respondTelemetryAlertError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTelemetryAlertRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "telemetry alert rule not found",
+		})
+	case errors.Is(err, service.ErrTelemetryAlertRuleExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrTelemetryAlertRuleInvalid), errors.Is(err, service.ErrTelemetryAlertInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "telemetry alert request failed",
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryAnomalyHandler struct {
+	service service.TelemetryAnomalyService
+}
+
+func NewTelemetryAnomalyHandler(service service.TelemetryAnomalyService) *TelemetryAnomalyHandler {
+	return &TelemetryAnomalyHandler{service: service}
+}
+
+// ListAnomalies аномалии за период с фильтрами channel, source, severity, detector
+func (h *TelemetryAnomalyHandler) ListAnomalies(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "100"))
+
+	result, err := h.service.List(c.Request.Context(), service.TelemetryAnomalyQuery{
+		From:     from,
+		To:       to,
+		Channel:  c.Query("channel"),
+		Source:   c.Query("source"),
+		Severity: c.Query("severity"),
+		Detector: c.Query("detector"),
+		Page:     page,
+		PerPage:  perPage,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrTelemetryAnomalyInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid anomaly query",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to list telemetry anomalies",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// parseTimeRange разбирает from/to (YYYY-MM-DD или RFC3339); пустые - нулевые, период задаст сервис
+func parseTimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	var from, to time.Time
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		t, err := parseTimeParam(fromStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid from date format, use YYYY-MM-DD or RFC3339")
+		}
+		from = t
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		t, err := parseTimeParam(toStr)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid to date format, use YYYY-MM-DD or RFC3339")
+		}
+		// Для даты без времени включаем весь последний день
+		if len(toStr) == len("2006-01-02") {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		to = t
+	}
+
+	return from, to, nil
+}
+
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryChannelHandler struct {
+	service service.TelemetryChannelService
+}
+
+func NewTelemetryChannelHandler(service service.TelemetryChannelService) *TelemetryChannelHandler {
+	return &TelemetryChannelHandler{service: service}
+}
+
+func (h *TelemetryChannelHandler) ListChannels(c *gin.Context) {
+	channels, err := h.service.List(c.Request.Context())
+	if err != nil {
+		respondTelemetryChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    channels,
+	})
+}
+
+func (h *TelemetryChannelHandler) CreateChannel(c *gin.Context) {
+	var input service.TelemetryChannelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	channel, err := h.service.Create(c.Request.Context(), input)
+	if err != nil {
+		respondTelemetryChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    channel,
+	})
+}
+
+func (h *TelemetryChannelHandler) GetChannel(c *gin.Context) {
+	channel, err := h.service.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondTelemetryChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    channel,
+	})
+}
+
+func (h *TelemetryChannelHandler) UpdateChannel(c *gin.Context) {
+	var input service.TelemetryChannelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	channel, err := h.service.Update(c.Request.Context(), c.Param("name"), input)
+	if err != nil {
+		respondTelemetryChannelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    channel,
+	})
+}
+
+func respondTelemetryChannelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTelemetryChannelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "telemetry channel not found",
+		})
+	case errors.Is(err, service.ErrTelemetryChannelExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, service.ErrTelemetryChannelInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "telemetry channel request failed",
+			"message": err.Error(),
+		})
+	}
+}
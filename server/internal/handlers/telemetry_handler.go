@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"mime"
 	"net/http"
 	_ "path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"cassiopeia/internal/service"
@@ -11,6 +15,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Общий WriteTimeout сервера (15 с) рассчитан на обычные запросы. Выгрузка получает свой
+// срок: он только защищает от зависшего клиента.
+const telemetryExportWriteTimeout = time.Hour
+
+// Импорт до TelemetryImportMaxBytes: медленная загрузка файла и разбор с дедупликацией
+// и детектором аномалий не укладываются в 15 с, а отчет о проверке - результат запроса
+const telemetryImportTimeout = 10 * time.Minute
+
 type TelemetryHandler struct {
 	service service.TelemetryService
 }
@@ -60,44 +72,35 @@ func (h *TelemetryHandler) GenerateTelemetry(c *gin.Context) {
 	})
 }
 
+// ExportTelemetry выгрузка за период в CSV, NDJSON, JSON или XLSX. Показания читаются курсором
+// и пишутся прямо в ответ (chunked), без промежуточного файла.
 func (h *TelemetryHandler) ExportTelemetry(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	format := c.Query("format")
-	if format == "" {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "format parameter is required (csv, excel, json)",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	// Парсим даты
-	var from, to time.Time
-	var err error
-
-	if fromStr := c.Query("from"); fromStr != "" {
-		from, err = time.Parse("2006-01-02", fromStr)
-		if err != nil {
+	// Формат и каналы проверяются до начала ответа - ошибки еще можно вернуть в JSON
+	export, err := h.service.PrepareExport(ctx, c.DefaultQuery("format", "csv"), from, to, parseChannelsQuery(c))
+	if err != nil {
+		if errors.Is(err, service.ErrTelemetryChannelNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid from date format, use YYYY-MM-DD",
+				"error":   "unknown telemetry channel",
+				"message": err.Error(),
 			})
 			return
 		}
-	}
-
-	if toStr := c.Query("to"); toStr != "" {
-		to, err = time.Parse("2006-01-02", toStr)
-		if err != nil {
+		if errors.Is(err, service.ErrTelemetryExportInvalid) {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid to date format, use YYYY-MM-DD",
+				"error": err.Error(),
 			})
 			return
 		}
-	}
-
-	// Экспортируем данные
-	filepath, err := h.service.ExportTelemetry(ctx, format, from, to)
-	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to export telemetry",
 			"message": err.Error(),
@@ -105,29 +108,50 @@ func (h *TelemetryHandler) ExportTelemetry(c *gin.Context) {
 		return
 	}
 
-	// Определяем Content-Type
-	var contentType string
-	var filename string
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	extendDeadlines(c, 0, telemetryExportWriteTimeout)
+	c.Status(http.StatusOK)
 
-	switch format {
-	case "csv":
-		contentType = "text/csv"
-		filename = "telemetry_export.csv"
-	case "excel", "xlsx":
-		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
-		filename = "telemetry_export.xlsx"
-	case "json":
-		contentType = "application/json"
-		filename = "telemetry_export.json"
-	default:
-		contentType = "application/octet-stream"
-		filename = "telemetry_export." + format
+	if err := h.service.WriteExport(ctx, export, c.Writer); err != nil {
+		// Заголовки уже могли уйти клиенту: обрываем ответ, чтобы неполный файл не выглядел целым
+		log.Printf("Telemetry export %s failed: %v", export.Filename, err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to export telemetry",
+				"message": err.Error(),
+			})
+			return
+		}
+		abortStream(c)
 	}
+}
 
-	// Отправляем файл
-	c.Header("Content-Type", contentType)
-	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.File(filepath)
+// extendDeadlines переносит сроки чтения запроса и записи ответа на read/write от текущего
+// момента вместо общих таймаутов сервера; ноль - срок не меняется
+func extendDeadlines(c *gin.Context, read, write time.Duration) {
+	controller := http.NewResponseController(c.Writer)
+	if read > 0 {
+		if err := controller.SetReadDeadline(time.Now().Add(read)); err != nil {
+			log.Printf("Failed to extend read deadline for %s: %v", c.Request.URL.Path, err)
+		}
+	}
+	if write > 0 {
+		if err := controller.SetWriteDeadline(time.Now().Add(write)); err != nil {
+			log.Printf("Failed to extend write deadline for %s: %v", c.Request.URL.Path, err)
+		}
+	}
+}
+
+// abortStream закрывает соединение посреди ответа: клиент получит ошибку чтения, а не обрезанный файл
+func abortStream(c *gin.Context) {
+	if conn, _, err := http.NewResponseController(c.Writer).Hijack(); err == nil {
+		conn.Close()
+	}
+	c.Abort()
 }
 
 func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
@@ -164,8 +188,15 @@ func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
 	// Получаем данные
-	history, err := h.service.GetTelemetryHistory(ctx, from, to)
+	history, err := h.service.GetTelemetryHistory(ctx, from, to, parseChannelsQuery(c))
 	if err != nil {
+		if errors.Is(err, service.ErrTelemetryChannelNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "unknown telemetry channel",
+				"message": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to get telemetry history",
 			"message": err.Error(),
@@ -174,18 +205,78 @@ func (h *TelemetryHandler) GetTelemetryHistory(c *gin.Context) {
 	}
 
 	// Ограничиваем количество записей
-	if len(history) > limit && limit > 0 {
-		history = history[:limit]
+	if len(history.Records) > limit && limit > 0 {
+		history.Records = history.Records[:limit]
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"telemetry": history,
-			"count":     len(history),
+			"channels":  history.Channels,
+			"telemetry": history.Records,
+			"count":     len(history.Records),
 			"from":      from.Format("2006-01-02"),
 			"to":        to.Format("2006-01-02"),
 			"limit":     limit,
 		},
 	})
 }
+
+// ImportTelemetry загрузка показаний: multipart-поле file с CSV, XLSX, JSON или NDJSON
+func (h *TelemetryHandler) ImportTelemetry(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	extendDeadlines(c, telemetryImportTimeout, telemetryImportTimeout)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.TelemetryImportMaxBytes+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "multipart field 'file' is required",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed to read uploaded file",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.ImportTelemetry(ctx, header.Filename, file)
+	if err != nil {
+		if errors.Is(err, service.ErrTelemetryImportInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid telemetry file",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to import telemetry",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Частичный импорт - не ошибка запроса: отклоненные строки перечислены в отчете
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// parseChannelsQuery список каналов из channels=voltage,temperature; пусто - все
+func parseChannelsQuery(c *gin.Context) []string {
+	var channels []string
+	for _, name := range strings.Split(c.Query("channels"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			channels = append(channels, name)
+		}
+	}
+	return channels
+}
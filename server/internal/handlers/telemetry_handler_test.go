@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowExportService пишет выгрузку построчно с паузами, как курсор по большой таблице;
+// delay - молчание до первого байта, как у XLSX, который собирается целиком перед отправкой
+type slowExportService struct {
+	service.TelemetryService
+	lines int
+	pause time.Duration
+	delay time.Duration
+}
+
+func (s slowExportService) PrepareExport(ctx context.Context, format string, from, to time.Time, channels []string) (*service.TelemetryExport, error) {
+	return &service.TelemetryExport{Format: format, ContentType: "text/csv; charset=utf-8", Filename: "telemetry.csv"}, nil
+}
+
+func (s slowExportService) WriteExport(ctx context.Context, export *service.TelemetryExport, w io.Writer) error {
+	time.Sleep(s.delay)
+	for i := 0; i < s.lines; i++ {
+		if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		time.Sleep(s.pause)
+	}
+	return nil
+}
+
+// slowImportService проверяет файл дольше delay, как импорт с дедупликацией и детектором аномалий
+type slowImportService struct {
+	service.TelemetryService
+	delay time.Duration
+}
+
+func (s slowImportService) ImportTelemetry(ctx context.Context, filename string, r io.Reader) (*service.TelemetryImportReport, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	time.Sleep(s.delay)
+	return &service.TelemetryImportReport{Filename: filename, TotalRows: strings.Count(string(data), "\n"), Imported: strings.Count(string(data), "\n")}, nil
+}
+
+// newDeadlineServer сервер с WriteTimeout как в main.go, только сжатым до timeout,
+// чтобы тест не ждал 15 с
+func newDeadlineServer(t *testing.T, timeout time.Duration, method, route string, handler gin.HandlerFunc) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, route, handler)
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ReadTimeout = timeout
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExportTelemetryOutlivesServerWriteTimeout(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	// Обе выгрузки идут в 4 раза дольше WriteTimeout
+	tests := []struct {
+		name   string
+		export slowExportService
+	}{
+		{"streamed", slowExportService{lines: 10, pause: 4 * timeout / 10}},
+		{"buffered", slowExportService{lines: 10, delay: 4 * timeout}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewTelemetryHandler(tt.export)
+			server := newDeadlineServer(t, timeout, http.MethodGet, "/export", handler.ExportTelemetry)
+
+			resp, err := http.Get(server.URL + "/export?format=csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("export cut off after %d bytes: %v", len(body), err)
+			}
+			if lines := strings.Count(string(body), "\n"); lines != tt.export.lines {
+				t.Fatalf("received %d lines, want %d", lines, tt.export.lines)
+			}
+		})
+	}
+}
+
+func TestImportTelemetryOutlivesServerTimeouts(t *testing.T) {
+	const timeout = 100 * time.Millisecond
+	const lines = 10
+	// Загрузка и проверка идут в 4 раза дольше ReadTimeout и WriteTimeout
+	tests := []struct {
+		name    string
+		pause   time.Duration
+		service slowImportService
+	}{
+		{"slow upload", 4 * timeout / lines, slowImportService{}},
+		{"slow processing", 0, slowImportService{delay: 4 * timeout}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewTelemetryHandler(tt.service)
+			server := newDeadlineServer(t, timeout, http.MethodPost, "/import", handler.ImportTelemetry)
+
+			body, upload := io.Pipe()
+			form := multipart.NewWriter(upload)
+			go func() {
+				part, err := form.CreateFormFile("file", "telemetry.ndjson")
+				for i := 0; i < lines && err == nil; i++ {
+					_, err = fmt.Fprintf(part, `{"recorded_at":"2026-01-02T03:04:%02dZ","values":{"voltage":1}}`+"\n", i)
+					time.Sleep(tt.pause)
+				}
+				if err == nil {
+					err = form.Close()
+				}
+				upload.CloseWithError(err)
+			}()
+
+			resp, err := http.Post(server.URL+"/import", form.FormDataContentType(), body)
+			if err != nil {
+				t.Fatalf("import cut off: %v", err)
+			}
+			defer resp.Body.Close()
+			var result struct {
+				Data service.TelemetryImportReport `json:"data"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("report cut off: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK || result.Data.Imported != lines {
+				t.Fatalf("status %d, imported %d, want 200 and %d", resp.StatusCode, result.Data.Imported, lines)
+			}
+		})
+	}
+}
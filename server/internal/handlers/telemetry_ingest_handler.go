@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryIngestHandler struct {
+	service service.TelemetryIngestService
+}
+
+func NewTelemetryIngestHandler(service service.TelemetryIngestService) *TelemetryIngestHandler {
+	return &TelemetryIngestHandler{service: service}
+}
+
+// Ingest прием показаний от наземных станций: JSON-массив, NDJSON или InfluxDB line protocol.
+// Токен источника - в Authorization: Bearer или X-Telemetry-Token; тело может быть сжато gzip.
+func (h *TelemetryIngestHandler) Ingest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	token := strings.TrimSpace(c.GetHeader("X-Telemetry-Token"))
+	if auth := c.GetHeader("Authorization"); token == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		token = strings.TrimSpace(auth[7:])
+	}
+
+	source, err := h.service.Authenticate(token)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="telemetry"`)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid or missing telemetry token",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.Ingest(ctx, source, service.TelemetryIngestRequest{
+		ContentType:     c.GetHeader("Content-Type"),
+		ContentEncoding: c.GetHeader("Content-Encoding"),
+		Format:          c.Query("format"),
+		Precision:       c.Query("precision"),
+		Body:            c.Request.Body,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTelemetryIngestInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid telemetry payload",
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrTelemetryIngestTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "telemetry payload is too large",
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrTelemetryIngestBusy):
+			// База не успевает за входящим потоком - клиент повторяет запрос позже
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "telemetry ingest is overloaded, retry later",
+				"message": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to ingest telemetry",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	// Показания записываются в базу асинхронно пачками
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
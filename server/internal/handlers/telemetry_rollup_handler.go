@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryRollupHandler struct {
+	service service.TelemetryRollupService
+}
+
+func NewTelemetryRollupHandler(service service.TelemetryRollupService) *TelemetryRollupHandler {
+	return &TelemetryRollupHandler{service: service}
+}
+
+// GetRollups агрегаты каналов; resolution=1m|1h|1d, по умолчанию выбирается по длине периода
+func (h *TelemetryRollupHandler) GetRollups(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	series, err := h.service.Query(c.Request.Context(), service.TelemetryRollupQuery{
+		From:       from,
+		To:         to,
+		Channels:   parseChannelsQuery(c),
+		Resolution: c.Query("resolution"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTelemetryRollupInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrTelemetryChannelNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "unknown telemetry channel",
+				"message": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to get telemetry rollups",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    series,
+	})
+}
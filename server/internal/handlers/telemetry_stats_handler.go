@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cassiopeia/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TelemetryStatsHandler struct {
+	service service.TelemetryStatsService
+}
+
+func NewTelemetryStatsHandler(service service.TelemetryStatsService) *TelemetryStatsHandler {
+	return &TelemetryStatsHandler{service: service}
+}
+
+// GetStats статистика каналов за период; group_by=hour|day|source_file, пусто - весь период одной группой
+func (h *TelemetryStatsHandler) GetStats(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.service.GetStats(c.Request.Context(), from, to, c.Query("group_by"))
+	if err != nil {
+		if errors.Is(err, service.ErrTelemetryStatsInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get telemetry stats",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	CollectionItemJWST = "jwst"
+	CollectionItemAPOD = "apod"
+)
+
+// Collection подборка изображений JWST и APOD (например, к уроку)
+type Collection struct {
+	ID          uint             `gorm:"primaryKey"`
+	Name        string           `gorm:"type:varchar(200);not null"`
+	Description string           `gorm:"type:text"`
+	Items       []CollectionItem `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time        `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time        `gorm:"autoUpdateTime"`
+}
+
+// CollectionItem ссылка на наблюдение JWST или дату APOD внутри подборки
+type CollectionItem struct {
+	ID           uint      `gorm:"primaryKey"`
+	CollectionID uint      `gorm:"not null;uniqueIndex:idx_collection_item_ref,priority:1;index"`
+	ItemType     string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_collection_item_ref,priority:2"`
+	Reference    string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_collection_item_ref,priority:3"`
+	Position     int       `gorm:"not null;default:0"`
+	Note         string    `gorm:"type:text"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
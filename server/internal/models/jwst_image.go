@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// JWSTImage запись локального каталога изображений JWST
+type JWSTImage struct {
+	ID            uint           `gorm:"primaryKey"`
+	FileID        string         `gorm:"uniqueIndex;not null"`
+	ObservationID string         `gorm:"index"`
+	Program       string         `gorm:"type:varchar(20);index"`
+	Suffix        string         `gorm:"type:varchar(50);index"`
+	Instruments   datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'"`
+	FileType      string         `gorm:"type:varchar(20)"`
+	ImageURL      string         `gorm:"type:text;not null"`
+	Link          string         `gorm:"type:text"`
+	Caption       string         `gorm:"type:text"`
+	TargetName    string         `gorm:"type:text"`
+	ObservedAt    *time.Time     `gorm:"index"`
+	FitsURL       string         `gorm:"type:text"`
+	RA            *float64
+	Dec           *float64
+	Filter        string `gorm:"type:varchar(50);index"`
+	ExposureTime  *float64
+	FitsCheckedAt *time.Time     `gorm:"index"`
+	Raw           datatypes.JSON `gorm:"type:jsonb;not null"`
+	FetchedAt     time.Time      `gorm:"not null;default:now()"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ObserverLocation сохраненное место наблюдения
+type ObserverLocation struct {
+	ID   uint    `gorm:"primaryKey"`
+	Name string  `gorm:"type:varchar(200);not null"`
+	Lat  float64 `gorm:"not null"`
+	Lon  float64 `gorm:"not null"`
+	// Elevation высота над уровнем моря, м
+	Elevation float64 `gorm:"not null;default:0"`
+	// Timezone имя пояса IANA (Europe/Moscow)
+	Timezone string `gorm:"type:varchar(64);not null"`
+	// TimezoneAuto пояс подсказан по координатам и не подтвержден пользователем (места,
+	// сохраненные до того, как пояс стал обязательным); новые и измененные места - false
+	TimezoneAuto bool      `gorm:"not null;default:false"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RetentionArchive запись манифеста архива: строки одной таблицы за одни сутки (UTC),
+// выгруженные в сжатый CSV перед удалением
+type RetentionArchive struct {
+	ID          uint      `gorm:"primaryKey"`
+	SourceTable string    `gorm:"type:varchar(63);not null;uniqueIndex:idx_retention_archive_part"`
+	Day         time.Time `gorm:"type:date;not null;uniqueIndex:idx_retention_archive_part"`
+	// Part номер файла за сутки: больше 1, если в уже архивированные сутки позже импортировали показания
+	Part   int    `gorm:"not null;default:1;uniqueIndex:idx_retention_archive_part"`
+	Path   string `gorm:"not null"`
+	Format string `gorm:"type:varchar(16);not null"`
+	// Columns столбцы CSV через запятую в порядке выгрузки
+	Columns string `gorm:"type:text;not null"`
+	Rows    int64  `gorm:"not null"`
+	Bytes   int64  `gorm:"not null"`
+	// SHA256 контрольная сумма сжатого файла
+	SHA256    string `gorm:"column:sha256;type:varchar(64);not null"`
+	FirstAt   time.Time
+	LastAt    time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// SpaceWeatherEvent событие DONKI (GST, FLR, CME), сохраненное локально
+type SpaceWeatherEvent struct {
+	ID        uint           `gorm:"primaryKey"`
+	EventID   string         `gorm:"uniqueIndex;not null"`
+	EventType string         `gorm:"type:varchar(10);not null;index"`
+	StartTime time.Time      `gorm:"not null;index"`
+	Payload   datatypes.JSON `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
+}
@@ -1,14 +1,83 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
 type Telemetry struct {
-	ID          uint      `gorm:"primaryKey"`
-	RecordedAt  time.Time `gorm:"not null"`
-	Voltage     float64   `gorm:"type:numeric(6,2);not null"`
-	Temperature float64   `gorm:"type:numeric(6,2);not null"`
-	SourceFile  string    `gorm:"not null"`
+	ID         uint      `gorm:"primaryKey"`
+	RecordedAt time.Time `gorm:"not null"`
+	// Values показания по имени канала из реестра TelemetryChannel
+	Values     TelemetryValues `gorm:"column:channel_values;type:jsonb;not null;default:'{}'"`
+	SourceFile string          `gorm:"not null"`
+	CreatedAt  time.Time       `gorm:"autoCreateTime"`
+}
+
+// TelemetryValues значения каналов одного показания; логические хранятся как 0 и 1
+type TelemetryValues map[string]float64
+
+func (v TelemetryValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (v *TelemetryValues) Scan(src interface{}) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*v = TelemetryValues{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("unsupported type for TelemetryValues")
+	}
+
+	values := TelemetryValues{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// Типы значений канала телеметрии
+const (
+	ChannelTypeFloat   = "float"
+	ChannelTypeInteger = "integer"
+	ChannelTypeBoolean = "boolean"
+)
+
+// TelemetryChannel канал (датчик) телеметрии: единица, тип и допустимый диапазон значений
+type TelemetryChannel struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"type:varchar(63);uniqueIndex;not null"`
+	Unit string `gorm:"type:varchar(32);not null;default:''"`
+	Type string `gorm:"type:varchar(16);not null;default:'float'"`
+	// MinValue, MaxValue границы допустимых значений; nil - без ограничения
+	MinValue *float64
+	MaxValue *float64
+	// Precision знаков после запятой при хранении и выгрузке
+	Precision int `gorm:"not null;default:2"`
+	// Пороги аномалий: значение вне [WarnMin, WarnMax] - предупреждение, вне [CritMin, CritMax] - критично
+	WarnMin *float64
+	WarnMax *float64
+	CritMin *float64
+	CritMax *float64
+	// MaxRate допустимая скорость изменения, единиц канала в секунду; nil - не проверяется
+	MaxRate     *float64
+	Description string    `gorm:"type:text"`
 	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Условия правил тревоги
+const (
+	// AlertConditionAbove значение выше порога не меньше For
+	AlertConditionAbove = "above"
+	// AlertConditionBelow значение ниже порога не меньше For
+	AlertConditionBelow = "below"
+	// AlertConditionRise рост больше порога за окно For
+	AlertConditionRise = "rise"
+	// AlertConditionFall падение больше порога за окно For
+	AlertConditionFall = "fall"
+)
+
+// Состояния тревоги
+const (
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+)
+
+// Каналы уведомлений
+const (
+	AlertNotifierWebhook = "webhook"
+	AlertNotifierEmail   = "email"
+)
+
+// TelemetryAlertRule правило вида "voltage < 3.5 V дольше 30 с"
+type TelemetryAlertRule struct {
+	ID      uint   `gorm:"primaryKey"`
+	Name    string `gorm:"type:varchar(100);not null;uniqueIndex"`
+	Channel string `gorm:"type:varchar(63);not null;index"`
+	// Source только показания этого источника; пусто - каждый источник отдельно
+	Source    string  `gorm:"not null;default:''"`
+	Condition string  `gorm:"type:varchar(16);not null"`
+	Threshold float64 `gorm:"not null"`
+	// ForSeconds сколько условие должно держаться (above/below) или окно изменения (rise/fall)
+	ForSeconds int `gorm:"not null;default:0"`
+	// Hysteresis запас, на который значение должно вернуться за порог, чтобы тревога снялась
+	Hysteresis float64 `gorm:"not null;default:0"`
+	// CooldownSeconds минимальный интервал между срабатываниями по одному источнику
+	CooldownSeconds int    `gorm:"not null;default:0"`
+	Severity        string `gorm:"type:varchar(16);not null"`
+	// Notify имена каналов уведомлений; пустой список - все настроенные
+	Notify    datatypes.JSON   `gorm:"type:jsonb;not null;default:'[]'"`
+	Enabled   bool             `gorm:"not null;default:true"`
+	Alerts    []TelemetryAlert `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time        `gorm:"autoCreateTime"`
+	UpdatedAt time.Time        `gorm:"autoUpdateTime"`
+}
+
+// TelemetryAlert срабатывание правила по одному источнику: от firing до resolved
+type TelemetryAlert struct {
+	ID         uint   `gorm:"primaryKey"`
+	RuleID     uint   `gorm:"not null;index"`
+	Channel    string `gorm:"type:varchar(63);not null"`
+	SourceFile string `gorm:"not null"`
+	State      string `gorm:"type:varchar(16);not null;index"`
+	Severity   string `gorm:"type:varchar(16);not null"`
+	// Value значение, на котором сработало правило (для rise/fall - изменение за окно)
+	Value     float64   `gorm:"not null"`
+	Threshold float64   `gorm:"not null"`
+	Message   string    `gorm:"type:text;not null"`
+	FiredAt   time.Time `gorm:"not null;index"`
+	// ResolvedAt и ResolvedValue заполняются при снятии тревоги
+	ResolvedAt    *time.Time
+	ResolvedValue *float64
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
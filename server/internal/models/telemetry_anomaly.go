@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Детекторы аномалий телеметрии
+const (
+	AnomalyDetectorThreshold = "threshold"
+	AnomalyDetectorZScore    = "zscore"
+	AnomalyDetectorEWMA      = "ewma"
+	AnomalyDetectorRate      = "rate"
+)
+
+// Уровни аномалий
+const (
+	AnomalySeverityWarning  = "warning"
+	AnomalySeverityCritical = "critical"
+)
+
+// TelemetryAnomaly значение канала, признанное аномальным одним из детекторов
+type TelemetryAnomaly struct {
+	ID          uint      `gorm:"primaryKey"`
+	TelemetryID uint      `gorm:"not null;uniqueIndex:idx_telemetry_anomaly_reading"`
+	Channel     string    `gorm:"type:varchar(63);not null;uniqueIndex:idx_telemetry_anomaly_reading"`
+	Detector    string    `gorm:"type:varchar(16);not null;uniqueIndex:idx_telemetry_anomaly_reading"`
+	Severity    string    `gorm:"type:varchar(16);not null;index"`
+	SourceFile  string    `gorm:"not null"`
+	RecordedAt  time.Time `gorm:"not null;index"`
+	Value       float64   `gorm:"not null"`
+	// Expected ожидаемое значение (среднее окна, EWMA, граница порога); nil для скорости изменения
+	Expected *float64
+	// Score отклонение в сигмах, превышение порога или скорость изменения в единицах канала в секунду
+	Score     float64   `gorm:"not null"`
+	Message   string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
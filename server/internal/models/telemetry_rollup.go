@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Разрешения сводок телеметрии
+const (
+	RollupMinute = "1m"
+	RollupHour   = "1h"
+	RollupDay    = "1d"
+)
+
+// TelemetryRollup агрегаты канала за интервал (минуту, час или сутки UTC).
+// Хранятся суммы, а не среднее, чтобы сводку можно было дополнять поздними показаниями.
+type TelemetryRollup struct {
+	Resolution string    `gorm:"type:varchar(4);primaryKey"`
+	Channel    string    `gorm:"type:varchar(63);primaryKey"`
+	Bucket     time.Time `gorm:"primaryKey"`
+	Count      int64     `gorm:"column:value_count;not null"`
+	Sum        float64   `gorm:"column:value_sum;not null"`
+	SumSquares float64   `gorm:"column:value_sum_squares;not null"`
+	Min        float64   `gorm:"column:value_min;not null"`
+	Max        float64   `gorm:"column:value_max;not null"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+// TelemetryRollupCursor до какого показания сводки уже посчитаны: показания упорядочены
+// по (tx_id, id) - транзакции, записавшей показание, и ID внутри нее
+type TelemetryRollupCursor struct {
+	Name      string    `gorm:"type:varchar(32);primaryKey"`
+	LastTxID  uint64    `gorm:"not null;default:0"`
+	LastID    uint      `gorm:"not null;default:0"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
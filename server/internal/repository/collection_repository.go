@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrCollectionItemOrder = errors.New("item order must list every item of the collection exactly once")
+
+type CollectionRepository interface {
+	Create(ctx context.Context, collection *models.Collection) error
+	List(ctx context.Context) ([]models.Collection, error)
+	// GetByID возвращает подборку с элементами по порядку; gorm.ErrRecordNotFound, если ее нет
+	GetByID(ctx context.Context, id uint) (*models.Collection, error)
+	Update(ctx context.Context, collection *models.Collection) error
+	Delete(ctx context.Context, id uint) error
+	CountItems(ctx context.Context, collectionIDs []uint) (map[uint]int64, error)
+
+	// AddItem вставляет элемент на position (< 0 - в конец), сдвигая последующие
+	AddItem(ctx context.Context, item *models.CollectionItem) error
+	GetItem(ctx context.Context, collectionID, itemID uint) (*models.CollectionItem, error)
+	UpdateItem(ctx context.Context, item *models.CollectionItem) error
+	RemoveItem(ctx context.Context, collectionID, itemID uint) error
+	// Reorder задает порядок элементов по списку их ID
+	Reorder(ctx context.Context, collectionID uint, itemIDs []uint) error
+}
+
+type collectionRepository struct {
+	db *gorm.DB
+}
+
+func NewCollectionRepository(db *gorm.DB) CollectionRepository {
+	return &collectionRepository{db: db}
+}
+
+func (r *collectionRepository) Create(ctx context.Context, collection *models.Collection) error {
+	return r.db.WithContext(ctx).Create(collection).Error
+}
+
+func (r *collectionRepository) List(ctx context.Context) ([]models.Collection, error) {
+	var collections []models.Collection
+	err := r.db.WithContext(ctx).
+		Order("updated_at DESC").
+		Find(&collections).
+		Error
+	return collections, err
+}
+
+func (r *collectionRepository) GetByID(ctx context.Context, id uint) (*models.Collection, error) {
+	var collection models.Collection
+	err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC, id ASC")
+		}).
+		First(&collection, id).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *collectionRepository) Update(ctx context.Context, collection *models.Collection) error {
+	return r.db.WithContext(ctx).
+		Model(collection).
+		Select("name", "description").
+		Updates(collection).
+		Error
+}
+
+func (r *collectionRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Collection{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *collectionRepository) CountItems(ctx context.Context, collectionIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64)
+	if len(collectionIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		CollectionID uint
+		Count        int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.CollectionItem{}).
+		Select("collection_id, COUNT(*) AS count").
+		Where("collection_id IN ?", collectionIDs).
+		Group("collection_id").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.CollectionID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *collectionRepository) AddItem(ctx context.Context, item *models.CollectionItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.CollectionItem{}).
+			Where("collection_id = ?", item.CollectionID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+
+		if item.Position < 0 || int64(item.Position) > count {
+			item.Position = int(count)
+		} else {
+			// Освобождаем место под новый элемент
+			if err := tx.Model(&models.CollectionItem{}).
+				Where("collection_id = ? AND position >= ?", item.CollectionID, item.Position).
+				Update("position", gorm.Expr("position + 1")).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return touchCollection(tx, item.CollectionID)
+	})
+}
+
+func (r *collectionRepository) GetItem(ctx context.Context, collectionID, itemID uint) (*models.CollectionItem, error) {
+	var item models.CollectionItem
+	err := r.db.WithContext(ctx).
+		Where("collection_id = ?", collectionID).
+		First(&item, itemID).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *collectionRepository) UpdateItem(ctx context.Context, item *models.CollectionItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(item).Select("note").Updates(item).Error; err != nil {
+			return err
+		}
+		return touchCollection(tx, item.CollectionID)
+	})
+}
+
+func (r *collectionRepository) RemoveItem(ctx context.Context, collectionID, itemID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var item models.CollectionItem
+		if err := tx.Where("collection_id = ?", collectionID).First(&item, itemID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&item).Error; err != nil {
+			return err
+		}
+
+		// Закрываем образовавшуюся дыру в нумерации
+		if err := tx.Model(&models.CollectionItem{}).
+			Where("collection_id = ? AND position > ?", collectionID, item.Position).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		return touchCollection(tx, collectionID)
+	})
+}
+
+func (r *collectionRepository) Reorder(ctx context.Context, collectionID uint, itemIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&models.CollectionItem{}).
+			Where("collection_id = ?", collectionID).
+			Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+
+		known := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			known[id] = true
+		}
+		if len(itemIDs) != len(existing) {
+			return ErrCollectionItemOrder
+		}
+		for _, id := range itemIDs {
+			if !known[id] {
+				return ErrCollectionItemOrder
+			}
+			// Повтор ID тоже ошибка
+			delete(known, id)
+		}
+
+		for position, id := range itemIDs {
+			if err := tx.Model(&models.CollectionItem{}).
+				Where("id = ?", id).
+				Update("position", position).Error; err != nil {
+				return fmt.Errorf("failed to move item %d: %w", id, err)
+			}
+		}
+		return touchCollection(tx, collectionID)
+	})
+}
+
+// touchCollection обновляет updated_at подборки при изменении ее элементов
+func touchCollection(tx *gorm.DB, collectionID uint) error {
+	return tx.Model(&models.Collection{}).
+		Where("id = ?", collectionID).
+		Update("updated_at", gorm.Expr("now()")).
+		Error
+}
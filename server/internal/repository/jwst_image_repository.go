@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JWSTImageRepository interface {
+	BulkUpsert(ctx context.Context, images []models.JWSTImage) error
+	ExistingFileIDs(ctx context.Context, fileIDs []string) (map[string]bool, error)
+	Find(ctx context.Context, filter JWSTImageFilter) ([]models.JWSTImage, error)
+	Search(ctx context.Context, query string, limit int) ([]models.JWSTImage, error)
+	FindByObservation(ctx context.Context, observationID string) ([]models.JWSTImage, error)
+	GetProgramStats(ctx context.Context, program string, sampleLimit int) (*JWSTProgramStats, error)
+	FindPendingFITS(ctx context.Context, retryBefore time.Time, limit int) ([]models.JWSTImage, error)
+	UpdateFITSMetadata(ctx context.Context, image *models.JWSTImage) error
+	Count(ctx context.Context) (int64, error)
+}
+
+// JWSTImageFilter параметры выборки из каталога; пустые поля не фильтруют
+type JWSTImageFilter struct {
+	Program      string
+	Instrument   string
+	Suffix       string
+	ObservedFrom *time.Time
+	ObservedTo   *time.Time
+	Page         int
+	PerPage      int
+}
+
+// JWSTProgramStats агрегаты каталога по одной программе
+type JWSTProgramStats struct {
+	Total         int64
+	ByInstrument  map[string]int64
+	BySuffix      map[string]int64
+	FirstObserved *time.Time
+	LastObserved  *time.Time
+	Samples       []models.JWSTImage
+}
+
+type jwstImageRepository struct {
+	db *gorm.DB
+}
+
+func NewJWSTImageRepository(db *gorm.DB) JWSTImageRepository {
+	return &jwstImageRepository{db: db}
+}
+
+func (r *jwstImageRepository) BulkUpsert(ctx context.Context, images []models.JWSTImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "file_id"}},
+			DoUpdates: append(clause.AssignmentColumns([]string{
+				"observation_id", "program", "suffix", "instruments", "file_type",
+				"image_url", "link", "raw", "fetched_at", "updated_at",
+			}), clause.Assignment{
+				// Подпись, дополненная данными FITS, не перезаписывается при повторном обходе
+				Column: clause.Column{Name: "caption"},
+				Value:  gorm.Expr("CASE WHEN jwst_images.fits_checked_at IS NULL THEN EXCLUDED.caption ELSE jwst_images.caption END"),
+			}, clause.Assignment{
+				// Дату наблюдения и цель не затираем, если upstream их не прислал
+				Column: clause.Column{Name: "observed_at"},
+				Value:  gorm.Expr("COALESCE(EXCLUDED.observed_at, jwst_images.observed_at)"),
+			}, clause.Assignment{
+				Column: clause.Column{Name: "target_name"},
+				Value:  gorm.Expr("COALESCE(NULLIF(EXCLUDED.target_name, ''), jwst_images.target_name)"),
+			}),
+		}).
+		CreateInBatches(images, 100).
+		Error
+}
+
+func (r *jwstImageRepository) ExistingFileIDs(ctx context.Context, fileIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(fileIDs) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).
+		Model(&models.JWSTImage{}).
+		Where("file_id IN ?", fileIDs).
+		Pluck("file_id", &found).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range found {
+		existing[id] = true
+	}
+	return existing, nil
+}
+
+func (r *jwstImageRepository) Find(ctx context.Context, filter JWSTImageFilter) ([]models.JWSTImage, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 || filter.PerPage > 100 {
+		filter.PerPage = 24
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.JWSTImage{})
+
+	if filter.Program != "" {
+		query = query.Where("program = ?", filter.Program)
+	}
+	if filter.Suffix != "" {
+		query = query.Where("suffix = ?", filter.Suffix)
+	}
+	if filter.Instrument != "" {
+		instruments, _ := json.Marshal([]string{strings.ToUpper(filter.Instrument)})
+		query = query.Where("instruments @> ?", string(instruments))
+	}
+	if filter.ObservedFrom != nil {
+		query = query.Where("observed_at >= ?", *filter.ObservedFrom)
+	}
+	if filter.ObservedTo != nil {
+		query = query.Where("observed_at <= ?", *filter.ObservedTo)
+	}
+
+	var images []models.JWSTImage
+	err := query.
+		Order("observed_at DESC NULLS LAST, id DESC").
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Find(&images).
+		Error
+
+	return images, err
+}
+
+// likeEscaper экранирует спецсимволы LIKE; в Postgres экранирующий символ по умолчанию - \
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *jwstImageRepository) Search(ctx context.Context, query string, limit int) ([]models.JWSTImage, error) {
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	// Пользовательский ввод ищется как подстрока: % и _ не должны работать шаблонами
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	var images []models.JWSTImage
+	err := r.db.WithContext(ctx).
+		Where("caption ILIKE ? OR program ILIKE ? OR target_name ILIKE ? OR observation_id ILIKE ?",
+			pattern, pattern, pattern, pattern).
+		Order("observed_at DESC NULLS LAST, id DESC").
+		Limit(limit).
+		Find(&images).
+		Error
+
+	return images, err
+}
+
+func (r *jwstImageRepository) FindByObservation(ctx context.Context, observationID string) ([]models.JWSTImage, error) {
+	var images []models.JWSTImage
+	err := r.db.WithContext(ctx).
+		Where("observation_id = ?", observationID).
+		Order("suffix, id").
+		Find(&images).
+		Error
+	return images, err
+}
+
+func (r *jwstImageRepository) GetProgramStats(ctx context.Context, program string, sampleLimit int) (*JWSTProgramStats, error) {
+	stats := &JWSTProgramStats{
+		ByInstrument: make(map[string]int64),
+		BySuffix:     make(map[string]int64),
+	}
+
+	// Общее количество и период наблюдений
+	row := r.db.WithContext(ctx).
+		Model(&models.JWSTImage{}).
+		Select("COUNT(*), MIN(observed_at), MAX(observed_at)").
+		Where("program = ?", program).
+		Row()
+	if err := row.Scan(&stats.Total, &stats.FirstObserved, &stats.LastObserved); err != nil {
+		return nil, err
+	}
+
+	if stats.Total == 0 {
+		return stats, nil
+	}
+
+	// Количество по инструментам (одно изображение может относиться к нескольким)
+	rows, err := r.db.WithContext(ctx).
+		Raw(`SELECT inst, COUNT(*) FROM jwst_images, jsonb_array_elements_text(instruments) AS inst
+			WHERE program = ? GROUP BY inst`, program).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		var count int64
+		if err := rows.Scan(&name, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.ByInstrument[name] = count
+	}
+	rows.Close()
+
+	// Количество по суффиксам
+	rows, err = r.db.WithContext(ctx).
+		Model(&models.JWSTImage{}).
+		Select("suffix, COUNT(*)").
+		Where("program = ?", program).
+		Group("suffix").
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var suffix string
+		var count int64
+		if err := rows.Scan(&suffix, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.BySuffix[suffix] = count
+	}
+	rows.Close()
+
+	// Представительные изображения: самое свежее для каждого суффикса
+	if sampleLimit > 0 {
+		err = r.db.WithContext(ctx).
+			Raw(`SELECT * FROM (
+				SELECT DISTINCT ON (suffix) * FROM jwst_images
+				WHERE program = ?
+				ORDER BY suffix, observed_at DESC NULLS LAST, id DESC
+			) AS samples ORDER BY observed_at DESC NULLS LAST, id DESC LIMIT ?`, program, sampleLimit).
+			Scan(&stats.Samples).
+			Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// FindPendingFITS возвращает записи без разобранного заголовка FITS: еще не
+// проверенные и неудачные попытки старше retryBefore
+func (r *jwstImageRepository) FindPendingFITS(ctx context.Context, retryBefore time.Time, limit int) ([]models.JWSTImage, error) {
+	var images []models.JWSTImage
+	err := r.db.WithContext(ctx).
+		Where("fits_checked_at IS NULL OR (fits_url = '' AND fits_checked_at < ?)", retryBefore).
+		Order("fits_checked_at NULLS FIRST, id DESC").
+		Limit(limit).
+		Find(&images).
+		Error
+	return images, err
+}
+
+func (r *jwstImageRepository) UpdateFITSMetadata(ctx context.Context, image *models.JWSTImage) error {
+	return r.db.WithContext(ctx).
+		Model(image).
+		Select("fits_url", "ra", "dec", "filter", "exposure_time", "target_name",
+			"observed_at", "caption", "fits_checked_at").
+		Updates(image).
+		Error
+}
+
+func (r *jwstImageRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.JWSTImage{}).
+		Count(&count).
+		Error
+	return count, err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type LocationRepository interface {
+	Create(ctx context.Context, location *models.ObserverLocation) error
+	List(ctx context.Context) ([]models.ObserverLocation, error)
+	// GetByID возвращает gorm.ErrRecordNotFound, если места нет
+	GetByID(ctx context.Context, id uint) (*models.ObserverLocation, error)
+	Update(ctx context.Context, location *models.ObserverLocation) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type locationRepository struct {
+	db *gorm.DB
+}
+
+func NewLocationRepository(db *gorm.DB) LocationRepository {
+	return &locationRepository{db: db}
+}
+
+func (r *locationRepository) Create(ctx context.Context, location *models.ObserverLocation) error {
+	return r.db.WithContext(ctx).Create(location).Error
+}
+
+func (r *locationRepository) List(ctx context.Context) ([]models.ObserverLocation, error) {
+	var locations []models.ObserverLocation
+	err := r.db.WithContext(ctx).
+		Order("name ASC, id ASC").
+		Find(&locations).
+		Error
+	return locations, err
+}
+
+func (r *locationRepository) GetByID(ctx context.Context, id uint) (*models.ObserverLocation, error) {
+	var location models.ObserverLocation
+	if err := r.db.WithContext(ctx).First(&location, id).Error; err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *locationRepository) Update(ctx context.Context, location *models.ObserverLocation) error {
+	return r.db.WithContext(ctx).
+		Model(location).
+		Select("name", "lat", "lon", "elevation", "timezone", "timezone_auto").
+		Updates(location).
+		Error
+}
+
+func (r *locationRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.ObserverLocation{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
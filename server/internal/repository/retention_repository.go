@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RetentionRowWriter получает строки архивируемого диапазона по мере чтения из базы
+type RetentionRowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	// Close завершает файл и возвращает запись манифеста; вызывается до удаления строк
+	Close() (*models.RetentionArchive, error)
+}
+
+type RetentionRepository interface {
+	// NextTime самое раннее значение column в [from, before); nil - строк нет
+	NextTime(ctx context.Context, table, column string, from, before time.Time) (*time.Time, error)
+	Count(ctx context.Context, table, column string, from, to time.Time) (int64, error)
+	// ArchiveRange выгружает строки [from, to) в writer, удаляет их и сохраняет манифест одной транзакцией
+	ArchiveRange(ctx context.Context, table, column string, from, to time.Time, writer RetentionRowWriter) (*models.RetentionArchive, error)
+	// DeleteRange удаляет строки [from, to) без архивации
+	DeleteRange(ctx context.Context, table, column string, from, to time.Time) (int64, error)
+	// LastPart последний номер файла архива таблицы за сутки; 0 - архива нет
+	LastPart(ctx context.Context, table string, day time.Time) (int, error)
+	ListArchives(ctx context.Context, table string, limit, offset int) ([]models.RetentionArchive, int64, error)
+}
+
+type retentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) RetentionRepository {
+	return &retentionRepository{db: db}
+}
+
+func (r *retentionRepository) NextTime(ctx context.Context, table, column string, from, before time.Time) (*time.Time, error) {
+	var next sql.NullTime
+	err := r.db.WithContext(ctx).
+		Raw("SELECT MIN(?) FROM ? WHERE ? >= ? AND ? < ?",
+			clause.Column{Name: column}, clause.Table{Name: table},
+			clause.Column{Name: column}, from, clause.Column{Name: column}, before).
+		Scan(&next).
+		Error
+	if err != nil || !next.Valid {
+		return nil, err
+	}
+	return &next.Time, nil
+}
+
+func (r *retentionRepository) Count(ctx context.Context, table, column string, from, to time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Table(table).
+		Where("? >= ? AND ? < ?", clause.Column{Name: column}, from, clause.Column{Name: column}, to).
+		Count(&count).
+		Error
+	return count, err
+}
+
+func (r *retentionRepository) ArchiveRange(ctx context.Context, table, column string, from, to time.Time,
+	writer RetentionRowWriter) (*models.RetentionArchive, error) {
+	var archive *models.RetentionArchive
+
+	// REPEATABLE READ: DELETE видит тот же снимок, что и выгрузка, и не удалит строки,
+	// вставленные в диапазон после ее начала
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rows, err := tx.Raw("SELECT * FROM ? WHERE ? >= ? AND ? < ? ORDER BY ?",
+			clause.Table{Name: table}, clause.Column{Name: column}, from, clause.Column{Name: column}, to,
+			clause.Column{Name: column}).
+			Rows()
+		if err != nil {
+			return err
+		}
+
+		exported, err := writeRetentionRows(rows, writer)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		archive, err = writer.Close()
+		if err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM ? WHERE ? >= ? AND ? < ?",
+			clause.Table{Name: table}, clause.Column{Name: column}, from, clause.Column{Name: column}, to)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != exported {
+			return fmt.Errorf("archived %d rows of %s but delete matched %d", exported, table, result.RowsAffected)
+		}
+
+		return tx.Create(archive).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func writeRetentionRows(rows *sql.Rows, writer RetentionRowWriter) (int64, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.WriteHeader(columns); err != nil {
+		return 0, err
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func (r *retentionRepository) DeleteRange(ctx context.Context, table, column string, from, to time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Exec("DELETE FROM ? WHERE ? >= ? AND ? < ?",
+			clause.Table{Name: table}, clause.Column{Name: column}, from, clause.Column{Name: column}, to)
+	return result.RowsAffected, result.Error
+}
+
+func (r *retentionRepository) LastPart(ctx context.Context, table string, day time.Time) (int, error) {
+	var part sql.NullInt64
+	err := r.db.WithContext(ctx).
+		Model(&models.RetentionArchive{}).
+		Select("MAX(part)").
+		Where("source_table = ? AND day = ?", table, day).
+		Scan(&part).
+		Error
+	return int(part.Int64), err
+}
+
+func (r *retentionRepository) ListArchives(ctx context.Context, table string, limit, offset int) ([]models.RetentionArchive, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.RetentionArchive{})
+	if table != "" {
+		query = query.Where("source_table = ?", table)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var archives []models.RetentionArchive
+	err := query.
+		Order("day DESC, source_table ASC, part DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&archives).
+		Error
+	return archives, total, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SpaceWeatherRepository interface {
+	BulkUpsert(ctx context.Context, events []models.SpaceWeatherEvent) error
+	GetSince(ctx context.Context, eventType string, since time.Time) ([]models.SpaceWeatherEvent, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+type spaceWeatherRepository struct {
+	db *gorm.DB
+}
+
+func NewSpaceWeatherRepository(db *gorm.DB) SpaceWeatherRepository {
+	return &spaceWeatherRepository{db: db}
+}
+
+func (r *spaceWeatherRepository) BulkUpsert(ctx context.Context, events []models.SpaceWeatherEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// DONKI обновляет анализы событий задним числом, поэтому перезаписываем payload
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"event_type", "start_time", "payload", "updated_at"}),
+		}).
+		CreateInBatches(events, 100).
+		Error
+}
+
+func (r *spaceWeatherRepository) GetSince(ctx context.Context, eventType string, since time.Time) ([]models.SpaceWeatherEvent, error) {
+	var events []models.SpaceWeatherEvent
+	err := r.db.WithContext(ctx).
+		Where("event_type = ? AND start_time >= ?", eventType, since).
+		Order("start_time DESC").
+		Find(&events).
+		Error
+	return events, err
+}
+
+func (r *spaceWeatherRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.SpaceWeatherEvent{}).
+		Count(&count).
+		Error
+	return count, err
+}
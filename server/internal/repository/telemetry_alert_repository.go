@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TelemetryAlertRepository interface {
+	ListRules(ctx context.Context) ([]models.TelemetryAlertRule, error)
+	// GetRule возвращает gorm.ErrRecordNotFound, если правила нет
+	GetRule(ctx context.Context, id uint) (*models.TelemetryAlertRule, error)
+	GetRuleByName(ctx context.Context, name string) (*models.TelemetryAlertRule, error)
+	CreateRule(ctx context.Context, rule *models.TelemetryAlertRule) error
+	UpdateRule(ctx context.Context, rule *models.TelemetryAlertRule) error
+	// DeleteRule удаляет правило вместе с историей его тревог
+	DeleteRule(ctx context.Context, id uint) error
+
+	CreateAlert(ctx context.Context, alert *models.TelemetryAlert) error
+	// ResolveAlert переводит тревогу в resolved
+	ResolveAlert(ctx context.Context, alert *models.TelemetryAlert) error
+	// LatestAlerts последняя тревога по каждой паре правило/источник
+	LatestAlerts(ctx context.Context) ([]models.TelemetryAlert, error)
+	ListAlerts(ctx context.Context, filter TelemetryAlertFilter) ([]models.TelemetryAlert, int64, error)
+}
+
+type TelemetryAlertFilter struct {
+	RuleID     uint
+	State      string
+	SourceFile string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+type telemetryAlertRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryAlertRepository(db *gorm.DB) TelemetryAlertRepository {
+	return &telemetryAlertRepository{db: db}
+}
+
+func (r *telemetryAlertRepository) ListRules(ctx context.Context) ([]models.TelemetryAlertRule, error) {
+	var rules []models.TelemetryAlertRule
+	err := r.db.WithContext(ctx).
+		Order("id ASC").
+		Find(&rules).
+		Error
+	return rules, err
+}
+
+func (r *telemetryAlertRepository) GetRule(ctx context.Context, id uint) (*models.TelemetryAlertRule, error) {
+	var rule models.TelemetryAlertRule
+	if err := r.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *telemetryAlertRepository) GetRuleByName(ctx context.Context, name string) (*models.TelemetryAlertRule, error) {
+	var rule models.TelemetryAlertRule
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *telemetryAlertRepository) CreateRule(ctx context.Context, rule *models.TelemetryAlertRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *telemetryAlertRepository) UpdateRule(ctx context.Context, rule *models.TelemetryAlertRule) error {
+	return r.db.WithContext(ctx).
+		Model(rule).
+		Select("name", "channel", "source", "condition", "threshold", "for_seconds",
+			"hysteresis", "cooldown_seconds", "severity", "notify", "enabled").
+		Updates(rule).
+		Error
+}
+
+func (r *telemetryAlertRepository) DeleteRule(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.TelemetryAlertRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *telemetryAlertRepository) CreateAlert(ctx context.Context, alert *models.TelemetryAlert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+func (r *telemetryAlertRepository) ResolveAlert(ctx context.Context, alert *models.TelemetryAlert) error {
+	return r.db.WithContext(ctx).
+		Model(alert).
+		Select("state", "resolved_at", "resolved_value").
+		Updates(alert).
+		Error
+}
+
+func (r *telemetryAlertRepository) LatestAlerts(ctx context.Context) ([]models.TelemetryAlert, error) {
+	var alerts []models.TelemetryAlert
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT ON (rule_id, source_file) *
+			FROM telemetry_alerts
+			ORDER BY rule_id, source_file, fired_at DESC, id DESC`).
+		Scan(&alerts).
+		Error
+	return alerts, err
+}
+
+func (r *telemetryAlertRepository) ListAlerts(ctx context.Context, filter TelemetryAlertFilter) ([]models.TelemetryAlert, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.TelemetryAlert{}).
+		Where("fired_at BETWEEN ? AND ?", filter.From, filter.To)
+	if filter.RuleID != 0 {
+		query = query.Where("rule_id = ?", filter.RuleID)
+	}
+	if filter.State != "" {
+		query = query.Where("state = ?", filter.State)
+	}
+	if filter.SourceFile != "" {
+		query = query.Where("source_file = ?", filter.SourceFile)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []models.TelemetryAlert
+	err := query.
+		Order("fired_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&alerts).
+		Error
+	return alerts, total, err
+}
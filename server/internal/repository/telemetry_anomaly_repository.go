@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TelemetryAnomalyRepository interface {
+	// CreateBatch сохраняет события; повторное срабатывание детектора на том же значении пропускается
+	CreateBatch(ctx context.Context, anomalies []models.TelemetryAnomaly) error
+	List(ctx context.Context, filter TelemetryAnomalyFilter) ([]models.TelemetryAnomaly, int64, error)
+	// ByTelemetryIDs события для отметки показаний в выгрузках
+	ByTelemetryIDs(ctx context.Context, ids []uint) ([]models.TelemetryAnomaly, error)
+}
+
+type TelemetryAnomalyFilter struct {
+	From       time.Time
+	To         time.Time
+	Channel    string
+	SourceFile string
+	Severity   string
+	Detector   string
+	Limit      int
+	Offset     int
+}
+
+type telemetryAnomalyRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryAnomalyRepository(db *gorm.DB) TelemetryAnomalyRepository {
+	return &telemetryAnomalyRepository{db: db}
+}
+
+func (r *telemetryAnomalyRepository) CreateBatch(ctx context.Context, anomalies []models.TelemetryAnomaly) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(anomalies, 100).
+		Error
+}
+
+func (r *telemetryAnomalyRepository) List(ctx context.Context, filter TelemetryAnomalyFilter) ([]models.TelemetryAnomaly, int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.TelemetryAnomaly{}).
+		Where("recorded_at BETWEEN ? AND ?", filter.From, filter.To)
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.SourceFile != "" {
+		query = query.Where("source_file = ?", filter.SourceFile)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Detector != "" {
+		query = query.Where("detector = ?", filter.Detector)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var anomalies []models.TelemetryAnomaly
+	err := query.
+		Order("recorded_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&anomalies).
+		Error
+	return anomalies, total, err
+}
+
+func (r *telemetryAnomalyRepository) ByTelemetryIDs(ctx context.Context, ids []uint) ([]models.TelemetryAnomaly, error) {
+	var anomalies []models.TelemetryAnomaly
+
+	// Частями, чтобы не упереться в предел параметров запроса
+	const chunk = 5000
+	for start := 0; start < len(ids); start += chunk {
+		end := start + chunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var part []models.TelemetryAnomaly
+		err := r.db.WithContext(ctx).
+			Where("telemetry_id IN ?", ids[start:end]).
+			Order("telemetry_id ASC, id ASC").
+			Find(&part).
+			Error
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, part...)
+	}
+	return anomalies, nil
+}
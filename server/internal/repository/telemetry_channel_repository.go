@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TelemetryChannelRepository interface {
+	List(ctx context.Context) ([]models.TelemetryChannel, error)
+	// GetByName возвращает gorm.ErrRecordNotFound, если канала нет
+	GetByName(ctx context.Context, name string) (*models.TelemetryChannel, error)
+	Create(ctx context.Context, channel *models.TelemetryChannel) error
+	Update(ctx context.Context, channel *models.TelemetryChannel) error
+}
+
+type telemetryChannelRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryChannelRepository(db *gorm.DB) TelemetryChannelRepository {
+	return &telemetryChannelRepository{db: db}
+}
+
+func (r *telemetryChannelRepository) List(ctx context.Context) ([]models.TelemetryChannel, error) {
+	var channels []models.TelemetryChannel
+	err := r.db.WithContext(ctx).
+		Order("id ASC").
+		Find(&channels).
+		Error
+	return channels, err
+}
+
+func (r *telemetryChannelRepository) GetByName(ctx context.Context, name string) (*models.TelemetryChannel, error) {
+	var channel models.TelemetryChannel
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&channel).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (r *telemetryChannelRepository) Create(ctx context.Context, channel *models.TelemetryChannel) error {
+	return r.db.WithContext(ctx).Create(channel).Error
+}
+
+func (r *telemetryChannelRepository) Update(ctx context.Context, channel *models.TelemetryChannel) error {
+	return r.db.WithContext(ctx).
+		Model(channel).
+		Select("unit", "type", "min_value", "max_value", "precision",
+			"warn_min", "warn_max", "crit_min", "crit_max", "max_rate", "description").
+		Updates(channel).
+		Error
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"cassiopeia/internal/models"
@@ -11,21 +13,73 @@ import (
 
 type TelemetryRepository interface {
 	Create(ctx context.Context, telemetry *models.Telemetry) error
-	BatchCreate(ctx context.Context, telemetries []models.Telemetry) error
-	GetByDateRange(ctx context.Context, from, to time.Time) ([]models.Telemetry, error)
+	// BatchCreate пишет показания одной транзакцией, пропуская уже сохраненные ключи
+	// (recorded_at, source_file) и повторы внутри пачки. Записанным показаниям проставляет ID,
+	// у пропущенных ID остается нулевым; возвращает число записанных
+	BatchCreate(ctx context.Context, telemetries []models.Telemetry) (int, error)
+	// GetByDateRange показания за период; если channels не пусто - только содержащие хотя бы один из них
+	GetByDateRange(ctx context.Context, from, to time.Time, channels []string) ([]models.Telemetry, error)
+	// StreamByDateRange читает показания за период одним курсором по возрастанию времени
+	// и передает их в fn пачками по batchSize; срез переиспользуется, ошибка fn прерывает чтение
+	StreamByDateRange(ctx context.Context, from, to time.Time, channels []string, batchSize int, fn func([]models.Telemetry) error) error
+	// ChannelNames имена каналов, встречающихся в показаниях за период
+	ChannelNames(ctx context.Context, from, to time.Time) ([]string, error)
 	GetLatest(ctx context.Context, limit int) ([]models.Telemetry, error)
-	GetStats(ctx context.Context, from, to time.Time) (*TelemetryStats, error)
+	// GetChannelHistory последние limit показаний источника с каналом до момента before, от новых к старым
+	GetChannelHistory(ctx context.Context, sourceFile, channel string, before time.Time, limit int) ([]models.Telemetry, error)
+	// GetChannelUpdates показания с каналом и ID больше afterID (не раньше since, если задан) в порядке ID
+	GetChannelUpdates(ctx context.Context, channel, sourceFile string, afterID uint, since time.Time, limit int) ([]models.Telemetry, error)
+	// GetStats агрегаты и процентили каналов за период [from, to) с группировкой TelemetryStatsBy*
+	GetStats(ctx context.Context, from, to time.Time, groupBy string) ([]TelemetryGroupStats, error)
 	DeleteOld(ctx context.Context, olderThan time.Time) error
 }
 
-type TelemetryStats struct {
-	Count          int64   `json:"count"`
-	AvgVoltage     float64 `json:"avg_voltage"`
-	AvgTemperature float64 `json:"avg_temperature"`
-	MinVoltage     float64 `json:"min_voltage"`
-	MaxVoltage     float64 `json:"max_voltage"`
-	MinTemperature float64 `json:"min_temperature"`
-	MaxTemperature float64 `json:"max_temperature"`
+// TelemetryKey естественный ключ показания: момент и источник, в базе - уникальный индекс
+type TelemetryKey struct {
+	RecordedAt time.Time
+	SourceFile string
+}
+
+// KeyOf ключ показания с точностью до микросекунды, как его хранит Postgres
+func KeyOf(record models.Telemetry) TelemetryKey {
+	return TelemetryKey{RecordedAt: record.RecordedAt.UTC().Truncate(time.Microsecond), SourceFile: record.SourceFile}
+}
+
+// Показаний в одном INSERT: по 4 параметра на показание, лимит протокола - 65535
+const telemetryInsertBatch = 1000
+
+// Группировки статистики телеметрии
+const (
+	TelemetryStatsByNone   = ""
+	TelemetryStatsByHour   = "hour"
+	TelemetryStatsByDay    = "day"
+	TelemetryStatsBySource = "source_file"
+)
+
+// Выражение группы для каждой группировки; часы и сутки - в UTC, строкой, чтобы сортировались
+var telemetryStatsGroups = map[string]string{
+	TelemetryStatsByNone:   `'all'::text`,
+	TelemetryStatsByHour:   `to_char(t.recorded_at AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:00:00"Z"')`,
+	TelemetryStatsByDay:    `to_char(t.recorded_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')`,
+	TelemetryStatsBySource: `t.source_file`,
+}
+
+type TelemetryGroupStats struct {
+	// Group начало часа (RFC3339), дата, имя источника или all
+	Group    string                  `json:"group"`
+	Count    int64                   `json:"count"`
+	Channels map[string]ChannelStats `json:"channels"`
+}
+
+type ChannelStats struct {
+	Count  int64   `json:"count"`
+	Avg    float64 `json:"avg"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
 }
 
 type telemetryRepository struct {
@@ -40,20 +94,133 @@ func (r *telemetryRepository) Create(ctx context.Context, telemetry *models.Tele
 	return r.db.WithContext(ctx).Create(telemetry).Error
 }
 
-func (r *telemetryRepository) BatchCreate(ctx context.Context, telemetries []models.Telemetry) error {
-	return r.db.WithContext(ctx).CreateInBatches(telemetries, 100).Error
+func (r *telemetryRepository) BatchCreate(ctx context.Context, telemetries []models.Telemetry) (int, error) {
+	created := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(telemetries); start += telemetryInsertBatch {
+			n, err := insertTelemetries(tx, telemetries[start:min(start+telemetryInsertBatch, len(telemetries))])
+			if err != nil {
+				return err
+			}
+			created += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// insertTelemetries один INSERT ... ON CONFLICT DO NOTHING. Запрос собирается вручную:
+// gorm раздает ID из RETURNING по порядку и при пропущенных строках путает их,
+// поэтому ID сопоставляются с показаниями по ключу
+func insertTelemetries(tx *gorm.DB, chunk []models.Telemetry) (int, error) {
+	now := tx.NowFunc()
+	var query strings.Builder
+	query.WriteString("INSERT INTO telemetries (recorded_at, channel_values, source_file, created_at) VALUES ")
+	args := make([]interface{}, 0, len(chunk)*4)
+	for i, record := range chunk {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(?, ?, ?, ?)")
+		createdAt := record.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		args = append(args, record.RecordedAt, record.Values, record.SourceFile, createdAt)
+	}
+	query.WriteString(" ON CONFLICT (source_file, recorded_at) DO NOTHING RETURNING id, recorded_at, source_file, created_at")
+
+	var inserted []models.Telemetry
+	if err := tx.Raw(query.String(), args...).Scan(&inserted).Error; err != nil {
+		return 0, err
+	}
+
+	rows := make(map[TelemetryKey]models.Telemetry, len(inserted))
+	for _, row := range inserted {
+		rows[KeyOf(row)] = row
+	}
+	for i := range chunk {
+		key := KeyOf(chunk[i])
+		row, ok := rows[key]
+		if !ok {
+			continue
+		}
+		// Повтор внутри пачки остается без ID: записано первое показание
+		delete(rows, key)
+		chunk[i].ID = row.ID
+		chunk[i].CreatedAt = row.CreatedAt
+	}
+	return len(inserted), nil
 }
 
-func (r *telemetryRepository) GetByDateRange(ctx context.Context, from, to time.Time) ([]models.Telemetry, error) {
+func (r *telemetryRepository) GetByDateRange(ctx context.Context, from, to time.Time, channels []string) ([]models.Telemetry, error) {
+	query := r.db.WithContext(ctx).
+		Where("recorded_at BETWEEN ? AND ?", from, to)
+	if len(channels) > 0 {
+		// Функция вместо оператора ?|, который GORM принял бы за плейсхолдер
+		query = query.Where("jsonb_exists_any(channel_values, string_to_array(?, ','))", strings.Join(channels, ","))
+	}
+
 	var telemetries []models.Telemetry
-	err := r.db.WithContext(ctx).
-		Where("recorded_at BETWEEN ? AND ?", from, to).
+	err := query.
 		Order("recorded_at DESC").
 		Find(&telemetries).
 		Error
 	return telemetries, err
 }
 
+func (r *telemetryRepository) StreamByDateRange(ctx context.Context, from, to time.Time, channels []string, batchSize int,
+	fn func([]models.Telemetry) error) error {
+	query := r.db.WithContext(ctx).
+		Model(&models.Telemetry{}).
+		Where("recorded_at BETWEEN ? AND ?", from, to)
+	if len(channels) > 0 {
+		query = query.Where("jsonb_exists_any(channel_values, string_to_array(?, ','))", strings.Join(channels, ","))
+	}
+
+	rows, err := query.Order("recorded_at ASC, id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := make([]models.Telemetry, 0, batchSize)
+	for rows.Next() {
+		var telemetry models.Telemetry
+		if err := r.db.ScanRows(rows, &telemetry); err != nil {
+			return err
+		}
+		batch = append(batch, telemetry)
+
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func (r *telemetryRepository) ChannelNames(ctx context.Context, from, to time.Time) ([]string, error) {
+	var names []string
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT jsonb_object_keys(channel_values) FROM telemetries WHERE recorded_at BETWEEN ? AND ?`, from, to).
+		Scan(&names).
+		Error
+	return names, err
+}
+
 func (r *telemetryRepository) GetLatest(ctx context.Context, limit int) ([]models.Telemetry, error) {
 	if limit < 1 || limit > 1000 {
 		limit = 100
@@ -68,40 +235,106 @@ func (r *telemetryRepository) GetLatest(ctx context.Context, limit int) ([]model
 	return telemetries, err
 }
 
-func (r *telemetryRepository) GetStats(ctx context.Context, from, to time.Time) (*TelemetryStats, error) {
-	var stats TelemetryStats
-
-	// Получаем количество записей
+func (r *telemetryRepository) GetChannelHistory(ctx context.Context, sourceFile, channel string, before time.Time, limit int) ([]models.Telemetry, error) {
+	var telemetries []models.Telemetry
 	err := r.db.WithContext(ctx).
-		Model(&models.Telemetry{}).
-		Where("recorded_at BETWEEN ? AND ?", from, to).
-		Count(&stats.Count).
+		Where("source_file = ?", sourceFile).
+		Where("jsonb_exists(channel_values, ?)", channel).
+		Where("recorded_at < ?", before).
+		Order("recorded_at DESC").
+		Limit(limit).
+		Find(&telemetries).
+		Error
+	return telemetries, err
+}
+
+func (r *telemetryRepository) GetChannelUpdates(ctx context.Context, channel, sourceFile string, afterID uint, since time.Time, limit int) ([]models.Telemetry, error) {
+	query := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Where("jsonb_exists(channel_values, ?)", channel)
+	if sourceFile != "" {
+		query = query.Where("source_file = ?", sourceFile)
+	}
+	if !since.IsZero() {
+		query = query.Where("recorded_at >= ?", since)
+	}
+
+	var telemetries []models.Telemetry
+	err := query.
+		Order("id ASC").
+		Limit(limit).
+		Find(&telemetries).
 		Error
+	return telemetries, err
+}
+
+func (r *telemetryRepository) GetStats(ctx context.Context, from, to time.Time, groupBy string) ([]TelemetryGroupStats, error) {
+	group, ok := telemetryStatsGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown telemetry stats grouping %q", groupBy)
+	}
+
+	// Число показаний в каждой группе
+	countRows, err := r.db.WithContext(ctx).
+		Raw(`SELECT `+group+` AS grp, COUNT(*)
+			FROM telemetries t
+			WHERE t.recorded_at >= ? AND t.recorded_at < ?
+			GROUP BY grp
+			ORDER BY grp`, from, to).
+		Rows()
 	if err != nil {
 		return nil, err
 	}
+	defer countRows.Close()
 
-	if stats.Count == 0 {
-		return &stats, nil
+	var groups []TelemetryGroupStats
+	index := make(map[string]int)
+	for countRows.Next() {
+		stats := TelemetryGroupStats{Channels: make(map[string]ChannelStats)}
+		if err := countRows.Scan(&stats.Group, &stats.Count); err != nil {
+			return nil, err
+		}
+		index[stats.Group] = len(groups)
+		groups = append(groups, stats)
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return []TelemetryGroupStats{}, nil
 	}
 
-	// Получаем средние значения
-	row := r.db.WithContext(ctx).
-		Model(&models.Telemetry{}).
-		Select("AVG(voltage) as avg_voltage, AVG(temperature) as avg_temperature, "+
-			"MIN(voltage) as min_voltage, MAX(voltage) as max_voltage, "+
-			"MIN(temperature) as min_temperature, MAX(temperature) as max_temperature").
-		Where("recorded_at BETWEEN ? AND ?", from, to).
-		Row()
-
-	err = row.Scan(&stats.AvgVoltage, &stats.AvgTemperature,
-		&stats.MinVoltage, &stats.MaxVoltage,
-		&stats.MinTemperature, &stats.MaxTemperature)
+	// Агрегаты и процентили по каждому каналу в группе
+	rows, err := r.db.WithContext(ctx).
+		Raw(`SELECT `+group+` AS grp, v.key,
+				COUNT(*), AVG(x.value), MIN(x.value), MAX(x.value), COALESCE(STDDEV_SAMP(x.value), 0),
+				percentile_cont(0.5) WITHIN GROUP (ORDER BY x.value),
+				percentile_cont(0.95) WITHIN GROUP (ORDER BY x.value),
+				percentile_cont(0.99) WITHIN GROUP (ORDER BY x.value)
+			FROM telemetries t
+			CROSS JOIN LATERAL jsonb_each_text(t.channel_values) v
+			CROSS JOIN LATERAL (SELECT v.value::float8 AS value) x
+			WHERE t.recorded_at >= ? AND t.recorded_at < ?
+			GROUP BY grp, v.key`, from, to).
+		Rows()
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, channelName string
+		var channel ChannelStats
+		if err := rows.Scan(&name, &channelName, &channel.Count, &channel.Avg, &channel.Min, &channel.Max,
+			&channel.StdDev, &channel.P50, &channel.P95, &channel.P99); err != nil {
+			return nil, err
+		}
+		if i, ok := index[name]; ok {
+			groups[i].Channels[channelName] = channel
+		}
+	}
 
-	return &stats, nil
+	return groups, rows.Err()
 }
 
 func (r *telemetryRepository) DeleteOld(ctx context.Context, olderThan time.Time) error {
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"cassiopeia/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// telemetryRollupCursorName единственный курсор: все разрешения считаются из одного диапазона ID
+const telemetryRollupCursorName = "telemetry"
+
+// Единица date_trunc для каждого разрешения
+var telemetryRollupUnits = map[string]string{
+	models.RollupMinute: "minute",
+	models.RollupHour:   "hour",
+	models.RollupDay:    "day",
+}
+
+type TelemetryRollupRepository interface {
+	// Advance досчитывает сводки по следующим batchSize показаниям завершенных транзакций.
+	// Возвращает false, если новых показаний нет.
+	Advance(ctx context.Context, batchSize int) (bool, error)
+	// Query сводки разрешения за период; пустой channels - все каналы
+	Query(ctx context.Context, resolution string, channels []string, from, to time.Time) ([]models.TelemetryRollup, error)
+}
+
+type telemetryRollupRepository struct {
+	db *gorm.DB
+}
+
+func NewTelemetryRollupRepository(db *gorm.DB) TelemetryRollupRepository {
+	return &telemetryRollupRepository{db: db}
+}
+
+func (r *telemetryRollupRepository) Advance(ctx context.Context, batchSize int) (bool, error) {
+	advanced := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокируем курсор, чтобы два экземпляра сервиса не посчитали одни показания дважды
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.TelemetryRollupCursor{Name: telemetryRollupCursorName}).Error; err != nil {
+			return err
+		}
+		var cursor models.TelemetryRollupCursor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("name = ?", telemetryRollupCursorName).
+			First(&cursor).Error; err != nil {
+			return err
+		}
+
+		// ID раздаются до коммита: долгий импорт коммитит показания с ID меньше уже
+		// посчитанных. Поэтому курсор идет по транзакциям и берет только те, что старше
+		// самой старой незавершенной (snapshot xmin) - их набор показаний окончательный
+		var last struct {
+			TxID string
+			ID   uint
+		}
+		if err := tx.Raw(`SELECT tx_id::text AS tx_id, id FROM (
+				SELECT tx_id, id FROM telemetries
+				WHERE (tx_id, id) > (CAST(? AS text)::xid8, ?) AND tx_id < pg_snapshot_xmin(pg_current_snapshot())
+				ORDER BY tx_id, id LIMIT ?
+			) batch
+			ORDER BY tx_id DESC, id DESC LIMIT 1`, strconv.FormatUint(cursor.LastTxID, 10), cursor.LastID, batchSize).
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		if last.TxID == "" {
+			return nil
+		}
+		lastTxID, err := strconv.ParseUint(last.TxID, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		for _, resolution := range []string{models.RollupMinute, models.RollupHour, models.RollupDay} {
+			err := tx.Exec(`INSERT INTO telemetry_rollups
+					(resolution, channel, bucket, value_count, value_sum, value_sum_squares, value_min, value_max, updated_at)
+				SELECT ?::text, v.key, date_trunc(?::text, t.recorded_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+					COUNT(*), SUM(v.value::float8), SUM(v.value::float8 * v.value::float8),
+					MIN(v.value::float8), MAX(v.value::float8), NOW()
+				FROM telemetries t, jsonb_each_text(t.channel_values) v
+				WHERE (t.tx_id, t.id) > (CAST(? AS text)::xid8, ?) AND (t.tx_id, t.id) <= (CAST(? AS text)::xid8, ?)
+				GROUP BY 2, 3
+				ON CONFLICT (resolution, channel, bucket) DO UPDATE SET
+					value_count = telemetry_rollups.value_count + EXCLUDED.value_count,
+					value_sum = telemetry_rollups.value_sum + EXCLUDED.value_sum,
+					value_sum_squares = telemetry_rollups.value_sum_squares + EXCLUDED.value_sum_squares,
+					value_min = LEAST(telemetry_rollups.value_min, EXCLUDED.value_min),
+					value_max = GREATEST(telemetry_rollups.value_max, EXCLUDED.value_max),
+					updated_at = NOW()`,
+				resolution, telemetryRollupUnits[resolution],
+				strconv.FormatUint(cursor.LastTxID, 10), cursor.LastID, last.TxID, last.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		advanced = true
+		return tx.Model(&cursor).Updates(map[string]interface{}{"last_tx_id": lastTxID, "last_id": last.ID}).Error
+	})
+	return advanced, err
+}
+
+func (r *telemetryRollupRepository) Query(ctx context.Context, resolution string, channels []string, from, to time.Time) ([]models.TelemetryRollup, error) {
+	query := r.db.WithContext(ctx).
+		Where("resolution = ?", resolution).
+		Where("bucket BETWEEN ? AND ?", from, to)
+	if len(channels) > 0 {
+		query = query.Where("channel IN ?", channels)
+	}
+
+	var rollups []models.TelemetryRollup
+	err := query.
+		Order("channel ASC, bucket ASC").
+		Find(&rollups).
+		Error
+	return rollups, err
+}
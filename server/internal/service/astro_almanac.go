@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cassiopeia/pkg/astro"
+)
+
+var ErrAlmanacInvalid = errors.New("invalid almanac request")
+
+// Границы лет: вне их полиномы ΔT и ряды Меёса для сезонов заметно теряют точность
+const (
+	AlmanacMinYear  = 1900
+	AlmanacMaxYear  = 2150
+	AlmanacMaxYears = 10
+)
+
+// Almanac затмения, сезоны и экстремальные полнолуния за годы [FromYear, ToYear]
+// с обстоятельствами для наблюдателя. Все моменты - в его поясе.
+type Almanac struct {
+	FromYear      int                     `json:"from_year"`
+	ToYear        int                     `json:"to_year"`
+	Location      string                  `json:"location,omitempty"`
+	Timezone      string                  `json:"timezone"`
+	Lat           float64                 `json:"lat"`
+	Lon           float64                 `json:"lon"`
+	Seasons       []astro.Season          `json:"seasons"`
+	SolarEclipses []AlmanacSolarEclipse   `json:"solar_eclipses"`
+	LunarEclipses []AlmanacLunarEclipse   `json:"lunar_eclipses"`
+	FullMoons     []astro.FullMoonExtreme `json:"full_moons"`
+}
+
+// AlmanacSolarEclipse глобальные обстоятельства и то, как затмение выглядит у наблюдателя.
+// Local равен nil, если из этого места Луна не закрывает Солнце.
+type AlmanacSolarEclipse struct {
+	astro.SolarEclipse
+	Local *astro.LocalSolarEclipse `json:"local"`
+}
+
+// AlmanacLunarEclipse лунное затмение; контакты содержат высоту Луны у наблюдателя
+type AlmanacLunarEclipse struct {
+	astro.LunarEclipse
+	Visible bool `json:"visible"`
+}
+
+func (s *astroService) GetAlmanac(ctx context.Context, observer Observer, fromYear, toYear int) (*Almanac, error) {
+	if err := validateCoordinates(observer.Lat, observer.Lon); err != nil {
+		return nil, err
+	}
+	if toYear == 0 {
+		toYear = fromYear
+	}
+	switch {
+	case fromYear < AlmanacMinYear || toYear > AlmanacMaxYear:
+		return nil, fmt.Errorf("%w: years must be within %d-%d", ErrAlmanacInvalid, AlmanacMinYear, AlmanacMaxYear)
+	case toYear < fromYear:
+		return nil, fmt.Errorf("%w: to_year is before year", ErrAlmanacInvalid)
+	case toYear-fromYear+1 > AlmanacMaxYears:
+		return nil, fmt.Errorf("%w: at most %d years per request", ErrAlmanacInvalid, AlmanacMaxYears)
+	}
+
+	zone := observer.location()
+	cacheKey := fmt.Sprintf("astro:almanac:%.4f:%.4f:%.0f:%s:%d:%d",
+		observer.Lat, observer.Lon, observer.Elevation, zone.String(), fromYear, toYear)
+
+	var cached Almanac
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.FromYear != 0 {
+		cached.Location = observer.Name
+		return &cached, nil
+	}
+
+	// Границы берем по UTC, как в опубликованных каталогах затмений
+	from := time.Date(fromYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(toYear+1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	almanac := &Almanac{
+		FromYear:      fromYear,
+		ToYear:        toYear,
+		Location:      observer.Name,
+		Timezone:      zone.String(),
+		Lat:           observer.Lat,
+		Lon:           observer.Lon,
+		SolarEclipses: []AlmanacSolarEclipse{},
+		LunarEclipses: []AlmanacLunarEclipse{},
+	}
+
+	for year := fromYear; year <= toYear; year++ {
+		for _, season := range astro.Seasons(year) {
+			season.Time = season.Time.In(zone)
+			almanac.Seasons = append(almanac.Seasons, season)
+		}
+	}
+
+	for _, eclipse := range astro.SolarEclipses(from, to) {
+		local := eclipse.Local(observer.Lat, observer.Lon, observer.Elevation)
+		eclipse.Greatest = eclipse.Greatest.In(zone)
+		if local != nil {
+			localizeContacts(local.Contacts, zone)
+		}
+		almanac.SolarEclipses = append(almanac.SolarEclipses, AlmanacSolarEclipse{
+			SolarEclipse: eclipse,
+			Local:        local,
+		})
+	}
+
+	for _, eclipse := range astro.LunarEclipses(from, to) {
+		contacts, visible := eclipse.Local(observer.Lat, observer.Lon)
+		localizeContacts(contacts, zone)
+		eclipse.Contacts = contacts
+		eclipse.Greatest = eclipse.Greatest.In(zone)
+		almanac.LunarEclipses = append(almanac.LunarEclipses, AlmanacLunarEclipse{
+			LunarEclipse: eclipse,
+			Visible:      visible,
+		})
+	}
+
+	almanac.FullMoons = astro.FullMoonExtremes(from, to)
+	for i := range almanac.FullMoons {
+		almanac.FullMoons[i].Time = almanac.FullMoons[i].Time.In(zone)
+	}
+
+	// Результат детерминирован - кэшируем надолго
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, almanac, 30*24*time.Hour); err != nil {
+		log.Printf("Failed to cache almanac: %v", err)
+	}
+
+	return almanac, nil
+}
+
+func localizeContacts(contacts []astro.EclipseContact, zone *time.Location) {
+	for i := range contacts {
+		contacts[i].Time = contacts[i].Time.In(zone)
+	}
+}
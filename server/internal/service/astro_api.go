@@ -0,0 +1,228 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/clients"
+	"cassiopeia/pkg/astro"
+)
+
+// ErrAstroMalformed запись AstronomyAPI не соответствует схеме и отброшена
+var ErrAstroMalformed = errors.New("malformed AstronomyAPI record")
+
+// Подтипы событий AstroEvent
+const (
+	AstroSubtypeRise        = "rise"
+	AstroSubtypeSet         = "set"
+	AstroSubtypeTransit     = "transit"
+	AstroSubtypeTwilight    = "twilight"
+	AstroSubtypePhase       = "phase"
+	AstroSubtypePolar       = "polar"
+	AstroSubtypeEclipse     = "eclipse"
+	AstroSubtypeOccultation = "occultation"
+)
+
+// eventsFromAPI переводит ответ /bodies/events в события. Некорректные записи
+// не попадают в результат, а возвращаются объединенной ошибкой вместе с остальными событиями.
+func eventsFromAPI(resp *clients.AstroEventsResponse) ([]AstroEvent, error) {
+	if resp == nil || resp.Data.Table == nil {
+		return nil, fmt.Errorf("%w: response has no data.table", ErrAstroMalformed)
+	}
+
+	var events []AstroEvent
+	var errs []error
+	for r, row := range resp.Data.Table.Rows {
+		if row.Entry.ID == "" {
+			errs = append(errs, fmt.Errorf("%w: row %d has no body id", ErrAstroMalformed, r))
+			continue
+		}
+
+		for c, cell := range row.Cells {
+			event, err := eventFromCell(row.Entry, cell)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s cell %d: %w", row.Entry.ID, c, err))
+			} else {
+				events = append(events, *event)
+			}
+
+			// Восход и заход тела в день события - отдельные записи со своими ошибками
+			for _, edge := range []struct {
+				value   *string
+				subtype string
+			}{{cell.Rise, AstroSubtypeRise}, {cell.Set, AstroSubtypeSet}} {
+				if edge.value == nil {
+					continue
+				}
+				when, err := parseAPITime(*edge.value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s cell %d %s: %w", row.Entry.ID, c, edge.subtype, err))
+					continue
+				}
+				events = append(events, AstroEvent{
+					Name:    entryName(row.Entry),
+					Body:    row.Entry.ID,
+					Type:    row.Entry.ID + edge.subtype,
+					Subtype: edge.subtype,
+					When:    when,
+					Source:  AstroSourceAPI,
+				})
+			}
+		}
+	}
+
+	return events, errors.Join(errs...)
+}
+
+// eventFromCell затмение или покрытие; обязателен момент peak
+func eventFromCell(entry clients.AstroEntry, cell clients.AstroEventCell) (*AstroEvent, error) {
+	var subtype string
+	switch {
+	case cell.Type == "":
+		return nil, fmt.Errorf("%w: event has no type", ErrAstroMalformed)
+	case strings.Contains(cell.Type, "eclipse"):
+		subtype = AstroSubtypeEclipse
+	case strings.Contains(cell.Type, "occultation"):
+		subtype = AstroSubtypeOccultation
+	default:
+		return nil, fmt.Errorf("%w: unsupported event type %q", ErrAstroMalformed, cell.Type)
+	}
+
+	peak, ok := cell.EventHighlights["peak"]
+	if !ok || peak == nil {
+		return nil, fmt.Errorf("%w: %s has no peak", ErrAstroMalformed, cell.Type)
+	}
+
+	event := &AstroEvent{
+		Name:    entryName(entry),
+		Body:    entry.ID,
+		Type:    cell.Type,
+		Subtype: subtype,
+		Source:  AstroSourceAPI,
+	}
+
+	// Начало и конец - самый ранний и самый поздний из ключевых моментов
+	for key, highlight := range cell.EventHighlights {
+		if highlight == nil {
+			continue
+		}
+		when, err := parseAPITime(highlight.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", cell.Type, key, err)
+		}
+		if key == "peak" {
+			event.When = when
+		}
+		if event.Start == nil || when.Before(*event.Start) {
+			start := when
+			event.Start = &start
+		}
+		if event.End == nil || when.After(*event.End) {
+			end := when
+			event.End = &end
+		}
+	}
+
+	if peak.Altitude != nil {
+		event.Altitude = float64(*peak.Altitude)
+	}
+	if cell.ExtraInfo.Obscuration != nil {
+		event.Obscuration = float64(*cell.ExtraInfo.Obscuration)
+	}
+
+	details := strings.ReplaceAll(cell.Type, "_", " ")
+	details = strings.ToUpper(details[:1]) + details[1:]
+	if event.Obscuration > 0 {
+		details += fmt.Sprintf(", obscuration %.0f%%", event.Obscuration*100)
+	}
+	if peak.Altitude != nil && event.Altitude < 0 {
+		details += ", below the horizon at peak"
+	}
+	event.Details = details
+
+	return event, nil
+}
+
+// parseAPITime время AstronomyAPI ("2024-04-08T18:17:16.000+00:00"); без подстановки текущего времени
+func parseAPITime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: empty time", ErrAstroMalformed)
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid time %q", ErrAstroMalformed, value)
+	}
+	return t, nil
+}
+
+func entryName(entry clients.AstroEntry) string {
+	if entry.Name != "" {
+		return entry.Name
+	}
+	return entry.ID
+}
+
+// positionsFromAPI ячейки /bodies/positions по id тела
+func positionsFromAPI(resp *clients.AstroPositionsResponse) (map[astro.Body]clients.AstroPositionCell, error) {
+	if resp == nil || resp.Data.Table == nil {
+		return nil, fmt.Errorf("%w: response has no data.table", ErrAstroMalformed)
+	}
+
+	cells := make(map[astro.Body]clients.AstroPositionCell)
+	for _, row := range resp.Data.Table.Rows {
+		if len(row.Cells) == 0 {
+			continue
+		}
+		cells[astro.Body(row.Entry.ID)] = row.Cells[0]
+	}
+	return cells, nil
+}
+
+// applyAPIPosition переносит в pos координаты, расстояние и блеск из ответа API.
+// Эклиптические координаты API не отдает - они остаются из локального расчета.
+func applyAPIPosition(pos *astro.BodyPosition, cell clients.AstroPositionCell, at time.Time) error {
+	when, err := parseAPITime(cell.Date)
+	if err != nil {
+		return err
+	}
+	if math.Abs(when.Sub(at).Seconds()) > 60 {
+		return fmt.Errorf("%w: position is for %s, requested %s", ErrAstroMalformed,
+			when.Format(time.RFC3339), at.Format(time.RFC3339))
+	}
+
+	p := cell.Position
+	required := map[string]*clients.APIFloat{
+		"altitude":    p.Horizontal.Altitude.Degrees,
+		"azimuth":     p.Horizontal.Azimuth.Degrees,
+		"ra":          p.Equatorial.RightAscension.Hours,
+		"declination": p.Equatorial.Declination.Degrees,
+		"distance":    cell.Distance.FromEarth.AU,
+	}
+	for name, value := range required {
+		if value == nil {
+			return fmt.Errorf("%w: %s has no %s", ErrAstroMalformed, cell.ID, name)
+		}
+	}
+
+	pos.Altitude = float64(*p.Horizontal.Altitude.Degrees)
+	pos.Azimuth = float64(*p.Horizontal.Azimuth.Degrees)
+	pos.RA = float64(*p.Equatorial.RightAscension.Hours) * 15
+	pos.Dec = float64(*p.Equatorial.Declination.Degrees)
+	pos.DistanceAU = float64(*cell.Distance.FromEarth.AU)
+	if p.Constellation.Name != "" {
+		pos.Constellation = p.Constellation.Name
+	}
+	if m := cell.ExtraInfo.Magnitude; m != nil {
+		pos.Magnitude = float64(*m)
+	}
+	if e := cell.ExtraInfo.Elongation; e != nil {
+		pos.Elongation = float64(*e)
+	}
+	if phase := cell.ExtraInfo.Phase; phase != nil && phase.Fraction != nil {
+		pos.Illumination = float64(*phase.Fraction)
+	}
+	return nil
+}
@@ -2,33 +2,100 @@ package service
 
 import (
 	"context"
-	_ "encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"cassiopeia/internal/clients"
 	"cassiopeia/internal/repository"
+	"cassiopeia/pkg/astro"
 )
 
 type AstroService interface {
-	GetEvents(ctx context.Context, lat, lon float64, days int) ([]AstroEvent, error)
+	// GetEvents берет события из AstronomyAPI, а если он недоступен - считает их локально
+	// Время событий - в поясе наблюдателя
+	GetEvents(ctx context.Context, observer Observer, days int) ([]AstroEvent, error)
 	GetBodies(ctx context.Context) (map[string]interface{}, error) // ДОБАВИТЬ
-	GetMoonPhase(ctx context.Context, date time.Time) (map[string]interface{}, error)
+	// GetMoonPhase фаза считается локально, изображение берется из AstronomyAPI
+	GetMoonPhase(ctx context.Context, observer Observer, date time.Time) (*MoonPhaseInfo, error)
+	GetSkyTimes(ctx context.Context, observer Observer, date time.Time) (*SkyTimes, error)
+	// GetPositions положения Солнца, Луны и планет: из AstronomyAPI, а при его
+	// недоступности или некорректном ответе - по локальной эфемериде
+	GetPositions(ctx context.Context, observer Observer, at time.Time) (*BodyPositions, error)
+	// GetAlmanac затмения, равноденствия, солнцестояния и суперлуния за годы
+	// [fromYear, toYear] (toYear = 0 - один год) с видимостью у наблюдателя
+	GetAlmanac(ctx context.Context, observer Observer, fromYear, toYear int) (*Almanac, error)
 }
 
+// BodyPositions положения тел для наблюдателя в момент времени
+type BodyPositions struct {
+	Time     time.Time   `json:"time"`
+	Location string      `json:"location,omitempty"`
+	Timezone string      `json:"timezone"`
+	Lat      float64     `json:"lat"`
+	Lon      float64     `json:"lon"`
+	Bodies   []BodyState `json:"bodies"`
+}
+
+// BodyState положение тела и его восход, кульминация и заход в местные сутки
+type BodyState struct {
+	astro.BodyPosition
+	// RAHours прямое восхождение в часах, как принято в каталогах
+	RAHours      float64 `json:"ra_hours"`
+	AboveHorizon bool    `json:"above_horizon"`
+	// Source откуда взято положение: AstronomyAPI или локальная эфемерида
+	Source string `json:"source"`
+	astro.RiseTransitSet
+}
+
+// MoonPhaseInfo фаза Луны и, если доступен AstronomyAPI, ссылка на ее изображение
+type MoonPhaseInfo struct {
+	astro.MoonPhase
+	ImageURL string `json:"image_url,omitempty"`
+	Source   string `json:"source"`
+}
+
+// SkyTimes восход/заход Солнца и Луны, сумерки и фаза Луны за сутки
+type SkyTimes struct {
+	Date     string          `json:"date"`
+	Location string          `json:"location,omitempty"`
+	Timezone string          `json:"timezone"`
+	Lat      float64         `json:"lat"`
+	Lon      float64         `json:"lon"`
+	Sun      astro.SunTimes  `json:"sun"`
+	Moon     astro.MoonTimes `json:"moon"`
+	Phase    astro.MoonPhase `json:"phase"`
+}
+
+const (
+	AstroSourceAPI     = "astronomyapi"
+	AstroSourceOffline = "offline"
+)
+
 type astroService struct {
 	cacheRepo repository.CacheRepository
 	client    clients.AstroClient
 }
 
+// AstroEvent событие на небе. Type - конкретный вид (sunrise, civil_dusk,
+// total_lunar_eclipse, ...), Subtype - его класс (AstroSubtype*).
+// Для протяженных событий When - момент максимума, Start и End - границы.
 type AstroEvent struct {
-	Name      string    `json:"name"`
-	Type      string    `json:"type"`
-	When      time.Time `json:"when"`
-	Magnitude float64   `json:"magnitude,omitempty"`
-	Altitude  float64   `json:"altitude,omitempty"`
-	Details   string    `json:"details,omitempty"`
+	Name        string     `json:"name"`
+	Body        string     `json:"body,omitempty"`
+	Type        string     `json:"type"`
+	Subtype     string     `json:"subtype"`
+	When        time.Time  `json:"when"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+	Magnitude   float64    `json:"magnitude,omitempty"`
+	Altitude    float64    `json:"altitude,omitempty"`
+	Obscuration float64    `json:"obscuration,omitempty"`
+	Details     string     `json:"details,omitempty"`
+	Source      string     `json:"source,omitempty"`
 }
 
 func NewAstroService(
@@ -41,13 +108,14 @@ func NewAstroService(
 	}
 }
 
-func (s *astroService) GetEvents(ctx context.Context, lat, lon float64, days int) ([]AstroEvent, error) {
+func (s *astroService) GetEvents(ctx context.Context, observer Observer, days int) ([]AstroEvent, error) {
 	if days < 1 || days > 30 {
 		days = 7
 	}
+	lat, lon, zone := observer.Lat, observer.Lon, observer.location()
 
-	// Генерируем ключ кэша
-	cacheKey := fmt.Sprintf("astro:events:%.4f:%.4f:%d", lat, lon, days)
+	// Генерируем ключ кэша; от пояса зависят границы суток и время событий
+	cacheKey := fmt.Sprintf("astro:events:v2:%.4f:%.4f:%s:%d", lat, lon, zone.String(), days)
 
 	// Пробуем получить из кэша
 	var cachedEvents []AstroEvent
@@ -58,200 +126,359 @@ func (s *astroService) GetEvents(ctx context.Context, lat, lon float64, days int
 
 	log.Printf("Fetching astronomy events for lat=%.4f, lon=%.4f, days=%d", lat, lon, days)
 
-	// Получаем данные от API
-	rawData, err := s.client.GetEvents(ctx, lat, lon, days)
+	// Сумерки, кульминацию и фазы API не отдает - их считаем всегда
+	events := computeAstroEvents(lat, lon, zone, days)
+
+	ttl := 6 * time.Hour
+	apiEvents, err := s.fetchAPIEvents(ctx, observer, zone, days)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch astronomy events: %w", err)
+		if !errors.Is(err, clients.ErrAstroNotConfigured) {
+			log.Printf("AstronomyAPI unavailable, computing events offline: %v", err)
+			// Сбой API может быть временным - локальный результат держим недолго
+			ttl = 30 * time.Minute
+		}
+	} else {
+		events = mergeAstroEvents(apiEvents, events, zone)
 	}
 
-	// Парсим данные
-	events := s.parseEvents(rawData)
+	for i := range events {
+		events[i].When = events[i].When.In(zone)
+		if events[i].Start != nil {
+			start := events[i].Start.In(zone)
+			events[i].Start = &start
+		}
+		if events[i].End != nil {
+			end := events[i].End.In(zone)
+			events[i].End = &end
+		}
+	}
 
 	// Кэшируем на 6 часов
-	if err := s.cacheRepo.SetJSON(ctx, cacheKey, events, 6*time.Hour); err != nil {
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, events, ttl); err != nil {
 		log.Printf("Failed to cache astronomy events: %v", err)
 	}
 
 	return events, nil
 }
 
-func (s *astroService) parseEvents(rawData map[string]interface{}) []AstroEvent {
-	var events []AstroEvent
-
-	// Рекурсивно ищем события в JSON
-	s.traverseJSON(rawData, &events)
-	return events
-}
+// fetchAPIEvents события Солнца и Луны из AstronomyAPI. Отброшенные записи
+// логируются; если не удалось разобрать ни одной, это ошибка.
+func (s *astroService) fetchAPIEvents(ctx context.Context, observer Observer, zone *time.Location, days int) ([]AstroEvent, error) {
+	now := time.Now().In(zone)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
+	to := from.AddDate(0, 0, days)
 
-func (s *astroService) traverseJSON(data interface{}, events *[]AstroEvent) {
-	switch v := data.(type) {
-	case map[string]interface{}:
-		// Проверяем, является ли этот объект событием
-		if s.isEventObject(v) {
-			event := s.normalizeEvent(v)
-			if event.Name != "" && event.Type != "" {
-				*events = append(*events, event)
-			}
+	var events []AstroEvent
+	var rejected []error
+	for _, body := range []string{"sun", "moon"} {
+		resp, err := s.client.GetEvents(ctx, body, observer.Lat, observer.Lon, observer.Elevation, from, to)
+		if err != nil {
+			return nil, err
 		}
 
-		// Рекурсивно обходим все поля
-		for _, value := range v {
-			s.traverseJSON(value, events)
+		bodyEvents, err := eventsFromAPI(resp)
+		if err != nil {
+			rejected = append(rejected, fmt.Errorf("%s: %w", body, err))
+		}
+		for _, event := range bodyEvents {
+			// Восход и заход в дни событий могут выходить за запрошенный интервал
+			if event.When.Before(from) || !event.When.Before(to) {
+				continue
+			}
+			events = append(events, event)
 		}
+	}
 
-	case []interface{}:
-		// Обходим массив
-		for _, item := range v {
-			s.traverseJSON(item, events)
+	if len(rejected) > 0 {
+		log.Printf("AstronomyAPI returned malformed records: %v", errors.Join(rejected...))
+		if len(events) == 0 {
+			return nil, fmt.Errorf("no valid AstronomyAPI events: %w", errors.Join(rejected...))
 		}
 	}
+	return events, nil
 }
 
-func (s *astroService) isEventObject(obj map[string]interface{}) bool {
-	// Проверяем наличие полей, характерных для астрономических событий
-	hasType := false
-	hasName := false
-	hasTime := false
-
-	for key, val := range obj {
-		switch key {
-		case "type", "event_type", "category", "kind":
-			if val != nil {
-				hasType = true
-			}
-		case "name", "body", "object", "target":
-			if val != nil {
-				hasName = true
-			}
-		case "time", "date", "occursAt", "peak", "instant":
-			if val != nil {
-				hasTime = true
-			}
+// mergeAstroEvents объединяет события API с локальными: событие API заменяет
+// локальное того же типа в те же местные сутки
+func mergeAstroEvents(apiEvents, offline []AstroEvent, zone *time.Location) []AstroEvent {
+	key := func(event AstroEvent) string {
+		return event.Type + "|" + event.When.In(zone).Format("2006-01-02")
+	}
+
+	seen := make(map[string]bool, len(apiEvents))
+	merged := make([]AstroEvent, 0, len(apiEvents)+len(offline))
+	for _, event := range apiEvents {
+		seen[key(event)] = true
+		merged = append(merged, event)
+	}
+	for _, event := range offline {
+		if !seen[key(event)] {
+			merged = append(merged, event)
 		}
 	}
 
-	return hasType && hasName && hasTime
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].When.Before(merged[j].When)
+	})
+	return merged
 }
 
-func (s *astroService) normalizeEvent(obj map[string]interface{}) AstroEvent {
-	event := AstroEvent{}
-
-	// Извлекаем имя
-	event.Name = s.extractString(obj, "name", "body", "object", "target")
+func (s *astroService) GetBodies(ctx context.Context) (map[string]interface{}, error) {
+	cacheKey := "astro:bodies"
 
-	// Извлекаем тип
-	event.Type = s.extractString(obj, "type", "event_type", "category", "kind")
+	// Пробуем кэш
+	var cachedBodies map[string]interface{}
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cachedBodies); err == nil && cachedBodies != nil {
+		return cachedBodies, nil
+	}
 
-	// Извлекаем время
-	event.When = s.extractTime(obj)
+	// Получаем от API
+	bodies, err := s.client.GetBodies(ctx)
+	if err != nil {
+		if !errors.Is(err, clients.ErrAstroNotConfigured) {
+			log.Printf("AstronomyAPI unavailable, listing offline bodies: %v", err)
+		}
+		// Без API отдаем тела, которые считает локальная эфемерида
+		list := make([]map[string]interface{}, 0, len(astro.Bodies))
+		for _, body := range astro.Bodies {
+			list = append(list, map[string]interface{}{"id": string(body), "name": body.Name()})
+		}
+		return map[string]interface{}{
+			"data":   map[string]interface{}{"bodies": list},
+			"source": AstroSourceOffline,
+		}, nil
+	}
 
-	// Извлекаем дополнительные данные
-	event.Magnitude = s.extractFloat(obj, "magnitude", "mag")
-	event.Altitude = s.extractFloat(obj, "altitude")
-	event.Details = s.extractString(obj, "note", "description")
+	// Кэшируем на 24 часа
+	s.cacheRepo.SetJSON(ctx, cacheKey, bodies, 24*time.Hour)
 
-	return event
+	return bodies, nil
 }
 
-func (s *astroService) extractString(obj map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := obj[key]; ok {
-			if str, ok := val.(string); ok && str != "" {
-				return str
-			}
-		}
+func (s *astroService) GetPositions(ctx context.Context, observer Observer, at time.Time) (*BodyPositions, error) {
+	lat, lon := observer.Lat, observer.Lon
+	if err := validateCoordinates(lat, lon); err != nil {
+		return nil, err
 	}
-	return ""
-}
 
-func (s *astroService) extractFloat(obj map[string]interface{}, keys ...string) float64 {
-	for _, key := range keys {
-		if val, ok := obj[key]; ok {
-			switch v := val.(type) {
-			case float64:
-				return v
-			case float32:
-				return float64(v)
-			case int:
-				return float64(v)
-			case string:
-				var f float64
-				if _, err := fmt.Sscanf(v, "%f", &f); err == nil {
-					return f
-				}
+	zone := observer.location()
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.In(zone)
+
+	result := &BodyPositions{
+		Time:     at,
+		Location: observer.Name,
+		Timezone: zone.String(),
+		Lat:      lat,
+		Lon:      lon,
+		Bodies:   make([]BodyState, 0, len(astro.Bodies)),
+	}
+
+	// API отвечает с точностью до минуты - на нее же округляем запрос и кэш
+	apiCells := s.fetchAPIPositions(ctx, observer, at.Truncate(time.Minute))
+
+	for _, body := range astro.Bodies {
+		position := astro.Position(body, at, lat, lon)
+		source := AstroSourceOffline
+		if cell, ok := apiCells[body]; ok {
+			apiPosition := position
+			if err := applyAPIPosition(&apiPosition, cell, at.Truncate(time.Minute)); err != nil {
+				log.Printf("Rejected AstronomyAPI position of %s: %v", body, err)
+			} else {
+				position, source = apiPosition, AstroSourceAPI
 			}
 		}
+
+		result.Bodies = append(result.Bodies, BodyState{
+			BodyPosition: position,
+			RAHours:      position.RA / 15,
+			AboveHorizon: position.Altitude > 0,
+			Source:       source,
+			// Восход, кульминация и заход API не отдает - они всегда локальные
+			RiseTransitSet: astro.BodyEvents(body, at, lat, lon),
+		})
 	}
-	return 0
+
+	return result, nil
 }
 
-func (s *astroService) extractTime(obj map[string]interface{}) time.Time {
-	timeKeys := []string{"time", "date", "occursAt", "peak", "instant"}
-
-	for _, key := range timeKeys {
-		if val, ok := obj[key]; ok {
-			switch v := val.(type) {
-			case string:
-				// Пробуем разные форматы времени
-				formats := []string{
-					time.RFC3339,
-					"2006-01-02T15:04:05Z",
-					"2006-01-02 15:04:05",
-					"2006-01-02",
-				}
-
-				for _, format := range formats {
-					if t, err := time.Parse(format, v); err == nil {
-						return t
-					}
-				}
-			case float64:
-				return time.Unix(int64(v), 0)
+// fetchAPIPositions ячейки положений AstronomyAPI по телам; при ошибке - пусто
+func (s *astroService) fetchAPIPositions(ctx context.Context, observer Observer, at time.Time) map[astro.Body]clients.AstroPositionCell {
+	cacheKey := fmt.Sprintf("astro:positions:%.4f:%.4f:%s", observer.Lat, observer.Lon, at.UTC().Format("200601021504"))
+
+	var resp clients.AstroPositionsResponse
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &resp); err != nil || resp.Data.Table == nil {
+		fetched, err := s.client.GetPositions(ctx, observer.Lat, observer.Lon, observer.Elevation, at)
+		if err != nil {
+			if !errors.Is(err, clients.ErrAstroNotConfigured) {
+				log.Printf("AstronomyAPI unavailable, computing positions offline: %v", err)
+			}
+			return nil
+		}
+		resp = *fetched
+		if resp.Data.Table != nil {
+			if err := s.cacheRepo.SetJSON(ctx, cacheKey, resp, 10*time.Minute); err != nil {
+				log.Printf("Failed to cache astronomy positions: %v", err)
 			}
 		}
 	}
 
-	return time.Now()
+	cells, err := positionsFromAPI(&resp)
+	if err != nil {
+		log.Printf("Rejected AstronomyAPI positions: %v", err)
+		return nil
+	}
+	return cells
 }
 
-func (s *astroService) GetBodies(ctx context.Context) (map[string]interface{}, error) {
-	cacheKey := "astro:bodies"
+func (s *astroService) GetMoonPhase(ctx context.Context, observer Observer, date time.Time) (*MoonPhaseInfo, error) {
+	if err := validateCoordinates(observer.Lat, observer.Lon); err != nil {
+		return nil, err
+	}
 
-	// Пробуем кэш
-	var cachedBodies map[string]interface{}
-	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cachedBodies); err == nil && cachedBodies != nil {
-		return cachedBodies, nil
+	info := &MoonPhaseInfo{
+		MoonPhase: astro.PhaseAt(date),
+		Source:    AstroSourceOffline,
 	}
 
-	// Получаем от API
-	bodies, err := s.client.GetBodies(ctx)
+	// Изображение зависит от даты и (через ориентацию серпа) от широты места
+	day := date.In(observer.location()).Format("2006-01-02")
+	cacheKey := fmt.Sprintf("astro:moon-image:%s:%.0f:%.0f", day, observer.Lat, observer.Lon)
+	if imageURL, err := s.cacheRepo.Get(ctx, cacheKey); err == nil && imageURL != "" {
+		info.ImageURL, info.Source = imageURL, AstroSourceAPI
+		return info, nil
+	}
+
+	resp, err := s.client.GetMoonPhase(ctx, observer.Lat, observer.Lon, date)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch celestial bodies: %w", err)
+		if !errors.Is(err, clients.ErrAstroNotConfigured) {
+			log.Printf("AstronomyAPI unavailable, moon phase without image: %v", err)
+		}
+		return info, nil
+	}
+	if !strings.HasPrefix(resp.Data.ImageURL, "http") {
+		log.Printf("Rejected AstronomyAPI moon phase: %v",
+			fmt.Errorf("%w: invalid imageUrl %q", ErrAstroMalformed, resp.Data.ImageURL))
+		return info, nil
 	}
 
-	// Кэшируем на 24 часа
-	s.cacheRepo.SetJSON(ctx, cacheKey, bodies, 24*time.Hour)
+	info.ImageURL, info.Source = resp.Data.ImageURL, AstroSourceAPI
+	if err := s.cacheRepo.Set(ctx, cacheKey, info.ImageURL, 30*24*time.Hour); err != nil {
+		log.Printf("Failed to cache moon phase image: %v", err)
+	}
+	return info, nil
+}
 
-	return bodies, nil
+func (s *astroService) GetSkyTimes(ctx context.Context, observer Observer, date time.Time) (*SkyTimes, error) {
+	lat, lon := observer.Lat, observer.Lon
+	if err := validateCoordinates(lat, lon); err != nil {
+		return nil, err
+	}
+
+	zone := observer.location()
+	if date.IsZero() {
+		date = time.Now()
+	}
+	// Календарная дата запроса в местном поясе; время берем на полдень
+	local := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, zone)
+
+	return &SkyTimes{
+		Date:     local.Format("2006-01-02"),
+		Location: observer.Name,
+		Timezone: zone.String(),
+		Lat:      lat,
+		Lon:      lon,
+		Sun:      astro.SunEvents(local, lat, lon),
+		Moon:     astro.MoonEvents(local, lat, lon),
+		Phase:    astro.PhaseAt(local),
+	}, nil
+}
+
+var offlineSubtypes = map[string]string{
+	"sunrise":           AstroSubtypeRise,
+	"moonrise":          AstroSubtypeRise,
+	"sunset":            AstroSubtypeSet,
+	"moonset":           AstroSubtypeSet,
+	"solar_noon":        AstroSubtypeTransit,
+	"astronomical_dawn": AstroSubtypeTwilight,
+	"nautical_dawn":     AstroSubtypeTwilight,
+	"civil_dawn":        AstroSubtypeTwilight,
+	"civil_dusk":        AstroSubtypeTwilight,
+	"nautical_dusk":     AstroSubtypeTwilight,
+	"astronomical_dusk": AstroSubtypeTwilight,
+	"polar_day":         AstroSubtypePolar,
+	"polar_night":       AstroSubtypePolar,
+	"moon_phase":        AstroSubtypePhase,
 }
 
-func (s *astroService) GetMoonPhase(ctx context.Context, date time.Time) (map[string]interface{}, error) {
-	cacheKey := fmt.Sprintf("astro:moon_phase:%s", date.Format("2006-01-02"))
+// computeAstroEvents локальный расчет событий на days суток вперед
+func computeAstroEvents(lat, lon float64, zone *time.Location, days int) []AstroEvent {
+	now := time.Now().In(zone)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
 
-	// Пробуем кэш
-	var cachedPhase map[string]interface{}
-	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cachedPhase); err == nil && cachedPhase != nil {
-		return cachedPhase, nil
+	var events []AstroEvent
+	add := func(name, eventType string, when *time.Time, details string) {
+		if when == nil {
+			return
+		}
+		body := "sun"
+		if eventType == "moonrise" || eventType == "moonset" || eventType == "moon_phase" {
+			body = "moon"
+		}
+		events = append(events, AstroEvent{
+			Name:    name,
+			Body:    body,
+			Type:    eventType,
+			Subtype: offlineSubtypes[eventType],
+			When:    *when,
+			Details: details,
+			Source:  AstroSourceOffline,
+		})
 	}
 
-	// Получаем от API
-	phase, err := s.client.GetMoonPhase(ctx, date)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch moon phase: %w", err)
+	for i := 0; i < days; i++ {
+		date := start.AddDate(0, 0, i)
+
+		sun := astro.SunEvents(date, lat, lon)
+		add("Sun", "astronomical_dawn", sun.AstronomicalDawn, "Astronomical twilight begins")
+		add("Sun", "nautical_dawn", sun.NauticalDawn, "Nautical twilight begins")
+		add("Sun", "civil_dawn", sun.CivilDawn, "Civil twilight begins")
+		add("Sun", "sunrise", sun.Sunrise, "")
+		add("Sun", "solar_noon", sun.SolarNoon, fmt.Sprintf("Day length %.1f h", sun.DayLength))
+		add("Sun", "sunset", sun.Sunset, "")
+		add("Sun", "civil_dusk", sun.CivilDusk, "Civil twilight ends")
+		add("Sun", "nautical_dusk", sun.NauticalDusk, "Nautical twilight ends")
+		add("Sun", "astronomical_dusk", sun.AstronomicalDusk, "Astronomical twilight ends")
+		if sun.AlwaysUp {
+			add("Sun", "polar_day", &date, "The Sun does not set")
+		} else if sun.AlwaysDown {
+			add("Sun", "polar_night", &date, "The Sun does not rise")
+		}
+
+		moon := astro.MoonEvents(date, lat, lon)
+		for _, when := range []*time.Time{moon.Moonrise, moon.Moonset} {
+			if when == nil {
+				continue
+			}
+			phase := astro.PhaseAt(*when)
+			details := fmt.Sprintf("%s, %.0f%% illuminated", phase.Name, phase.Illumination*100)
+			if when == moon.Moonrise {
+				add("Moon", "moonrise", when, details)
+			} else {
+				add("Moon", "moonset", when, details)
+			}
+		}
 	}
 
-	// Кэшируем на 24 часа
-	s.cacheRepo.SetJSON(ctx, cacheKey, phase, 24*time.Hour)
+	for _, phase := range astro.PhasesBetween(start, start.AddDate(0, 0, days)) {
+		when := phase.Time.In(zone)
+		add(phase.Name, "moon_phase", &when, "")
+	}
 
-	return phase, nil
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].When.Before(events[j].When)
+	})
+	return events
 }
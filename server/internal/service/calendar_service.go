@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/pkg/ical"
+)
+
+var ErrCalendarInvalid = errors.New("invalid calendar request")
+
+// Категории событий календаря, выбираются параметром include
+const (
+	CalendarSun    = "sun"    // восход, заход, кульминация, сумерки, полярные день и ночь
+	CalendarMoon   = "moon"   // восход и заход Луны
+	CalendarPhases = "phases" // главные фазы Луны
+	CalendarOther  = "other"  // прочие события AstronomyAPI
+	CalendarISS    = "iss"    // видимые пролеты МКС
+	CalendarNEO    = "neo"    // сближения астероидов с Землей
+
+	// CalendarAstro все астрономические категории сразу
+	CalendarAstro = "astro"
+)
+
+// calendarISSMaxDays дальше прогноз по одному TLE теряет точность
+const calendarISSMaxDays = 10
+
+type CalendarService interface {
+	// AstroCalendar календарь событий для наблюдателя на days суток вперед
+	AstroCalendar(ctx context.Context, observer Observer, options CalendarOptions) (*ical.Calendar, error)
+}
+
+type CalendarOptions struct {
+	Days int
+	// Include категории; пусто - все астрономические, без МКС и астероидов
+	Include []string
+}
+
+type calendarService struct {
+	astroService AstroService
+	issService   ISSService
+	nasaService  NASAService
+}
+
+func NewCalendarService(astroService AstroService, issService ISSService, nasaService NASAService) CalendarService {
+	return &calendarService{
+		astroService: astroService,
+		issService:   issService,
+		nasaService:  nasaService,
+	}
+}
+
+// ParseCalendarInclude разбирает список категорий через запятую
+func ParseCalendarInclude(value string) ([]string, error) {
+	var include []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch part {
+		case "":
+			continue
+		case CalendarAstro:
+			include = append(include, CalendarSun, CalendarMoon, CalendarPhases, CalendarOther)
+		case CalendarSun, CalendarMoon, CalendarPhases, CalendarOther, CalendarISS, CalendarNEO:
+			include = append(include, part)
+		default:
+			return nil, fmt.Errorf("%w: unknown category %q", ErrCalendarInvalid, part)
+		}
+	}
+	return include, nil
+}
+
+func (s *calendarService) AstroCalendar(ctx context.Context, observer Observer, options CalendarOptions) (*ical.Calendar, error) {
+	if options.Days < 1 || options.Days > 30 {
+		options.Days = 7
+	}
+	include := make(map[string]bool)
+	for _, category := range options.Include {
+		include[category] = true
+	}
+	if len(include) == 0 {
+		include = map[string]bool{CalendarSun: true, CalendarMoon: true, CalendarPhases: true, CalendarOther: true}
+	}
+
+	zone := observer.location()
+	place := observer.Name
+	if place == "" {
+		place = fmt.Sprintf("%.4f, %.4f", observer.Lat, observer.Lon)
+	}
+
+	calendar := &ical.Calendar{
+		ProdID:          "-//Cassiopeia//Astronomy Events//EN",
+		Name:            "Astronomy: " + place,
+		Description:     fmt.Sprintf("Sky events for %s, next %d days", place, options.Days),
+		Timezone:        zone.String(),
+		RefreshInterval: 6 * time.Hour,
+		Stamp:           time.Now().UTC(),
+	}
+	geo := &[2]float64{observer.Lat, observer.Lon}
+	scope := observerScope(observer)
+
+	if include[CalendarSun] || include[CalendarMoon] || include[CalendarPhases] || include[CalendarOther] {
+		events, err := s.astroService.GetEvents(ctx, observer, options.Days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get astronomy events: %w", err)
+		}
+
+		for _, event := range events {
+			category := astroEventCategory(event.Type)
+			if !include[category] {
+				continue
+			}
+
+			local := event.When.In(zone)
+			entry := ical.Event{
+				// Время события от расчета к расчету (и между API и локальным расчетом)
+				// сдвигается на секунды, поэтому в UID - только тип и местная дата
+				UID:        calendarUID("astro", scope, event.Type, event.Name, local.Format("2006-01-02")),
+				Start:      local,
+				Summary:    astroEventSummary(event),
+				Location:   place,
+				Geo:        geo,
+				Categories: []string{"Astronomy", strings.ToUpper(category[:1]) + category[1:]},
+			}
+
+			// У затмений и покрытий событие календаря - весь интервал от начала до конца
+			if event.Start != nil && event.End != nil && event.End.After(*event.Start) {
+				entry.Start = event.Start.In(zone)
+				entry.End = event.End.In(zone)
+			}
+
+			description := fmt.Sprintf("Local time %s (%s).", local.Format("15:04"), zone.String())
+			if event.Details != "" {
+				description = event.Details + ". " + description
+			}
+			entry.Description = description
+
+			if event.Type == "polar_day" || event.Type == "polar_night" {
+				entry.AllDay = true
+				entry.Start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, zone)
+				entry.Description = event.Details + "."
+			}
+
+			calendar.Events = append(calendar.Events, entry)
+		}
+	}
+
+	if include[CalendarISS] {
+		days := options.Days
+		if days > calendarISSMaxDays {
+			days = calendarISSMaxDays
+		}
+
+		// Ошибки МКС и NeoWs не глотаем: пусть подписчик сохранит прошлую версию
+		// календаря, а не получит версию без этих событий
+		passes, err := s.issService.GetPasses(ctx, observer, days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ISS passes: %w", err)
+		}
+
+		for _, pass := range passes.Passes {
+			start := pass.Start.In(zone)
+			peak := pass.Max.In(zone)
+			end := pass.End.In(zone)
+
+			calendar.Events = append(calendar.Events, ical.Event{
+				// Номер витка не меняется, когда новый TLE сдвигает пролет на минуты
+				UID:     calendarUID("iss", scope, passes.Satellite, strconv.Itoa(pass.Orbit)),
+				Start:   start,
+				End:     end,
+				Summary: fmt.Sprintf("ISS visible pass, max %.0f°", pass.MaxAltitude),
+				Description: fmt.Sprintf(
+					"Appears %s in the %s (%.0f°), highest %.0f° at %s in the %s, disappears %s in the %s. Magnitude %.1f. Times in %s.",
+					start.Format("15:04:05"), compassPoint(pass.StartAzimuth), pass.StartAzimuth,
+					pass.MaxAltitude, peak.Format("15:04:05"), compassPoint(pass.MaxAzimuth),
+					end.Format("15:04:05"), compassPoint(pass.EndAzimuth),
+					pass.Magnitude, zone.String(),
+				),
+				Location:   place,
+				Geo:        geo,
+				Categories: []string{"Astronomy", "ISS"},
+			})
+		}
+	}
+
+	if include[CalendarNEO] {
+		approaches, err := s.nasaService.GetCloseApproaches(ctx, options.Days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get NEO close approaches: %w", err)
+		}
+
+		for _, approach := range approaches {
+			summary := fmt.Sprintf("Asteroid %s passes Earth at %.1f LD", approach.Name, approach.MissDistanceLunar)
+			if approach.Hazardous {
+				summary += " (potentially hazardous)"
+			}
+
+			// Сближение не зависит от наблюдателя - UID общий для всех мест. Время сближения
+			// JPL уточняет вместе с орбитой, поэтому в UID только дата
+			calendar.Events = append(calendar.Events, ical.Event{
+				UID:     calendarUID("neo", approach.ID, approach.Time.UTC().Format("2006-01-02")),
+				Start:   approach.Time.In(zone),
+				Summary: summary,
+				Description: fmt.Sprintf(
+					"Miss distance %.0f km (%.2f lunar distances), relative velocity %.2f km/s, estimated diameter %.0f-%.0f m.",
+					approach.MissDistanceKm, approach.MissDistanceLunar,
+					approach.VelocityKmS, approach.DiameterMinM, approach.DiameterMaxM,
+				),
+				URL:        approach.URL,
+				Categories: []string{"Astronomy", "NEO"},
+			})
+		}
+	}
+
+	return calendar, nil
+}
+
+// observerScope часть UID, задающая место: сохраненное место или координаты до сотых
+func observerScope(observer Observer) string {
+	if observer.LocationID != 0 {
+		return fmt.Sprintf("location-%d", observer.LocationID)
+	}
+	return fmt.Sprintf("%.2f,%.2f", observer.Lat, observer.Lon)
+}
+
+// calendarUID стабильный UID из значимых частей события
+func calendarUID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:12]) + "@cassiopeia"
+}
+
+func astroEventCategory(eventType string) string {
+	switch eventType {
+	case "sunrise", "sunset", "solar_noon", "civil_dawn", "civil_dusk", "nautical_dawn",
+		"nautical_dusk", "astronomical_dawn", "astronomical_dusk", "polar_day", "polar_night":
+		return CalendarSun
+	case "moonrise", "moonset":
+		return CalendarMoon
+	case "moon_phase":
+		return CalendarPhases
+	}
+	return CalendarOther
+}
+
+var astroEventTitles = map[string]string{
+	"sunrise":           "Sunrise",
+	"sunset":            "Sunset",
+	"solar_noon":        "Solar noon",
+	"civil_dawn":        "Civil dawn",
+	"civil_dusk":        "Civil dusk",
+	"nautical_dawn":     "Nautical dawn",
+	"nautical_dusk":     "Nautical dusk",
+	"astronomical_dawn": "Astronomical dawn",
+	"astronomical_dusk": "Astronomical dusk",
+	"polar_day":         "Polar day",
+	"polar_night":       "Polar night",
+	"moonrise":          "Moonrise",
+	"moonset":           "Moonset",
+}
+
+func astroEventSummary(event AstroEvent) string {
+	if event.Type == "moon_phase" {
+		return event.Name
+	}
+	if title, ok := astroEventTitles[event.Type]; ok {
+		return title
+	}
+	return fmt.Sprintf("%s: %s", event.Name, strings.ReplaceAll(event.Type, "_", " "))
+}
+
+// compassPoint румб по азимуту (N, NE, E, ...)
+func compassPoint(azimuth float64) string {
+	points := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return points[int(math.Round(azimuth/45))%8]
+}
@@ -0,0 +1,592 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCollectionNotFound     = errors.New("collection not found")
+	ErrCollectionItemNotFound = errors.New("collection item not found")
+	ErrCollectionItemExists   = errors.New("item is already in the collection")
+	ErrCollectionInvalid      = errors.New("invalid collection request")
+)
+
+// Первый выпуск APOD
+var apodFirstDate = time.Date(1995, 6, 16, 0, 0, 0, 0, time.UTC)
+
+type CollectionService interface {
+	List(ctx context.Context) ([]CollectionView, error)
+	Create(ctx context.Context, input CollectionInput) (*CollectionView, error)
+	Get(ctx context.Context, id uint) (*CollectionView, error)
+	Update(ctx context.Context, id uint, input CollectionInput) (*CollectionView, error)
+	Delete(ctx context.Context, id uint) error
+
+	AddItem(ctx context.Context, collectionID uint, input CollectionItemInput) (*CollectionItemView, error)
+	UpdateItem(ctx context.Context, collectionID, itemID uint, note string) (*CollectionItemView, error)
+	RemoveItem(ctx context.Context, collectionID, itemID uint) error
+	Reorder(ctx context.Context, collectionID uint, itemIDs []uint) (*CollectionView, error)
+
+	// Export собирает манифест подборки; изображения скачиваются при записи ZIP
+	Export(ctx context.Context, collectionID uint) (*CollectionExport, error)
+}
+
+type CollectionInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CollectionItemInput struct {
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+	Note      string `json:"note"`
+	// Position место вставки (с нуля); nil - в конец подборки
+	Position *int `json:"position"`
+}
+
+type CollectionView struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	ItemCount   int64                `json:"item_count"`
+	Items       []CollectionItemView `json:"items,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+type CollectionItemView struct {
+	ID        uint      `json:"id"`
+	Type      string    `json:"type"`
+	Reference string    `json:"reference"`
+	Position  int       `json:"position"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CollectionManifestEntry строка манифеста: одно изображение элемента подборки
+type CollectionManifestEntry struct {
+	Position  int    `json:"position"`
+	Type      string `json:"type"`
+	Reference string `json:"reference"`
+	Note      string `json:"note,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Target    string `json:"target,omitempty"`
+	SourceURL string `json:"source_url,omitempty"`
+	File      string `json:"file,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	// index номер изображения внутри элемента подборки
+	index int
+}
+
+// CollectionExport подготовленная выгрузка: манифест без файлов, файлы добавляет WriteZip
+type CollectionExport struct {
+	Collection  CollectionView            `json:"collection"`
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Entries     []CollectionManifestEntry `json:"entries"`
+
+	media MediaService
+}
+
+type collectionService struct {
+	repo         repository.CollectionRepository
+	jwstService  JWSTService
+	nasaService  NASAService
+	mediaService MediaService
+}
+
+func NewCollectionService(
+	repo repository.CollectionRepository,
+	jwstService JWSTService,
+	nasaService NASAService,
+	mediaService MediaService,
+) CollectionService {
+	return &collectionService{
+		repo:         repo,
+		jwstService:  jwstService,
+		nasaService:  nasaService,
+		mediaService: mediaService,
+	}
+}
+
+func (s *collectionService) List(ctx context.Context) ([]CollectionView, error) {
+	collections, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	ids := make([]uint, 0, len(collections))
+	for _, c := range collections {
+		ids = append(ids, c.ID)
+	}
+	counts, err := s.repo.CountItems(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count collection items: %w", err)
+	}
+
+	views := make([]CollectionView, 0, len(collections))
+	for _, c := range collections {
+		view := toCollectionView(&c)
+		view.ItemCount = counts[c.ID]
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func (s *collectionService) Create(ctx context.Context, input CollectionInput) (*CollectionView, error) {
+	collection := &models.Collection{}
+	if err := applyCollectionInput(collection, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, collection); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	view := toCollectionView(collection)
+	return &view, nil
+}
+
+func (s *collectionService) Get(ctx context.Context, id uint) (*CollectionView, error) {
+	collection, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	view := toCollectionView(collection)
+	return &view, nil
+}
+
+func (s *collectionService) Update(ctx context.Context, id uint, input CollectionInput) (*CollectionView, error) {
+	collection, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyCollectionInput(collection, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, collection); err != nil {
+		return nil, fmt.Errorf("failed to update collection: %w", err)
+	}
+
+	return s.Get(ctx, id)
+}
+
+func (s *collectionService) Delete(ctx context.Context, id uint) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCollectionNotFound
+		}
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+func (s *collectionService) AddItem(ctx context.Context, collectionID uint, input CollectionItemInput) (*CollectionItemView, error) {
+	collection, err := s.load(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	itemType := strings.ToLower(strings.TrimSpace(input.Type))
+	reference, err := s.validateReference(ctx, itemType, strings.TrimSpace(input.Reference))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range collection.Items {
+		if existing.ItemType == itemType && existing.Reference == reference {
+			return nil, ErrCollectionItemExists
+		}
+	}
+
+	item := &models.CollectionItem{
+		CollectionID: collectionID,
+		ItemType:     itemType,
+		Reference:    reference,
+		Note:         strings.TrimSpace(input.Note),
+		Position:     -1,
+	}
+	if input.Position != nil {
+		if *input.Position < 0 {
+			return nil, fmt.Errorf("%w: position must not be negative", ErrCollectionInvalid)
+		}
+		item.Position = *input.Position
+	}
+
+	if err := s.repo.AddItem(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to add collection item: %w", err)
+	}
+
+	view := toCollectionItemView(item)
+	return &view, nil
+}
+
+func (s *collectionService) UpdateItem(ctx context.Context, collectionID, itemID uint, note string) (*CollectionItemView, error) {
+	item, err := s.repo.GetItem(ctx, collectionID, itemID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection item: %w", err)
+	}
+
+	item.Note = strings.TrimSpace(note)
+	if err := s.repo.UpdateItem(ctx, item); err != nil {
+		return nil, fmt.Errorf("failed to update collection item: %w", err)
+	}
+
+	view := toCollectionItemView(item)
+	return &view, nil
+}
+
+func (s *collectionService) RemoveItem(ctx context.Context, collectionID, itemID uint) error {
+	if err := s.repo.RemoveItem(ctx, collectionID, itemID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCollectionItemNotFound
+		}
+		return fmt.Errorf("failed to remove collection item: %w", err)
+	}
+	return nil
+}
+
+func (s *collectionService) Reorder(ctx context.Context, collectionID uint, itemIDs []uint) (*CollectionView, error) {
+	if _, err := s.load(ctx, collectionID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Reorder(ctx, collectionID, itemIDs); err != nil {
+		if errors.Is(err, repository.ErrCollectionItemOrder) {
+			return nil, fmt.Errorf("%w: %v", ErrCollectionInvalid, err)
+		}
+		return nil, fmt.Errorf("failed to reorder collection: %w", err)
+	}
+
+	return s.Get(ctx, collectionID)
+}
+
+func (s *collectionService) Export(ctx context.Context, collectionID uint) (*CollectionExport, error) {
+	collection, err := s.load(ctx, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &CollectionExport{
+		Collection:  toCollectionView(collection),
+		GeneratedAt: time.Now().UTC(),
+		media:       s.mediaService,
+	}
+
+	for _, item := range collection.Items {
+		entries := s.resolveItem(ctx, item)
+		for i := range entries {
+			entries[i].index = i
+		}
+		export.Entries = append(export.Entries, entries...)
+	}
+
+	return export, nil
+}
+
+// resolveItem превращает элемент подборки в строки манифеста с адресами изображений
+func (s *collectionService) resolveItem(ctx context.Context, item models.CollectionItem) []CollectionManifestEntry {
+	base := CollectionManifestEntry{
+		Position:  item.Position + 1,
+		Type:      item.ItemType,
+		Reference: item.Reference,
+		Note:      item.Note,
+	}
+
+	switch item.ItemType {
+	case models.CollectionItemAPOD:
+		apod, err := s.nasaService.GetAPOD(ctx, item.Reference)
+		if err != nil {
+			base.Error = err.Error()
+			return []CollectionManifestEntry{base}
+		}
+
+		base.Title = extractString(apod, "title")
+		// Для видео берем превью, для изображений - версию в высоком разрешении
+		if extractString(apod, "media_type") == "video" {
+			base.SourceURL = extractString(apod, "thumbnail_url")
+		} else {
+			base.SourceURL = extractString(apod, "hdurl", "url")
+		}
+		if base.SourceURL == "" {
+			base.Error = "APOD entry has no image"
+		}
+		return []CollectionManifestEntry{base}
+
+	case models.CollectionItemJWST:
+		observation, err := s.jwstService.GetObservation(ctx, item.Reference)
+		if err != nil {
+			base.Error = err.Error()
+			return []CollectionManifestEntry{base}
+		}
+
+		base.Target = observation.Target
+		entries := make([]CollectionManifestEntry, 0, len(observation.Images))
+		for _, image := range observation.Images {
+			entry := base
+			entry.Title = image.Caption
+			entry.SourceURL = image.URL
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			base.Error = "observation has no images"
+			return []CollectionManifestEntry{base}
+		}
+		return entries
+	}
+
+	base.Error = "unknown item type"
+	return []CollectionManifestEntry{base}
+}
+
+// WriteZip пишет архив: изображения и манифест в форматах JSON и CSV.
+// Каждое изображение копируется в архив сразу после загрузки: кэш медиа может вытеснить
+// файлы, скачанные раньше, если подборка больше лимита кэша.
+func (e *CollectionExport) WriteZip(ctx context.Context, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	for i := range e.Entries {
+		if err := e.addEntryFile(ctx, archive, &e.Entries[i]); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", e.Entries[i].File, err)
+		}
+	}
+
+	manifest, err := archive.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(e); err != nil {
+		return fmt.Errorf("failed to write JSON manifest: %w", err)
+	}
+
+	manifestCSV, err := archive.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	if err := e.writeCSV(manifestCSV); err != nil {
+		return fmt.Errorf("failed to write CSV manifest: %w", err)
+	}
+
+	return archive.Close()
+}
+
+// addEntryFile скачивает изображение строки манифеста и пишет его в архив. Ошибка загрузки
+// попадает в манифест; возвращаются только ошибки записи архива.
+func (e *CollectionExport) addEntryFile(ctx context.Context, archive *zip.Writer, entry *CollectionManifestEntry) error {
+	if entry.Error != "" || entry.SourceURL == "" {
+		return nil
+	}
+
+	media, file, err := e.openMedia(ctx, entry.SourceURL)
+	if err != nil {
+		log.Printf("Failed to fetch collection image %s: %v", entry.SourceURL, err)
+		entry.Error = err.Error()
+		return nil
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(path.Ext(strings.SplitN(entry.SourceURL, "?", 2)[0]))
+	if ext == "" || len(ext) > 5 {
+		if exts, _ := mime.ExtensionsByType(media.ContentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+
+	entry.File = fmt.Sprintf("images/%03d_%s_%02d%s", entry.Position, fileSafeName(entry.Reference), entry.index+1, ext)
+
+	// Изображения уже сжаты - храним без повторного сжатия
+	dst, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     entry.File,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	size, err := io.Copy(dst, file)
+	if err != nil {
+		return err
+	}
+	entry.Size = size
+	return nil
+}
+
+// openMedia открывает изображение из кэша медиа; если файл вытеснили между загрузкой
+// и открытием, скачивает его еще раз. Открытый файл читается и после вытеснения.
+func (e *CollectionExport) openMedia(ctx context.Context, src string) (*MediaFile, *os.File, error) {
+	for attempt := 0; ; attempt++ {
+		media, err := e.media.Fetch(ctx, src, 0, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		file, err := os.Open(media.Path)
+		if err == nil {
+			return media, file, nil
+		}
+		if !os.IsNotExist(err) || attempt == 1 {
+			return nil, nil, fmt.Errorf("failed to open cached media: %w", err)
+		}
+	}
+}
+
+// Filename имя архива для Content-Disposition
+func (e *CollectionExport) Filename() string {
+	name := fileSafeName(e.Collection.Name)
+	if name == "" {
+		name = "collection_" + strconv.FormatUint(uint64(e.Collection.ID), 10)
+	}
+	return name + ".zip"
+}
+
+func (e *CollectionExport) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"position", "type", "reference", "title", "target", "note", "file", "size", "source_url", "error"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, entry := range e.Entries {
+		size := ""
+		if entry.Size > 0 {
+			size = strconv.FormatInt(entry.Size, 10)
+		}
+		record := []string{
+			strconv.Itoa(entry.Position),
+			entry.Type,
+			entry.Reference,
+			entry.Title,
+			entry.Target,
+			entry.Note,
+			entry.File,
+			size,
+			entry.SourceURL,
+			entry.Error,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *collectionService) load(ctx context.Context, id uint) (*models.Collection, error) {
+	collection, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection, nil
+}
+
+// validateReference проверяет ссылку элемента и приводит ее к каноническому виду
+func (s *collectionService) validateReference(ctx context.Context, itemType, reference string) (string, error) {
+	if reference == "" {
+		return "", fmt.Errorf("%w: reference is required", ErrCollectionInvalid)
+	}
+
+	switch itemType {
+	case models.CollectionItemAPOD:
+		date, err := time.Parse("2006-01-02", reference)
+		if err != nil {
+			return "", fmt.Errorf("%w: APOD reference must be a date in YYYY-MM-DD format", ErrCollectionInvalid)
+		}
+		if date.Before(apodFirstDate) || date.After(time.Now().UTC()) {
+			return "", fmt.Errorf("%w: APOD date must be between %s and today", ErrCollectionInvalid, apodFirstDate.Format("2006-01-02"))
+		}
+		return date.Format("2006-01-02"), nil
+
+	case models.CollectionItemJWST:
+		if len(reference) > 100 {
+			return "", fmt.Errorf("%w: observation ID is too long", ErrCollectionInvalid)
+		}
+		// Неизвестное наблюдение отклоняем; сбой upstream не мешает добавить элемент
+		if _, err := s.jwstService.GetObservation(ctx, reference); err != nil {
+			if errors.Is(err, ErrJWSTNotFound) {
+				return "", fmt.Errorf("%w: JWST observation %s not found", ErrCollectionInvalid, reference)
+			}
+			log.Printf("Could not verify JWST observation %s: %v", reference, err)
+		}
+		return reference, nil
+	}
+
+	return "", fmt.Errorf("%w: type must be 'jwst' or 'apod'", ErrCollectionInvalid)
+}
+
+func applyCollectionInput(collection *models.Collection, input CollectionInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrCollectionInvalid)
+	}
+	if len([]rune(name)) > 200 {
+		return fmt.Errorf("%w: name must be at most 200 characters", ErrCollectionInvalid)
+	}
+
+	collection.Name = name
+	collection.Description = strings.TrimSpace(input.Description)
+	return nil
+}
+
+func toCollectionView(collection *models.Collection) CollectionView {
+	view := CollectionView{
+		ID:          collection.ID,
+		Name:        collection.Name,
+		Description: collection.Description,
+		ItemCount:   int64(len(collection.Items)),
+		CreatedAt:   collection.CreatedAt,
+		UpdatedAt:   collection.UpdatedAt,
+	}
+
+	for i := range collection.Items {
+		view.Items = append(view.Items, toCollectionItemView(&collection.Items[i]))
+	}
+	return view
+}
+
+func toCollectionItemView(item *models.CollectionItem) CollectionItemView {
+	return CollectionItemView{
+		ID:        item.ID,
+		Type:      item.ItemType,
+		Reference: item.Reference,
+		Position:  item.Position,
+		Note:      item.Note,
+		CreatedAt: item.CreatedAt,
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func fileSafeName(value string) string {
+	return strings.Trim(unsafeFileChars.ReplaceAllString(value, "_"), "_.")
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cassiopeia/pkg/fits"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrFITSInvalidURL = errors.New("invalid FITS URL")
+
+type FITSService interface {
+	// ReadHeader возвращает первичный заголовок FITS, скачивая только его начало
+	ReadHeader(ctx context.Context, fitsURL string) (*fits.Header, error)
+	GetMetadata(ctx context.Context, fitsURL string) (*FITSMetadata, error)
+	// ProductURL строит ссылку на FITS-продукт по имени файла
+	ProductURL(fileID string) string
+}
+
+type FITSConfig struct {
+	CacheDir       string
+	ProductURL     string
+	MaxHeaderBytes int
+	ChunkSize      int
+}
+
+// FITSMetadata поля заголовка, которые показываем в каталоге
+type FITSMetadata struct {
+	Target       string     `json:"target,omitempty"`
+	Instrument   string     `json:"instrument,omitempty"`
+	Filter       string     `json:"filter,omitempty"`
+	Pupil        string     `json:"pupil,omitempty"`
+	RA           *float64   `json:"ra,omitempty"`
+	Dec          *float64   `json:"dec,omitempty"`
+	ExposureTime *float64   `json:"exposure_time,omitempty"`
+	ObservedAt   *time.Time `json:"observed_at,omitempty"`
+}
+
+type fitsService struct {
+	config FITSConfig
+	client *http.Client
+	group  singleflight.Group
+}
+
+func NewFITSService(config FITSConfig) (FITSService, error) {
+	if config.CacheDir == "" {
+		config.CacheDir = "./data/fits"
+	}
+	if config.MaxHeaderBytes < fits.BlockSize {
+		config.MaxHeaderBytes = 1 << 20
+	}
+	// Запрашиваем целое число блоков, по умолчанию 20 блоков (~56 КБ)
+	if config.ChunkSize < fits.BlockSize {
+		config.ChunkSize = 20 * fits.BlockSize
+	}
+	config.ChunkSize -= config.ChunkSize % fits.BlockSize
+
+	if err := os.MkdirAll(config.CacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create FITS cache directory: %w", err)
+	}
+
+	return &fitsService{
+		config: config,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}, nil
+}
+
+func (s *fitsService) ProductURL(fileID string) string {
+	if s.config.ProductURL == "" || fileID == "" {
+		return ""
+	}
+	fileID = strings.TrimSuffix(strings.TrimSuffix(fileID, ".jpg"), ".fits")
+	return fmt.Sprintf(s.config.ProductURL, fileID)
+}
+
+func (s *fitsService) ReadHeader(ctx context.Context, fitsURL string) (*fits.Header, error) {
+	if !strings.HasPrefix(fitsURL, "http://") && !strings.HasPrefix(fitsURL, "https://") {
+		return nil, ErrFITSInvalidURL
+	}
+
+	path := filepath.Join(s.config.CacheDir, mediaKey(fitsURL)+".hdr")
+	if header, err := s.readCached(path); err == nil {
+		return header, nil
+	}
+
+	result, err, _ := s.group.Do(path, func() (interface{}, error) {
+		return s.fetchHeader(ctx, fitsURL, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*fits.Header), nil
+}
+
+func (s *fitsService) GetMetadata(ctx context.Context, fitsURL string) (*FITSMetadata, error) {
+	header, err := s.ReadHeader(ctx, fitsURL)
+	if err != nil {
+		return nil, err
+	}
+	return metadataFromHeader(header), nil
+}
+
+func (s *fitsService) readCached(path string) (*fits.Header, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return fits.ParseHeader(file, s.config.MaxHeaderBytes)
+}
+
+// fetchHeader читает заголовок порциями через Range и сохраняет прочитанные блоки на диск
+func (s *fitsService) fetchHeader(ctx context.Context, fitsURL, path string) (*fits.Header, error) {
+	log.Printf("Reading FITS header: %s", fitsURL)
+
+	reader := &rangeReader{
+		ctx:       ctx,
+		client:    s.client,
+		url:       fitsURL,
+		chunkSize: s.config.ChunkSize,
+	}
+	defer reader.Close()
+
+	var raw bytes.Buffer
+	header, err := fits.ParseHeader(io.TeeReader(reader, &raw), s.config.MaxHeaderBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FITS header: %w", err)
+	}
+
+	// В кэш кладем ровно блоки заголовка, без лишнего хвоста последнего чанка
+	if _, err := writeFileAtomic(path, bytes.NewReader(raw.Bytes()[:header.Size])); err != nil {
+		log.Printf("Failed to cache FITS header %s: %v", fitsURL, err)
+	}
+
+	return header, nil
+}
+
+// rangeReader последовательно запрашивает файл диапазонами chunkSize байт.
+// Если сервер игнорирует Range и отдает весь файл, читаем из этого ответа потоком.
+type rangeReader struct {
+	ctx       context.Context
+	client    *http.Client
+	url       string
+	chunkSize int
+
+	offset int64
+	body   io.ReadCloser
+	// stream true, когда body содержит файл до конца, а не один диапазон
+	stream bool
+	eof    bool
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if r.eof {
+				return 0, io.EOF
+			}
+			if err := r.open(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == io.EOF {
+			r.body.Close()
+			r.body = nil
+			if r.stream {
+				r.eof = true
+			}
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *rangeReader) open() error {
+	req, err := http.NewRequestWithContext(r.ctx, "GET", r.url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", r.offset, r.offset+int64(r.chunkSize)-1))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Короткий диапазон означает конец файла
+		if resp.ContentLength >= 0 && resp.ContentLength < int64(r.chunkSize) {
+			r.eof = true
+		}
+		r.body = resp.Body
+	case http.StatusOK:
+		if _, err := io.CopyN(io.Discard, resp.Body, r.offset); err != nil {
+			resp.Body.Close()
+			return fmt.Errorf("skip to offset %d: %w", r.offset, err)
+		}
+		r.body = resp.Body
+		r.stream = true
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		r.eof = true
+		return io.EOF
+	default:
+		resp.Body.Close()
+		return fmt.Errorf("FITS upstream returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body != nil {
+		return r.body.Close()
+	}
+	return nil
+}
+
+// metadataFromHeader извлекает ключевые слова JWST с запасными вариантами
+// из общих соглашений FITS (HST, наземные обсерватории)
+func metadataFromHeader(header *fits.Header) *FITSMetadata {
+	meta := &FITSMetadata{
+		Target:     firstHeaderString(header, "TARGPROP", "TARGNAME", "OBJECT"),
+		Instrument: firstHeaderString(header, "INSTRUME"),
+		Filter:     firstHeaderString(header, "FILTER", "FILTER1"),
+		Pupil:      firstHeaderString(header, "PUPIL"),
+		RA:         firstHeaderFloat(header, "TARG_RA", "RA_TARG", "RA", "CRVAL1"),
+		Dec:        firstHeaderFloat(header, "TARG_DEC", "DEC_TARG", "DEC", "CRVAL2"),
+		// EFFEXPTM - эффективная экспозиция JWST, EXPTIME - общее соглашение
+		ExposureTime: firstHeaderFloat(header, "EFFEXPTM", "EXPTIME", "TEXPTIME"),
+	}
+
+	// Значения-заглушки вроде CLEAR/N/A не несут информации о фильтре
+	switch strings.ToUpper(meta.Pupil) {
+	case "", "CLEAR", "NONE", "N/A":
+		meta.Pupil = ""
+	}
+	switch strings.ToUpper(meta.Filter) {
+	case "CLEAR", "NONE", "N/A":
+		if meta.Pupil != "" {
+			meta.Filter, meta.Pupil = meta.Pupil, ""
+		}
+	}
+
+	if value, ok := header.String("DATE-BEG"); ok {
+		meta.ObservedAt = parseFITSTime(value)
+	}
+	if meta.ObservedAt == nil {
+		if date, ok := header.String("DATE-OBS"); ok {
+			if clock, ok := header.String("TIME-OBS"); ok && !strings.Contains(date, "T") {
+				date += "T" + clock
+			}
+			meta.ObservedAt = parseFITSTime(date)
+		}
+	}
+
+	return meta
+}
+
+func firstHeaderString(header *fits.Header, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := header.String(key); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func firstHeaderFloat(header *fits.Header, keys ...string) *float64 {
+	for _, key := range keys {
+		if value, ok := header.Float(key); ok {
+			return &value
+		}
+	}
+	return nil
+}
+
+// parseFITSTime разбирает даты FITS (ISO-8601 без зоны, время в UTC)
+func parseFITSTime(value string) *time.Time {
+	formats := []string{
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02T15:04:05",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, strings.TrimSpace(value)); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
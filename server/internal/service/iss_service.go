@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -11,6 +12,7 @@ import (
 	"cassiopeia/internal/clients"
 	"cassiopeia/internal/models"
 	"cassiopeia/internal/repository"
+	"cassiopeia/pkg/astro"
 )
 
 type ISSService interface {
@@ -18,8 +20,31 @@ type ISSService interface {
 	GetLastPosition(ctx context.Context) (*models.ISSLog, error)
 	GetTrend(ctx context.Context, limit int) (*models.ISSTrend, error)
 	GetPositionsHistory(ctx context.Context, hours int) ([]*models.ISSLog, error)
+	// GetPasses видимые пролеты МКС для наблюдателя на days суток вперед
+	GetPasses(ctx context.Context, observer Observer, days int) (*ISSPasses, error)
 }
 
+// ISSPasses прогноз видимых пролетов по текущему TLE
+type ISSPasses struct {
+	Satellite string                `json:"satellite"`
+	TLEEpoch  time.Time             `json:"tle_epoch"`
+	Location  string                `json:"location,omitempty"`
+	Timezone  string                `json:"timezone"`
+	Lat       float64               `json:"lat"`
+	Lon       float64               `json:"lon"`
+	Days      int                   `json:"days"`
+	Passes    []astro.SatellitePass `json:"passes"`
+}
+
+const (
+	// issStandardMagnitude блеск МКС на 1000 км при фазе 90°
+	issStandardMagnitude = -1.8
+	// issTLEStaleAfter после этого запасная копия TLE уже не годится для прогноза
+	issTLEStaleAfter = 14 * 24 * time.Hour
+)
+
+var ErrISSTLEUnavailable = errors.New("ISS orbital elements are unavailable")
+
 type issService struct {
 	repo      repository.ISSRepository
 	cacheRepo repository.CacheRepository
@@ -29,6 +54,7 @@ type issService struct {
 
 type ISSConfig struct {
 	URL      string
+	TLEURL   string
 	Interval time.Duration
 }
 
@@ -266,3 +292,89 @@ func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 
 	return R * c
 }
+
+func (s *issService) GetPasses(ctx context.Context, observer Observer, days int) (*ISSPasses, error) {
+	if days < 1 || days > 10 {
+		days = 3
+	}
+	if err := validateCoordinates(observer.Lat, observer.Lon); err != nil {
+		return nil, err
+	}
+	zone := observer.location()
+
+	// v2: у пролетов появился номер витка
+	cacheKey := fmt.Sprintf("iss:passes:v2:%.4f:%.4f:%.0f:%s:%d",
+		observer.Lat, observer.Lon, observer.Elevation, zone.String(), days)
+	var cached ISSPasses
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.Satellite != "" {
+		return &cached, nil
+	}
+
+	tle, err := s.getTLE(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Начинаем с начала текущих местных суток, чтобы прогноз не "плыл" в течение дня
+	now := time.Now().In(zone)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
+	passes := astro.VisiblePasses(tle, from, from.AddDate(0, 0, days),
+		observer.Lat, observer.Lon, observer.Elevation, issStandardMagnitude)
+	if passes == nil {
+		passes = []astro.SatellitePass{}
+	}
+
+	result := &ISSPasses{
+		Satellite: tle.String(),
+		TLEEpoch:  tle.Epoch,
+		Location:  observer.Name,
+		Timezone:  zone.String(),
+		Lat:       observer.Lat,
+		Lon:       observer.Lon,
+		Days:      days,
+		Passes:    passes,
+	}
+
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, result, time.Hour); err != nil {
+		log.Printf("Failed to cache ISS passes: %v", err)
+	}
+	return result, nil
+}
+
+// getTLE берет свежий TLE из кэша или API; при сбое API - запасную копию до двух недель
+func (s *issService) getTLE(ctx context.Context) (*astro.TLE, error) {
+	const (
+		freshKey = "iss:tle"
+		spareKey = "iss:tle:spare"
+	)
+
+	if text, err := s.cacheRepo.Get(ctx, freshKey); err == nil && text != "" {
+		if tle, err := astro.ParseTLE(text); err == nil {
+			return tle, nil
+		}
+	}
+
+	text, fetchErr := s.client.GetTLE(ctx)
+	if fetchErr == nil {
+		tle, err := astro.ParseTLE(text)
+		if err == nil {
+			if err := s.cacheRepo.Set(ctx, freshKey, text, 6*time.Hour); err != nil {
+				log.Printf("Failed to cache ISS TLE: %v", err)
+			}
+			if err := s.cacheRepo.Set(ctx, spareKey, text, issTLEStaleAfter); err != nil {
+				log.Printf("Failed to cache spare ISS TLE: %v", err)
+			}
+			return tle, nil
+		}
+		fetchErr = err
+	}
+
+	log.Printf("Failed to refresh ISS TLE, trying spare copy: %v", fetchErr)
+	if text, err := s.cacheRepo.Get(ctx, spareKey); err == nil && text != "" {
+		if tle, err := astro.ParseTLE(text); err == nil && tle.Age(time.Now()) < issTLEStaleAfter {
+			return tle, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrISSTLEUnavailable, fetchErr)
+}
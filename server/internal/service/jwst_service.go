@@ -2,98 +2,496 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"cassiopeia/internal/clients"
+	"cassiopeia/internal/models"
 	"cassiopeia/internal/repository"
 )
 
 type JWSTService interface {
-	GetFeed(ctx context.Context, source, suffix, program, instrument string, page, perPage int) ([]JWSTImage, error)
-	GetObservation(ctx context.Context, observationID string) (map[string]interface{}, error)
+	GetFeed(ctx context.Context, filter JWSTFeedFilter) (*JWSTFeed, error)
+	GetObservation(ctx context.Context, observationID string) (*JWSTObservation, error)
+	GetProgramSummary(ctx context.Context, programID string) (*JWSTProgramSummary, error)
 	GetProgramImages(ctx context.Context, programID string, page, perPage int) ([]JWSTImage, error)
+	Search(ctx context.Context, query string, page, perPage int) (*JWSTSearchResult, error)
+	SyncCatalog(ctx context.Context) error
 }
 
 type jwstService struct {
-	cacheRepo repository.CacheRepository
-	client    clients.JWSTClient
+	cacheRepo   repository.CacheRepository
+	catalogRepo repository.JWSTImageRepository
+	client      clients.JWSTClient
+	fits        FITSService
+	catalog     JWSTCatalogConfig
 }
 
 type JWSTImage struct {
-	URL         string   `json:"url"`
-	ObsID       string   `json:"obs"`
-	Program     string   `json:"program"`
-	Suffix      string   `json:"suffix"`
-	Instruments []string `json:"inst"`
-	Caption     string   `json:"caption"`
-	Link        string   `json:"link"`
+	URL         string     `json:"url"`
+	ObsID       string     `json:"obs"`
+	Program     string     `json:"program"`
+	Suffix      string     `json:"suffix"`
+	Instruments []string   `json:"inst"`
+	Caption     string     `json:"caption"`
+	Link        string     `json:"link"`
+	Target      string     `json:"target,omitempty"`
+	ObservedAt  *time.Time `json:"observed_at,omitempty"`
+	RA          *float64   `json:"ra,omitempty"`
+	Dec         *float64   `json:"dec,omitempty"`
+	Filter      string     `json:"filter,omitempty"`
+	Exposure    *float64   `json:"exposure,omitempty"`
 }
 
+// JWSTSearchResult страница результатов поиска
+type JWSTSearchResult struct {
+	Query   string      `json:"query"`
+	Images  []JWSTImage `json:"images"`
+	Total   int         `json:"total"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"perPage"`
+}
+
+// JWSTObservation нормализованное наблюдение со всеми его изображениями
+type JWSTObservation struct {
+	ObservationID string      `json:"observation_id"`
+	Program       string      `json:"program"`
+	Target        string      `json:"target,omitempty"`
+	Instruments   []string    `json:"instruments"`
+	Suffixes      []string    `json:"suffixes"`
+	ObservedFrom  *time.Time  `json:"observed_from,omitempty"`
+	ObservedTo    *time.Time  `json:"observed_to,omitempty"`
+	ImageCount    int         `json:"image_count"`
+	Images        []JWSTImage `json:"images"`
+}
+
+// JWSTProgramSummary сводка по программе наблюдений из локального каталога
+type JWSTProgramSummary struct {
+	Program       string           `json:"program"`
+	ImageCount    int64            `json:"image_count"`
+	ByInstrument  map[string]int64 `json:"by_instrument"`
+	BySuffix      map[string]int64 `json:"by_suffix"`
+	FirstObserved *time.Time       `json:"first_observed,omitempty"`
+	LastObserved  *time.Time       `json:"last_observed,omitempty"`
+	Thumbnails    []JWSTImage      `json:"thumbnails"`
+}
+
+// JWSTFeedFilter параметры ленты JWST
+type JWSTFeedFilter struct {
+	Source       string
+	Suffix       string
+	Program      string
+	Instrument   string
+	ObservedFrom *time.Time
+	ObservedTo   *time.Time
+	Page         int
+	PerPage      int
+	// Cursor продолжает выдачу с места, где остановилась предыдущая страница
+	Cursor string
+}
+
+// JWSTFeed страница ленты и курсор следующей страницы (пустой, если данных больше нет)
+type JWSTFeed struct {
+	Images []JWSTImage `json:"images"`
+	Next   string      `json:"next,omitempty"`
+}
+
+const (
+	// Сколько страниц upstream можно прочитать за один запрос с фильтром
+	jwstUpstreamPageBudget = 5
+	jwstFilteredPageSize   = 100
+)
+
+var (
+	ErrJWSTInvalidCursor = errors.New("invalid JWST feed cursor")
+	ErrJWSTEmptyQuery    = errors.New("search query is empty")
+	ErrJWSTNotFound      = errors.New("JWST record not found")
+)
+
+const (
+	// Сколько результатов поиска собираем из каждого источника для одного запроса
+	jwstSearchUpstreamPages = 2
+	jwstSearchCatalogLimit  = 200
+	jwstProgramSampleCount  = 6
+)
+
+// jwstCursor позиция в выдаче: страница, смещение в ней и размер страницы upstream
+type jwstCursor struct {
+	Page   int
+	Offset int
+	Size   int
+}
+
+type jwstUpstreamPage struct {
+	Images []JWSTImage `json:"images"`
+	Total  int         `json:"total"`
+}
+
+func (c jwstCursor) encode() string {
+	raw := fmt.Sprintf("%d.%d.%d", c.Page, c.Offset, c.Size)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeJWSTCursor(value string) (*jwstCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrJWSTInvalidCursor
+	}
+
+	var c jwstCursor
+	if _, err := fmt.Sscanf(string(raw), "%d.%d.%d", &c.Page, &c.Offset, &c.Size); err != nil {
+		return nil, ErrJWSTInvalidCursor
+	}
+	if c.Page < 1 || c.Offset < 0 || c.Size < 0 {
+		return nil, ErrJWSTInvalidCursor
+	}
+	return &c, nil
+}
+
+// JWSTCatalogConfig настройки обхода jwstapi.com для локального каталога
+type JWSTCatalogConfig struct {
+	PagesPerRun int
+	PerPage     int
+	// FITSPerRun сколько записей дополнять заголовками FITS за один запуск
+	FITSPerRun int
+}
+
+const (
+	// Неудачное чтение заголовка FITS повторяем не чаще раза в неделю
+	jwstFITSRetryInterval = 7 * 24 * time.Hour
+)
+
 func NewJWSTService(
 	cacheRepo repository.CacheRepository,
+	catalogRepo repository.JWSTImageRepository,
 	client clients.JWSTClient,
+	fitsService FITSService,
+	catalog JWSTCatalogConfig,
 ) JWSTService {
+	if catalog.PagesPerRun < 1 {
+		catalog.PagesPerRun = 5
+	}
+	if catalog.PerPage < 1 || catalog.PerPage > 100 {
+		catalog.PerPage = 100
+	}
+
 	return &jwstService{
-		cacheRepo: cacheRepo,
-		client:    client,
+		cacheRepo:   cacheRepo,
+		catalogRepo: catalogRepo,
+		client:      client,
+		fits:        fitsService,
+		catalog:     catalog,
 	}
 }
 
-func (s *jwstService) GetObservation(ctx context.Context, observationID string) (map[string]interface{}, error) {
+func (s *jwstService) GetObservation(ctx context.Context, observationID string) (*JWSTObservation, error) {
 	cacheKey := fmt.Sprintf("jwst:observation:%s", observationID)
 
 	// Пробуем кэш
-	var cachedData map[string]interface{}
-	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cachedData); err == nil && cachedData != nil {
-		return cachedData, nil
+	var cached JWSTObservation
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.ObservationID != "" {
+		return &cached, nil
 	}
 
-	// Получаем от API
-	data, err := s.client.Get(ctx, fmt.Sprintf("observation/%s", observationID), nil)
+	records, err := s.catalogRepo.FindByObservation(ctx, observationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch observation: %w", err)
+		return nil, fmt.Errorf("failed to query JWST catalog: %w", err)
+	}
+
+	// В каталоге наблюдения еще нет - берем из API и сохраняем
+	if len(records) == 0 {
+		data, err := s.client.Get(ctx, fmt.Sprintf("observation/%s", observationID), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch observation: %w", err)
+		}
+
+		records, _, err = s.storeCatalogItems(ctx, s.extractItems(data))
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	if len(records) == 0 {
+		return nil, ErrJWSTNotFound
+	}
+
+	observation := buildObservation(observationID, records)
+
 	// Кэшируем на 6 часов
-	s.cacheRepo.SetJSON(ctx, cacheKey, data, 6*time.Hour)
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, observation, 6*time.Hour); err != nil {
+		log.Printf("Failed to cache JWST observation: %v", err)
+	}
+
+	return observation, nil
+}
+
+func (s *jwstService) GetProgramSummary(ctx context.Context, programID string) (*JWSTProgramSummary, error) {
+	cacheKey := fmt.Sprintf("jwst:program_summary:%s", programID)
+
+	// Пробуем кэш
+	var cached JWSTProgramSummary
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.Program != "" {
+		return &cached, nil
+	}
+
+	stats, err := s.catalogRepo.GetProgramStats(ctx, programID, jwstProgramSampleCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JWST program stats: %w", err)
+	}
+
+	// Программа еще не обойдена воркером - подтягиваем ее страницы сейчас
+	if stats.Total == 0 {
+		for page := 1; page <= s.catalog.PagesPerRun; page++ {
+			_, total, err := s.crawlPage(ctx, fmt.Sprintf("program/id/%s", programID), page)
+			if err != nil {
+				return nil, err
+			}
+			if total < s.catalog.PerPage {
+				break
+			}
+		}
+
+		stats, err = s.catalogRepo.GetProgramStats(ctx, programID, jwstProgramSampleCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get JWST program stats: %w", err)
+		}
+	}
+
+	if stats.Total == 0 {
+		return nil, ErrJWSTNotFound
+	}
+
+	summary := &JWSTProgramSummary{
+		Program:       programID,
+		ImageCount:    stats.Total,
+		ByInstrument:  stats.ByInstrument,
+		BySuffix:      stats.BySuffix,
+		FirstObserved: stats.FirstObserved,
+		LastObserved:  stats.LastObserved,
+		Thumbnails:    make([]JWSTImage, 0, len(stats.Samples)),
+	}
+	for _, record := range stats.Samples {
+		summary.Thumbnails = append(summary.Thumbnails, catalogRecordToImage(record))
+	}
+
+	// Кэшируем на 1 час
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, summary, time.Hour); err != nil {
+		log.Printf("Failed to cache JWST program summary: %v", err)
+	}
+
+	return summary, nil
+}
+
+// buildObservation сводит файлы наблюдения в одну нормализованную запись
+func buildObservation(observationID string, records []models.JWSTImage) *JWSTObservation {
+	observation := &JWSTObservation{
+		ObservationID: observationID,
+		Instruments:   []string{},
+		Suffixes:      []string{},
+		Images:        make([]JWSTImage, 0, len(records)),
+	}
+
+	instruments := make(map[string]bool)
+	suffixes := make(map[string]bool)
+
+	for _, record := range records {
+		image := catalogRecordToImage(record)
+		observation.Images = append(observation.Images, image)
+
+		if observation.Program == "" {
+			observation.Program = image.Program
+		}
+		if observation.Target == "" {
+			observation.Target = image.Target
+		}
+		for _, inst := range image.Instruments {
+			if !instruments[inst] {
+				instruments[inst] = true
+				observation.Instruments = append(observation.Instruments, inst)
+			}
+		}
+		if image.Suffix != "" && !suffixes[image.Suffix] {
+			suffixes[image.Suffix] = true
+			observation.Suffixes = append(observation.Suffixes, image.Suffix)
+		}
+		if image.ObservedAt != nil {
+			if observation.ObservedFrom == nil || image.ObservedAt.Before(*observation.ObservedFrom) {
+				observation.ObservedFrom = image.ObservedAt
+			}
+			if observation.ObservedTo == nil || image.ObservedAt.After(*observation.ObservedTo) {
+				observation.ObservedTo = image.ObservedAt
+			}
+		}
+	}
 
-	return data, nil
+	sort.Strings(observation.Instruments)
+	sort.Strings(observation.Suffixes)
+	observation.ImageCount = len(observation.Images)
+
+	return observation
 }
 
 func (s *jwstService) GetProgramImages(ctx context.Context, programID string, page, perPage int) ([]JWSTImage, error) {
 	// Используем существующий GetFeed с параметром program
-	return s.GetFeed(ctx, "program", "", programID, "", page, perPage)
+	feed, err := s.GetFeed(ctx, JWSTFeedFilter{
+		Source:  "program",
+		Program: programID,
+		Page:    page,
+		PerPage: perPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed.Images, nil
 }
 
-func (s *jwstService) GetFeed(ctx context.Context, source, suffix, program, instrument string, page, perPage int) ([]JWSTImage, error) {
-	// Генерируем ключ кэша
-	cacheKey := fmt.Sprintf("jwst:feed:%s:%s:%s:%s:%d:%d",
-		source, suffix, program, instrument, page, perPage)
+func (s *jwstService) GetFeed(ctx context.Context, filter JWSTFeedFilter) (*JWSTFeed, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 || filter.PerPage > 100 {
+		filter.PerPage = 24
+	}
 
-	// Пробуем получить из кэша
-	var cachedImages []JWSTImage
-	err := s.cacheRepo.GetJSON(ctx, cacheKey, &cachedImages)
-	if err == nil && len(cachedImages) > 0 {
-		log.Printf("JWST feed served from cache: %s", cacheKey)
-		return cachedImages, nil
+	var cursor *jwstCursor
+	if filter.Cursor != "" {
+		decoded, err := decodeJWSTCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = decoded
 	}
 
+	// Основной источник - локальный каталог; к API идем, только пока он пуст
+	count, err := s.catalogRepo.Count(ctx)
+	if err != nil {
+		log.Printf("JWST catalog unavailable, falling back to upstream: %v", err)
+	}
+	if err == nil && count > 0 {
+		return s.getCatalogFeed(ctx, filter, cursor)
+	}
+
+	return s.getUpstreamFeed(ctx, filter, cursor)
+}
+
+func (s *jwstService) getCatalogFeed(ctx context.Context, filter JWSTFeedFilter, cursor *jwstCursor) (*JWSTFeed, error) {
+	page := filter.Page
+	if cursor != nil {
+		page = cursor.Page
+	}
+
+	records, err := s.catalogRepo.Find(ctx, repository.JWSTImageFilter{
+		Program:      filter.Program,
+		Instrument:   filter.Instrument,
+		Suffix:       filter.Suffix,
+		ObservedFrom: filter.ObservedFrom,
+		ObservedTo:   filter.ObservedTo,
+		Page:         page,
+		PerPage:      filter.PerPage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query JWST catalog: %w", err)
+	}
+
+	feed := &JWSTFeed{Images: make([]JWSTImage, 0, len(records))}
+	for _, record := range records {
+		feed.Images = append(feed.Images, catalogRecordToImage(record))
+	}
+
+	// Фильтры применяются в SQL, поэтому полная страница означает, что данные могут продолжаться
+	if len(records) == filter.PerPage {
+		feed.Next = jwstCursor{Page: page + 1}.encode()
+	}
+	return feed, nil
+}
+
+// getUpstreamFeed читает страницы jwstapi.com подряд, пока не наберет perPage
+// изображений после фильтра по инструменту или не исчерпает бюджет запросов
+func (s *jwstService) getUpstreamFeed(ctx context.Context, filter JWSTFeedFilter, cursor *jwstCursor) (*JWSTFeed, error) {
 	// Определяем путь API
 	path := "all/type/jpg"
-	switch source {
+	switch filter.Source {
 	case "suffix":
-		if suffix != "" {
-			path = fmt.Sprintf("all/suffix/%s", strings.TrimPrefix(suffix, "/"))
+		if filter.Suffix != "" {
+			path = fmt.Sprintf("all/suffix/%s", strings.TrimPrefix(filter.Suffix, "/"))
 		}
 	case "program":
-		if program != "" {
-			path = fmt.Sprintf("program/id/%s", program)
+		if filter.Program != "" {
+			path = fmt.Sprintf("program/id/%s", filter.Program)
+		}
+	}
+
+	// Без фильтра страницы upstream совпадают со страницами клиента;
+	// с фильтром читаем крупными страницами, чтобы тратить меньше запросов
+	position := jwstCursor{Page: filter.Page, Size: filter.PerPage}
+	if filter.Instrument != "" {
+		position = jwstCursor{Page: 1, Size: jwstFilteredPageSize}
+	}
+	if cursor != nil {
+		position = *cursor
+		if position.Size < 1 || position.Size > 100 {
+			position.Size = filter.PerPage
 		}
 	}
 
+	feed := &JWSTFeed{Images: []JWSTImage{}}
+
+	for fetched := 0; fetched < jwstUpstreamPageBudget; fetched++ {
+		page, err := s.fetchUpstreamPage(ctx, path, position.Page, position.Size)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := position.Offset; i < len(page.Images); i++ {
+			image := page.Images[i]
+			if filter.Instrument != "" && !containsInstrument(image.Instruments, filter.Instrument) {
+				continue
+			}
+
+			feed.Images = append(feed.Images, image)
+			if len(feed.Images) == filter.PerPage {
+				next := jwstCursor{Page: position.Page, Offset: i + 1, Size: position.Size}
+				if next.Offset >= len(page.Images) {
+					if page.Total < position.Size {
+						return feed, nil
+					}
+					next = jwstCursor{Page: position.Page + 1, Size: position.Size}
+				}
+				feed.Next = next.encode()
+				return feed, nil
+			}
+		}
+
+		// Неполная страница - данные закончились
+		if page.Total < position.Size {
+			return feed, nil
+		}
+
+		position = jwstCursor{Page: position.Page + 1, Size: position.Size}
+	}
+
+	// Бюджет исчерпан: отдаем то, что нашли, и продолжим со следующей страницы
+	feed.Next = position.encode()
+	return feed, nil
+}
+
+// fetchUpstreamPage возвращает нормализованную страницу без фильтров (с кэшем на 15 минут)
+func (s *jwstService) fetchUpstreamPage(ctx context.Context, path string, page, perPage int) (*jwstUpstreamPage, error) {
+	cacheKey := fmt.Sprintf("jwst:upstream:%s:%d:%d", path, page, perPage)
+
+	var cached jwstUpstreamPage
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.Images != nil {
+		log.Printf("JWST page served from cache: %s", cacheKey)
+		return &cached, nil
+	}
+
 	// Получаем данные от API
 	data, err := s.client.Get(ctx, path, map[string]string{
 		"page":    fmt.Sprintf("%d", page),
@@ -103,61 +501,476 @@ func (s *jwstService) GetFeed(ctx context.Context, source, suffix, program, inst
 		return nil, fmt.Errorf("failed to fetch JWST data: %w", err)
 	}
 
-	// Обрабатываем данные
-	images := s.processJWSTData(data, instrument)
+	// Смещения курсора считаются по нормализованным изображениям, а конец
+	// данных - по числу сырых элементов страницы
+	result := &jwstUpstreamPage{
+		Images: s.processJWSTData(data),
+		Total:  len(s.extractItems(data)),
+	}
+	if result.Images == nil {
+		result.Images = []JWSTImage{}
+	}
 
 	// Кэшируем на 15 минут
-	if err := s.cacheRepo.SetJSON(ctx, cacheKey, images, 15*time.Minute); err != nil {
-		log.Printf("Failed to cache JWST feed: %v", err)
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, result, 15*time.Minute); err != nil {
+		log.Printf("Failed to cache JWST page: %v", err)
 	}
 
-	return images, nil
+	return result, nil
 }
 
-func (s *jwstService) processJWSTData(data map[string]interface{}, instrumentFilter string) []JWSTImage {
+// Search объединяет поиск jwstapi.com с поиском по локальному каталогу
+// (подпись, программа, цель наблюдения). Полный список кэшируется на запрос,
+// страницы нарезаются из него.
+func (s *jwstService) Search(ctx context.Context, query string, page, perPage int) (*JWSTSearchResult, error) {
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if query == "" {
+		return nil, ErrJWSTEmptyQuery
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 100 {
+		perPage = 24
+	}
+
+	cacheKey := fmt.Sprintf("jwst:search:%s", query)
+
 	var images []JWSTImage
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &images); err != nil || images == nil {
+		collected, err := s.collectSearchResults(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		images = collected
+
+		// Кэшируем на 15 минут
+		if err := s.cacheRepo.SetJSON(ctx, cacheKey, images, 15*time.Minute); err != nil {
+			log.Printf("Failed to cache JWST search: %v", err)
+		}
+	}
+
+	result := &JWSTSearchResult{
+		Query:   query,
+		Images:  []JWSTImage{},
+		Total:   len(images),
+		Page:    page,
+		PerPage: perPage,
+	}
+
+	start := (page - 1) * perPage
+	if start < len(images) {
+		end := start + perPage
+		if end > len(images) {
+			end = len(images)
+		}
+		result.Images = images[start:end]
+	}
+
+	return result, nil
+}
+
+func (s *jwstService) collectSearchResults(ctx context.Context, query string) ([]JWSTImage, error) {
+	images := []JWSTImage{}
+	seen := make(map[string]bool)
+	add := func(image JWSTImage) {
+		if seen[image.URL] {
+			return
+		}
+		seen[image.URL] = true
+		images = append(images, image)
+	}
+
+	// 1. Поиск jwstapi.com
+	var upstreamErr error
+	for page := 1; page <= jwstSearchUpstreamPages; page++ {
+		data, err := s.client.Search(ctx, query, page, jwstFilteredPageSize)
+		if err != nil {
+			upstreamErr = err
+			break
+		}
+
+		for _, image := range s.processJWSTData(data) {
+			add(image)
+		}
+		if len(s.extractItems(data)) < jwstFilteredPageSize {
+			break
+		}
+	}
+
+	// 2. Локальный каталог дополняет совпадения по подписи, программе и цели
+	records, catalogErr := s.catalogRepo.Search(ctx, query, jwstSearchCatalogLimit)
+	for _, record := range records {
+		add(catalogRecordToImage(record))
+	}
+
+	if upstreamErr != nil && catalogErr != nil {
+		return nil, fmt.Errorf("failed to search JWST images: %w", upstreamErr)
+	}
+	if upstreamErr != nil {
+		log.Printf("JWST upstream search failed, using catalog only: %v", upstreamErr)
+	}
+	if catalogErr != nil {
+		log.Printf("JWST catalog search failed, using upstream only: %v", catalogErr)
+	}
+
+	return images, nil
+}
+
+// SyncCatalog пополняет локальный каталог: свежие страницы all/type/jpg до первой
+// полностью известной страницы, затем продолжение обхода архива и одна программа за запуск
+func (s *jwstService) SyncCatalog(ctx context.Context) error {
+	log.Println("Syncing JWST catalog...")
+
+	// 1. Новые изображения в начале ленты
+	added := 0
+	for page := 1; page <= s.catalog.PagesPerRun; page++ {
+		newItems, total, err := s.crawlPage(ctx, "all/type/jpg", page)
+		if err != nil {
+			return err
+		}
+		added += newItems
+		if total == 0 || newItems == 0 {
+			break
+		}
+	}
+
+	// 2. Продолжаем обход архива с сохраненной страницы
+	cursorKey := "jwst:catalog:backfill_page"
+	page := 1
+	if cached, _ := s.cacheRepo.Get(ctx, cursorKey); cached != "" {
+		if p, err := strconv.Atoi(cached); err == nil && p > 0 {
+			page = p
+		}
+	}
+	for i := 0; i < s.catalog.PagesPerRun; i++ {
+		newItems, total, err := s.crawlPage(ctx, "all/type/jpg", page)
+		if err != nil {
+			return err
+		}
+		added += newItems
+		if total == 0 {
+			// Архив пройден целиком - следующий обход начнем сначала
+			page = 1
+			break
+		}
+		page++
+	}
+	if err := s.cacheRepo.Set(ctx, cursorKey, strconv.Itoa(page), 0); err != nil {
+		log.Printf("Failed to save JWST backfill cursor: %v", err)
+	}
+
+	// 3. Одна программа за запуск по кругу
+	programs, err := s.listPrograms(ctx)
+	if err != nil {
+		log.Printf("Failed to list JWST programs: %v", err)
+	} else if len(programs) > 0 {
+		programKey := "jwst:catalog:program_index"
+		index := 0
+		if cached, _ := s.cacheRepo.Get(ctx, programKey); cached != "" {
+			if i, err := strconv.Atoi(cached); err == nil && i >= 0 {
+				index = i % len(programs)
+			}
+		}
+
+		program := programs[index]
+		for page := 1; page <= s.catalog.PagesPerRun; page++ {
+			newItems, total, err := s.crawlPage(ctx, fmt.Sprintf("program/id/%s", program), page)
+			if err != nil {
+				log.Printf("Failed to crawl JWST program %s: %v", program, err)
+				break
+			}
+			added += newItems
+			if total < s.catalog.PerPage {
+				break
+			}
+		}
+
+		if err := s.cacheRepo.Set(ctx, programKey, strconv.Itoa(index+1), 0); err != nil {
+			log.Printf("Failed to save JWST program cursor: %v", err)
+		}
+	}
+
+	// 4. Метаданные из заголовков FITS для части записей
+	enriched := s.enrichFromFITS(ctx)
+
+	log.Printf("JWST catalog synced: %d new images, %d enriched from FITS", added, enriched)
+	return nil
+}
+
+// enrichFromFITS читает заголовки FITS для записей каталога без метаданных
+// и дополняет цель, координаты, фильтр, экспозицию и подпись
+func (s *jwstService) enrichFromFITS(ctx context.Context) int {
+	if s.fits == nil || s.catalog.FITSPerRun < 1 {
+		return 0
+	}
+
+	pending, err := s.catalogRepo.FindPendingFITS(ctx, time.Now().Add(-jwstFITSRetryInterval), s.catalog.FITSPerRun)
+	if err != nil {
+		log.Printf("Failed to load JWST records for FITS enrichment: %v", err)
+		return 0
+	}
+
+	enriched := 0
+	for i := range pending {
+		record := &pending[i]
+
+		var item map[string]interface{}
+		if len(record.Raw) > 0 {
+			json.Unmarshal(record.Raw, &item)
+		}
+
+		now := time.Now().UTC()
+		record.FitsCheckedAt = &now
+
+		fitsURL := s.resolveFITSURL(item, record.FileID)
+		var meta *FITSMetadata
+		if fitsURL != "" {
+			meta, err = s.fits.GetMetadata(ctx, fitsURL)
+			if err != nil {
+				log.Printf("Failed to read FITS header for %s: %v", record.FileID, err)
+			}
+		}
+
+		if meta != nil {
+			record.FitsURL = fitsURL
+			applyFITSMetadata(record, meta)
+			var instruments []string
+			json.Unmarshal(record.Instruments, &instruments)
+			record.Caption = s.generateCaption(item, instruments, meta)
+			enriched++
+		}
+
+		if err := s.catalogRepo.UpdateFITSMetadata(ctx, record); err != nil {
+			log.Printf("Failed to save FITS metadata for %s: %v", record.FileID, err)
+		}
+	}
+
+	return enriched
+}
+
+// resolveFITSURL берет ссылку на FITS из элемента upstream, иначе строит ссылку
+// на продукт MAST по имени файла
+func (s *jwstService) resolveFITSURL(item map[string]interface{}, fileID string) string {
+	for _, key := range []string{"location", "url", "file_url", "s3_url"} {
+		if value, ok := item[key].(string); ok && strings.HasSuffix(strings.ToLower(value), ".fits") {
+			return value
+		}
+	}
+
+	// Продукты JWST называются jw<программа>...; прочие записи не угадываем
+	if !strings.HasPrefix(strings.ToLower(fileID), "jw") || strings.Contains(fileID, "/") {
+		return ""
+	}
+	return s.fits.ProductURL(fileID)
+}
+
+func applyFITSMetadata(record *models.JWSTImage, meta *FITSMetadata) {
+	if meta.Target != "" {
+		record.TargetName = meta.Target
+	}
+	if meta.ObservedAt != nil {
+		record.ObservedAt = meta.ObservedAt
+	}
+	record.RA = meta.RA
+	record.Dec = meta.Dec
+	record.Filter = meta.Filter
+	record.ExposureTime = meta.ExposureTime
+}
+
+// crawlPage сохраняет одну страницу upstream и возвращает число новых и всех записей
+func (s *jwstService) crawlPage(ctx context.Context, path string, page int) (int, int, error) {
+	data, err := s.client.Get(ctx, path, map[string]string{
+		"page":    strconv.Itoa(page),
+		"perPage": strconv.Itoa(s.catalog.PerPage),
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch JWST %s page %d: %w", path, page, err)
+	}
 
-	// Извлекаем список элементов
 	items := s.extractItems(data)
+	_, added, err := s.storeCatalogItems(ctx, items)
+	if err != nil {
+		return 0, 0, err
+	}
 
+	return added, len(items), nil
+}
+
+// storeCatalogItems сохраняет элементы upstream в каталог и возвращает записи и число новых
+func (s *jwstService) storeCatalogItems(ctx context.Context, items []map[string]interface{}) ([]models.JWSTImage, int, error) {
+	records := make([]models.JWSTImage, 0, len(items))
+	fileIDs := make([]string, 0, len(items))
+
+	seen := make(map[string]bool)
 	for _, item := range items {
-		// Получаем URL изображения
-		imageURL := s.extractImageURL(item)
-		if imageURL == "" {
+		record, ok := s.toCatalogRecord(item)
+		if !ok || seen[record.FileID] {
 			continue
 		}
+		seen[record.FileID] = true
+		records = append(records, record)
+		fileIDs = append(fileIDs, record.FileID)
+	}
+
+	existing, err := s.catalogRepo.ExistingFileIDs(ctx, fileIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to check JWST catalog: %w", err)
+	}
 
-		// Получаем инструменты
-		instruments := s.extractInstruments(item)
+	if err := s.catalogRepo.BulkUpsert(ctx, records); err != nil {
+		return nil, 0, fmt.Errorf("failed to save JWST catalog items: %w", err)
+	}
 
-		// Фильтруем по инструменту если задан фильтр
-		if instrumentFilter != "" && len(instruments) > 0 {
-			if !containsInstrument(instruments, instrumentFilter) {
-				continue
+	return records, len(records) - len(existing), nil
+}
+
+func (s *jwstService) listPrograms(ctx context.Context) ([]string, error) {
+	cacheKey := "jwst:catalog:programs"
+
+	var programs []string
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &programs); err == nil && len(programs) > 0 {
+		return programs, nil
+	}
+
+	data, err := s.client.Get(ctx, "program/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	if body, ok := data["body"].([]interface{}); ok {
+		for _, entry := range body {
+			var program string
+			switch v := entry.(type) {
+			case map[string]interface{}:
+				program = extractProgram(v)
+			default:
+				program = stringifyValue(v)
+			}
+			if program != "" && !seen[program] {
+				seen[program] = true
+				programs = append(programs, program)
 			}
 		}
+	}
 
-		// Создаем структуру изображения
-		image := JWSTImage{
-			URL:         imageURL,
-			ObsID:       s.extractString(item, "observation_id", "observationId", "id"),
-			Program:     s.extractString(item, "program"),
-			Suffix:      s.extractSuffix(item),
-			Instruments: instruments,
-			Caption:     s.generateCaption(item, instruments),
-			Link:        s.extractString(item, "location", "url", "href"),
-		}
+	// Список программ меняется редко - кэшируем на сутки
+	if len(programs) > 0 {
+		s.cacheRepo.SetJSON(ctx, cacheKey, programs, 24*time.Hour)
+	}
 
-		if image.Link == "" {
-			image.Link = imageURL
-		}
+	return programs, nil
+}
+
+func (s *jwstService) toCatalogRecord(item map[string]interface{}) (models.JWSTImage, bool) {
+	image, ok := s.normalizeItem(item)
+	if !ok {
+		return models.JWSTImage{}, false
+	}
 
+	fileID := s.extractString(item, "id", "file_id")
+	if fileID == "" {
+		fileID = image.URL
+	}
+
+	instruments, _ := json.Marshal(image.Instruments)
+	if image.Instruments == nil {
+		instruments = []byte("[]")
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return models.JWSTImage{}, false
+	}
+
+	return models.JWSTImage{
+		FileID:        fileID,
+		ObservationID: image.ObsID,
+		Program:       image.Program,
+		Suffix:        image.Suffix,
+		Instruments:   instruments,
+		FileType:      s.extractString(item, "file_type"),
+		ImageURL:      image.URL,
+		Link:          image.Link,
+		Caption:       image.Caption,
+		TargetName:    image.Target,
+		ObservedAt:    image.ObservedAt,
+		Raw:           raw,
+		FetchedAt:     time.Now().UTC(),
+	}, true
+}
+
+func catalogRecordToImage(record models.JWSTImage) JWSTImage {
+	var instruments []string
+	if len(record.Instruments) > 0 {
+		json.Unmarshal(record.Instruments, &instruments)
+	}
+
+	return JWSTImage{
+		URL:         record.ImageURL,
+		ObsID:       record.ObservationID,
+		Program:     record.Program,
+		Suffix:      record.Suffix,
+		Instruments: instruments,
+		Caption:     record.Caption,
+		Link:        record.Link,
+		Target:      record.TargetName,
+		ObservedAt:  record.ObservedAt,
+		RA:          record.RA,
+		Dec:         record.Dec,
+		Filter:      record.Filter,
+		Exposure:    record.ExposureTime,
+	}
+}
+
+func (s *jwstService) processJWSTData(data map[string]interface{}) []JWSTImage {
+	var images []JWSTImage
+
+	// Извлекаем список элементов
+	items := s.extractItems(data)
+
+	for _, item := range items {
+		image, ok := s.normalizeItem(item)
+		if !ok {
+			continue
+		}
 		images = append(images, image)
 	}
 
 	return images
 }
 
+// normalizeItem приводит элемент ответа jwstapi.com к JWSTImage
+func (s *jwstService) normalizeItem(item map[string]interface{}) (JWSTImage, bool) {
+	// Получаем URL изображения
+	imageURL := s.extractImageURL(item)
+	if imageURL == "" {
+		return JWSTImage{}, false
+	}
+
+	// Получаем инструменты
+	instruments := s.extractInstruments(item)
+
+	// Создаем структуру изображения
+	image := JWSTImage{
+		URL:         imageURL,
+		ObsID:       s.extractString(item, "observation_id", "observationId", "id"),
+		Program:     extractProgram(item),
+		Suffix:      s.extractSuffix(item),
+		Instruments: instruments,
+		Caption:     s.generateCaption(item, instruments, nil),
+		Link:        s.extractString(item, "location", "url", "href"),
+		Target:      s.extractTarget(item),
+		ObservedAt:  s.extractObservedAt(item),
+	}
+
+	if image.Link == "" {
+		image.Link = imageURL
+	}
+
+	return image, true
+}
+
 func (s *jwstService) extractItems(data map[string]interface{}) []map[string]interface{} {
 	var items []map[string]interface{}
 
@@ -241,7 +1054,9 @@ func (s *jwstService) extractSuffix(item map[string]interface{}) string {
 	return ""
 }
 
-func (s *jwstService) generateCaption(item map[string]interface{}, instruments []string) string {
+// generateCaption собирает подпись из полей upstream; meta (если есть) добавляет
+// цель, фильтр, экспозицию и координаты из заголовка FITS
+func (s *jwstService) generateCaption(item map[string]interface{}, instruments []string, meta *FITSMetadata) string {
 	var parts []string
 
 	// ID наблюдения
@@ -251,7 +1066,7 @@ func (s *jwstService) generateCaption(item map[string]interface{}, instruments [
 	}
 
 	// Программа
-	program := s.extractString(item, "program")
+	program := extractProgram(item)
 	if program != "" {
 		parts = append(parts, "P"+program)
 	}
@@ -267,9 +1082,90 @@ func (s *jwstService) generateCaption(item map[string]interface{}, instruments [
 		parts = append(parts, strings.Join(instruments, "/"))
 	}
 
+	if meta != nil {
+		if meta.Target != "" {
+			parts = append(parts, meta.Target)
+		}
+		if meta.Filter != "" {
+			filter := meta.Filter
+			if meta.Pupil != "" {
+				filter += "+" + meta.Pupil
+			}
+			parts = append(parts, filter)
+		}
+		if meta.ExposureTime != nil && *meta.ExposureTime > 0 {
+			parts = append(parts, fmt.Sprintf("%.0f s", *meta.ExposureTime))
+		}
+		if meta.RA != nil && meta.Dec != nil {
+			parts = append(parts, fmt.Sprintf("RA %.4f° Dec %+.4f°", *meta.RA, *meta.Dec))
+		}
+	}
+
 	return strings.Join(parts, " · ")
 }
 
+// extractTarget ищет имя цели наблюдения в элементе или его details
+func (s *jwstService) extractTarget(item map[string]interface{}) string {
+	if target := s.extractString(item, "target_name", "targname", "target"); target != "" {
+		return target
+	}
+	if details, ok := item["details"].(map[string]interface{}); ok {
+		return s.extractString(details, "target_name", "targname", "target")
+	}
+	return ""
+}
+
+// extractObservedAt ищет дату наблюдения, если upstream ее передает
+func (s *jwstService) extractObservedAt(item map[string]interface{}) *time.Time {
+	sources := []map[string]interface{}{item}
+	if details, ok := item["details"].(map[string]interface{}); ok {
+		sources = append(sources, details)
+	}
+
+	formats := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05.000",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+
+	for _, source := range sources {
+		value := s.extractString(source, "date_obs", "observation_date", "observed_at", "date")
+		if value == "" {
+			continue
+		}
+		for _, format := range formats {
+			if t, err := time.Parse(format, value); err == nil {
+				t = t.UTC()
+				return &t
+			}
+		}
+	}
+
+	return nil
+}
+
+// extractProgram возвращает номер программы; jwstapi.com отдает его и числом, и строкой
+func extractProgram(item map[string]interface{}) string {
+	if val, ok := item["program"]; ok {
+		return stringifyValue(val)
+	}
+	return ""
+}
+
+func stringifyValue(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
 func containsInstrument(instruments []string, target string) bool {
 	targetUpper := strings.ToUpper(target)
 	for _, inst := range instruments {
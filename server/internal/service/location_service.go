@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+	"cassiopeia/pkg/tzlookup"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLocationNotFound = errors.New("location not found")
+	ErrLocationInvalid  = errors.New("invalid location")
+)
+
+// TimezoneError пояс места не задан или неизвестен; Suggestions - пояса-кандидаты по координатам,
+// которые клиент предлагает пользователю подтвердить
+type TimezoneError struct {
+	Message     string
+	Suggestions []string
+}
+
+func (e *TimezoneError) Error() string {
+	return ErrLocationInvalid.Error() + ": " + e.Message
+}
+
+func (e *TimezoneError) Is(target error) bool {
+	return target == ErrLocationInvalid
+}
+
+type LocationService interface {
+	List(ctx context.Context) ([]LocationView, error)
+	Create(ctx context.Context, input LocationInput) (*LocationView, error)
+	Get(ctx context.Context, id uint) (*LocationView, error)
+	Update(ctx context.Context, id uint, input LocationInput) (*LocationView, error)
+	Delete(ctx context.Context, id uint) error
+
+	// Observer наблюдатель по сохраненному месту
+	Observer(ctx context.Context, id uint) (*Observer, error)
+	// ObserverAt наблюдатель в произвольной точке; без timezone пояс подсказывается по координатам
+	// и помечается приблизительным
+	ObserverAt(lat, lon float64, timezone string) (*Observer, error)
+	// SuggestTimezone пояса-кандидаты для координат, самый вероятный первым
+	SuggestTimezone(lat, lon float64) ([]string, error)
+}
+
+type LocationInput struct {
+	Name      string   `json:"name"`
+	Lat       *float64 `json:"lat"`
+	Lon       *float64 `json:"lon"`
+	Elevation float64  `json:"elevation"`
+	// Timezone имя пояса IANA, обязательно: по координатам пояс только подсказывается
+	Timezone string `json:"timezone"`
+}
+
+type LocationView struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	Lat          float64   `json:"lat"`
+	Lon          float64   `json:"lon"`
+	Elevation    float64   `json:"elevation"`
+	Timezone     string    `json:"timezone"`
+	TimezoneAuto bool      `json:"timezone_auto"`
+	UTCOffset    string    `json:"utc_offset"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Observer точка наблюдения для астрономических расчетов
+type Observer struct {
+	LocationID uint    `json:"location_id,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Lat        float64 `json:"lat"`
+	Lon        float64 `json:"lon"`
+	Elevation  float64 `json:"elevation"`
+	Timezone   string  `json:"timezone"`
+	// TimezoneApproximate пояс не задан явно, а подсказан по координатам и может быть неверным
+	TimezoneApproximate bool `json:"timezone_approximate,omitempty"`
+	// Zone загруженный пояс; события возвращаются в местном времени
+	Zone *time.Location `json:"-"`
+}
+
+// location пояс наблюдателя; если он не задан - определяется по координатам
+func (o Observer) location() *time.Location {
+	if o.Zone != nil {
+		return o.Zone
+	}
+	return tzlookup.Location(o.Lat, o.Lon)
+}
+
+type locationService struct {
+	repo repository.LocationRepository
+}
+
+func NewLocationService(repo repository.LocationRepository) LocationService {
+	return &locationService{repo: repo}
+}
+
+func (s *locationService) List(ctx context.Context) ([]LocationView, error) {
+	locations, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locations: %w", err)
+	}
+
+	views := make([]LocationView, 0, len(locations))
+	for i := range locations {
+		views = append(views, toLocationView(&locations[i]))
+	}
+	return views, nil
+}
+
+func (s *locationService) Create(ctx context.Context, input LocationInput) (*LocationView, error) {
+	location := &models.ObserverLocation{}
+	if err := applyLocationInput(location, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, location); err != nil {
+		return nil, fmt.Errorf("failed to create location: %w", err)
+	}
+
+	view := toLocationView(location)
+	return &view, nil
+}
+
+func (s *locationService) Get(ctx context.Context, id uint) (*LocationView, error) {
+	location, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	view := toLocationView(location)
+	return &view, nil
+}
+
+func (s *locationService) Update(ctx context.Context, id uint, input LocationInput) (*LocationView, error) {
+	location, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyLocationInput(location, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, location); err != nil {
+		return nil, fmt.Errorf("failed to update location: %w", err)
+	}
+
+	return s.Get(ctx, id)
+}
+
+func (s *locationService) Delete(ctx context.Context, id uint) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLocationNotFound
+		}
+		return fmt.Errorf("failed to delete location: %w", err)
+	}
+	return nil
+}
+
+func (s *locationService) Observer(ctx context.Context, id uint) (*Observer, error) {
+	location, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := time.LoadLocation(location.Timezone)
+	if err != nil {
+		// Пояс мог исчезнуть из базы tzdata - не ломаем расчеты, берем пояс по координатам
+		zone = tzlookup.Location(location.Lat, location.Lon)
+	}
+
+	return &Observer{
+		LocationID: location.ID,
+		Name:       location.Name,
+		Lat:        location.Lat,
+		Lon:        location.Lon,
+		Elevation:  location.Elevation,
+		Timezone:   zone.String(),
+		Zone:       zone,
+	}, nil
+}
+
+func (s *locationService) ObserverAt(lat, lon float64, timezone string) (*Observer, error) {
+	if err := validateCoordinates(lat, lon); err != nil {
+		return nil, err
+	}
+
+	observer := &Observer{Lat: lat, Lon: lon}
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		zone, err := loadTimezone(timezone, lat, lon)
+		if err != nil {
+			return nil, err
+		}
+		observer.Zone = zone
+	} else {
+		observer.Zone = tzlookup.Location(lat, lon)
+		observer.TimezoneApproximate = true
+	}
+	observer.Timezone = observer.Zone.String()
+	return observer, nil
+}
+
+func (s *locationService) SuggestTimezone(lat, lon float64) ([]string, error) {
+	if err := validateCoordinates(lat, lon); err != nil {
+		return nil, err
+	}
+	return tzlookup.Suggest(lat, lon), nil
+}
+
+func (s *locationService) load(ctx context.Context, id uint) (*models.ObserverLocation, error) {
+	location, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+	return location, nil
+}
+
+func applyLocationInput(location *models.ObserverLocation, input LocationInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrLocationInvalid)
+	}
+	if len(name) > 200 {
+		return fmt.Errorf("%w: name is too long", ErrLocationInvalid)
+	}
+	if input.Lat == nil || input.Lon == nil {
+		return fmt.Errorf("%w: lat and lon are required", ErrLocationInvalid)
+	}
+	if err := validateCoordinates(*input.Lat, *input.Lon); err != nil {
+		return err
+	}
+	// От Мертвого моря до высокогорных обсерваторий с запасом
+	if input.Elevation < -500 || input.Elevation > 9000 {
+		return fmt.Errorf("%w: elevation must be between -500 and 9000 m", ErrLocationInvalid)
+	}
+
+	zone, err := loadTimezone(strings.TrimSpace(input.Timezone), *input.Lat, *input.Lon)
+	if err != nil {
+		return err
+	}
+
+	location.Name = name
+	location.Lat = *input.Lat
+	location.Lon = *input.Lon
+	location.Elevation = input.Elevation
+	location.Timezone = zone.String()
+	// Пояс подтвержден пользователем; true остается только у мест, сохраненных до этого
+	location.TimezoneAuto = false
+	return nil
+}
+
+// loadTimezone проверяет явно заданный пояс; при ошибке подсказывает пояса по координатам
+func loadTimezone(timezone string, lat, lon float64) (*time.Location, error) {
+	if timezone == "" {
+		return nil, &TimezoneError{Message: "timezone is required", Suggestions: tzlookup.Suggest(lat, lon)}
+	}
+	zone, err := time.LoadLocation(timezone)
+	if err != nil || strings.EqualFold(timezone, "local") {
+		return nil, &TimezoneError{
+			Message:     fmt.Sprintf("unknown timezone %q", timezone),
+			Suggestions: tzlookup.Suggest(lat, lon),
+		}
+	}
+	return zone, nil
+}
+
+func validateCoordinates(lat, lon float64) error {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("%w: coordinates out of range: lat=%.4f lon=%.4f", ErrLocationInvalid, lat, lon)
+	}
+	return nil
+}
+
+func toLocationView(location *models.ObserverLocation) LocationView {
+	offset := ""
+	if zone, err := time.LoadLocation(location.Timezone); err == nil {
+		offset = time.Now().In(zone).Format("-07:00")
+	}
+
+	return LocationView{
+		ID:           location.ID,
+		Name:         location.Name,
+		Lat:          location.Lat,
+		Lon:          location.Lon,
+		Elevation:    location.Elevation,
+		Timezone:     location.Timezone,
+		TimezoneAuto: location.TimezoneAuto,
+		UTCOffset:    offset,
+		CreatedAt:    location.CreatedAt,
+		UpdatedAt:    location.UpdatedAt,
+	}
+}
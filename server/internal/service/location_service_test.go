@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+type locationRepo struct {
+	repository.LocationRepository
+	created []*models.ObserverLocation
+}
+
+func (r *locationRepo) Create(ctx context.Context, location *models.ObserverLocation) error {
+	r.created = append(r.created, location)
+	return nil
+}
+
+func TestLocationCreateRequiresTimezone(t *testing.T) {
+	repo := &locationRepo{}
+	s := NewLocationService(repo)
+	lat, lon := 35.15, -90.05
+
+	_, err := s.Create(context.Background(), LocationInput{Name: "Memphis", Lat: &lat, Lon: &lon})
+	var timezoneErr *TimezoneError
+	if !errors.As(err, &timezoneErr) || !errors.Is(err, ErrLocationInvalid) {
+		t.Fatalf("err = %v, want TimezoneError wrapping ErrLocationInvalid", err)
+	}
+	if !slices.Contains(timezoneErr.Suggestions, "America/Chicago") {
+		t.Fatalf("suggestions %v, want America/Chicago among them", timezoneErr.Suggestions)
+	}
+
+	_, err = s.Create(context.Background(), LocationInput{Name: "Memphis", Lat: &lat, Lon: &lon, Timezone: "Mars/Olympus"})
+	if !errors.As(err, &timezoneErr) {
+		t.Fatalf("unknown timezone: err = %v, want TimezoneError", err)
+	}
+	if len(repo.created) != 0 {
+		t.Fatal("location without a valid timezone was saved")
+	}
+
+	view, err := s.Create(context.Background(), LocationInput{Name: "Memphis", Lat: &lat, Lon: &lon, Timezone: "America/Chicago"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Timezone != "America/Chicago" || view.TimezoneAuto {
+		t.Fatalf("timezone %s auto=%v, want explicit America/Chicago", view.Timezone, view.TimezoneAuto)
+	}
+}
+
+func TestObserverAtMarksSuggestedTimezone(t *testing.T) {
+	s := NewLocationService(&locationRepo{})
+
+	observer, err := s.ObserverAt(-23.70, 133.88, "Australia/Darwin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if observer.Timezone != "Australia/Darwin" || observer.TimezoneApproximate {
+		t.Fatalf("timezone %s approximate=%v, want explicit Australia/Darwin", observer.Timezone, observer.TimezoneApproximate)
+	}
+
+	observer, err = s.ObserverAt(-23.70, 133.88, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !observer.TimezoneApproximate {
+		t.Fatalf("suggested timezone %s not marked approximate", observer.Timezone)
+	}
+
+	if _, err := s.ObserverAt(-23.70, 133.88, "Nowhere/City"); !errors.Is(err, ErrLocationInvalid) {
+		t.Fatalf("err = %v, want ErrLocationInvalid", err)
+	}
+}
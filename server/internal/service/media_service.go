@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"cassiopeia/internal/cache"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrMediaHostNotAllowed = errors.New("media host is not allowed")
+	ErrMediaInvalidSource  = errors.New("invalid media source URL")
+	ErrMediaWidthInvalid   = errors.New("thumbnail width is not configured")
+	ErrMediaFormatInvalid  = errors.New("unsupported thumbnail format")
+	ErrMediaTooLarge       = errors.New("media image is too large")
+)
+
+type MediaService interface {
+	// Fetch возвращает оригинал (width == 0) или миниатюру из локального кэша,
+	// скачивая источник при первом обращении
+	Fetch(ctx context.Context, src string, width int, format string) (*MediaFile, error)
+	ThumbWidths() []int
+}
+
+type MediaConfig struct {
+	CacheDir      string
+	AllowedHosts  []string
+	ThumbWidths   []int
+	MaxCacheBytes int64
+	MaxSourceSize int64
+	// MaxPixels предел ширина*высота оригинала для декодирования; 0 - 100 Мп
+	MaxPixels int64
+}
+
+type MediaFile struct {
+	Path        string
+	ContentType string
+	ETag        string
+	Size        int64
+	ModTime     time.Time
+}
+
+type mediaService struct {
+	config MediaConfig
+	lru    *cache.DiskLRU
+	client *http.Client
+	group  singleflight.Group
+}
+
+func NewMediaService(config MediaConfig) (MediaService, error) {
+	if config.CacheDir == "" {
+		config.CacheDir = "./data/media"
+	}
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = 100000000
+	}
+
+	for _, sub := range []string{"originals", "thumbs"} {
+		if err := os.MkdirAll(filepath.Join(config.CacheDir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create media cache directory: %w", err)
+		}
+	}
+
+	lru, err := cache.NewDiskLRU(config.CacheDir, config.MaxCacheBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index media cache: %w", err)
+	}
+
+	media := &mediaService{
+		config: config,
+		lru:    lru,
+	}
+
+	// Соединения только с публичными адресами: проверяется уже разрешенный IP,
+	// поэтому не помогут ни DNS-имена на внутренние адреса, ни редиректы на них.
+	// Прокси из окружения не используется - иначе проверялся бы адрес прокси.
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkMediaAddress(address)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	media.client = &http.Client{
+		Timeout:   60 * time.Second,
+		Transport: transport,
+		// Каждый редирект проверяется по списку хостов до перехода
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return media.validateSource(req.URL.String())
+		},
+	}
+	return media, nil
+}
+
+// checkMediaAddress отклоняет loopback, частные, link-local и прочие непубличные адреса
+func checkMediaAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() ||
+		mediaBlockedPrefix(addr) {
+		return fmt.Errorf("%w: address %s is not public", ErrMediaHostNotAllowed, addr)
+	}
+	return nil
+}
+
+// Диапазоны, которые IsPrivate не покрывает: CGNAT, 0.0.0.0/8, сеть тестов и NAT64
+var mediaBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func mediaBlockedPrefix(addr netip.Addr) bool {
+	for _, prefix := range mediaBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *mediaService) ThumbWidths() []int {
+	return s.config.ThumbWidths
+}
+
+func (s *mediaService) Fetch(ctx context.Context, src string, width int, format string) (*MediaFile, error) {
+	if err := s.validateSource(src); err != nil {
+		return nil, err
+	}
+
+	key := mediaKey(src)
+	originalPath := filepath.Join(s.config.CacheDir, "originals", key)
+
+	if err := s.ensureOriginal(ctx, src, originalPath); err != nil {
+		return nil, err
+	}
+
+	if width == 0 {
+		return s.describe(originalPath, key+"-orig", "")
+	}
+
+	if !s.isAllowedWidth(width) {
+		return nil, ErrMediaWidthInvalid
+	}
+
+	var ext string
+	switch format {
+	case "", "jpeg", "jpg":
+		format, ext = "jpeg", ".jpg"
+	case "webp":
+		ext = ".webp"
+	default:
+		return nil, ErrMediaFormatInvalid
+	}
+
+	thumbPath := filepath.Join(s.config.CacheDir, "thumbs", fmt.Sprintf("%s_%d%s", key, width, ext))
+	if !s.lru.Touch(thumbPath) {
+		_, err, _ := s.group.Do(thumbPath, func() (interface{}, error) {
+			return nil, s.renderThumbnail(originalPath, thumbPath, width, format)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.describe(thumbPath, fmt.Sprintf("%s-%d-%s", key, width, format), "image/"+format)
+}
+
+func (s *mediaService) validateSource(src string) error {
+	parsed, err := url.Parse(src)
+	if err != nil || parsed.Host == "" {
+		return ErrMediaInvalidSource
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ErrMediaInvalidSource
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range s.config.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+
+	return ErrMediaHostNotAllowed
+}
+
+func (s *mediaService) isAllowedWidth(width int) bool {
+	for _, w := range s.config.ThumbWidths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureOriginal скачивает источник один раз; параллельные запросы ждут одну загрузку
+func (s *mediaService) ensureOriginal(ctx context.Context, src, path string) error {
+	if s.lru.Touch(path) {
+		return nil
+	}
+
+	_, err, _ := s.group.Do(path, func() (interface{}, error) {
+		if _, err := os.Stat(path); err == nil {
+			return nil, nil
+		}
+		return nil, s.download(ctx, src, path)
+	})
+	return err
+}
+
+func (s *mediaService) download(ctx context.Context, src, path string) error {
+	log.Printf("Downloading media: %s", src)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", src, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Отказ по списку хостов или адресу на любом шаге редиректа
+		if errors.Is(err, ErrMediaHostNotAllowed) {
+			return ErrMediaHostNotAllowed
+		}
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("media upstream returned status %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("media upstream returned non-image content: %s", contentType)
+	}
+
+	var body io.Reader = resp.Body
+	if s.config.MaxSourceSize > 0 {
+		body = io.LimitReader(resp.Body, s.config.MaxSourceSize+1)
+	}
+
+	size, err := writeFileAtomic(path, body)
+	if err != nil {
+		return err
+	}
+	if s.config.MaxSourceSize > 0 && size > s.config.MaxSourceSize {
+		os.Remove(path)
+		return fmt.Errorf("media source exceeds %d bytes", s.config.MaxSourceSize)
+	}
+
+	s.lru.Add(path, size)
+	return nil
+}
+
+func (s *mediaService) renderThumbnail(originalPath, thumbPath string, width int, format string) error {
+	file, err := os.Open(originalPath)
+	if err != nil {
+		return fmt.Errorf("open original: %w", err)
+	}
+	defer file.Close()
+
+	// Размеры из заголовка - до выделения памяти под все пиксели
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return fmt.Errorf("decode original: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > s.config.MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrMediaTooLarge, config.Width, config.Height)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("open original: %w", err)
+	}
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("decode original: %w", err)
+	}
+
+	// Миниатюры не увеличиваем
+	bounds := src.Bounds()
+	if width > bounds.Dx() {
+		width = bounds.Dx()
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	pr, pw := io.Pipe()
+	go func() {
+		var encodeErr error
+		if format == "webp" {
+			encodeErr = nativewebp.Encode(pw, dst, nil)
+		} else {
+			encodeErr = jpeg.Encode(pw, dst, &jpeg.Options{Quality: 82})
+		}
+		pw.CloseWithError(encodeErr)
+	}()
+
+	size, err := writeFileAtomic(thumbPath, pr)
+	if err != nil {
+		return fmt.Errorf("encode thumbnail: %w", err)
+	}
+
+	s.lru.Add(thumbPath, size)
+	return nil
+}
+
+func (s *mediaService) describe(path, etag, contentType string) (*MediaFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cached media missing: %w", err)
+	}
+
+	if contentType == "" {
+		contentType = sniffContentType(path)
+	}
+
+	return &MediaFile{
+		Path:        path,
+		ContentType: contentType,
+		ETag:        fmt.Sprintf("\"%s-%d\"", etag, info.Size()),
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func mediaKey(src string) string {
+	sum := sha256.Sum256([]byte(src))
+	return hex.EncodeToString(sum[:16])
+}
+
+func sniffContentType(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(file, buf)
+	return http.DetectContentType(buf[:n])
+}
+
+// writeFileAtomic пишет во временный файл и переименовывает, чтобы читатели
+// никогда не видели недописанный файл
+func writeFileAtomic(path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
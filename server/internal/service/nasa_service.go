@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"time"
 
 	"cassiopeia/internal/clients"
@@ -16,33 +18,56 @@ type NASAService interface {
 	FetchAndStoreOSDR(ctx context.Context) error
 	FetchAndStoreAPOD(ctx context.Context) error
 	FetchAndStoreNEO(ctx context.Context) error
+	FetchAndStoreDONKI(ctx context.Context) error
 	GetOSDRList(ctx context.Context, page, limit int) ([]models.OSDRItem, error)
 	GetLatestAPOD(ctx context.Context) (map[string]interface{}, error)
 	GetLatestNEO(ctx context.Context, days int) (map[string]interface{}, error)
 
 	GetAPOD(ctx context.Context, date string) (map[string]interface{}, error)
 	GetNEOWatch(ctx context.Context, days int) (map[string]interface{}, error)
+	// GetCloseApproaches сближения астероидов с Землей на days суток начиная с сегодня
+	GetCloseApproaches(ctx context.Context, days int) ([]NEOApproach, error)
 	GetDONKI(ctx context.Context, eventType string, days int) ([]map[string]interface{}, error)
 }
 
+// NEOApproach сближение околоземного объекта с Землей
+type NEOApproach struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Time              time.Time `json:"time"`
+	MissDistanceKm    float64   `json:"miss_distance_km"`
+	MissDistanceLunar float64   `json:"miss_distance_lunar"`
+	VelocityKmS       float64   `json:"velocity_km_s"`
+	DiameterMinM      float64   `json:"diameter_min_m"`
+	DiameterMaxM      float64   `json:"diameter_max_m"`
+	Hazardous         bool      `json:"hazardous"`
+	URL               string    `json:"url,omitempty"`
+}
+
 type nasaService struct {
-	repo           repository.OSDRRepository
-	spaceCacheRepo repository.SpaceCacheRepository
-	cacheRepo      repository.CacheRepository
-	client         clients.NASAClient
+	repo             repository.OSDRRepository
+	spaceCacheRepo   repository.SpaceCacheRepository
+	spaceWeatherRepo repository.SpaceWeatherRepository
+	cacheRepo        repository.CacheRepository
+	client           clients.NASAClient
 }
 
+// Типы событий DONKI, которые сохраняются для сводки космической погоды
+var donkiStoredTypes = []string{"GST", "FLR", "CME"}
+
 func NewNASAService(
 	repo repository.OSDRRepository,
 	spaceCacheRepo repository.SpaceCacheRepository,
+	spaceWeatherRepo repository.SpaceWeatherRepository,
 	cacheRepo repository.CacheRepository,
 	client clients.NASAClient,
 ) NASAService {
 	return &nasaService{
-		repo:           repo,
-		spaceCacheRepo: spaceCacheRepo,
-		cacheRepo:      cacheRepo,
-		client:         client,
+		repo:             repo,
+		spaceCacheRepo:   spaceCacheRepo,
+		spaceWeatherRepo: spaceWeatherRepo,
+		cacheRepo:        cacheRepo,
+		client:           client,
 	}
 }
 
@@ -131,6 +156,89 @@ func (s *nasaService) FetchAndStoreNEO(ctx context.Context) error {
 	return nil
 }
 
+func (s *nasaService) FetchAndStoreDONKI(ctx context.Context) error {
+	log.Println("Fetching DONKI space weather events...")
+
+	stored := 0
+	for _, eventType := range donkiStoredTypes {
+		// Берем максимальное окно, чтобы догнать пропущенные синхронизации
+		rawEvents, err := s.client.FetchDONKI(ctx, eventType, 30)
+		if err != nil {
+			return fmt.Errorf("failed to fetch DONKI %s: %w", eventType, err)
+		}
+
+		var events []models.SpaceWeatherEvent
+		for _, raw := range rawEvents {
+			event, ok := toSpaceWeatherEvent(eventType, raw)
+			if !ok {
+				continue
+			}
+			events = append(events, event)
+		}
+
+		if err := s.spaceWeatherRepo.BulkUpsert(ctx, events); err != nil {
+			return fmt.Errorf("failed to save DONKI %s: %w", eventType, err)
+		}
+		stored += len(events)
+	}
+
+	log.Printf("DONKI events stored: %d", stored)
+	return nil
+}
+
+// toSpaceWeatherEvent приводит сырое событие DONKI к модели хранения
+func toSpaceWeatherEvent(eventType string, raw map[string]interface{}) (models.SpaceWeatherEvent, bool) {
+	var idKey, timeKey string
+	switch eventType {
+	case "GST":
+		idKey, timeKey = "gstID", "startTime"
+	case "FLR":
+		idKey, timeKey = "flrID", "beginTime"
+	case "CME":
+		idKey, timeKey = "activityID", "startTime"
+	default:
+		return models.SpaceWeatherEvent{}, false
+	}
+
+	eventID := extractString(raw, idKey)
+	start, ok := parseDONKITime(extractString(raw, timeKey))
+	if eventID == "" || !ok {
+		return models.SpaceWeatherEvent{}, false
+	}
+
+	payload, err := json.Marshal(raw)
+	if err != nil {
+		return models.SpaceWeatherEvent{}, false
+	}
+
+	return models.SpaceWeatherEvent{
+		EventID:   eventID,
+		EventType: eventType,
+		StartTime: start,
+		Payload:   payload,
+	}, true
+}
+
+// parseDONKITime разбирает время DONKI, которое приходит без секунд ("2024-05-10T17:00Z")
+func parseDONKITime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	formats := []string{
+		"2006-01-02T15:04Z",
+		time.RFC3339,
+		"2006-01-02T15:04:05Z",
+	}
+
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
 func (s *nasaService) GetOSDRList(ctx context.Context, page, limit int) ([]models.OSDRItem, error) {
 	if page < 1 {
 		page = 1
@@ -244,8 +352,32 @@ func extractTime(data map[string]interface{}) *time.Time {
 }
 
 func (s *nasaService) GetAPOD(ctx context.Context, date string) (map[string]interface{}, error) {
-	// Просто используем существующий метод
-	return s.GetLatestAPOD(ctx)
+	if date == "" || date == time.Now().UTC().Format("2006-01-02") {
+		return s.GetLatestAPOD(ctx)
+	}
+
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid APOD date %q: %w", date, err)
+	}
+
+	cacheKey := "nasa:apod:" + date
+
+	var apodData map[string]interface{}
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &apodData); err == nil && apodData != nil {
+		return apodData, nil
+	}
+
+	apodData, err := s.client.FetchAPOD(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch APOD for %s: %w", date, err)
+	}
+
+	// Прошлые выпуски не меняются - кэшируем надолго
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, apodData, 30*24*time.Hour); err != nil {
+		log.Printf("Failed to cache APOD %s: %v", date, err)
+	}
+
+	return apodData, nil
 }
 
 func (s *nasaService) GetNEOWatch(ctx context.Context, days int) (map[string]interface{}, error) {
@@ -277,3 +409,127 @@ func (s *nasaService) GetDONKI(ctx context.Context, eventType string, days int)
 
 	return events, nil
 }
+
+func (s *nasaService) GetCloseApproaches(ctx context.Context, days int) ([]NEOApproach, error) {
+	if days < 1 || days > 30 {
+		days = 7
+	}
+
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	cacheKey := fmt.Sprintf("nasa:neo:approaches:%s:%d", start.Format("2006-01-02"), days)
+
+	var approaches []NEOApproach
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &approaches); err == nil && approaches != nil {
+		return approaches, nil
+	}
+
+	approaches = []NEOApproach{}
+	seen := make(map[string]bool)
+	end := start.AddDate(0, 0, days)
+
+	// NeoWs отдает не больше недели за запрос - идем окнами по 7 суток (границы включительно)
+	for from := start; from.Before(end); from = from.AddDate(0, 0, 7) {
+		to := from.AddDate(0, 0, 6)
+		if last := end.AddDate(0, 0, -1); to.After(last) {
+			to = last
+		}
+
+		feed, err := s.client.FetchNEOFeedRange(ctx, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch NEO feed: %w", err)
+		}
+
+		for _, approach := range parseNEOApproaches(feed) {
+			key := approach.ID + "|" + approach.Time.Format(time.RFC3339)
+			if seen[key] || approach.Time.Before(start) || !approach.Time.Before(end) {
+				continue
+			}
+			seen[key] = true
+			approaches = append(approaches, approach)
+		}
+	}
+
+	sort.Slice(approaches, func(i, j int) bool {
+		return approaches[i].Time.Before(approaches[j].Time)
+	})
+
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, approaches, 6*time.Hour); err != nil {
+		log.Printf("Failed to cache NEO approaches: %v", err)
+	}
+	return approaches, nil
+}
+
+// parseNEOApproaches достает сближения с Землей из ответа NeoWs /feed
+func parseNEOApproaches(feed map[string]interface{}) []NEOApproach {
+	byDate, _ := feed["near_earth_objects"].(map[string]interface{})
+
+	var approaches []NEOApproach
+	for _, list := range byDate {
+		objects, _ := list.([]interface{})
+		for _, raw := range objects {
+			obj, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			base := NEOApproach{
+				ID:   extractString(obj, "id", "neo_reference_id"),
+				Name: extractString(obj, "name"),
+				URL:  extractString(obj, "nasa_jpl_url"),
+			}
+			base.Hazardous, _ = obj["is_potentially_hazardous_asteroid"].(bool)
+			if diameter, ok := nestedMap(obj, "estimated_diameter", "meters"); ok {
+				base.DiameterMinM = parseNumber(diameter["estimated_diameter_min"])
+				base.DiameterMaxM = parseNumber(diameter["estimated_diameter_max"])
+			}
+
+			closeApproaches, _ := obj["close_approach_data"].([]interface{})
+			for _, rawApproach := range closeApproaches {
+				data, ok := rawApproach.(map[string]interface{})
+				if !ok || extractString(data, "orbiting_body") != "Earth" {
+					continue
+				}
+				epoch := parseNumber(data["epoch_date_close_approach"])
+				if epoch == 0 {
+					continue
+				}
+
+				approach := base
+				approach.Time = time.UnixMilli(int64(epoch)).UTC()
+				if miss, ok := nestedMap(data, "miss_distance"); ok {
+					approach.MissDistanceKm = parseNumber(miss["kilometers"])
+					approach.MissDistanceLunar = parseNumber(miss["lunar"])
+				}
+				if velocity, ok := nestedMap(data, "relative_velocity"); ok {
+					approach.VelocityKmS = parseNumber(velocity["kilometers_per_second"])
+				}
+				approaches = append(approaches, approach)
+			}
+		}
+	}
+	return approaches
+}
+
+func nestedMap(data map[string]interface{}, keys ...string) (map[string]interface{}, bool) {
+	current := data
+	for _, key := range keys {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
+
+// parseNumber число из JSON: NeoWs отдает расстояния и скорости строками
+func parseNumber(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"cassiopeia/pkg/astro"
+)
+
+// Уровни темноты ночи
+const (
+	DarknessAstronomical = "astronomical" // Солнце ниже -18°
+	DarknessNautical     = "nautical"     // белые ночи: Солнце не опускается ниже -18°
+	DarknessNone         = "none"         // Солнце не опускается ниже -12°
+)
+
+// Вклад факторов в оценку, баллы
+const (
+	observingDarknessPoints = 40
+	observingMoonPoints     = 50
+	// observingISSPoints бонус к оценке: пролет МКС украшает ночь, но без него небо не хуже
+	observingISSPoints = 10
+	// observingFullDarkHours столько часов темноты дают полный балл
+	observingFullDarkHours = 6
+	// observingISSForecastDays дальше прогноз пролетов по одному TLE не строим
+	observingISSForecastDays = 10
+)
+
+type ObservingService interface {
+	// GetObservingWindow оценка ночи, начинающейся вечером date (местная дата наблюдателя)
+	GetObservingWindow(ctx context.Context, observer Observer, date time.Time) (*ObservingWindow, error)
+}
+
+// ObservingWindow условия наблюдений за ночь и итоговая оценка 0-100.
+// Погода не учитывается - только геометрия Солнца, Луны и МКС.
+type ObservingWindow struct {
+	Date     string  `json:"date"`
+	Location string  `json:"location,omitempty"`
+	Timezone string  `json:"timezone"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+
+	Score   int               `json:"score"`
+	Rating  string            `json:"rating"`
+	Factors []ObservingFactor `json:"factors"`
+
+	Darkness ObservingDarkness `json:"darkness"`
+	Moon     ObservingMoon     `json:"moon"`
+	// BestWindow самый длинный темный интервал без Луны над горизонтом
+	BestWindow *TimeRange            `json:"best_window,omitempty"`
+	ISSPasses  []astro.SatellitePass `json:"iss_passes"`
+	Samples    []ObservingSample     `json:"samples"`
+}
+
+// ObservingFactor составляющая оценки
+type ObservingFactor struct {
+	Name      string `json:"name"`
+	Points    int    `json:"points"`
+	MaxPoints int    `json:"max_points"`
+	// Available false, если фактор не удалось оценить (он не входит в итог)
+	Available bool `json:"available"`
+	// Bonus баллы фактора добавляются к итогу сверх доли остальных, отсутствие не снижает оценку
+	Bonus   bool   `json:"bonus"`
+	Details string `json:"details"`
+}
+
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Hours float64   `json:"hours"`
+}
+
+type ObservingDarkness struct {
+	Level  string     `json:"level"`
+	Window *TimeRange `json:"window,omitempty"`
+}
+
+type ObservingMoon struct {
+	Phase        string     `json:"phase"`
+	Illumination float64    `json:"illumination"`
+	Moonrise     *time.Time `json:"moonrise,omitempty"`
+	Moonset      *time.Time `json:"moonset,omitempty"`
+	// UpHours сколько часов темного интервала Луна над горизонтом
+	UpHours float64 `json:"up_hours"`
+	// Impact средняя засветка от Луны за темный интервал, 0-1
+	Impact float64 `json:"impact"`
+}
+
+// ObservingSample почасовое состояние неба
+type ObservingSample struct {
+	Time             time.Time `json:"time"`
+	SunAltitude      float64   `json:"sun_altitude"`
+	MoonAltitude     float64   `json:"moon_altitude"`
+	MoonIllumination float64   `json:"moon_illumination"`
+}
+
+type observingService struct {
+	issService ISSService
+}
+
+func NewObservingService(issService ISSService) ObservingService {
+	return &observingService{
+		issService: issService,
+	}
+}
+
+func (s *observingService) GetObservingWindow(ctx context.Context, observer Observer, date time.Time) (*ObservingWindow, error) {
+	lat, lon := observer.Lat, observer.Lon
+	if err := validateCoordinates(lat, lon); err != nil {
+		return nil, err
+	}
+
+	zone := observer.location()
+	if date.IsZero() {
+		date = time.Now().In(zone)
+	}
+	// Ночь - от полудня даты до полудня следующих суток
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, zone)
+	nextNoon := noon.AddDate(0, 0, 1)
+
+	result := &ObservingWindow{
+		Date:      noon.Format("2006-01-02"),
+		Location:  observer.Name,
+		Timezone:  zone.String(),
+		Lat:       lat,
+		Lon:       lon,
+		ISSPasses: []astro.SatellitePass{},
+	}
+
+	// Темный интервал: самый длинный отрезок с Солнцем ниже порога, шаг - минута
+	sunBelow := func(limit float64) *TimeRange {
+		return longestRange(noon, nextNoon, time.Minute, func(t time.Time) bool {
+			return astro.SunAltitude(t, lat, lon) < limit
+		})
+	}
+	result.Darkness.Level = DarknessAstronomical
+	result.Darkness.Window = sunBelow(astro.AstronomicalTwilight)
+	if result.Darkness.Window == nil {
+		result.Darkness.Level = DarknessNautical
+		result.Darkness.Window = sunBelow(astro.NauticalTwilight)
+	}
+	if result.Darkness.Window == nil {
+		result.Darkness.Level = DarknessNone
+	}
+
+	darkness := ObservingFactor{Name: "darkness", MaxPoints: observingDarknessPoints, Available: true}
+	switch dark := result.Darkness.Window; {
+	case dark == nil:
+		darkness.Details = "The Sun stays above -12°, the sky never gets dark"
+	case result.Darkness.Level == DarknessNautical:
+		// Белые ночи: засчитываем половину
+		darkness.Points = int(math.Round(observingDarknessPoints / 2 * math.Min(dark.Hours, observingFullDarkHours) / observingFullDarkHours))
+		darkness.Details = fmt.Sprintf("No astronomical darkness, %.1f h of nautical darkness", dark.Hours)
+	default:
+		darkness.Points = int(math.Round(observingDarknessPoints * math.Min(dark.Hours, observingFullDarkHours) / observingFullDarkHours))
+		darkness.Details = fmt.Sprintf("%.1f h of astronomical darkness", dark.Hours)
+	}
+
+	moon := ObservingFactor{Name: "moon", MaxPoints: observingMoonPoints, Available: true}
+	s.evaluateMoon(result, noon, &moon)
+
+	iss := ObservingFactor{Name: "iss", MaxPoints: observingISSPoints, Bonus: true}
+	s.evaluateISS(ctx, observer, result, noon, nextNoon, &iss)
+
+	result.Factors = []ObservingFactor{darkness, moon, iss}
+
+	// Итог - доля набранных баллов среди оцененных факторов плюс бонусы, не выше 100.
+	// МКС в долю не входит: иначе одна и та же ночь без пролета получала бы разную
+	// оценку в пределах горизонта прогноза пролетов и за ним
+	var points, maxPoints, bonus int
+	for _, factor := range result.Factors {
+		switch {
+		case !factor.Available:
+		case factor.Bonus:
+			bonus += factor.Points
+		default:
+			points += factor.Points
+			maxPoints += factor.MaxPoints
+		}
+	}
+	result.Score = min(100, int(math.Round(float64(points)*100/float64(maxPoints)))+bonus)
+	result.Rating = observingRating(result.Score)
+
+	// Почасовая картина от вечерних до утренних сумерек (или с 18 до 6 часов)
+	start, end := noon.Add(6*time.Hour), nextNoon.Add(-6*time.Hour)
+	if evening := astro.SunEvents(noon, lat, lon); evening.Sunset != nil {
+		start = evening.Sunset.Truncate(time.Hour).Add(time.Hour)
+	}
+	if morning := astro.SunEvents(nextNoon, lat, lon); morning.Sunrise != nil && morning.Sunrise.After(start) {
+		end = *morning.Sunrise
+	}
+	for t := start; !t.After(end); t = t.Add(time.Hour) {
+		result.Samples = append(result.Samples, ObservingSample{
+			Time:             t.In(zone),
+			SunAltitude:      round1(astro.SunAltitude(t, lat, lon)),
+			MoonAltitude:     round1(astro.MoonAltitude(t, lat, lon)),
+			MoonIllumination: round2(astro.Illumination(astro.JulianDay(t))),
+		})
+	}
+
+	return result, nil
+}
+
+// evaluateMoon засветка от Луны в темный интервал. Луна под горизонтом не мешает,
+// над горизонтом мешает пропорционально освещенности и тем сильнее, чем выше стоит.
+func (s *observingService) evaluateMoon(result *ObservingWindow, noon time.Time, factor *ObservingFactor) {
+	lat, lon := result.Lat, result.Lon
+	zone := noon.Location()
+
+	nextDay := noon.AddDate(0, 0, 1)
+	evening := astro.MoonEvents(noon, lat, lon)
+	morning := astro.MoonEvents(nextDay, lat, lon)
+	for _, t := range []*time.Time{evening.Moonrise, morning.Moonrise} {
+		if t != nil && !t.Before(noon) && t.Before(nextDay) && result.Moon.Moonrise == nil {
+			result.Moon.Moonrise = t
+		}
+	}
+	for _, t := range []*time.Time{evening.Moonset, morning.Moonset} {
+		if t != nil && !t.Before(noon) && t.Before(nextDay) && result.Moon.Moonset == nil {
+			result.Moon.Moonset = t
+		}
+	}
+
+	dark := result.Darkness.Window
+	middle := noon.Add(12 * time.Hour)
+	if dark != nil {
+		middle = dark.Start.Add(dark.End.Sub(dark.Start) / 2)
+	}
+	phase := astro.PhaseAt(middle)
+	result.Moon.Phase = phase.Name
+	result.Moon.Illumination = round2(phase.Illumination)
+
+	if dark == nil {
+		// Без темноты Луна уже ничего не портит - фактор не оцениваем
+		factor.Available = false
+		factor.Details = "No darkness window to evaluate"
+		return
+	}
+
+	const step = 5 * time.Minute
+	var impact float64
+	var samples, up int
+	for t := dark.Start; !t.After(dark.End); t = t.Add(step) {
+		samples++
+		altitude := astro.MoonAltitude(t, lat, lon)
+		if altitude <= 0 {
+			continue
+		}
+		up++
+		height := 0.5 + 0.5*math.Min(altitude, 30)/30
+		impact += astro.Illumination(astro.JulianDay(t)) * height
+	}
+	impact /= float64(samples)
+
+	result.Moon.UpHours = round1(float64(up) * step.Hours())
+	result.Moon.Impact = round2(impact)
+	factor.Points = int(math.Round(observingMoonPoints * (1 - impact)))
+
+	switch {
+	case up == 0:
+		factor.Details = fmt.Sprintf("%s below the horizon all night", phase.Name)
+	default:
+		factor.Details = fmt.Sprintf("%s (%.0f%% illuminated) up for %.1f h of darkness",
+			phase.Name, phase.Illumination*100, result.Moon.UpHours)
+	}
+
+	best := longestRange(dark.Start, dark.End, step, func(t time.Time) bool {
+		return astro.MoonAltitude(t, lat, lon) <= 0
+	})
+	if best != nil {
+		best.Start, best.End = best.Start.In(zone), best.End.In(zone)
+		result.BestWindow = best
+	}
+}
+
+// evaluateISS видимые пролеты МКС за ночь; без TLE фактор не оценивается
+func (s *observingService) evaluateISS(ctx context.Context, observer Observer, result *ObservingWindow, from, to time.Time, factor *ObservingFactor) {
+	today := time.Now().In(from.Location())
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, from.Location())
+	days := int(math.Ceil(to.Sub(today).Hours() / 24))
+	if from.Before(today) || days > observingISSForecastDays {
+		factor.Details = fmt.Sprintf("ISS passes are forecast only for the next %d days", observingISSForecastDays)
+		return
+	}
+
+	passes, err := s.issService.GetPasses(ctx, observer, days)
+	if err != nil {
+		log.Printf("ISS passes unavailable for observing window: %v", err)
+		factor.Details = "ISS orbital elements are unavailable"
+		return
+	}
+	factor.Available = true
+
+	var best float64
+	for _, pass := range passes.Passes {
+		if pass.Max.Before(from) || !pass.Max.Before(to) {
+			continue
+		}
+		result.ISSPasses = append(result.ISSPasses, pass)
+		best = math.Max(best, pass.MaxAltitude)
+	}
+
+	switch {
+	case len(result.ISSPasses) == 0:
+		factor.Details = "No visible ISS passes"
+	case best >= 40:
+		factor.Points = observingISSPoints
+	case best >= 20:
+		factor.Points = observingISSPoints * 7 / 10
+	default:
+		factor.Points = observingISSPoints / 2
+	}
+	if len(result.ISSPasses) > 0 {
+		factor.Details = fmt.Sprintf("%d visible ISS pass(es), highest %.0f°", len(result.ISSPasses), best)
+	}
+}
+
+// longestRange самый длинный отрезок [from, to], на котором выполняется ok, с точностью step
+func longestRange(from, to time.Time, step time.Duration, ok func(time.Time) bool) *TimeRange {
+	var best *TimeRange
+	var start time.Time
+	inside := false
+
+	for t := from; ; t = t.Add(step) {
+		last := !t.Before(to)
+		if last {
+			t = to
+		}
+		good := ok(t)
+		switch {
+		case good && !inside:
+			start, inside = t, true
+		case !good && inside:
+			inside = false
+			if best == nil || t.Sub(start) > best.End.Sub(best.Start) {
+				best = &TimeRange{Start: start, End: t}
+			}
+		}
+		if last {
+			break
+		}
+	}
+	if inside && (best == nil || to.Sub(start) > best.End.Sub(best.Start)) {
+		best = &TimeRange{Start: start, End: to}
+	}
+
+	if best != nil {
+		best.Hours = round1(best.End.Sub(best.Start).Hours())
+	}
+	return best
+}
+
+func observingRating(score int) string {
+	switch {
+	case score >= 80:
+		return "excellent"
+	case score >= 60:
+		return "good"
+	case score >= 40:
+		return "fair"
+	}
+	return "poor"
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
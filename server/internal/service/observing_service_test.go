@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cassiopeia/pkg/astro"
+)
+
+// observingISS отдает заданные пролеты; err - TLE недоступен
+type observingISS struct {
+	ISSService
+	passes []astro.SatellitePass
+	err    error
+}
+
+func (s observingISS) GetPasses(ctx context.Context, observer Observer, days int) (*ISSPasses, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ISSPasses{Satellite: "ISS (ZARYA)", Passes: s.passes}, nil
+}
+
+// Пролет МКС - бонус: ночь без пролета оценивается так же, как ночь, для которой
+// пролеты не прогнозируются (дальше горизонта TLE или без элементов орбиты)
+func TestObservingScoreTreatsISSAsBonus(t *testing.T) {
+	zone := time.FixedZone("CLT", -4*3600)
+	observer := Observer{Name: "Paranal", Lat: -24.63, Lon: -70.40, Zone: zone}
+	tomorrow := time.Now().In(zone).AddDate(0, 0, 1)
+	date := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, zone)
+	midnight := date.Add(24 * time.Hour)
+
+	score := func(iss observingISS) (int, ObservingFactor) {
+		t.Helper()
+		window, err := NewObservingService(iss).GetObservingWindow(context.Background(), observer, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, factor := range window.Factors {
+			if factor.Name == "iss" {
+				return window.Score, factor
+			}
+		}
+		t.Fatal("no iss factor")
+		return 0, ObservingFactor{}
+	}
+
+	unavailable, factor := score(observingISS{err: errors.New("no TLE")})
+	if factor.Available {
+		t.Fatalf("iss factor available without TLE: %+v", factor)
+	}
+	noPass, factor := score(observingISS{})
+	if !factor.Available || !factor.Bonus || factor.Points != 0 {
+		t.Fatalf("iss factor without passes: %+v", factor)
+	}
+	if noPass != unavailable {
+		t.Fatalf("night without ISS passes scored %d, without forecast %d", noPass, unavailable)
+	}
+
+	withPass, factor := score(observingISS{passes: []astro.SatellitePass{{Max: midnight, MaxAltitude: 60}}})
+	if factor.Points != observingISSPoints {
+		t.Fatalf("iss factor for a high pass: %+v", factor)
+	}
+	if want := min(100, noPass+observingISSPoints); withPass != want {
+		t.Fatalf("night with a high ISS pass scored %d, want %d", withPass, want)
+	}
+}
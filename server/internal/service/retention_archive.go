@@ -0,0 +1,179 @@
+package service
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+)
+
+// Архивы пишутся только в gzip CSV: Parquet не поддерживается, его можно получить из CSV внешними средствами
+const retentionArchiveFormat = "csv.gz"
+
+// retentionArchivePath путь файла архива: <dir>/<table>/<YYYY>/<MM>/<table>_<YYYY-MM-DD>[_partN].csv.gz
+func retentionArchivePath(dir, table string, day time.Time, part int) string {
+	name := fmt.Sprintf("%s_%s", table, day.Format("2006-01-02"))
+	if part > 1 {
+		name += fmt.Sprintf("_part%d", part)
+	}
+	return filepath.Join(dir, table, day.Format("2006"), day.Format("01"), name+"."+retentionArchiveFormat)
+}
+
+// csvArchiveWriter пишет строки в gzip CSV через временный файл; готовый файл появляется только после Close
+type csvArchiveWriter struct {
+	archive    models.RetentionArchive
+	timeColumn string
+	timeIndex  int
+	tmpPath    string
+	file       *os.File
+	gzip       *gzip.Writer
+	csv        *csv.Writer
+	counter    *countingWriter
+	hash       hash.Hash
+	record     []string
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newCSVArchiveWriter(path, table, timeColumn string, day time.Time, part int) (*csvArchiveWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	// Контрольная сумма и размер считаются по сжатым байтам - так их можно сверить с файлом
+	sum := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, sum)}
+	zw, _ := gzip.NewWriterLevel(counter, gzip.BestCompression)
+	zw.Name = strings.TrimSuffix(filepath.Base(path), ".gz")
+	zw.ModTime = day
+
+	return &csvArchiveWriter{
+		archive: models.RetentionArchive{
+			SourceTable: table,
+			Day:         day,
+			Part:        part,
+			Path:        path,
+			Format:      retentionArchiveFormat,
+		},
+		timeColumn: timeColumn,
+		timeIndex:  -1,
+		tmpPath:    tmpPath,
+		file:       file,
+		gzip:       zw,
+		csv:        csv.NewWriter(zw),
+		counter:    counter,
+		hash:       sum,
+	}, nil
+}
+
+func (w *csvArchiveWriter) WriteHeader(columns []string) error {
+	for i, column := range columns {
+		if column == w.timeColumn {
+			w.timeIndex = i
+		}
+	}
+	w.archive.Columns = strings.Join(columns, ",")
+	w.record = make([]string, len(columns))
+	return w.csv.Write(columns)
+}
+
+func (w *csvArchiveWriter) WriteRow(values []interface{}) error {
+	for i, value := range values {
+		w.record[i] = formatArchiveValue(value)
+	}
+
+	if w.timeIndex >= 0 {
+		if t, ok := values[w.timeIndex].(time.Time); ok {
+			if w.archive.Rows == 0 || t.Before(w.archive.FirstAt) {
+				w.archive.FirstAt = t
+			}
+			if t.After(w.archive.LastAt) {
+				w.archive.LastAt = t
+			}
+		}
+	}
+	w.archive.Rows++
+
+	return w.csv.Write(w.record)
+}
+
+func (w *csvArchiveWriter) Close() (*models.RetentionArchive, error) {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := w.gzip.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync archive: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+	w.file = nil
+
+	// Повторная архивация тех же суток после сбоя перезапишет файл без записи в манифесте
+	if err := os.Rename(w.tmpPath, w.archive.Path); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive: %w", err)
+	}
+
+	w.archive.Bytes = w.counter.n
+	w.archive.SHA256 = hex.EncodeToString(w.hash.Sum(nil))
+	archive := w.archive
+	return &archive, nil
+}
+
+// Discard удаляет файл, если транзакция архивации не прошла
+func (w *csvArchiveWriter) Discard() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	os.Remove(w.tmpPath)
+	os.Remove(w.archive.Path)
+}
+
+func formatArchiveValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
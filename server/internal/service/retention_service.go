@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+var (
+	ErrRetentionBusy         = errors.New("retention run is already in progress")
+	ErrRetentionUnauthorized = errors.New("invalid or missing retention admin token")
+	ErrRetentionRunDisabled  = errors.New("retention runs with deletion are disabled over HTTP")
+)
+
+type RetentionConfig struct {
+	ArchiveDir string
+	// DryRun только отчет о том, что будет архивировано и удалено, для всех запусков
+	DryRun bool
+	// MaxDaysPerRun суток на таблицу за запуск, чтобы первый проход по старой базе не шел часами
+	MaxDaysPerRun int
+	// Сроки хранения в сутках; 0 - таблица не очищается
+	TelemetryDays  int
+	AnomalyDays    int
+	ISSLogDays     int
+	SpaceCacheDays int
+	// ExportDays срок хранения файлов выгрузки телеметрии в ExportDir
+	ExportDays int
+	ExportDir  string
+	// AdminToken токен ручного запуска с удалением; пусто - вручную доступен только dry run
+	AdminToken string
+}
+
+// retentionPolicy правило хранения таблицы: строки старше срока по столбцу времени
+type retentionPolicy struct {
+	table  string
+	column string
+	days   int
+	// archive выгружать ли строки в архив перед удалением; кэш восстанавливается из источника
+	archive bool
+}
+
+type RetentionService interface {
+	Policies() []RetentionPolicyView
+	// Run архивирует и удаляет устаревшие строки по всем правилам; при dryRun только считает
+	Run(ctx context.Context, dryRun bool) (*RetentionReport, error)
+	// AuthorizeRun проверяет токен ручного запуска с удалением
+	AuthorizeRun(token string) error
+	ListArchives(ctx context.Context, table string, page, perPage int) (*RetentionArchivePage, error)
+}
+
+type RetentionPolicyView struct {
+	Table         string    `json:"table"`
+	Column        string    `json:"column"`
+	RetentionDays int       `json:"retention_days"`
+	Archive       bool      `json:"archive"`
+	Cutoff        time.Time `json:"cutoff"`
+}
+
+type RetentionReport struct {
+	DryRun     bool                   `json:"dry_run"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt time.Time              `json:"finished_at"`
+	Tables     []RetentionTableResult `json:"tables"`
+	Exports    *RetentionFilesResult  `json:"exports,omitempty"`
+}
+
+type RetentionTableResult struct {
+	Table   string               `json:"table"`
+	Cutoff  time.Time            `json:"cutoff"`
+	Archive bool                 `json:"archive"`
+	Rows    int64                `json:"rows"`
+	Days    []RetentionDayResult `json:"days"`
+	// Pending остались сутки сверх MaxDaysPerRun - их обработает следующий запуск
+	Pending bool   `json:"pending"`
+	Error   string `json:"error,omitempty"`
+}
+
+type RetentionDayResult struct {
+	Day     string                `json:"day"`
+	Rows    int64                 `json:"rows"`
+	Archive *RetentionArchiveView `json:"archive,omitempty"`
+}
+
+type RetentionFilesResult struct {
+	Dir    string    `json:"dir"`
+	Cutoff time.Time `json:"cutoff"`
+	Files  int       `json:"files"`
+	Bytes  int64     `json:"bytes"`
+	Error  string    `json:"error,omitempty"`
+}
+
+type RetentionArchiveView struct {
+	ID        uint      `json:"id"`
+	Table     string    `json:"table"`
+	Day       string    `json:"day"`
+	Part      int       `json:"part"`
+	Path      string    `json:"path"`
+	Format    string    `json:"format"`
+	Columns   string    `json:"columns"`
+	Rows      int64     `json:"rows"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+	FirstAt   time.Time `json:"first_at"`
+	LastAt    time.Time `json:"last_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RetentionArchivePage struct {
+	Archives []RetentionArchiveView `json:"archives"`
+	Total    int64                  `json:"total"`
+	Page     int                    `json:"page"`
+	PerPage  int                    `json:"per_page"`
+}
+
+type retentionService struct {
+	repo     repository.RetentionRepository
+	config   RetentionConfig
+	policies []retentionPolicy
+	mu       sync.Mutex
+}
+
+func NewRetentionService(repo repository.RetentionRepository, config RetentionConfig) RetentionService {
+	if config.ArchiveDir == "" {
+		config.ArchiveDir = "./data/archive"
+	}
+	if config.MaxDaysPerRun <= 0 {
+		config.MaxDaysPerRun = 31
+	}
+
+	candidates := []retentionPolicy{
+		{table: "telemetries", column: "recorded_at", days: config.TelemetryDays, archive: true},
+		{table: "telemetry_anomalies", column: "recorded_at", days: config.AnomalyDays, archive: true},
+		{table: "iss_logs", column: "fetched_at", days: config.ISSLogDays, archive: true},
+		{table: "space_caches", column: "fetched_at", days: config.SpaceCacheDays, archive: false},
+	}
+	var policies []retentionPolicy
+	for _, policy := range candidates {
+		if policy.days > 0 {
+			policies = append(policies, policy)
+		}
+	}
+
+	return &retentionService{
+		repo:     repo,
+		config:   config,
+		policies: policies,
+	}
+}
+
+// retentionCutoff граница хранения по началу суток UTC: архивы всегда содержат целые сутки
+func retentionCutoff(now time.Time, days int) time.Time {
+	return now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+}
+
+func (s *retentionService) Policies() []RetentionPolicyView {
+	now := time.Now()
+	views := make([]RetentionPolicyView, 0, len(s.policies))
+	for _, policy := range s.policies {
+		views = append(views, RetentionPolicyView{
+			Table:         policy.table,
+			Column:        policy.column,
+			RetentionDays: policy.days,
+			Archive:       policy.archive,
+			Cutoff:        retentionCutoff(now, policy.days),
+		})
+	}
+	return views
+}
+
+func (s *retentionService) AuthorizeRun(token string) error {
+	if s.config.AdminToken == "" {
+		return ErrRetentionRunDisabled
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+		return ErrRetentionUnauthorized
+	}
+	return nil
+}
+
+func (s *retentionService) Run(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	if !s.mu.TryLock() {
+		return nil, ErrRetentionBusy
+	}
+	defer s.mu.Unlock()
+
+	report := &RetentionReport{
+		DryRun:    dryRun || s.config.DryRun,
+		StartedAt: time.Now().UTC(),
+		Tables:    []RetentionTableResult{},
+	}
+
+	for _, policy := range s.policies {
+		if ctx.Err() != nil {
+			break
+		}
+		result := s.applyPolicy(ctx, policy, report.StartedAt, report.DryRun)
+		if result.Error != "" {
+			log.Printf("Retention %s: %s", policy.table, result.Error)
+		}
+		report.Tables = append(report.Tables, result)
+	}
+
+	if s.config.ExportDays > 0 && s.config.ExportDir != "" {
+		report.Exports = s.cleanExports(report.StartedAt, report.DryRun)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, ctx.Err()
+}
+
+// applyPolicy обрабатывает устаревшие строки по суткам, начиная с самых старых
+func (s *retentionService) applyPolicy(ctx context.Context, policy retentionPolicy, now time.Time, dryRun bool) RetentionTableResult {
+	result := RetentionTableResult{
+		Table:   policy.table,
+		Cutoff:  retentionCutoff(now, policy.days),
+		Archive: policy.archive,
+		Days:    []RetentionDayResult{},
+	}
+
+	var from time.Time
+	for {
+		next, err := s.repo.NextTime(ctx, policy.table, policy.column, from, result.Cutoff)
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to find expired rows: %v", err)
+			return result
+		}
+		if next == nil {
+			return result
+		}
+		if len(result.Days) == s.config.MaxDaysPerRun {
+			result.Pending = true
+			return result
+		}
+
+		day := next.UTC().Truncate(24 * time.Hour)
+		end := day.Add(24 * time.Hour)
+
+		dayResult, err := s.applyDay(ctx, policy, day, end, dryRun)
+		if err != nil {
+			result.Error = fmt.Sprintf("%s: %v", day.Format("2006-01-02"), err)
+			return result
+		}
+		result.Days = append(result.Days, dayResult)
+		result.Rows += dayResult.Rows
+		from = end
+	}
+}
+
+func (s *retentionService) applyDay(ctx context.Context, policy retentionPolicy, day, end time.Time, dryRun bool) (RetentionDayResult, error) {
+	dayResult := RetentionDayResult{Day: day.Format("2006-01-02")}
+
+	if dryRun {
+		count, err := s.repo.Count(ctx, policy.table, policy.column, day, end)
+		if err != nil {
+			return dayResult, fmt.Errorf("failed to count rows: %w", err)
+		}
+		dayResult.Rows = count
+		return dayResult, nil
+	}
+
+	if !policy.archive {
+		deleted, err := s.repo.DeleteRange(ctx, policy.table, policy.column, day, end)
+		if err != nil {
+			return dayResult, fmt.Errorf("failed to delete rows: %w", err)
+		}
+		dayResult.Rows = deleted
+		return dayResult, nil
+	}
+
+	part, err := s.repo.LastPart(ctx, policy.table, day)
+	if err != nil {
+		return dayResult, fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+	path := retentionArchivePath(s.config.ArchiveDir, policy.table, day, part+1)
+
+	writer, err := newCSVArchiveWriter(path, policy.table, policy.column, day, part+1)
+	if err != nil {
+		return dayResult, err
+	}
+	archive, err := s.repo.ArchiveRange(ctx, policy.table, policy.column, day, end, writer)
+	if err != nil {
+		// Строки остались в базе - файл без записи в манифесте не нужен
+		writer.Discard()
+		return dayResult, fmt.Errorf("failed to archive rows: %w", err)
+	}
+
+	view := toRetentionArchiveView(archive)
+	dayResult.Rows = archive.Rows
+	dayResult.Archive = &view
+	log.Printf("Retention %s: archived %d rows of %s to %s", policy.table, archive.Rows, dayResult.Day, archive.Path)
+	return dayResult, nil
+}
+
+// cleanExports удаляет старые файлы выгрузки телеметрии: они пересоздаются из базы по запросу
+func (s *retentionService) cleanExports(now time.Time, dryRun bool) *RetentionFilesResult {
+	result := &RetentionFilesResult{
+		Dir:    s.config.ExportDir,
+		Cutoff: retentionCutoff(now, s.config.ExportDays),
+	}
+
+	entries, err := os.ReadDir(s.config.ExportDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.Error = fmt.Sprintf("failed to read export directory: %v", err)
+		}
+		return result
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(result.Cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(filepath.Join(s.config.ExportDir, entry.Name())); err != nil {
+				log.Printf("Retention: failed to remove export %s: %v", entry.Name(), err)
+				continue
+			}
+		}
+		result.Files++
+		result.Bytes += info.Size()
+	}
+	return result
+}
+
+func (s *retentionService) ListArchives(ctx context.Context, table string, page, perPage int) (*RetentionArchivePage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 500 {
+		perPage = 50
+	}
+
+	archives, total, err := s.repo.ListArchives(ctx, table, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention archives: %w", err)
+	}
+
+	result := &RetentionArchivePage{
+		Archives: make([]RetentionArchiveView, 0, len(archives)),
+		Total:    total,
+		Page:     page,
+		PerPage:  perPage,
+	}
+	for i := range archives {
+		result.Archives = append(result.Archives, toRetentionArchiveView(&archives[i]))
+	}
+	return result, nil
+}
+
+func toRetentionArchiveView(archive *models.RetentionArchive) RetentionArchiveView {
+	return RetentionArchiveView{
+		ID:        archive.ID,
+		Table:     archive.SourceTable,
+		Day:       archive.Day.Format("2006-01-02"),
+		Part:      archive.Part,
+		Path:      archive.Path,
+		Format:    archive.Format,
+		Columns:   archive.Columns,
+		Rows:      archive.Rows,
+		Bytes:     archive.Bytes,
+		SHA256:    archive.SHA256,
+		FirstAt:   archive.FirstAt,
+		LastAt:    archive.LastAt,
+		CreatedAt: archive.CreatedAt,
+	}
+}
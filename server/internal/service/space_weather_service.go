@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/repository"
+)
+
+type SpaceWeatherService interface {
+	GetSummary(ctx context.Context) (*SpaceWeatherSummary, error)
+}
+
+type spaceWeatherService struct {
+	repo      repository.SpaceWeatherRepository
+	cacheRepo repository.CacheRepository
+}
+
+const (
+	spaceWeatherHistoryDays = 30
+	recentFlareWindow       = 24 * time.Hour
+	defaultCMEImpactHours   = 24.0
+)
+
+type SpaceWeatherSummary struct {
+	GeneratedAt    time.Time         `json:"generated_at"`
+	Kp             *KpReading        `json:"kp"`
+	StrongestFlare *FlareSummary     `json:"strongest_flare"`
+	CMEArrivals    []CMEArrival      `json:"cme_arrivals"`
+	History        []SpaceWeatherDay `json:"history"`
+	Sources        map[string]int    `json:"sources"`
+}
+
+type KpReading struct {
+	Value      float64   `json:"value"`
+	ObservedAt time.Time `json:"observed_at"`
+	GScale     int       `json:"g_scale"`
+	GLabel     string    `json:"g_label"`
+	Source     string    `json:"source,omitempty"`
+	EventID    string    `json:"event_id"`
+}
+
+type FlareSummary struct {
+	Class          string     `json:"class"`
+	BeginTime      time.Time  `json:"begin_time"`
+	PeakTime       *time.Time `json:"peak_time,omitempty"`
+	SourceLocation string     `json:"source_location,omitempty"`
+	ActiveRegion   int        `json:"active_region,omitempty"`
+	EventID        string     `json:"event_id"`
+}
+
+type CMEArrival struct {
+	ActivityID     string    `json:"activity_id"`
+	StartTime      time.Time `json:"start_time"`
+	ArrivalTime    time.Time `json:"arrival_time"`
+	DurationHours  float64   `json:"duration_hours,omitempty"`
+	Status         string    `json:"status"`
+	Speed          float64   `json:"speed,omitempty"`
+	GlancingBlow   bool      `json:"glancing_blow"`
+	MinorImpact    bool      `json:"minor_impact"`
+	ExpectedMaxKp  float64   `json:"expected_max_kp,omitempty"`
+	ExpectedGScale int       `json:"expected_g_scale"`
+}
+
+type SpaceWeatherDay struct {
+	Date           string  `json:"date"`
+	MaxKp          float64 `json:"max_kp"`
+	GScale         int     `json:"g_scale"`
+	StrongestFlare string  `json:"strongest_flare,omitempty"`
+	FlareCount     int     `json:"flare_count"`
+	CMECount       int     `json:"cme_count"`
+}
+
+// Структуры ответов DONKI (только используемые поля)
+type donkiGST struct {
+	GSTID      string `json:"gstID"`
+	StartTime  string `json:"startTime"`
+	AllKpIndex []struct {
+		ObservedTime string  `json:"observedTime"`
+		KpIndex      float64 `json:"kpIndex"`
+		Source       string  `json:"source"`
+	} `json:"allKpIndex"`
+}
+
+type donkiFLR struct {
+	FLRID           string `json:"flrID"`
+	BeginTime       string `json:"beginTime"`
+	PeakTime        string `json:"peakTime"`
+	ClassType       string `json:"classType"`
+	SourceLocation  string `json:"sourceLocation"`
+	ActiveRegionNum int    `json:"activeRegionNum"`
+}
+
+type donkiCME struct {
+	ActivityID  string `json:"activityID"`
+	StartTime   string `json:"startTime"`
+	CMEAnalyses []struct {
+		IsMostAccurate bool    `json:"isMostAccurate"`
+		Speed          float64 `json:"speed"`
+		EnlilList      []struct {
+			ModelCompletionTime       string   `json:"modelCompletionTime"`
+			EstimatedShockArrivalTime *string  `json:"estimatedShockArrivalTime"`
+			EstimatedDuration         *float64 `json:"estimatedDuration"`
+			IsEarthGB                 bool     `json:"isEarthGB"`
+			IsEarthMinorImpact        bool     `json:"isEarthMinorImpact"`
+			Kp18                      *float64 `json:"kp_18"`
+			Kp90                      *float64 `json:"kp_90"`
+			Kp135                     *float64 `json:"kp_135"`
+			Kp180                     *float64 `json:"kp_180"`
+		} `json:"enlilList"`
+	} `json:"cmeAnalyses"`
+}
+
+func NewSpaceWeatherService(
+	repo repository.SpaceWeatherRepository,
+	cacheRepo repository.CacheRepository,
+) SpaceWeatherService {
+	return &spaceWeatherService{
+		repo:      repo,
+		cacheRepo: cacheRepo,
+	}
+}
+
+func (s *spaceWeatherService) GetSummary(ctx context.Context) (*SpaceWeatherSummary, error) {
+	cacheKey := "space_weather:summary"
+
+	// Пробуем кэш
+	var cached SpaceWeatherSummary
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && !cached.GeneratedAt.IsZero() {
+		return &cached, nil
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(spaceWeatherHistoryDays - 1))
+
+	// Берем события с запасом: буря или CME могли начаться раньше окна истории
+	gstEvents, err := s.repo.GetSince(ctx, "GST", since.AddDate(0, 0, -3))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GST events: %w", err)
+	}
+	flrEvents, err := s.repo.GetSince(ctx, "FLR", since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get FLR events: %w", err)
+	}
+	cmeEvents, err := s.repo.GetSince(ctx, "CME", since.AddDate(0, 0, -5))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CME events: %w", err)
+	}
+
+	history := make([]SpaceWeatherDay, spaceWeatherHistoryDays)
+	for i := range history {
+		history[i].Date = since.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	dayIndex := func(t time.Time) int {
+		if t.Before(since) {
+			return -1
+		}
+		idx := int(t.Sub(since) / (24 * time.Hour))
+		if idx >= len(history) {
+			return -1
+		}
+		return idx
+	}
+
+	summary := &SpaceWeatherSummary{
+		GeneratedAt: now,
+		CMEArrivals: []CMEArrival{},
+		Sources: map[string]int{
+			"GST": len(gstEvents),
+			"FLR": len(flrEvents),
+			"CME": len(cmeEvents),
+		},
+	}
+
+	// Kp индекс и G-шкала из геомагнитных бурь
+	for _, event := range gstEvents {
+		var gst donkiGST
+		if err := json.Unmarshal(event.Payload, &gst); err != nil {
+			log.Printf("Skipping malformed GST event %s: %v", event.EventID, err)
+			continue
+		}
+
+		for _, kp := range gst.AllKpIndex {
+			observed, ok := parseDONKITime(kp.ObservedTime)
+			if !ok {
+				continue
+			}
+
+			if summary.Kp == nil || observed.After(summary.Kp.ObservedAt) {
+				summary.Kp = &KpReading{
+					Value:      kp.KpIndex,
+					ObservedAt: observed,
+					GScale:     kpToGScale(kp.KpIndex),
+					GLabel:     gScaleLabel(kpToGScale(kp.KpIndex)),
+					Source:     kp.Source,
+					EventID:    event.EventID,
+				}
+			}
+
+			if idx := dayIndex(observed); idx >= 0 && kp.KpIndex > history[idx].MaxKp {
+				history[idx].MaxKp = kp.KpIndex
+				history[idx].GScale = kpToGScale(kp.KpIndex)
+			}
+		}
+	}
+
+	// Самая сильная вспышка за последние сутки
+	var strongestFlux float64
+	for _, event := range flrEvents {
+		var flr donkiFLR
+		if err := json.Unmarshal(event.Payload, &flr); err != nil {
+			log.Printf("Skipping malformed FLR event %s: %v", event.EventID, err)
+			continue
+		}
+
+		flux, ok := flareFlux(flr.ClassType)
+		if !ok {
+			continue
+		}
+
+		begin, _ := parseDONKITime(flr.BeginTime)
+		moment := begin
+		var peakPtr *time.Time
+		if peak, ok := parseDONKITime(flr.PeakTime); ok {
+			moment = peak
+			peakPtr = &peak
+		}
+
+		if idx := dayIndex(moment); idx >= 0 {
+			history[idx].FlareCount++
+			if current, ok := flareFlux(history[idx].StrongestFlare); !ok || flux > current {
+				history[idx].StrongestFlare = strings.ToUpper(flr.ClassType)
+			}
+		}
+
+		if now.Sub(moment) <= recentFlareWindow && flux > strongestFlux {
+			strongestFlux = flux
+			summary.StrongestFlare = &FlareSummary{
+				Class:          strings.ToUpper(flr.ClassType),
+				BeginTime:      begin,
+				PeakTime:       peakPtr,
+				SourceLocation: flr.SourceLocation,
+				ActiveRegion:   flr.ActiveRegionNum,
+				EventID:        event.EventID,
+			}
+		}
+	}
+
+	// Ожидаемые и текущие прибытия CME к Земле
+	for _, event := range cmeEvents {
+		var cme donkiCME
+		if err := json.Unmarshal(event.Payload, &cme); err != nil {
+			log.Printf("Skipping malformed CME event %s: %v", event.EventID, err)
+			continue
+		}
+
+		if idx := dayIndex(event.StartTime); idx >= 0 {
+			history[idx].CMECount++
+		}
+
+		if arrival, ok := activeCMEArrival(cme, event.StartTime, now); ok {
+			summary.CMEArrivals = append(summary.CMEArrivals, arrival)
+		}
+	}
+
+	summary.History = history
+
+	// Кэшируем на 5 минут
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, summary, 5*time.Minute); err != nil {
+		log.Printf("Failed to cache space weather summary: %v", err)
+	}
+
+	return summary, nil
+}
+
+// activeCMEArrival выбирает самый свежий прогноз WSA-Enlil и проверяет, актуально ли прибытие
+func activeCMEArrival(cme donkiCME, start, now time.Time) (CMEArrival, bool) {
+	for _, analysis := range cme.CMEAnalyses {
+		if !analysis.IsMostAccurate {
+			continue
+		}
+
+		var best CMEArrival
+		var bestModel time.Time
+		found := false
+
+		for _, enlil := range analysis.EnlilList {
+			if enlil.EstimatedShockArrivalTime == nil {
+				continue
+			}
+			arrival, ok := parseDONKITime(*enlil.EstimatedShockArrivalTime)
+			if !ok {
+				continue
+			}
+			modelTime, _ := parseDONKITime(enlil.ModelCompletionTime)
+			if found && modelTime.Before(bestModel) {
+				continue
+			}
+
+			duration := defaultCMEImpactHours
+			if enlil.EstimatedDuration != nil && *enlil.EstimatedDuration > 0 {
+				duration = *enlil.EstimatedDuration
+			}
+
+			maxKp := maxFloat(enlil.Kp18, enlil.Kp90, enlil.Kp135, enlil.Kp180)
+			best = CMEArrival{
+				ActivityID:     cme.ActivityID,
+				StartTime:      start,
+				ArrivalTime:    arrival,
+				DurationHours:  duration,
+				Speed:          analysis.Speed,
+				GlancingBlow:   enlil.IsEarthGB,
+				MinorImpact:    enlil.IsEarthMinorImpact,
+				ExpectedMaxKp:  maxKp,
+				ExpectedGScale: kpToGScale(maxKp),
+			}
+			bestModel = modelTime
+			found = true
+		}
+
+		if !found {
+			return CMEArrival{}, false
+		}
+
+		end := best.ArrivalTime.Add(time.Duration(best.DurationHours * float64(time.Hour)))
+		switch {
+		case now.Before(best.ArrivalTime):
+			best.Status = "expected"
+		case now.Before(end):
+			best.Status = "in_progress"
+		default:
+			return CMEArrival{}, false
+		}
+		return best, true
+	}
+
+	return CMEArrival{}, false
+}
+
+// kpToGScale переводит Kp в шкалу геомагнитных бурь NOAA (G0 - бури нет)
+func kpToGScale(kp float64) int {
+	rounded := int(math.Round(kp))
+	if rounded < 5 {
+		return 0
+	}
+	if rounded > 9 {
+		rounded = 9
+	}
+	return rounded - 4
+}
+
+func gScaleLabel(scale int) string {
+	labels := []string{"None", "Minor", "Moderate", "Strong", "Severe", "Extreme"}
+	if scale < 0 || scale >= len(labels) {
+		return ""
+	}
+	if scale == 0 {
+		return labels[0]
+	}
+	return fmt.Sprintf("G%d %s", scale, labels[scale])
+}
+
+// flareFlux возвращает пиковый поток рентгеновского излучения (Вт/м²) для класса вспышки
+func flareFlux(class string) (float64, bool) {
+	class = strings.ToUpper(strings.TrimSpace(class))
+	if len(class) < 1 {
+		return 0, false
+	}
+
+	bases := map[byte]float64{'A': 1e-8, 'B': 1e-7, 'C': 1e-6, 'M': 1e-5, 'X': 1e-4}
+	base, ok := bases[class[0]]
+	if !ok {
+		return 0, false
+	}
+
+	multiplier := 1.0
+	if len(class) > 1 {
+		value, err := strconv.ParseFloat(class[1:], 64)
+		if err != nil {
+			return 0, false
+		}
+		multiplier = value
+	}
+
+	return base * multiplier, true
+}
+
+func maxFloat(values ...*float64) float64 {
+	var result float64
+	for _, v := range values {
+		if v != nil && *v > result {
+			result = *v
+		}
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+)
+
+// Событие уведомления: пробное отправляется из API, чтобы проверить настройку канала
+const AlertEventTest = "test"
+
+// AlertNotifier канал доставки уведомлений о тревогах
+type AlertNotifier interface {
+	// Name имя канала, на которое ссылаются правила (webhook, email)
+	Name() string
+	Notify(ctx context.Context, notification AlertNotification) error
+}
+
+// AlertNotification тело уведомления; для webhook уходит как есть в JSON
+type AlertNotification struct {
+	// Event firing, resolved или test
+	Event      string     `json:"event"`
+	AlertID    uint       `json:"alert_id,omitempty"`
+	RuleID     uint       `json:"rule_id"`
+	Rule       string     `json:"rule"`
+	Channel    string     `json:"channel"`
+	Source     string     `json:"source"`
+	Condition  string     `json:"condition"`
+	Severity   string     `json:"severity"`
+	Threshold  float64    `json:"threshold"`
+	Value      float64    `json:"value"`
+	Message    string     `json:"message"`
+	FiredAt    time.Time  `json:"fired_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Subject короткая строка для темы письма и логов
+func (n AlertNotification) Subject() string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(n.Event), n.Rule, n.Message)
+}
+
+// newAlertNotifiers каналы, для которых задан адрес; новый канал добавляется здесь
+func newAlertNotifiers(config TelemetryAlertConfig) map[string]AlertNotifier {
+	notifiers := make(map[string]AlertNotifier)
+	if config.WebhookURL != "" {
+		notifiers[models.AlertNotifierWebhook] = &webhookAlertNotifier{
+			url:    config.WebhookURL,
+			secret: config.WebhookSecret,
+			client: &http.Client{Timeout: config.NotifyTimeout},
+		}
+	}
+	if config.SMTPHost != "" && config.SMTPFrom != "" && len(config.SMTPTo) > 0 {
+		notifiers[models.AlertNotifierEmail] = &smtpAlertNotifier{
+			host:     config.SMTPHost,
+			port:     config.SMTPPort,
+			username: config.SMTPUsername,
+			password: config.SMTPPassword,
+			from:     config.SMTPFrom,
+			to:       config.SMTPTo,
+		}
+	}
+	return notifiers
+}
+
+// webhookAlertNotifier POST уведомления в JSON; при заданном секрете тело подписывается HMAC-SHA256
+type webhookAlertNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (n *webhookAlertNotifier) Name() string {
+	return models.AlertNotifierWebhook
+}
+
+func (n *webhookAlertNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Cosmos-Dashboard/1.0")
+	req.Header.Set("X-Cosmos-Event", notification.Event)
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set("X-Cosmos-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// smtpAlertNotifier письмо в text/plain; STARTTLS, если сервер его предлагает,
+// авторизация - только если задан логин (локальные тестовые серверы обходятся без нее)
+type smtpAlertNotifier struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (n *smtpAlertNotifier) Name() string {
+	return models.AlertNotifierEmail
+}
+
+func (n *smtpAlertNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.host, strconv.Itoa(n.port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message: %w", err)
+	}
+	if _, err := w.Write(n.message(notification)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return client.Quit()
+}
+
+func (n *smtpAlertNotifier) message(notification AlertNotification) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject()))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", notification.Message)
+	fmt.Fprintf(&b, "Rule:      %s (#%d)\r\n", notification.Rule, notification.RuleID)
+	fmt.Fprintf(&b, "Channel:   %s\r\n", notification.Channel)
+	fmt.Fprintf(&b, "Source:    %s\r\n", notification.Source)
+	fmt.Fprintf(&b, "Severity:  %s\r\n", notification.Severity)
+	fmt.Fprintf(&b, "Condition: %s %g\r\n", notification.Condition, notification.Threshold)
+	fmt.Fprintf(&b, "Value:     %g\r\n", notification.Value)
+	fmt.Fprintf(&b, "Fired at:  %s\r\n", notification.FiredAt.UTC().Format(time.RFC3339))
+	if notification.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved:  %s\r\n", notification.ResolvedAt.UTC().Format(time.RFC3339))
+	}
+	return b.Bytes()
+}
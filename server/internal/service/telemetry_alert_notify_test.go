@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/models"
+)
+
+func testAlertNotification() AlertNotification {
+	return AlertNotification{
+		Event:     models.AlertStateFiring,
+		AlertID:   7,
+		RuleID:    3,
+		Rule:      "battery low",
+		Channel:   "voltage",
+		Source:    "station-1",
+		Condition: models.AlertConditionBelow,
+		Severity:  models.AnomalySeverityCritical,
+		Threshold: 3.5,
+		Value:     3.2,
+		Message:   "voltage is 3.20 V below 3.50 V",
+		FiredAt:   time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookNotifierSignsPayload(t *testing.T) {
+	const secret = "s3cret"
+	type request struct {
+		event, signature string
+		body             []byte
+	}
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{r.Header.Get("X-Cosmos-Event"), r.Header.Get("X-Cosmos-Signature"), body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier := newAlertNotifiers(TelemetryAlertConfig{
+		WebhookURL:    server.URL,
+		WebhookSecret: secret,
+		NotifyTimeout: 5 * time.Second,
+	})[models.AlertNotifierWebhook]
+	if notifier == nil {
+		t.Fatal("webhook notifier is not configured")
+	}
+	if err := notifier.Notify(context.Background(), testAlertNotification()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := <-received
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.signature != want {
+		t.Fatalf("signature %q, want %q", req.signature, want)
+	}
+	if req.event != models.AlertStateFiring {
+		t.Fatalf("X-Cosmos-Event %q, want firing", req.event)
+	}
+
+	var payload AlertNotification
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.AlertID != 7 || payload.Rule != "battery low" || payload.Value != 3.2 {
+		t.Fatalf("payload %+v does not match the notification", payload)
+	}
+}
+
+func TestWebhookNotifierReportsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Cosmos-Signature") != "" {
+			t.Error("payload signed without a secret")
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := newAlertNotifiers(TelemetryAlertConfig{WebhookURL: server.URL, NotifyTimeout: 5 * time.Second})[models.AlertNotifierWebhook]
+	err := notifier.Notify(context.Background(), testAlertNotification())
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("err = %v, want status 502", err)
+	}
+}
+
+// smtpMessage конверт и текст письма, принятые тестовым SMTP-сервером
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPServer минимальный SMTP-сервер без STARTTLS и авторизации на одно соединение
+func startSMTPServer(t *testing.T) (string, int, <-chan smtpMessage) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP test")
+
+		var msg smtpMessage
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				msg.from = smtpPath(line)
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				msg.to = append(msg.to, smtpPath(line))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				messages <- msg
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, messages
+}
+
+// smtpPath адрес из "MAIL FROM:<a@b> BODY=8BITMIME"
+func smtpPath(line string) string {
+	start, end := strings.IndexByte(line, '<'), strings.IndexByte(line, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestEmailNotifierDeliversToLocalServer(t *testing.T) {
+	host, port, messages := startSMTPServer(t)
+	notifier := newAlertNotifiers(TelemetryAlertConfig{
+		SMTPHost: host,
+		SMTPPort: port,
+		SMTPFrom: "alerts@example.org",
+		SMTPTo:   []string{"ops@example.org", "oncall@example.org"},
+	})[models.AlertNotifierEmail]
+	if notifier == nil {
+		t.Fatal("email notifier is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, testAlertNotification()); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg smtpMessage
+	select {
+	case msg = <-messages:
+	case <-ctx.Done():
+		t.Fatal("SMTP server received no message")
+	}
+	if msg.from != "alerts@example.org" || len(msg.to) != 2 || msg.to[1] != "oncall@example.org" {
+		t.Fatalf("envelope from %q to %v", msg.from, msg.to)
+	}
+	for _, want := range []string{
+		"Subject: [FIRING] battery low: voltage is 3.20 V below 3.50 V\r\n",
+		"Rule:      battery low (#3)\r\n",
+		"Value:     3.2\r\n",
+		"Fired at:  2026-04-01T10:00:00Z\r\n",
+	} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("message has no %q:\n%s", want, msg.data)
+		}
+	}
+}
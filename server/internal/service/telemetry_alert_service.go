@@ -0,0 +1,973 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTelemetryAlertRuleNotFound = errors.New("telemetry alert rule not found")
+	ErrTelemetryAlertRuleInvalid  = errors.New("invalid telemetry alert rule")
+	ErrTelemetryAlertRuleExists   = errors.New("telemetry alert rule already exists")
+	ErrTelemetryAlertInvalid      = errors.New("invalid telemetry alert query")
+)
+
+const (
+	// telemetryAlertBatchSize показаний за один запрос плановой проверки
+	telemetryAlertBatchSize = 5000
+	// telemetryAlertLookback с какой глубины плановая проверка начинает новое правило
+	telemetryAlertLookback = 10 * time.Minute
+	telemetryAlertMaxFor   = 24 * time.Hour
+	// telemetryAlertNotifyAttempts попыток доставки уведомления по каждому каналу
+	telemetryAlertNotifyAttempts = 3
+)
+
+type TelemetryAlertConfig struct {
+	// Interval период плановой проверки правил по данным в базе
+	Interval      time.Duration
+	NotifyTimeout time.Duration
+	WebhookURL    string
+	// WebhookSecret ключ подписи X-Cosmos-Signature; пусто - без подписи
+	WebhookSecret string
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+	SMTPTo        []string
+}
+
+type TelemetryAlertService interface {
+	ListRules(ctx context.Context) ([]TelemetryAlertRuleView, error)
+	GetRule(ctx context.Context, id uint) (*TelemetryAlertRuleView, error)
+	CreateRule(ctx context.Context, input TelemetryAlertRuleInput) (*TelemetryAlertRuleView, error)
+	// UpdateRule меняет все поля правила; открытые тревоги снимаются уже по новым условиям
+	UpdateRule(ctx context.Context, id uint, input TelemetryAlertRuleInput) (*TelemetryAlertRuleView, error)
+	DeleteRule(ctx context.Context, id uint) error
+	// TestRule отправляет пробное уведомление по каналам правила и сообщает результат доставки
+	TestRule(ctx context.Context, id uint) ([]TelemetryAlertDelivery, error)
+
+	ListAlerts(ctx context.Context, query TelemetryAlertQuery) (*TelemetryAlertPage, error)
+
+	// Evaluate прогоняет только что записанные показания через правила
+	Evaluate(ctx context.Context, records []models.Telemetry) error
+	// Run периодически проверяет правила по показаниям в базе до закрытия stop,
+	// затем дожидается отправки уведомлений
+	Run(stop <-chan struct{})
+}
+
+type TelemetryAlertRuleInput struct {
+	Name      string   `json:"name"`
+	Channel   string   `json:"channel"`
+	Source    string   `json:"source"`
+	Condition string   `json:"condition"`
+	Threshold *float64 `json:"threshold"`
+	// For длительность в формате Go: "30s", "1m"
+	For        string   `json:"for"`
+	Hysteresis float64  `json:"hysteresis"`
+	Cooldown   string   `json:"cooldown"`
+	Severity   string   `json:"severity"`
+	Notify     []string `json:"notify"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+type TelemetryAlertRuleView struct {
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Channel    string   `json:"channel"`
+	Source     string   `json:"source"`
+	Condition  string   `json:"condition"`
+	Threshold  float64  `json:"threshold"`
+	For        string   `json:"for"`
+	Hysteresis float64  `json:"hysteresis"`
+	Cooldown   string   `json:"cooldown"`
+	Severity   string   `json:"severity"`
+	Notify     []string `json:"notify"`
+	Enabled    bool     `json:"enabled"`
+	// Firing число источников, по которым тревога сейчас активна
+	Firing    int       `json:"firing"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TelemetryAlertDelivery struct {
+	Notifier  string `json:"notifier"`
+	Delivered bool   `json:"delivered"`
+	Error     string `json:"error,omitempty"`
+}
+
+type TelemetryAlertQuery struct {
+	RuleID  uint
+	State   string
+	Source  string
+	From    time.Time
+	To      time.Time
+	Page    int
+	PerPage int
+}
+
+type TelemetryAlertPage struct {
+	Alerts  []TelemetryAlertView `json:"alerts"`
+	Total   int64                `json:"total"`
+	Page    int                  `json:"page"`
+	PerPage int                  `json:"per_page"`
+	From    time.Time            `json:"from"`
+	To      time.Time            `json:"to"`
+}
+
+type TelemetryAlertView struct {
+	ID            uint       `json:"id"`
+	RuleID        uint       `json:"rule_id"`
+	Rule          string     `json:"rule"`
+	Channel       string     `json:"channel"`
+	Source        string     `json:"source"`
+	State         string     `json:"state"`
+	Severity      string     `json:"severity"`
+	Value         float64    `json:"value"`
+	Threshold     float64    `json:"threshold"`
+	Message       string     `json:"message"`
+	FiredAt       time.Time  `json:"fired_at"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+	ResolvedValue *float64   `json:"resolved_value"`
+}
+
+// alertKey состояние правила ведется отдельно по каждому источнику
+type alertKey struct {
+	rule   uint
+	source string
+}
+
+type alertPoint struct {
+	t time.Time
+	v float64
+}
+
+type alertState struct {
+	// last время последнего учтенного показания; более ранние пропускаются
+	last time.Time
+	// pendingSince начало непрерывного нарушения для above/below
+	pendingSince time.Time
+	// window показания за окно For для rise/fall
+	window    []alertPoint
+	lastFired time.Time
+	open      *models.TelemetryAlert
+}
+
+type telemetryAlertService struct {
+	repo          repository.TelemetryAlertRepository
+	telemetryRepo repository.TelemetryRepository
+	channels      TelemetryChannelService
+	config        TelemetryAlertConfig
+	notifiers     map[string]AlertNotifier
+
+	// mu защищает кэш правил и состояние вычислителя
+	mu         sync.Mutex
+	rules      []models.TelemetryAlertRule
+	states     map[alertKey]*alertState
+	statesDone bool
+	// watermarks последний ID показания, прочитанный плановой проверкой, по правилу
+	watermarks map[uint]uint
+
+	sending sync.WaitGroup
+}
+
+func NewTelemetryAlertService(repo repository.TelemetryAlertRepository, telemetryRepo repository.TelemetryRepository,
+	channels TelemetryChannelService, config TelemetryAlertConfig) TelemetryAlertService {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.NotifyTimeout <= 0 {
+		config.NotifyTimeout = 10 * time.Second
+	}
+	if config.SMTPPort <= 0 {
+		config.SMTPPort = 587
+	}
+
+	notifiers := newAlertNotifiers(config)
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		log.Println("Telemetry alerts: no notifiers configured, alerts are only recorded")
+	} else {
+		log.Printf("Telemetry alerts: notifiers %s", strings.Join(names, ", "))
+	}
+
+	return &telemetryAlertService{
+		repo:          repo,
+		telemetryRepo: telemetryRepo,
+		channels:      channels,
+		config:        config,
+		notifiers:     notifiers,
+		states:        make(map[alertKey]*alertState),
+		watermarks:    make(map[uint]uint),
+	}
+}
+
+func (s *telemetryAlertService) ListRules(ctx context.Context) ([]TelemetryAlertRuleView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.loadRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]TelemetryAlertRuleView, 0, len(rules))
+	for i := range rules {
+		views = append(views, s.ruleView(&rules[i]))
+	}
+	return views, nil
+}
+
+func (s *telemetryAlertService) GetRule(ctx context.Context, id uint) (*TelemetryAlertRuleView, error) {
+	rule, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.viewOf(ctx, rule)
+}
+
+func (s *telemetryAlertService) CreateRule(ctx context.Context, input TelemetryAlertRuleInput) (*TelemetryAlertRuleView, error) {
+	rule := &models.TelemetryAlertRule{}
+	if err := s.applyRuleInput(ctx, rule, input); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetRuleByName(ctx, rule.Name); err == nil {
+		return nil, ErrTelemetryAlertRuleExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load telemetry alert rule: %w", err)
+	}
+
+	if err := s.repo.CreateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create telemetry alert rule: %w", err)
+	}
+	s.invalidate(rule.ID)
+
+	return s.viewOf(ctx, rule)
+}
+
+func (s *telemetryAlertService) UpdateRule(ctx context.Context, id uint, input TelemetryAlertRuleInput) (*TelemetryAlertRuleView, error) {
+	rule, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	previousName := rule.Name
+	if err := s.applyRuleInput(ctx, rule, input); err != nil {
+		return nil, err
+	}
+
+	if rule.Name != previousName {
+		if _, err := s.repo.GetRuleByName(ctx, rule.Name); err == nil {
+			return nil, ErrTelemetryAlertRuleExists
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load telemetry alert rule: %w", err)
+		}
+	}
+
+	if err := s.repo.UpdateRule(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update telemetry alert rule: %w", err)
+	}
+	s.invalidate(rule.ID)
+
+	return s.viewOf(ctx, rule)
+}
+
+func (s *telemetryAlertService) DeleteRule(ctx context.Context, id uint) error {
+	if err := s.repo.DeleteRule(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTelemetryAlertRuleNotFound
+		}
+		return fmt.Errorf("failed to delete telemetry alert rule: %w", err)
+	}
+	s.invalidate(id)
+	return nil
+}
+
+func (s *telemetryAlertService) TestRule(ctx context.Context, id uint) ([]TelemetryAlertDelivery, error) {
+	rule, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	notifiers := s.ruleNotifiers(rule)
+	if len(notifiers) == 0 {
+		return nil, fmt.Errorf("%w: none of the rule's notifiers is configured", ErrTelemetryAlertRuleInvalid)
+	}
+
+	notification := AlertNotification{
+		Event:     AlertEventTest,
+		RuleID:    rule.ID,
+		Rule:      rule.Name,
+		Channel:   rule.Channel,
+		Source:    rule.Source,
+		Condition: rule.Condition,
+		Severity:  rule.Severity,
+		Threshold: rule.Threshold,
+		Message:   fmt.Sprintf("Test notification for rule %q", rule.Name),
+		FiredAt:   time.Now().UTC(),
+	}
+
+	deliveries := make([]TelemetryAlertDelivery, 0, len(notifiers))
+	for _, notifier := range notifiers {
+		sendCtx, cancel := context.WithTimeout(ctx, s.config.NotifyTimeout)
+		err := notifier.Notify(sendCtx, notification)
+		cancel()
+
+		delivery := TelemetryAlertDelivery{Notifier: notifier.Name(), Delivered: err == nil}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (s *telemetryAlertService) ListAlerts(ctx context.Context, query TelemetryAlertQuery) (*TelemetryAlertPage, error) {
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-7 * 24 * time.Hour)
+	}
+	if query.To.Before(query.From) {
+		return nil, fmt.Errorf("%w: to is before from", ErrTelemetryAlertInvalid)
+	}
+	switch query.State {
+	case "", models.AlertStateFiring, models.AlertStateResolved:
+	default:
+		return nil, fmt.Errorf("%w: state must be firing or resolved", ErrTelemetryAlertInvalid)
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > 1000 {
+		query.PerPage = 100
+	}
+
+	alerts, total, err := s.repo.ListAlerts(ctx, repository.TelemetryAlertFilter{
+		RuleID:     query.RuleID,
+		State:      query.State,
+		SourceFile: query.Source,
+		From:       query.From,
+		To:         query.To,
+		Limit:      query.PerPage,
+		Offset:     (query.Page - 1) * query.PerPage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry alerts: %w", err)
+	}
+
+	s.mu.Lock()
+	rules, err := s.loadRules(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(rules))
+	for _, rule := range rules {
+		names[rule.ID] = rule.Name
+	}
+
+	page := &TelemetryAlertPage{
+		Alerts:  make([]TelemetryAlertView, 0, len(alerts)),
+		Total:   total,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+		From:    query.From,
+		To:      query.To,
+	}
+	for _, alert := range alerts {
+		page.Alerts = append(page.Alerts, TelemetryAlertView{
+			ID:            alert.ID,
+			RuleID:        alert.RuleID,
+			Rule:          names[alert.RuleID],
+			Channel:       alert.Channel,
+			Source:        alert.SourceFile,
+			State:         alert.State,
+			Severity:      alert.Severity,
+			Value:         alert.Value,
+			Threshold:     alert.Threshold,
+			Message:       alert.Message,
+			FiredAt:       alert.FiredAt,
+			ResolvedAt:    alert.ResolvedAt,
+			ResolvedValue: alert.ResolvedValue,
+		})
+	}
+	return page, nil
+}
+
+func (s *telemetryAlertService) Evaluate(ctx context.Context, records []models.Telemetry) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	registry, err := s.channels.Registry(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.loadRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+		if err := s.evaluateRule(ctx, rule, registry[rule.Channel], records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *telemetryAlertService) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.evaluateStored()
+	for {
+		select {
+		case <-ticker.C:
+			s.evaluateStored()
+		case <-stop:
+			s.sending.Wait()
+			return
+		}
+	}
+}
+
+// evaluateStored плановая проверка: показания, записанные в обход Evaluate или
+// пришедшие до создания правила, дочитываются из базы по ID
+func (s *telemetryAlertService) evaluateStored() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	registry, err := s.channels.Registry(ctx)
+	if err != nil {
+		log.Printf("Telemetry alerts: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules, err := s.loadRules(ctx)
+	if err != nil {
+		log.Printf("Telemetry alerts: %v", err)
+		return
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+
+		afterID := s.watermarks[rule.ID]
+		var since time.Time
+		if afterID == 0 {
+			lookback := max(telemetryAlertLookback, time.Duration(rule.ForSeconds)*time.Second)
+			since = time.Now().UTC().Add(-lookback)
+		}
+
+		for {
+			records, err := s.telemetryRepo.GetChannelUpdates(ctx, rule.Channel, rule.Source, afterID, since, telemetryAlertBatchSize)
+			if err != nil {
+				log.Printf("Telemetry alerts: failed to load readings for rule %q: %v", rule.Name, err)
+				break
+			}
+			if len(records) == 0 {
+				break
+			}
+			if err := s.evaluateRule(ctx, rule, registry[rule.Channel], records); err != nil {
+				log.Printf("Telemetry alerts: failed to evaluate rule %q: %v", rule.Name, err)
+				break
+			}
+			afterID = records[len(records)-1].ID
+			s.watermarks[rule.ID] = afterID
+			if len(records) < telemetryAlertBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// evaluateRule прогоняет показания с каналом правила по источникам в хронологическом порядке
+func (s *telemetryAlertService) evaluateRule(ctx context.Context, rule *models.TelemetryAlertRule,
+	channel models.TelemetryChannel, records []models.Telemetry) error {
+	if channel.Name == "" {
+		channel = models.TelemetryChannel{Name: rule.Channel, Precision: 2}
+	}
+
+	series := make(map[string][]alertPoint)
+	for i := range records {
+		record := &records[i]
+		if rule.Source != "" && record.SourceFile != rule.Source {
+			continue
+		}
+		if v, ok := record.Values[rule.Channel]; ok {
+			series[record.SourceFile] = append(series[record.SourceFile], alertPoint{t: record.RecordedAt, v: v})
+		}
+	}
+
+	sources := make([]string, 0, len(series))
+	for source := range series {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		points := series[source]
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].t.Before(points[j].t)
+		})
+
+		key := alertKey{rule: rule.ID, source: source}
+		state, ok := s.states[key]
+		if !ok {
+			state = &alertState{}
+			s.states[key] = state
+		}
+
+		for _, p := range points {
+			if !state.last.IsZero() && !p.t.After(state.last) {
+				continue
+			}
+			state.last = p.t
+			if err := s.step(ctx, rule, channel, key, state, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// step применяет условие правила к одному показанию
+func (s *telemetryAlertService) step(ctx context.Context, rule *models.TelemetryAlertRule,
+	channel models.TelemetryChannel, key alertKey, state *alertState, p alertPoint) error {
+	window := time.Duration(rule.ForSeconds) * time.Second
+
+	switch rule.Condition {
+	case models.AlertConditionAbove, models.AlertConditionBelow:
+		above := rule.Condition == models.AlertConditionAbove
+		if state.open != nil {
+			// Гистерезис: тревога снимается, только когда значение ушло за порог с запасом
+			if (above && p.v <= rule.Threshold-rule.Hysteresis) || (!above && p.v >= rule.Threshold+rule.Hysteresis) {
+				return s.resolve(ctx, rule, state, p, fmt.Sprintf("%s back to %s", channel.Name, formatAlertValue(channel, p.v)))
+			}
+			return nil
+		}
+
+		if (above && p.v <= rule.Threshold) || (!above && p.v >= rule.Threshold) {
+			state.pendingSince = time.Time{}
+			return nil
+		}
+		if state.pendingSince.IsZero() {
+			state.pendingSince = p.t
+		}
+		held := p.t.Sub(state.pendingSince)
+		if held < window {
+			return nil
+		}
+
+		side := "above"
+		if !above {
+			side = "below"
+		}
+		message := fmt.Sprintf("%s is %s %s %s", channel.Name, formatAlertValue(channel, p.v), side,
+			formatAlertValue(channel, rule.Threshold))
+		if window > 0 {
+			message += fmt.Sprintf(" for %s", held)
+		}
+		return s.fire(ctx, rule, key, state, p, p.v, message)
+
+	case models.AlertConditionRise, models.AlertConditionFall:
+		// Окно: показания не старше For относительно текущего
+		state.window = append(state.window, p)
+		start := 0
+		for start < len(state.window) && p.t.Sub(state.window[start].t) > window {
+			start++
+		}
+		state.window = state.window[start:]
+
+		var change float64
+		for _, w := range state.window {
+			if rule.Condition == models.AlertConditionRise {
+				change = max(change, p.v-w.v)
+			} else {
+				change = max(change, w.v-p.v)
+			}
+		}
+
+		verb := "rose"
+		if rule.Condition == models.AlertConditionFall {
+			verb = "fell"
+		}
+		if state.open != nil {
+			if change <= rule.Threshold-rule.Hysteresis {
+				return s.resolve(ctx, rule, state, p, fmt.Sprintf("%s %s by %s within %s, back within %s",
+					channel.Name, verb, formatAlertValue(channel, change), window, formatAlertValue(channel, rule.Threshold)))
+			}
+			return nil
+		}
+		if change <= rule.Threshold {
+			return nil
+		}
+		return s.fire(ctx, rule, key, state, p, change, fmt.Sprintf("%s %s by %s within %s (limit %s), now %s",
+			channel.Name, verb, formatAlertValue(channel, change), window,
+			formatAlertValue(channel, rule.Threshold), formatAlertValue(channel, p.v)))
+	}
+	return nil
+}
+
+func (s *telemetryAlertService) fire(ctx context.Context, rule *models.TelemetryAlertRule, key alertKey,
+	state *alertState, p alertPoint, value float64, message string) error {
+	// Пауза между срабатываниями: нарушение продолжает копиться и сработает после нее
+	cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+	if !state.lastFired.IsZero() && p.t.Sub(state.lastFired) < cooldown {
+		return nil
+	}
+
+	alert := &models.TelemetryAlert{
+		RuleID:     rule.ID,
+		Channel:    rule.Channel,
+		SourceFile: key.source,
+		State:      models.AlertStateFiring,
+		Severity:   rule.Severity,
+		Value:      value,
+		Threshold:  rule.Threshold,
+		Message:    message,
+		FiredAt:    p.t,
+	}
+	if err := s.repo.CreateAlert(ctx, alert); err != nil {
+		return fmt.Errorf("failed to store telemetry alert: %w", err)
+	}
+
+	state.open = alert
+	state.lastFired = p.t
+	state.pendingSince = time.Time{}
+
+	log.Printf("Telemetry alert firing: %s [%s] %s", rule.Name, key.source, message)
+	s.notify(rule, alert, models.AlertStateFiring)
+	return nil
+}
+
+func (s *telemetryAlertService) resolve(ctx context.Context, rule *models.TelemetryAlertRule,
+	state *alertState, p alertPoint, message string) error {
+	alert := state.open
+	resolvedAt := p.t
+	resolvedValue := p.v
+	alert.State = models.AlertStateResolved
+	alert.ResolvedAt = &resolvedAt
+	alert.ResolvedValue = &resolvedValue
+	if err := s.repo.ResolveAlert(ctx, alert); err != nil {
+		alert.State = models.AlertStateFiring
+		alert.ResolvedAt = nil
+		alert.ResolvedValue = nil
+		return fmt.Errorf("failed to resolve telemetry alert: %w", err)
+	}
+
+	state.open = nil
+	state.pendingSince = time.Time{}
+
+	log.Printf("Telemetry alert resolved: %s [%s] %s", rule.Name, alert.SourceFile, message)
+	resolved := *alert
+	resolved.Message = message
+	s.notify(rule, &resolved, models.AlertStateResolved)
+	return nil
+}
+
+// notify рассылает уведомление в фоне, чтобы медленный канал не задерживал запись показаний
+func (s *telemetryAlertService) notify(rule *models.TelemetryAlertRule, alert *models.TelemetryAlert, event string) {
+	notification := AlertNotification{
+		Event:      event,
+		AlertID:    alert.ID,
+		RuleID:     rule.ID,
+		Rule:       rule.Name,
+		Channel:    alert.Channel,
+		Source:     alert.SourceFile,
+		Condition:  rule.Condition,
+		Severity:   alert.Severity,
+		Threshold:  alert.Threshold,
+		Value:      alert.Value,
+		Message:    alert.Message,
+		FiredAt:    alert.FiredAt,
+		ResolvedAt: alert.ResolvedAt,
+	}
+	if alert.ResolvedValue != nil {
+		notification.Value = *alert.ResolvedValue
+	}
+
+	for _, notifier := range s.ruleNotifiers(rule) {
+		s.sending.Add(1)
+		go func(notifier AlertNotifier) {
+			defer s.sending.Done()
+			for attempt := 1; ; attempt++ {
+				ctx, cancel := context.WithTimeout(context.Background(), s.config.NotifyTimeout)
+				err := notifier.Notify(ctx, notification)
+				cancel()
+				if err == nil {
+					return
+				}
+				if attempt == telemetryAlertNotifyAttempts {
+					log.Printf("Telemetry alerts: %s notification for %q failed: %v", notifier.Name(), rule.Name, err)
+					return
+				}
+				time.Sleep(time.Duration(attempt) * 2 * time.Second)
+			}
+		}(notifier)
+	}
+}
+
+// ruleNotifiers настроенные каналы правила; пустой список в правиле - все настроенные
+func (s *telemetryAlertService) ruleNotifiers(rule *models.TelemetryAlertRule) []AlertNotifier {
+	names := decodeAlertNotify(rule.Notify)
+	if len(names) == 0 {
+		for name := range s.notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var notifiers []AlertNotifier
+	for _, name := range names {
+		if notifier, ok := s.notifiers[name]; ok {
+			notifiers = append(notifiers, notifier)
+		}
+	}
+	return notifiers
+}
+
+// loadRules правила из кэша; при первом обращении после изменений перечитывает их
+// и восстанавливает открытые тревоги и время последних срабатываний. Вызывается под s.mu.
+func (s *telemetryAlertService) loadRules(ctx context.Context) ([]models.TelemetryAlertRule, error) {
+	if s.rules == nil {
+		rules, err := s.repo.ListRules(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load telemetry alert rules: %w", err)
+		}
+		if rules == nil {
+			rules = []models.TelemetryAlertRule{}
+		}
+		s.rules = rules
+	}
+
+	if !s.statesDone {
+		latest, err := s.repo.LatestAlerts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load telemetry alerts: %w", err)
+		}
+		for i := range latest {
+			alert := latest[i]
+			key := alertKey{rule: alert.RuleID, source: alert.SourceFile}
+			if _, ok := s.states[key]; ok {
+				continue
+			}
+			state := &alertState{lastFired: alert.FiredAt}
+			if alert.State == models.AlertStateFiring {
+				state.open = &alert
+			}
+			s.states[key] = state
+		}
+		s.statesDone = true
+	}
+	return s.rules, nil
+}
+
+// invalidate сбрасывает кэш правил и состояние изменившегося правила
+func (s *telemetryAlertService) invalidate(ruleID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules = nil
+	s.statesDone = false
+	delete(s.watermarks, ruleID)
+	for key := range s.states {
+		if key.rule == ruleID {
+			delete(s.states, key)
+		}
+	}
+}
+
+func (s *telemetryAlertService) getRule(ctx context.Context, id uint) (*models.TelemetryAlertRule, error) {
+	rule, err := s.repo.GetRule(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTelemetryAlertRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to load telemetry alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (s *telemetryAlertService) viewOf(ctx context.Context, rule *models.TelemetryAlertRule) (*TelemetryAlertRuleView, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.loadRules(ctx); err != nil {
+		return nil, err
+	}
+
+	view := s.ruleView(rule)
+	return &view, nil
+}
+
+// ruleView вызывается под s.mu после loadRules, чтобы посчитать активные тревоги
+func (s *telemetryAlertService) ruleView(rule *models.TelemetryAlertRule) TelemetryAlertRuleView {
+	firing := 0
+	for key, state := range s.states {
+		if key.rule == rule.ID && state.open != nil {
+			firing++
+		}
+	}
+
+	return TelemetryAlertRuleView{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Channel:    rule.Channel,
+		Source:     rule.Source,
+		Condition:  rule.Condition,
+		Threshold:  rule.Threshold,
+		For:        (time.Duration(rule.ForSeconds) * time.Second).String(),
+		Hysteresis: rule.Hysteresis,
+		Cooldown:   (time.Duration(rule.CooldownSeconds) * time.Second).String(),
+		Severity:   rule.Severity,
+		Notify:     decodeAlertNotify(rule.Notify),
+		Enabled:    rule.Enabled,
+		Firing:     firing,
+		CreatedAt:  rule.CreatedAt,
+		UpdatedAt:  rule.UpdatedAt,
+	}
+}
+
+func (s *telemetryAlertService) applyRuleInput(ctx context.Context, rule *models.TelemetryAlertRule, input TelemetryAlertRuleInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w: name is required and must be at most 100 characters", ErrTelemetryAlertRuleInvalid)
+	}
+
+	channel, err := s.channels.Get(ctx, strings.TrimSpace(input.Channel))
+	if err != nil {
+		if errors.Is(err, ErrTelemetryChannelNotFound) {
+			return fmt.Errorf("%w: unknown channel %q", ErrTelemetryAlertRuleInvalid, input.Channel)
+		}
+		return err
+	}
+
+	if input.Threshold == nil {
+		return fmt.Errorf("%w: threshold is required", ErrTelemetryAlertRuleInvalid)
+	}
+
+	window, err := parseAlertDuration("for", input.For, telemetryAlertMaxFor)
+	if err != nil {
+		return err
+	}
+	cooldown, err := parseAlertDuration("cooldown", input.Cooldown, 7*24*time.Hour)
+	if err != nil {
+		return err
+	}
+	if input.Hysteresis < 0 {
+		return fmt.Errorf("%w: hysteresis must not be negative", ErrTelemetryAlertRuleInvalid)
+	}
+
+	switch input.Condition {
+	case models.AlertConditionAbove, models.AlertConditionBelow:
+	case models.AlertConditionRise, models.AlertConditionFall:
+		if channel.Type == models.ChannelTypeBoolean {
+			return fmt.Errorf("%w: %s is not supported for boolean channels", ErrTelemetryAlertRuleInvalid, input.Condition)
+		}
+		if window <= 0 {
+			return fmt.Errorf("%w: %s needs a window in 'for', e.g. \"1m\"", ErrTelemetryAlertRuleInvalid, input.Condition)
+		}
+		if *input.Threshold <= 0 {
+			return fmt.Errorf("%w: %s threshold must be positive", ErrTelemetryAlertRuleInvalid, input.Condition)
+		}
+	default:
+		return fmt.Errorf("%w: condition must be above, below, rise or fall", ErrTelemetryAlertRuleInvalid)
+	}
+
+	severity := input.Severity
+	if severity == "" {
+		severity = models.AnomalySeverityWarning
+	}
+	if severity != models.AnomalySeverityWarning && severity != models.AnomalySeverityCritical {
+		return fmt.Errorf("%w: severity must be warning or critical", ErrTelemetryAlertRuleInvalid)
+	}
+
+	notify := []string{}
+	seen := make(map[string]bool)
+	for _, n := range input.Notify {
+		n = strings.TrimSpace(n)
+		if n != models.AlertNotifierWebhook && n != models.AlertNotifierEmail {
+			return fmt.Errorf("%w: unknown notifier %q, use webhook or email", ErrTelemetryAlertRuleInvalid, n)
+		}
+		if !seen[n] {
+			seen[n] = true
+			notify = append(notify, n)
+		}
+	}
+	notifyJSON, _ := json.Marshal(notify)
+
+	rule.Name = name
+	rule.Channel = channel.Name
+	rule.Source = strings.TrimSpace(input.Source)
+	rule.Condition = input.Condition
+	rule.Threshold = *input.Threshold
+	rule.ForSeconds = int(window / time.Second)
+	rule.Hysteresis = input.Hysteresis
+	rule.CooldownSeconds = int(cooldown / time.Second)
+	rule.Severity = severity
+	rule.Notify = notifyJSON
+	rule.Enabled = input.Enabled == nil || *input.Enabled
+	return nil
+}
+
+func parseAlertDuration(field, value string, limit time.Duration) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 || d > limit {
+		return 0, fmt.Errorf("%w: %s must be a duration like \"30s\" or \"5m\" up to %s", ErrTelemetryAlertRuleInvalid, field, limit)
+	}
+	return d, nil
+}
+
+func decodeAlertNotify(raw []byte) []string {
+	names := []string{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &names)
+	}
+	return names
+}
+
+// formatAlertValue значение с точностью и единицей канала: "3.20 V"
+func formatAlertValue(channel models.TelemetryChannel, v float64) string {
+	if channel.Unit == "" {
+		return FormatChannelValue(channel, v)
+	}
+	return FormatChannelValue(channel, v) + " " + channel.Unit
+}
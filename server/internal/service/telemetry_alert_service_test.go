@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+// alertRepo хранит тревоги в памяти; правила задаются тестом
+type alertRepo struct {
+	repository.TelemetryAlertRepository
+	rules    []models.TelemetryAlertRule
+	alerts   []*models.TelemetryAlert
+	resolved []models.TelemetryAlert
+}
+
+func (r *alertRepo) ListRules(ctx context.Context) ([]models.TelemetryAlertRule, error) {
+	return r.rules, nil
+}
+
+func (r *alertRepo) LatestAlerts(ctx context.Context) ([]models.TelemetryAlert, error) {
+	return nil, nil
+}
+
+func (r *alertRepo) CreateAlert(ctx context.Context, alert *models.TelemetryAlert) error {
+	alert.ID = uint(len(r.alerts) + 1)
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *alertRepo) ResolveAlert(ctx context.Context, alert *models.TelemetryAlert) error {
+	r.resolved = append(r.resolved, *alert)
+	return nil
+}
+
+// recordingNotifier запоминает уведомления вместо отправки
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []AlertNotification
+}
+
+func (n *recordingNotifier) Name() string {
+	return models.AlertNotifierWebhook
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification AlertNotification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) events() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	events := make([]string, 0, len(n.notifications))
+	for _, notification := range n.notifications {
+		events = append(events, notification.Event)
+	}
+	return events
+}
+
+var alertTestStart = time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+
+func newAlertTestService(rule models.TelemetryAlertRule) (*telemetryAlertService, *alertRepo, *recordingNotifier) {
+	rule.ID = 1
+	rule.Name = "test rule"
+	rule.Channel = "voltage"
+	rule.Enabled = true
+	repo := &alertRepo{rules: []models.TelemetryAlertRule{rule}}
+	notifier := &recordingNotifier{}
+
+	s := NewTelemetryAlertService(repo, nil, roundTripChannels{channels: roundTripChannelList()},
+		TelemetryAlertConfig{}).(*telemetryAlertService)
+	s.notifiers = map[string]AlertNotifier{models.AlertNotifierWebhook: notifier}
+	return s, repo, notifier
+}
+
+// evaluateAt прогоняет показания канала voltage: пары "секунды от начала, значение"
+func evaluateAt(t *testing.T, s *telemetryAlertService, points ...[2]float64) {
+	t.Helper()
+	records := make([]models.Telemetry, 0, len(points))
+	for _, p := range points {
+		records = append(records, models.Telemetry{
+			RecordedAt: alertTestStart.Add(time.Duration(p[0]) * time.Second),
+			SourceFile: "station-1",
+			Values:     models.TelemetryValues{"voltage": p[1]},
+		})
+	}
+	if err := s.Evaluate(context.Background(), records); err != nil {
+		t.Fatal(err)
+	}
+	s.sending.Wait()
+}
+
+func assertFiredAt(t *testing.T, repo *alertRepo, seconds ...float64) {
+	t.Helper()
+	if len(repo.alerts) != len(seconds) {
+		t.Fatalf("%d alerts fired, want %d", len(repo.alerts), len(seconds))
+	}
+	for i, sec := range seconds {
+		want := alertTestStart.Add(time.Duration(sec) * time.Second)
+		if !repo.alerts[i].FiredAt.Equal(want) {
+			t.Fatalf("alert %d fired at %s, want %s", i, repo.alerts[i].FiredAt, want)
+		}
+	}
+}
+
+func TestTelemetryAlertThresholdForDuration(t *testing.T) {
+	s, repo, notifier := newAlertTestService(models.TelemetryAlertRule{
+		Condition:  models.AlertConditionBelow,
+		Threshold:  3.5,
+		ForSeconds: 30,
+	})
+
+	// Нарушение прерывается на 20-й секунде и начинается заново
+	evaluateAt(t, s, [2]float64{0, 3.4}, [2]float64{10, 3.3}, [2]float64{20, 3.6}, [2]float64{30, 3.4}, [2]float64{50, 3.4})
+	assertFiredAt(t, repo)
+
+	// Пакеты приходят по частям: отсчет нарушения сохраняется между вызовами
+	evaluateAt(t, s, [2]float64{60, 3.3}, [2]float64{70, 3.2})
+	assertFiredAt(t, repo, 60)
+	if repo.alerts[0].Value != 3.3 || repo.alerts[0].State != models.AlertStateFiring {
+		t.Fatalf("alert %+v, want firing with value 3.3", repo.alerts[0])
+	}
+	if events := notifier.events(); len(events) != 1 || events[0] != models.AlertStateFiring {
+		t.Fatalf("notifications %v, want one firing", events)
+	}
+
+	// Показания старше уже учтенных пропускаются
+	evaluateAt(t, s, [2]float64{5, 4.0})
+	if len(repo.resolved) != 0 {
+		t.Fatal("alert resolved by an out-of-order reading")
+	}
+}
+
+func TestTelemetryAlertHysteresis(t *testing.T) {
+	s, repo, notifier := newAlertTestService(models.TelemetryAlertRule{
+		Condition:  models.AlertConditionAbove,
+		Threshold:  50,
+		Hysteresis: 5,
+	})
+
+	// Колебания у порога не снимают тревогу, пока значение не опустится до 45
+	evaluateAt(t, s, [2]float64{0, 51}, [2]float64{10, 49}, [2]float64{20, 52}, [2]float64{30, 46})
+	assertFiredAt(t, repo, 0)
+	if len(repo.resolved) != 0 {
+		t.Fatalf("resolved inside the hysteresis band: %+v", repo.resolved)
+	}
+
+	evaluateAt(t, s, [2]float64{40, 45})
+	if len(repo.resolved) != 1 || *repo.resolved[0].ResolvedValue != 45 {
+		t.Fatalf("resolved %+v, want one resolution at 45", repo.resolved)
+	}
+	want := alertTestStart.Add(40 * time.Second)
+	if !repo.resolved[0].ResolvedAt.Equal(want) {
+		t.Fatalf("resolved at %s, want %s", repo.resolved[0].ResolvedAt, want)
+	}
+
+	events := notifier.events()
+	if len(events) != 2 || events[0] != models.AlertStateFiring || events[1] != models.AlertStateResolved {
+		t.Fatalf("notifications %v, want firing then resolved", events)
+	}
+}
+
+func TestTelemetryAlertRiseInWindow(t *testing.T) {
+	s, repo, _ := newAlertTestService(models.TelemetryAlertRule{
+		Condition:  models.AlertConditionRise,
+		Threshold:  10,
+		ForSeconds: 60,
+		Hysteresis: 2,
+	})
+
+	// Медленный рост: всего на 15, но в пределах минуты не больше чем на 5
+	evaluateAt(t, s, [2]float64{0, 0}, [2]float64{40, 5}, [2]float64{80, 10}, [2]float64{120, 15})
+	assertFiredAt(t, repo)
+
+	// Скачок на 11 за 30 с относительно показания на 120-й секунде
+	evaluateAt(t, s, [2]float64{150, 26})
+	assertFiredAt(t, repo, 150)
+	if repo.alerts[0].Value != 11 {
+		t.Fatalf("alert value %v, want the rise of 11", repo.alerts[0].Value)
+	}
+
+	// Рост за окно 9 - выше порога минус гистерезис, тревога остается
+	evaluateAt(t, s, [2]float64{200, 35})
+	if len(repo.resolved) != 0 {
+		t.Fatal("resolved while the rise is still above threshold minus hysteresis")
+	}
+	// Окно ушло вперед, роста нет
+	evaluateAt(t, s, [2]float64{260, 35})
+	if len(repo.resolved) != 1 {
+		t.Fatalf("%d resolutions, want 1 once the window has no rise", len(repo.resolved))
+	}
+}
+
+func TestTelemetryAlertCooldown(t *testing.T) {
+	s, repo, notifier := newAlertTestService(models.TelemetryAlertRule{
+		Condition:       models.AlertConditionAbove,
+		Threshold:       50,
+		CooldownSeconds: 300,
+	})
+
+	// Повторное нарушение через 20 с после срабатывания ждет конца паузы
+	evaluateAt(t, s, [2]float64{0, 60}, [2]float64{10, 40}, [2]float64{20, 60}, [2]float64{200, 61})
+	assertFiredAt(t, repo, 0)
+
+	evaluateAt(t, s, [2]float64{300, 62})
+	assertFiredAt(t, repo, 0, 300)
+
+	// Каналы рассылают уведомления параллельно, поэтому сравниваем без учета порядка
+	counts := make(map[string]int)
+	for _, event := range notifier.events() {
+		counts[event]++
+	}
+	if counts[models.AlertStateFiring] != 2 || counts[models.AlertStateResolved] != 1 {
+		t.Fatalf("notifications %v, want two firing and one resolved", counts)
+	}
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+var ErrTelemetryAnomalyInvalid = errors.New("invalid telemetry anomaly request")
+
+// telemetryAnomalyMaxStates при большем числе пар источник/канал забываем давно молчащие
+const telemetryAnomalyMaxStates = 10000
+
+type TelemetryAnomalyConfig struct {
+	// Window число предыдущих значений канала для скользящего z-score
+	Window        int
+	MinSamples    int
+	WarnSigma     float64
+	CriticalSigma float64
+	EWMAAlpha     float64
+	// MaxGap после такого перерыва в данных статистика канала набирается заново
+	MaxGap time.Duration
+}
+
+type TelemetryAnomalyService interface {
+	// Detect проверяет сохраненные показания (с ID) и сохраняет найденные аномалии
+	Detect(ctx context.Context, records []models.Telemetry) ([]models.TelemetryAnomaly, error)
+	List(ctx context.Context, query TelemetryAnomalyQuery) (*TelemetryAnomalyPage, error)
+	// ForRecords аномалии по ID показаний - для отметки в выгрузках
+	ForRecords(ctx context.Context, records []models.Telemetry) (map[uint][]models.TelemetryAnomaly, error)
+}
+
+type TelemetryAnomalyQuery struct {
+	From     time.Time
+	To       time.Time
+	Channel  string
+	Source   string
+	Severity string
+	Detector string
+	Page     int
+	PerPage  int
+}
+
+type TelemetryAnomalyPage struct {
+	Anomalies []TelemetryAnomalyView `json:"anomalies"`
+	Total     int64                  `json:"total"`
+	Page      int                    `json:"page"`
+	PerPage   int                    `json:"per_page"`
+	From      time.Time              `json:"from"`
+	To        time.Time              `json:"to"`
+}
+
+type TelemetryAnomalyView struct {
+	ID          uint      `json:"id"`
+	TelemetryID uint      `json:"telemetry_id"`
+	Channel     string    `json:"channel"`
+	Detector    string    `json:"detector"`
+	Severity    string    `json:"severity"`
+	Source      string    `json:"source"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	Value       float64   `json:"value"`
+	Expected    *float64  `json:"expected"`
+	Score       float64   `json:"score"`
+	Message     string    `json:"message"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// anomalyKey статистика ведется отдельно по каждому источнику и каналу
+type anomalyKey struct {
+	source  string
+	channel string
+}
+
+// anomalyState скользящее окно, EWMA и последнее значение канала
+type anomalyState struct {
+	last      time.Time
+	lastValue float64
+	window    []float64
+	ewma      float64
+	ewmVar    float64
+	samples   int
+}
+
+type telemetryAnomalyService struct {
+	repo          repository.TelemetryAnomalyRepository
+	telemetryRepo repository.TelemetryRepository
+	channels      TelemetryChannelService
+	config        TelemetryAnomalyConfig
+
+	mu     sync.Mutex
+	states map[anomalyKey]*anomalyState
+}
+
+func NewTelemetryAnomalyService(repo repository.TelemetryAnomalyRepository, telemetryRepo repository.TelemetryRepository,
+	channels TelemetryChannelService, config TelemetryAnomalyConfig) TelemetryAnomalyService {
+	if config.Window < 2 {
+		config.Window = 60
+	}
+	if config.MinSamples < 2 || config.MinSamples > config.Window {
+		config.MinSamples = min(10, config.Window)
+	}
+	if config.WarnSigma <= 0 {
+		config.WarnSigma = 3
+	}
+	if config.CriticalSigma < config.WarnSigma {
+		config.CriticalSigma = config.WarnSigma + 2
+	}
+	if config.EWMAAlpha <= 0 || config.EWMAAlpha >= 1 {
+		config.EWMAAlpha = 0.3
+	}
+	if config.MaxGap <= 0 {
+		config.MaxGap = time.Hour
+	}
+
+	return &telemetryAnomalyService{
+		repo:          repo,
+		telemetryRepo: telemetryRepo,
+		channels:      channels,
+		config:        config,
+		states:        make(map[anomalyKey]*anomalyState),
+	}
+}
+
+func (s *telemetryAnomalyService) Detect(ctx context.Context, records []models.Telemetry) ([]models.TelemetryAnomaly, error) {
+	registry, err := s.channels.Registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Значения по паре источник/канал в хронологическом порядке
+	type point struct {
+		record *models.Telemetry
+		value  float64
+	}
+	series := make(map[anomalyKey][]point)
+	for i := range records {
+		record := &records[i]
+		if record.ID == 0 {
+			continue
+		}
+		for name, value := range record.Values {
+			if _, ok := registry[name]; !ok {
+				continue
+			}
+			key := anomalyKey{source: record.SourceFile, channel: name}
+			series[key] = append(series[key], point{record: record, value: value})
+		}
+	}
+
+	keys := make([]anomalyKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].source != keys[j].source {
+			return keys[i].source < keys[j].source
+		}
+		return keys[i].channel < keys[j].channel
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var anomalies []models.TelemetryAnomaly
+	for _, key := range keys {
+		points := series[key]
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].record.RecordedAt.Before(points[j].record.RecordedAt)
+		})
+
+		channel := registry[key.channel]
+		state, err := s.state(ctx, key, points[0].record.RecordedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range points {
+			for _, found := range s.check(channel, state, p.record.RecordedAt, p.value) {
+				found.TelemetryID = p.record.ID
+				found.SourceFile = key.source
+				found.RecordedAt = p.record.RecordedAt
+				anomalies = append(anomalies, found)
+			}
+		}
+	}
+	s.evictStates()
+
+	if err := s.repo.CreateBatch(ctx, anomalies); err != nil {
+		return nil, fmt.Errorf("failed to store telemetry anomalies: %w", err)
+	}
+	if len(anomalies) > 0 {
+		log.Printf("Telemetry anomalies: %d found in %d readings", len(anomalies), len(records))
+	}
+	return anomalies, nil
+}
+
+// state статистика канала; при первой встрече набирается по истории из базы,
+// чтобы окно не начиналось с нуля после перезапуска
+func (s *telemetryAnomalyService) state(ctx context.Context, key anomalyKey, before time.Time) (*anomalyState, error) {
+	if state, ok := s.states[key]; ok {
+		return state, nil
+	}
+
+	state := &anomalyState{}
+	history, err := s.telemetryRepo.GetChannelHistory(ctx, key.source, key.channel, before, s.config.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load telemetry history for anomaly detection: %w", err)
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		t := history[i].RecordedAt
+		if state.samples > 0 && t.Sub(state.last) > s.config.MaxGap {
+			*state = anomalyState{}
+		}
+		s.observe(state, t, history[i].Values[key.channel])
+	}
+
+	s.states[key] = state
+	return state, nil
+}
+
+// check прогоняет значение через детекторы и добавляет его в статистику
+func (s *telemetryAnomalyService) check(channel models.TelemetryChannel, state *anomalyState, t time.Time, v float64) []models.TelemetryAnomaly {
+	var found []models.TelemetryAnomaly
+	if anomaly := thresholdAnomaly(channel, v); anomaly != nil {
+		found = append(found, *anomaly)
+	}
+
+	// Для логических каналов статистика не имеет смысла, для запоздавших значений - порядка
+	if channel.Type == models.ChannelTypeBoolean || (state.samples > 0 && !t.After(state.last)) {
+		return found
+	}
+	if state.samples > 0 && t.Sub(state.last) > s.config.MaxGap {
+		*state = anomalyState{}
+	}
+
+	if channel.MaxRate != nil && state.samples > 0 {
+		seconds := t.Sub(state.last).Seconds()
+		rate := math.Abs(v-state.lastValue) / seconds
+		if rate > *channel.MaxRate {
+			severity := models.AnomalySeverityWarning
+			if rate > 2**channel.MaxRate {
+				severity = models.AnomalySeverityCritical
+			}
+			found = append(found, models.TelemetryAnomaly{
+				Channel:  channel.Name,
+				Detector: models.AnomalyDetectorRate,
+				Severity: severity,
+				Value:    v,
+				Score:    round2(rate),
+				Message: fmt.Sprintf("%s changed by %s in %.0f s (%.3g %s/s, limit %.3g %s/s)",
+					channel.Name, FormatChannelValue(channel, v-state.lastValue), seconds,
+					rate, channel.Unit, *channel.MaxRate, channel.Unit),
+			})
+		}
+	}
+
+	if len(state.window) >= s.config.MinSamples {
+		mean, std := meanStd(state.window)
+		if std > 0 {
+			z := math.Abs(v-mean) / std
+			if severity := s.sigmaSeverity(z); severity != "" {
+				expected := mean
+				found = append(found, models.TelemetryAnomaly{
+					Channel:  channel.Name,
+					Detector: models.AnomalyDetectorZScore,
+					Severity: severity,
+					Value:    v,
+					Expected: &expected,
+					Score:    round2(z),
+					Message: fmt.Sprintf("%s %s is %.1fσ from the mean %s of the last %d values",
+						channel.Name, FormatChannelValue(channel, v), z, FormatChannelValue(channel, mean), len(state.window)),
+				})
+			}
+		}
+	}
+
+	if state.samples >= s.config.MinSamples && state.ewmVar > 0 {
+		deviation := math.Abs(v-state.ewma) / math.Sqrt(state.ewmVar)
+		if severity := s.sigmaSeverity(deviation); severity != "" {
+			expected := state.ewma
+			found = append(found, models.TelemetryAnomaly{
+				Channel:  channel.Name,
+				Detector: models.AnomalyDetectorEWMA,
+				Severity: severity,
+				Value:    v,
+				Expected: &expected,
+				Score:    round2(deviation),
+				Message: fmt.Sprintf("%s %s is %.1fσ from the moving average %s",
+					channel.Name, FormatChannelValue(channel, v), deviation, FormatChannelValue(channel, state.ewma)),
+			})
+		}
+	}
+
+	s.observe(state, t, v)
+	return found
+}
+
+// observe добавляет значение в окно и экспоненциально взвешенные среднее и дисперсию
+func (s *telemetryAnomalyService) observe(state *anomalyState, t time.Time, v float64) {
+	if state.samples == 0 {
+		state.ewma = v
+		state.ewmVar = 0
+	} else {
+		diff := v - state.ewma
+		increment := s.config.EWMAAlpha * diff
+		state.ewma += increment
+		state.ewmVar = (1 - s.config.EWMAAlpha) * (state.ewmVar + diff*increment)
+	}
+
+	state.window = append(state.window, v)
+	if len(state.window) > s.config.Window {
+		state.window = state.window[len(state.window)-s.config.Window:]
+	}
+	state.last = t
+	state.lastValue = v
+	state.samples++
+}
+
+func (s *telemetryAnomalyService) sigmaSeverity(sigma float64) string {
+	switch {
+	case sigma >= s.config.CriticalSigma:
+		return models.AnomalySeverityCritical
+	case sigma >= s.config.WarnSigma:
+		return models.AnomalySeverityWarning
+	}
+	return ""
+}
+
+// evictStates забывает каналы, молчащие дольше MaxGap, когда их становится слишком много
+func (s *telemetryAnomalyService) evictStates() {
+	if len(s.states) <= telemetryAnomalyMaxStates {
+		return
+	}
+	cutoff := time.Now().Add(-s.config.MaxGap)
+	for key, state := range s.states {
+		if state.last.Before(cutoff) {
+			delete(s.states, key)
+		}
+	}
+}
+
+// thresholdAnomaly статические пороги канала; критический порог проверяется первым
+func thresholdAnomaly(channel models.TelemetryChannel, v float64) *models.TelemetryAnomaly {
+	check := func(severity string, low, high *float64) *models.TelemetryAnomaly {
+		var bound float64
+		var side string
+		switch {
+		case low != nil && v < *low:
+			bound, side = *low, "below"
+		case high != nil && v > *high:
+			bound, side = *high, "above"
+		default:
+			return nil
+		}
+		return &models.TelemetryAnomaly{
+			Channel:  channel.Name,
+			Detector: models.AnomalyDetectorThreshold,
+			Severity: severity,
+			Value:    v,
+			Expected: &bound,
+			Score:    round2(math.Abs(v - bound)),
+			Message: fmt.Sprintf("%s %s %s is %s the %s threshold %s",
+				channel.Name, FormatChannelValue(channel, v), channel.Unit, side, severity, FormatChannelValue(channel, bound)),
+		}
+	}
+
+	if anomaly := check(models.AnomalySeverityCritical, channel.CritMin, channel.CritMax); anomaly != nil {
+		return anomaly
+	}
+	return check(models.AnomalySeverityWarning, channel.WarnMin, channel.WarnMax)
+}
+
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)-1))
+}
+
+func (s *telemetryAnomalyService) List(ctx context.Context, query TelemetryAnomalyQuery) (*TelemetryAnomalyPage, error) {
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-7 * 24 * time.Hour)
+	}
+	if query.To.Before(query.From) {
+		return nil, fmt.Errorf("%w: to is before from", ErrTelemetryAnomalyInvalid)
+	}
+
+	switch query.Severity {
+	case "", models.AnomalySeverityWarning, models.AnomalySeverityCritical:
+	default:
+		return nil, fmt.Errorf("%w: severity must be warning or critical", ErrTelemetryAnomalyInvalid)
+	}
+	switch query.Detector {
+	case "", models.AnomalyDetectorThreshold, models.AnomalyDetectorZScore, models.AnomalyDetectorEWMA, models.AnomalyDetectorRate:
+	default:
+		return nil, fmt.Errorf("%w: detector must be threshold, zscore, ewma or rate", ErrTelemetryAnomalyInvalid)
+	}
+
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 || query.PerPage > 1000 {
+		query.PerPage = 100
+	}
+
+	anomalies, total, err := s.repo.List(ctx, repository.TelemetryAnomalyFilter{
+		From:       query.From,
+		To:         query.To,
+		Channel:    query.Channel,
+		SourceFile: query.Source,
+		Severity:   query.Severity,
+		Detector:   query.Detector,
+		Limit:      query.PerPage,
+		Offset:     (query.Page - 1) * query.PerPage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry anomalies: %w", err)
+	}
+
+	page := &TelemetryAnomalyPage{
+		Anomalies: make([]TelemetryAnomalyView, 0, len(anomalies)),
+		Total:     total,
+		Page:      query.Page,
+		PerPage:   query.PerPage,
+		From:      query.From,
+		To:        query.To,
+	}
+	for _, anomaly := range anomalies {
+		page.Anomalies = append(page.Anomalies, TelemetryAnomalyView{
+			ID:          anomaly.ID,
+			TelemetryID: anomaly.TelemetryID,
+			Channel:     anomaly.Channel,
+			Detector:    anomaly.Detector,
+			Severity:    anomaly.Severity,
+			Source:      anomaly.SourceFile,
+			RecordedAt:  anomaly.RecordedAt,
+			Value:       anomaly.Value,
+			Expected:    anomaly.Expected,
+			Score:       anomaly.Score,
+			Message:     anomaly.Message,
+			DetectedAt:  anomaly.CreatedAt,
+		})
+	}
+	return page, nil
+}
+
+func (s *telemetryAnomalyService) ForRecords(ctx context.Context, records []models.Telemetry) (map[uint][]models.TelemetryAnomaly, error) {
+	ids := make([]uint, 0, len(records))
+	for _, record := range records {
+		if record.ID != 0 {
+			ids = append(ids, record.ID)
+		}
+	}
+
+	byRecord := make(map[uint][]models.TelemetryAnomaly)
+	if len(ids) == 0 {
+		return byRecord, nil
+	}
+
+	anomalies, err := s.repo.ByTelemetryIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load telemetry anomalies: %w", err)
+	}
+	for _, anomaly := range anomalies {
+		byRecord[anomaly.TelemetryID] = append(byRecord[anomaly.TelemetryID], anomaly)
+	}
+	return byRecord, nil
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+// anomalyStore запоминает сохраненные события
+type anomalyStore struct {
+	repository.TelemetryAnomalyRepository
+	stored []models.TelemetryAnomaly
+}
+
+func (r *anomalyStore) CreateBatch(ctx context.Context, anomalies []models.TelemetryAnomaly) error {
+	r.stored = append(r.stored, anomalies...)
+	return nil
+}
+
+// anomalyHistory история канала в базе, от новых к старым
+type anomalyHistory struct {
+	repository.TelemetryRepository
+	history []models.Telemetry
+}
+
+func (r anomalyHistory) GetChannelHistory(ctx context.Context, sourceFile, channel string, before time.Time, limit int) ([]models.Telemetry, error) {
+	return r.history, nil
+}
+
+const anomalyTestStep = 10 * time.Second
+
+var anomalyTestStart = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// anomalyBaseline 10 и 11 по очереди: среднее 10.5, σ ≈ 0.53, EWMA ≈ 10.57 при σ ≈ 0.49,
+// скорость 0.1 в секунду
+var anomalyBaseline = []float64{10, 11, 10, 11, 10, 11, 10, 11, 10, 11}
+
+func newAnomalyTestService(history []models.Telemetry, channel models.TelemetryChannel) (TelemetryAnomalyService, *anomalyStore) {
+	store := &anomalyStore{}
+	s := NewTelemetryAnomalyService(store, anomalyHistory{history: history},
+		roundTripChannels{channels: []models.TelemetryChannel{channel}}, TelemetryAnomalyConfig{
+			Window:        10,
+			MinSamples:    5,
+			WarnSigma:     3,
+			CriticalSigma: 5,
+			EWMAAlpha:     0.3,
+			MaxGap:        10 * time.Minute,
+		})
+	return s, store
+}
+
+// anomalyReadings показания канала с шагом anomalyTestStep начиная с start; ID с firstID
+func anomalyReadings(channel string, start time.Time, firstID uint, values ...float64) []models.Telemetry {
+	records := make([]models.Telemetry, len(values))
+	for i, v := range values {
+		records[i] = models.Telemetry{
+			ID:         firstID + uint(i),
+			RecordedAt: start.Add(time.Duration(i) * anomalyTestStep),
+			SourceFile: "station-1",
+			Values:     models.TelemetryValues{channel: v},
+		}
+	}
+	return records
+}
+
+// anomaliesOf события показания; detector пустой - всех детекторов
+func anomaliesOf(anomalies []models.TelemetryAnomaly, telemetryID uint, detector string) []models.TelemetryAnomaly {
+	var found []models.TelemetryAnomaly
+	for _, anomaly := range anomalies {
+		if anomaly.TelemetryID == telemetryID && (detector == "" || anomaly.Detector == detector) {
+			found = append(found, anomaly)
+		}
+	}
+	return found
+}
+
+func anomalyFloat(v float64) *float64 { return &v }
+
+func TestTelemetryAnomalyDetectors(t *testing.T) {
+	plain := models.TelemetryChannel{Name: "voltage", Unit: "V", Type: models.ChannelTypeFloat, Precision: 2}
+	rated := plain
+	rated.MaxRate = anomalyFloat(0.1)
+	limited := plain
+	limited.WarnMin, limited.WarnMax = anomalyFloat(-20), anomalyFloat(50)
+	limited.CritMin, limited.CritMax = anomalyFloat(-40), anomalyFloat(80)
+
+	tests := []struct {
+		name     string
+		channel  models.TelemetryChannel
+		value    float64
+		detector string
+		// severity пустая - детектор не должен сработать
+		severity string
+	}{
+		{"zscore quiet", plain, 11.5, models.AnomalyDetectorZScore, ""},
+		{"zscore warning", plain, 12.6, models.AnomalyDetectorZScore, models.AnomalySeverityWarning},
+		{"zscore critical", plain, 14, models.AnomalyDetectorZScore, models.AnomalySeverityCritical},
+		{"ewma quiet", plain, 11.5, models.AnomalyDetectorEWMA, ""},
+		{"ewma warning", plain, 12.6, models.AnomalyDetectorEWMA, models.AnomalySeverityWarning},
+		{"ewma critical", plain, 14, models.AnomalyDetectorEWMA, models.AnomalySeverityCritical},
+		// Последнее значение базы 11, шаг 10 с, предел 0.1/с, критично - вдвое выше
+		{"rate quiet", rated, 12, models.AnomalyDetectorRate, ""},
+		{"rate warning", rated, 12.5, models.AnomalyDetectorRate, models.AnomalySeverityWarning},
+		{"rate critical", rated, 13.5, models.AnomalyDetectorRate, models.AnomalySeverityCritical},
+		{"rate without limit", plain, 13.5, models.AnomalyDetectorRate, ""},
+		{"threshold quiet", limited, 45, models.AnomalyDetectorThreshold, ""},
+		{"threshold warning above", limited, 60, models.AnomalyDetectorThreshold, models.AnomalySeverityWarning},
+		{"threshold critical above", limited, 90, models.AnomalyDetectorThreshold, models.AnomalySeverityCritical},
+		{"threshold warning below", limited, -25, models.AnomalyDetectorThreshold, models.AnomalySeverityWarning},
+		{"threshold critical below", limited, -45, models.AnomalyDetectorThreshold, models.AnomalySeverityCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newAnomalyTestService(nil, tt.channel)
+			records := anomalyReadings(tt.channel.Name, anomalyTestStart, 1, append(append([]float64(nil), anomalyBaseline...), tt.value)...)
+			last := records[len(records)-1]
+
+			anomalies, err := s.Detect(context.Background(), records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(store.stored) != len(anomalies) {
+				t.Fatalf("stored %d anomalies, found %d", len(store.stored), len(anomalies))
+			}
+			for _, record := range records[:len(records)-1] {
+				if found := anomaliesOf(anomalies, record.ID, ""); len(found) > 0 {
+					t.Fatalf("baseline reading %d flagged: %+v", record.ID, found)
+				}
+			}
+
+			found := anomaliesOf(anomalies, last.ID, tt.detector)
+			if tt.severity == "" {
+				if len(found) > 0 {
+					t.Fatalf("unexpected %s anomaly: %+v", tt.detector, found)
+				}
+				return
+			}
+			if len(found) != 1 {
+				t.Fatalf("got %d %s anomalies, want 1: %+v", len(found), tt.detector, anomalies)
+			}
+			anomaly := found[0]
+			if anomaly.Severity != tt.severity {
+				t.Errorf("severity %s, want %s (score %.2f)", anomaly.Severity, tt.severity, anomaly.Score)
+			}
+			if anomaly.Value != tt.value || anomaly.SourceFile != last.SourceFile || !anomaly.RecordedAt.Equal(last.RecordedAt) {
+				t.Errorf("anomaly %+v does not describe reading %+v", anomaly, last)
+			}
+		})
+	}
+}
+
+// После перерыва дольше MaxGap статистика набирается заново: скачок не сравнивается
+// с устаревшим окном, а статические пороги действуют как прежде
+func TestTelemetryAnomalyMaxGapResetsStatistics(t *testing.T) {
+	channel := models.TelemetryChannel{Name: "voltage", Unit: "V", Type: models.ChannelTypeFloat, Precision: 2,
+		MaxRate: anomalyFloat(0.1), WarnMax: anomalyFloat(50), CritMax: anomalyFloat(80)}
+	statistical := []string{models.AnomalyDetectorZScore, models.AnomalyDetectorEWMA, models.AnomalyDetectorRate}
+
+	baseline := anomalyReadings(channel.Name, anomalyTestStart, 1, anomalyBaseline...)
+	baselineEnd := baseline[len(baseline)-1].RecordedAt
+	// История из базы - от новых к старым
+	history := make([]models.Telemetry, len(baseline))
+	for i, record := range baseline {
+		history[len(baseline)-1-i] = record
+	}
+
+	tests := []struct {
+		name    string
+		history []models.Telemetry
+		batch   []models.Telemetry
+		gap     time.Duration
+		// flagged детекторы, которые должны сработать на скачке
+		flagged []string
+	}{
+		{"in batch without gap", nil, baseline, anomalyTestStep, statistical},
+		{"in batch after gap", nil, baseline, 20 * time.Minute, nil},
+		{"after history without gap", history, nil, anomalyTestStep, statistical},
+		{"after history with gap", history, nil, 20 * time.Minute, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, value := range []float64{14, 90} {
+				s, _ := newAnomalyTestService(tt.history, channel)
+				spike := anomalyReadings(channel.Name, baselineEnd.Add(tt.gap), 100, value)
+				anomalies, err := s.Detect(context.Background(), append(append([]models.Telemetry(nil), tt.batch...), spike...))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, detector := range statistical {
+					want := false
+					for _, flagged := range tt.flagged {
+						want = want || flagged == detector
+					}
+					if got := len(anomaliesOf(anomalies, 100, detector)) > 0; got != want {
+						t.Errorf("value %v: %s fired %v, want %v", value, detector, got, want)
+					}
+				}
+
+				threshold := anomaliesOf(anomalies, 100, models.AnomalyDetectorThreshold)
+				if wantThreshold := value > 50; (len(threshold) > 0) != wantThreshold {
+					t.Errorf("value %v: threshold fired %v, want %v", value, len(threshold) > 0, wantThreshold)
+				}
+			}
+		})
+	}
+}
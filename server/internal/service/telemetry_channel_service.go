@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTelemetryChannelNotFound = errors.New("telemetry channel not found")
+	ErrTelemetryChannelInvalid  = errors.New("invalid telemetry channel")
+	ErrTelemetryChannelExists   = errors.New("telemetry channel already exists")
+)
+
+// channelNamePattern имя канала - ключ в JSON и заголовок столбца выгрузки
+var channelNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// telemetryChannelCacheTTL реестр меняется редко, а нужен на каждую строку импорта
+const telemetryChannelCacheTTL = 30 * time.Second
+
+type TelemetryChannelService interface {
+	List(ctx context.Context) ([]TelemetryChannelView, error)
+	Get(ctx context.Context, name string) (*TelemetryChannelView, error)
+	Create(ctx context.Context, input TelemetryChannelInput) (*TelemetryChannelView, error)
+	// Update меняет все поля, кроме имени
+	Update(ctx context.Context, name string, input TelemetryChannelInput) (*TelemetryChannelView, error)
+
+	// Resolve каналы по именам в порядке запроса; пустой список - все каналы реестра
+	Resolve(ctx context.Context, names []string) ([]models.TelemetryChannel, error)
+	// Registry каналы по имени для проверки значений
+	Registry(ctx context.Context) (map[string]models.TelemetryChannel, error)
+}
+
+type TelemetryChannelInput struct {
+	Name        string   `json:"name"`
+	Unit        string   `json:"unit"`
+	Type        string   `json:"type"`
+	Min         *float64 `json:"min"`
+	Max         *float64 `json:"max"`
+	Precision   *int     `json:"precision"`
+	WarnMin     *float64 `json:"warn_min"`
+	WarnMax     *float64 `json:"warn_max"`
+	CritMin     *float64 `json:"crit_min"`
+	CritMax     *float64 `json:"crit_max"`
+	MaxRate     *float64 `json:"max_rate"`
+	Description string   `json:"description"`
+}
+
+type TelemetryChannelView struct {
+	Name        string    `json:"name"`
+	Unit        string    `json:"unit"`
+	Type        string    `json:"type"`
+	Min         *float64  `json:"min"`
+	Max         *float64  `json:"max"`
+	Precision   int       `json:"precision"`
+	WarnMin     *float64  `json:"warn_min"`
+	WarnMax     *float64  `json:"warn_max"`
+	CritMin     *float64  `json:"crit_min"`
+	CritMax     *float64  `json:"crit_max"`
+	MaxRate     *float64  `json:"max_rate"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type telemetryChannelService struct {
+	repo repository.TelemetryChannelRepository
+
+	mu       sync.Mutex
+	cached   []models.TelemetryChannel
+	cachedAt time.Time
+}
+
+func NewTelemetryChannelService(repo repository.TelemetryChannelRepository) TelemetryChannelService {
+	return &telemetryChannelService{repo: repo}
+}
+
+func (s *telemetryChannelService) List(ctx context.Context) ([]TelemetryChannelView, error) {
+	channels, err := s.channels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]TelemetryChannelView, 0, len(channels))
+	for i := range channels {
+		views = append(views, ToTelemetryChannelView(&channels[i]))
+	}
+	return views, nil
+}
+
+func (s *telemetryChannelService) Get(ctx context.Context, name string) (*TelemetryChannelView, error) {
+	channel, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTelemetryChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to load telemetry channel: %w", err)
+	}
+
+	view := ToTelemetryChannelView(channel)
+	return &view, nil
+}
+
+func (s *telemetryChannelService) Create(ctx context.Context, input TelemetryChannelInput) (*TelemetryChannelView, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if !channelNamePattern.MatchString(input.Name) {
+		return nil, fmt.Errorf("%w: name must be lowercase latin letters, digits and _, starting with a letter", ErrTelemetryChannelInvalid)
+	}
+
+	if _, err := s.repo.GetByName(ctx, input.Name); err == nil {
+		return nil, ErrTelemetryChannelExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load telemetry channel: %w", err)
+	}
+
+	channel := &models.TelemetryChannel{Name: input.Name}
+	if err := applyTelemetryChannelInput(channel, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, channel); err != nil {
+		return nil, fmt.Errorf("failed to create telemetry channel: %w", err)
+	}
+	s.invalidate()
+
+	view := ToTelemetryChannelView(channel)
+	return &view, nil
+}
+
+func (s *telemetryChannelService) Update(ctx context.Context, name string, input TelemetryChannelInput) (*TelemetryChannelView, error) {
+	channel, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTelemetryChannelNotFound
+		}
+		return nil, fmt.Errorf("failed to load telemetry channel: %w", err)
+	}
+	if input.Name != "" && input.Name != channel.Name {
+		return nil, fmt.Errorf("%w: channel name cannot be changed", ErrTelemetryChannelInvalid)
+	}
+
+	if err := applyTelemetryChannelInput(channel, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, channel); err != nil {
+		return nil, fmt.Errorf("failed to update telemetry channel: %w", err)
+	}
+	s.invalidate()
+
+	view := ToTelemetryChannelView(channel)
+	return &view, nil
+}
+
+func (s *telemetryChannelService) Resolve(ctx context.Context, names []string) ([]models.TelemetryChannel, error) {
+	channels, err := s.channels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return channels, nil
+	}
+
+	byName := make(map[string]models.TelemetryChannel, len(channels))
+	for _, channel := range channels {
+		byName[channel.Name] = channel
+	}
+
+	resolved := make([]models.TelemetryChannel, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		channel, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrTelemetryChannelNotFound, name)
+		}
+		seen[name] = true
+		resolved = append(resolved, channel)
+	}
+	return resolved, nil
+}
+
+func (s *telemetryChannelService) Registry(ctx context.Context) (map[string]models.TelemetryChannel, error) {
+	channels, err := s.channels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := make(map[string]models.TelemetryChannel, len(channels))
+	for _, channel := range channels {
+		registry[channel.Name] = channel
+	}
+	return registry, nil
+}
+
+// channels реестр из памяти, не старше telemetryChannelCacheTTL
+func (s *telemetryChannelService) channels(ctx context.Context) ([]models.TelemetryChannel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && time.Since(s.cachedAt) < telemetryChannelCacheTTL {
+		return s.cached, nil
+	}
+
+	channels, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telemetry channels: %w", err)
+	}
+	s.cached = channels
+	s.cachedAt = time.Now()
+	return channels, nil
+}
+
+func (s *telemetryChannelService) invalidate() {
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+}
+
+func applyTelemetryChannelInput(channel *models.TelemetryChannel, input TelemetryChannelInput) error {
+	unit := strings.TrimSpace(input.Unit)
+	if len(unit) > 32 {
+		return fmt.Errorf("%w: unit is longer than 32 characters", ErrTelemetryChannelInvalid)
+	}
+
+	channelType := strings.ToLower(strings.TrimSpace(input.Type))
+	switch channelType {
+	case "":
+		channelType = models.ChannelTypeFloat
+	case models.ChannelTypeFloat, models.ChannelTypeInteger, models.ChannelTypeBoolean:
+	default:
+		return fmt.Errorf("%w: type must be float, integer or boolean", ErrTelemetryChannelInvalid)
+	}
+
+	precision := 2
+	if input.Precision != nil {
+		precision = *input.Precision
+	}
+	if precision < 0 || precision > 6 {
+		return fmt.Errorf("%w: precision must be within 0-6", ErrTelemetryChannelInvalid)
+	}
+
+	min, max := input.Min, input.Max
+	for _, bound := range []*float64{min, max, input.WarnMin, input.WarnMax, input.CritMin, input.CritMax, input.MaxRate} {
+		if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0)) {
+			return fmt.Errorf("%w: range bounds and thresholds must be finite", ErrTelemetryChannelInvalid)
+		}
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("%w: min is greater than max", ErrTelemetryChannelInvalid)
+	}
+	if input.WarnMin != nil && input.WarnMax != nil && *input.WarnMin > *input.WarnMax {
+		return fmt.Errorf("%w: warn_min is greater than warn_max", ErrTelemetryChannelInvalid)
+	}
+	if input.CritMin != nil && input.CritMax != nil && *input.CritMin > *input.CritMax {
+		return fmt.Errorf("%w: crit_min is greater than crit_max", ErrTelemetryChannelInvalid)
+	}
+	if input.MaxRate != nil && *input.MaxRate <= 0 {
+		return fmt.Errorf("%w: max_rate must be positive", ErrTelemetryChannelInvalid)
+	}
+
+	// Для целых и логических каналов дробная часть не хранится
+	if channelType != models.ChannelTypeFloat {
+		precision = 0
+	}
+	if channelType == models.ChannelTypeBoolean {
+		zero, one := 0.0, 1.0
+		min, max = &zero, &one
+	}
+
+	channel.Unit = unit
+	channel.Type = channelType
+	channel.MinValue = min
+	channel.MaxValue = max
+	channel.Precision = precision
+	channel.WarnMin = input.WarnMin
+	channel.WarnMax = input.WarnMax
+	channel.CritMin = input.CritMin
+	channel.CritMax = input.CritMax
+	channel.MaxRate = input.MaxRate
+	channel.Description = strings.TrimSpace(input.Description)
+	return nil
+}
+
+func ToTelemetryChannelView(channel *models.TelemetryChannel) TelemetryChannelView {
+	return TelemetryChannelView{
+		Name:        channel.Name,
+		Unit:        channel.Unit,
+		Type:        channel.Type,
+		Min:         channel.MinValue,
+		Max:         channel.MaxValue,
+		Precision:   channel.Precision,
+		WarnMin:     channel.WarnMin,
+		WarnMax:     channel.WarnMax,
+		CritMin:     channel.CritMin,
+		CritMax:     channel.CritMax,
+		MaxRate:     channel.MaxRate,
+		Description: channel.Description,
+		CreatedAt:   channel.CreatedAt,
+		UpdatedAt:   channel.UpdatedAt,
+	}
+}
+
+// normalizeChannelValue проверяет значение по типу и диапазону канала и округляет его
+// до точности канала. Текст ошибки предназначен для отчета по строке.
+func normalizeChannelValue(channel models.TelemetryChannel, v float64) (float64, string) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, "not a finite number"
+	}
+
+	switch channel.Type {
+	case models.ChannelTypeBoolean:
+		if v != 0 && v != 1 {
+			return 0, "must be true/false or 1/0"
+		}
+		return v, ""
+	case models.ChannelTypeInteger:
+		if v != math.Trunc(v) {
+			return 0, "must be an integer"
+		}
+	}
+
+	if (channel.MinValue != nil && v < *channel.MinValue) || (channel.MaxValue != nil && v > *channel.MaxValue) {
+		return 0, "out of range " + formatChannelRange(channel)
+	}
+
+	scale := math.Pow(10, float64(channel.Precision))
+	return math.Round(v*scale) / scale, ""
+}
+
+func formatChannelRange(channel models.TelemetryChannel) string {
+	bound := func(v *float64, open string) string {
+		if v == nil {
+			return open
+		}
+		return FormatChannelValue(channel, *v)
+	}
+	return bound(channel.MinValue, "-inf") + ".." + bound(channel.MaxValue, "+inf")
+}
+
+// FormatChannelValue значение с точностью канала для CSV и отчетов
+func FormatChannelValue(channel models.TelemetryChannel, v float64) string {
+	if channel.Type == models.ChannelTypeBoolean {
+		if v != 0 {
+			return "true"
+		}
+		return "false"
+	}
+	return fmt.Sprintf("%.*f", channel.Precision, v)
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/utils"
+)
+
+var ErrTelemetryExportInvalid = errors.New("invalid telemetry export")
+
+const (
+	// telemetryExportBatchSize показаний за одно чтение курсора; после каждой пачки ответ сбрасывается клиенту
+	telemetryExportBatchSize = 1000
+	telemetryExportMaxRange  = 366 * 24 * time.Hour
+)
+
+// Форматы выгрузки: расширение файла и Content-Type
+var telemetryExportFormats = map[string][2]string{
+	"csv":    {"csv", "text/csv; charset=utf-8"},
+	"ndjson": {"ndjson", "application/x-ndjson"},
+	"json":   {"json", "application/json"},
+	"xlsx":   {"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+}
+
+// TelemetryExport подготовленная выгрузка: имя файла и тип известны до чтения показаний
+type TelemetryExport struct {
+	Format      string
+	ContentType string
+	Filename    string
+	From        time.Time
+	To          time.Time
+	// channels столбцы выгрузки в порядке реестра
+	channels []models.TelemetryChannel
+	// selected в показаниях остаются только эти каналы; пусто - все
+	selected bool
+}
+
+func (s *telemetryService) PrepareExport(ctx context.Context, format string, from, to time.Time, channels []string) (*TelemetryExport, error) {
+	if format == "excel" {
+		format = "xlsx"
+	}
+	spec, ok := telemetryExportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported format %q, use csv, ndjson, json or xlsx", ErrTelemetryExportInvalid, format)
+	}
+
+	if to.IsZero() {
+		to = time.Now().UTC()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: to is before from", ErrTelemetryExportInvalid)
+	}
+	if to.Sub(from) > telemetryExportMaxRange {
+		return nil, fmt.Errorf("%w: range is limited to 366 days", ErrTelemetryExportInvalid)
+	}
+
+	selected, err := s.channels.Resolve(ctx, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &TelemetryExport{
+		Format:      format,
+		ContentType: spec[1],
+		Filename: fmt.Sprintf("telemetry_%s_%s.%s",
+			from.UTC().Format("20060102T150405Z"), to.UTC().Format("20060102T150405Z"), spec[0]),
+		From:     from,
+		To:       to,
+		channels: selected,
+		selected: len(channels) > 0,
+	}
+
+	// Без явного списка - только каналы, которые есть в показаниях периода
+	if !export.selected {
+		names, err := s.repo.ChannelNames(ctx, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list telemetry channels in range: %w", err)
+		}
+		present := make(map[string]bool, len(names))
+		for _, name := range names {
+			present[name] = true
+		}
+		export.channels = export.channels[:0:0]
+		for _, channel := range selected {
+			if present[channel.Name] {
+				export.channels = append(export.channels, channel)
+			}
+		}
+	}
+
+	return export, nil
+}
+
+func (s *telemetryService) WriteExport(ctx context.Context, export *TelemetryExport, w io.Writer) error {
+	var names []string
+	if export.selected {
+		for _, channel := range export.channels {
+			names = append(names, channel.Name)
+		}
+	}
+
+	// stream читает показания пачками и отмечает их аномалии
+	stream := func(write func([]models.Telemetry, map[uint][]models.TelemetryAnomaly) error) error {
+		return s.repo.StreamByDateRange(ctx, export.From, export.To, names, telemetryExportBatchSize,
+			func(records []models.Telemetry) error {
+				anomalies, err := s.anomalies.ForRecords(ctx, records)
+				if err != nil {
+					return err
+				}
+				return write(records, anomalies)
+			})
+	}
+
+	switch export.Format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(telemetryCSVHeader(export.channels, true)); err != nil {
+			return err
+		}
+		err := stream(func(records []models.Telemetry, anomalies map[uint][]models.TelemetryAnomaly) error {
+			if err := writeTelemetryCSVRows(writer, export.channels, records, anomalies); err != nil {
+				return err
+			}
+			writer.Flush()
+			flushExport(w)
+			return writer.Error()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export telemetry: %w", err)
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case "ndjson":
+		buf := bufio.NewWriter(w)
+		err := stream(func(records []models.Telemetry, anomalies map[uint][]models.TelemetryAnomaly) error {
+			for _, record := range records {
+				if err := writeTelemetryJSONRecord(buf, export.channels, record, anomalies[record.ID]); err != nil {
+					return err
+				}
+				buf.WriteByte('\n')
+			}
+			if err := buf.Flush(); err != nil {
+				return err
+			}
+			flushExport(w)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export telemetry: %w", err)
+		}
+		return buf.Flush()
+
+	case "json":
+		buf := bufio.NewWriter(w)
+		if err := writeTelemetryJSONHead(buf, export); err != nil {
+			return err
+		}
+		count := 0
+		err := stream(func(records []models.Telemetry, anomalies map[uint][]models.TelemetryAnomaly) error {
+			for _, record := range records {
+				if count > 0 {
+					buf.WriteByte(',')
+				}
+				buf.WriteByte('\n')
+				if err := writeTelemetryJSONRecord(buf, export.channels, record, anomalies[record.ID]); err != nil {
+					return err
+				}
+				count++
+			}
+			if err := buf.Flush(); err != nil {
+				return err
+			}
+			flushExport(w)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export telemetry: %w", err)
+		}
+		// Число показаний известно только в конце - поле идет после массива
+		fmt.Fprintf(buf, "\n],\"count\":%d}\n", count)
+		return buf.Flush()
+
+	case "xlsx":
+		book, err := utils.NewExcelStream(export.channels)
+		if err != nil {
+			return fmt.Errorf("failed to create Excel workbook: %w", err)
+		}
+		defer book.Close()
+
+		if err := stream(book.WriteRecords); err != nil {
+			return fmt.Errorf("failed to export telemetry: %w", err)
+		}
+		if _, err := book.WriteTo(w); err != nil {
+			return fmt.Errorf("failed to write Excel workbook: %w", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: unsupported format %q", ErrTelemetryExportInvalid, export.Format)
+	}
+}
+
+// telemetryExportChannel описание столбца в конверте JSON-выгрузки
+type telemetryExportChannel struct {
+	Name      string `json:"name"`
+	Unit      string `json:"unit"`
+	Type      string `json:"type"`
+	Precision int    `json:"precision"`
+}
+
+// writeTelemetryJSONHead начало конверта JSON-выгрузки:
+// {"from", "to", "generated_at", "channels": [...], "telemetry": [...], "count"}
+func writeTelemetryJSONHead(buf *bufio.Writer, export *TelemetryExport) error {
+	channels := make([]telemetryExportChannel, 0, len(export.channels))
+	for _, channel := range export.channels {
+		channels = append(channels, telemetryExportChannel{
+			Name:      channel.Name,
+			Unit:      channel.Unit,
+			Type:      channel.Type,
+			Precision: channel.Precision,
+		})
+	}
+
+	head, err := json.Marshal(struct {
+		From        time.Time                `json:"from"`
+		To          time.Time                `json:"to"`
+		GeneratedAt time.Time                `json:"generated_at"`
+		Channels    []telemetryExportChannel `json:"channels"`
+	}{
+		From:        export.From.UTC(),
+		To:          export.To.UTC(),
+		GeneratedAt: time.Now().UTC(),
+		Channels:    channels,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Открываем массив показаний внутри того же объекта
+	buf.Write(head[:len(head)-1])
+	buf.WriteString(`,"telemetry":[`)
+	return nil
+}
+
+// telemetryExportAnomaly отметка аномалии показания в JSON-выгрузках
+type telemetryExportAnomaly struct {
+	Channel  string `json:"channel"`
+	Detector string `json:"detector"`
+	Severity string `json:"severity"`
+}
+
+// writeTelemetryJSONRecord объект показания в виде, который принимает /telemetry/ingest:
+// {"recorded_at": ..., "source": ..., "values": {канал: значение}, "anomalies": [...]} с точностью канала.
+// Массив anomalies при приеме пропускается.
+func writeTelemetryJSONRecord(buf *bufio.Writer, channels []models.TelemetryChannel, record models.Telemetry,
+	anomalies []models.TelemetryAnomaly) error {
+	recordedAt, _ := json.Marshal(record.RecordedAt.UTC().Format(time.RFC3339Nano))
+	source, _ := json.Marshal(record.SourceFile)
+
+	buf.WriteString(`{"recorded_at":`)
+	buf.Write(recordedAt)
+	buf.WriteString(`,"source":`)
+	buf.Write(source)
+	buf.WriteString(`,"values":{`)
+	written := 0
+	for _, channel := range channels {
+		v, ok := record.Values[channel.Name]
+		if !ok {
+			continue
+		}
+		if written > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(channel.Name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		// Точность канала: "true"/"false" или число - уже корректный JSON
+		buf.WriteString(FormatChannelValue(channel, v))
+		written++
+	}
+
+	marks := make([]telemetryExportAnomaly, 0, len(anomalies))
+	for _, anomaly := range anomalies {
+		marks = append(marks, telemetryExportAnomaly{
+			Channel:  anomaly.Channel,
+			Detector: anomaly.Detector,
+			Severity: anomaly.Severity,
+		})
+	}
+	encoded, err := json.Marshal(marks)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(`},"anomalies":`)
+	buf.Write(encoded)
+	buf.WriteByte('}')
+	return nil
+}
+
+// flushExport отправляет клиенту уже записанную часть ответа, если w это поддерживает
+func flushExport(w io.Writer) {
+	if flusher, ok := w.(interface{ Flush() }); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,523 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrTelemetryImportInvalid файл целиком не годится для импорта (формат, заголовок, размер)
+var ErrTelemetryImportInvalid = errors.New("invalid telemetry import file")
+
+const (
+	TelemetryImportMaxBytes = 20 << 20
+	TelemetryImportMaxRows  = 100000
+	// telemetryImportMaxErrors больше ошибок в отчет не пишем, только считаем
+	telemetryImportMaxErrors = 1000
+	// telemetryFutureSkew показания из будущего дальше этого считаем ошибкой часов
+	telemetryFutureSkew = time.Hour
+)
+
+// Служебные столбцы: как пишет saveToCSV и как подписывает столбцы выгрузка в Excel.
+// Остальные столбцы сопоставляются с каналами реестра по имени ("voltage" или "voltage (V)").
+var telemetryImportColumns = map[string]string{
+	"recorded_at": "recorded_at",
+	"timestamp":   "recorded_at",
+	"source_file": "source_file",
+	"source file": "source_file",
+	"source":      "source_file",
+	"created_at":  "created_at",
+	"created at":  "created_at",
+	"anomalies":   "anomalies",
+}
+
+// telemetryImportHeader номера столбцов файла; sourceFile = -1, если столбца нет
+type telemetryImportHeader struct {
+	recordedAt int
+	sourceFile int
+	channels   []telemetryImportChannel
+}
+
+type telemetryImportChannel struct {
+	index   int
+	channel models.TelemetryChannel
+}
+
+var telemetryTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+}
+
+// TelemetryImportReport итог импорта с ошибками по строкам. Номера строк -
+// как в файле: заголовок - строка 1, первая строка данных - 2.
+type TelemetryImportReport struct {
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	// Channels каналы, найденные в заголовке; IgnoredColumns - не найденные в реестре
+	Channels       []string `json:"channels"`
+	IgnoredColumns []string `json:"ignored_columns,omitempty"`
+	TotalRows      int      `json:"total_rows"`
+	Imported       int      `json:"imported"`
+	Duplicates     int      `json:"duplicates"`
+	Invalid        int      `json:"invalid"`
+	// Anomalies аномальных значений среди загруженных показаний
+	Anomalies int `json:"anomalies"`
+	// ErrorsTruncated в Errors попали не все отклоненные строки
+	ErrorsTruncated bool                `json:"errors_truncated,omitempty"`
+	Errors          []TelemetryRowError `json:"errors"`
+}
+
+type TelemetryRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r *TelemetryImportReport) reject(rowErr TelemetryRowError) {
+	if len(r.Errors) >= telemetryImportMaxErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, rowErr)
+}
+
+// ImportTelemetry загружает показания из CSV, XLSX, JSON или NDJSON. Некорректные строки и дубликаты
+// (по recorded_at и источнику - в файле и в базе) пропускаются и попадают в отчет.
+func (s *telemetryService) ImportTelemetry(ctx context.Context, filename string, r io.Reader) (*TelemetryImportReport, error) {
+	data, err := io.ReadAll(io.LimitReader(r, TelemetryImportMaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if len(data) > TelemetryImportMaxBytes {
+		return nil, fmt.Errorf("%w: file is larger than %d MB", ErrTelemetryImportInvalid, TelemetryImportMaxBytes>>20)
+	}
+
+	report := &TelemetryImportReport{
+		Filename: filepath.Base(filename),
+		Channels: []string{},
+		Errors:   []TelemetryRowError{},
+	}
+
+	var rows [][]string
+	var items []telemetryJSONItem
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ext == ".xlsx" || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		report.Format = "xlsx"
+		rows, err = readXLSXRows(data)
+	case ext == ".csv" || ext == ".txt":
+		report.Format = "csv"
+		rows, err = readCSVRows(data)
+	case ext == ".json":
+		report.Format = "json"
+		items, err = readJSONItems(data)
+	case ext == ".ndjson" || ext == ".jsonl":
+		report.Format = "ndjson"
+		items, err = readNDJSONItems(data)
+	default:
+		return nil, fmt.Errorf("%w: unsupported file type, use .csv, .xlsx, .json or .ndjson", ErrTelemetryImportInvalid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := s.channels.Registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Источник по умолчанию - имя загруженного файла
+	defaultSource := report.Filename
+	now := time.Now().UTC()
+
+	var records []models.Telemetry
+	var recordRows []int
+	seen := make(map[repository.TelemetryKey]int)
+
+	// accept учитывает разобранную строку: ошибка, повтор внутри файла или новое показание
+	accept := func(rowNum int, record models.Telemetry, rowErr *TelemetryRowError) {
+		report.TotalRows++
+		if rowErr != nil {
+			rowErr.Row = rowNum
+			report.Invalid++
+			report.reject(*rowErr)
+			return
+		}
+
+		key := repository.KeyOf(record)
+		if first, ok := seen[key]; ok {
+			report.Duplicates++
+			report.reject(TelemetryRowError{Row: rowNum, Message: fmt.Sprintf("duplicate of row %d", first)})
+			return
+		}
+		seen[key] = rowNum
+
+		records = append(records, record)
+		recordRows = append(recordRows, rowNum)
+	}
+
+	if report.Format == "json" || report.Format == "ndjson" {
+		if len(items) == 0 {
+			return nil, fmt.Errorf("%w: file has no readings", ErrTelemetryImportInvalid)
+		}
+		if len(items) > TelemetryImportMaxRows {
+			return nil, fmt.Errorf("%w: more than %d readings", ErrTelemetryImportInvalid, TelemetryImportMaxRows)
+		}
+
+		// Объекты показаний - как у /telemetry/ingest и выгрузки JSON/NDJSON
+		channels := make(map[string]bool)
+		for _, item := range items {
+			record, rowErr := parseTelemetryJSONItem(item.raw, defaultSource, now, registry)
+			if rowErr == nil {
+				for name := range record.Values {
+					channels[name] = true
+				}
+			}
+			accept(item.row, record, rowErr)
+		}
+		for name := range channels {
+			report.Channels = append(report.Channels, name)
+		}
+		sort.Strings(report.Channels)
+	} else {
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("%w: file is empty", ErrTelemetryImportInvalid)
+		}
+		if len(rows)-1 > TelemetryImportMaxRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrTelemetryImportInvalid, TelemetryImportMaxRows)
+		}
+
+		header, err := mapTelemetryHeader(rows[0], registry, report)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, row := range rows[1:] {
+			if isBlankRow(row) {
+				continue
+			}
+			record, rowErr := parseTelemetryRow(row, header, defaultSource, now)
+			accept(i+2, record, rowErr)
+		}
+	}
+
+	if len(records) > 0 {
+		// Уже сохраненные показания пропускает уникальный ключ в базе - в том числе
+		// записанные параллельным приемом, пока шел этот импорт
+		created, err := s.repo.BatchCreate(ctx, records)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store telemetry: %w", err)
+		}
+		report.Duplicates += len(records) - created
+
+		fresh := records[:0]
+		for i, record := range records {
+			if record.ID == 0 {
+				report.reject(TelemetryRowError{Row: recordRows[i], Message: "reading is already stored"})
+				continue
+			}
+			fresh = append(fresh, record)
+		}
+		records = fresh
+	}
+
+	if len(records) > 0 {
+		// Ошибка детектора не отменяет уже сохраненный импорт
+		anomalies, err := s.anomalies.Detect(ctx, records)
+		if err != nil {
+			log.Printf("Failed to detect anomalies in %s: %v", report.Filename, err)
+		}
+		report.Anomalies = len(anomalies)
+	}
+	report.Imported = len(records)
+
+	log.Printf("Telemetry imported from %s: %d of %d rows (%d duplicates, %d invalid)",
+		report.Filename, report.Imported, report.TotalRows, report.Duplicates, report.Invalid)
+
+	return report, nil
+}
+
+func readCSVRows(data []byte) ([][]string, error) {
+	// BOM от Excel мешает распознать первый заголовок
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	// Разделитель ; - так CSV сохраняет Excel в локалях с десятичной запятой
+	if firstLine, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTelemetryImportInvalid, err)
+	}
+	return rows, nil
+}
+
+// telemetryJSONItem объект показания и его номер: элемент массива или строка NDJSON
+type telemetryJSONItem struct {
+	row int
+	raw json.RawMessage
+}
+
+// readJSONItems показания из выгрузки JSON (конверт с массивом telemetry) или просто массива объектов
+func readJSONItems(data []byte) ([]telemetryJSONItem, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: file is empty", ErrTelemetryImportInvalid)
+	}
+
+	var raw []json.RawMessage
+	switch data[0] {
+	case '[':
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("%w: malformed JSON: %v", ErrTelemetryImportInvalid, err)
+		}
+	case '{':
+		var envelope struct {
+			Telemetry []json.RawMessage `json:"telemetry"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("%w: malformed JSON: %v", ErrTelemetryImportInvalid, err)
+		}
+		if envelope.Telemetry == nil {
+			return nil, fmt.Errorf("%w: JSON object has no telemetry array", ErrTelemetryImportInvalid)
+		}
+		raw = envelope.Telemetry
+	default:
+		return nil, fmt.Errorf("%w: expected a JSON array or export envelope", ErrTelemetryImportInvalid)
+	}
+
+	items := make([]telemetryJSONItem, len(raw))
+	for i := range raw {
+		items[i] = telemetryJSONItem{row: i + 1, raw: raw[i]}
+	}
+	return items, nil
+}
+
+// readNDJSONItems объект на строку; пустые строки пропускаются, номер - строка файла
+func readNDJSONItems(data []byte) ([]telemetryJSONItem, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	var items []telemetryJSONItem
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		items = append(items, telemetryJSONItem{row: line, raw: append(json.RawMessage(nil), text...)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTelemetryImportInvalid, err)
+	}
+	return items, nil
+}
+
+// parseTelemetryJSONItem объект показания с проверкой по реестру, как при приеме по HTTP;
+// без поля source источником считается файл
+func parseTelemetryJSONItem(raw []byte, defaultSource string, now time.Time,
+	registry map[string]models.TelemetryChannel) (models.Telemetry, *TelemetryRowError) {
+	reading, rowErr := decodeIngestObject(raw)
+	if rowErr != nil {
+		return models.Telemetry{}, rowErr
+	}
+
+	source := reading.Source
+	if source == "" {
+		source = defaultSource
+	}
+	if len(source) > 255 {
+		return models.Telemetry{}, &TelemetryRowError{Column: "source", Message: "longer than 255 characters"}
+	}
+	return reading.toRecord(source, now, registry)
+}
+
+// readXLSXRows строки листа Telemetry (как у выгрузки), иначе первого листа
+func readXLSXRows(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTelemetryImportInvalid, err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("%w: workbook has no sheets", ErrTelemetryImportInvalid)
+	}
+	sheet := sheets[0]
+	for _, name := range sheets {
+		if name == "Telemetry" {
+			sheet = name
+		}
+	}
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTelemetryImportInvalid, err)
+	}
+	return rows, nil
+}
+
+// mapTelemetryHeader номера служебных столбцов и столбцов каналов; source_file необязателен
+func mapTelemetryHeader(row []string, registry map[string]models.TelemetryChannel, report *TelemetryImportReport) (*telemetryImportHeader, error) {
+	header := &telemetryImportHeader{
+		recordedAt: -1,
+		sourceFile: -1,
+	}
+	seen := make(map[string]bool)
+
+	for i, name := range row {
+		label := strings.ToLower(strings.TrimSpace(name))
+		if label == "" {
+			continue
+		}
+
+		column, ok := telemetryImportColumns[label]
+		if !ok {
+			// "voltage (V)" -> канал voltage
+			if open := strings.LastIndex(label, " ("); open > 0 && strings.HasSuffix(label, ")") {
+				label = label[:open]
+			}
+			channel, found := registry[strings.ReplaceAll(label, " ", "_")]
+			if !found {
+				report.IgnoredColumns = append(report.IgnoredColumns, strings.TrimSpace(name))
+				continue
+			}
+			column = channel.Name
+			header.channels = append(header.channels, telemetryImportChannel{index: i, channel: channel})
+			report.Channels = append(report.Channels, channel.Name)
+		}
+
+		if seen[column] {
+			return nil, fmt.Errorf("%w: column %s appears twice", ErrTelemetryImportInvalid, column)
+		}
+		seen[column] = true
+
+		switch column {
+		case "recorded_at":
+			header.recordedAt = i
+		case "source_file":
+			header.sourceFile = i
+		}
+	}
+
+	if header.recordedAt < 0 || len(header.channels) == 0 {
+		return nil, fmt.Errorf("%w: header must contain recorded_at and at least one registered channel (and optionally source_file)", ErrTelemetryImportInvalid)
+	}
+	return header, nil
+}
+
+func parseTelemetryRow(row []string, header *telemetryImportHeader, defaultSource string, now time.Time) (models.Telemetry, *TelemetryRowError) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	record := models.Telemetry{SourceFile: defaultSource, Values: models.TelemetryValues{}}
+
+	rawTime := cell(header.recordedAt)
+	if rawTime == "" {
+		return record, &TelemetryRowError{Column: "recorded_at", Message: "value is required"}
+	}
+	recordedAt, err := parseTelemetryTime(rawTime)
+	if err != nil {
+		return record, &TelemetryRowError{Column: "recorded_at", Value: rawTime,
+			Message: "invalid time, use YYYY-MM-DD HH:MM:SS or RFC 3339"}
+	}
+	if recordedAt.After(now.Add(telemetryFutureSkew)) {
+		return record, &TelemetryRowError{Column: "recorded_at", Value: rawTime, Message: "time is in the future"}
+	}
+	record.RecordedAt = recordedAt
+
+	// Пустая ячейка - канал в этой строке не измерялся
+	for _, column := range header.channels {
+		channel := column.channel
+		raw := cell(column.index)
+		if raw == "" {
+			continue
+		}
+		v, ok := parseChannelText(channel, raw)
+		if !ok {
+			return record, &TelemetryRowError{Column: channel.Name, Value: raw, Message: "not a number"}
+		}
+		value, problem := normalizeChannelValue(channel, v)
+		if problem != "" {
+			return record, &TelemetryRowError{Column: channel.Name, Value: raw, Message: problem}
+		}
+		record.Values[channel.Name] = value
+	}
+	if len(record.Values) == 0 {
+		return record, &TelemetryRowError{Message: "row has no channel values"}
+	}
+
+	if source := cell(header.sourceFile); source != "" {
+		if len(source) > 255 {
+			return record, &TelemetryRowError{Column: "source_file", Message: "longer than 255 characters"}
+		}
+		record.SourceFile = source
+	}
+
+	return record, nil
+}
+
+// parseChannelText число из ячейки; для логических каналов также true/false, yes/no
+func parseChannelText(channel models.TelemetryChannel, raw string) (float64, bool) {
+	if channel.Type == models.ChannelTypeBoolean {
+		switch strings.ToLower(raw) {
+		case "true", "yes", "t":
+			return 1, true
+		case "false", "no", "f":
+			return 0, true
+		}
+	}
+
+	// Десятичная запятая из русской локали Excel
+	v, err := strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+	return v, err == nil
+}
+
+// parseTelemetryTime время без пояса считается UTC, как его пишет saveToCSV
+func parseTelemetryTime(value string) (time.Time, error) {
+	for _, layout := range telemetryTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Truncate(time.Microsecond), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
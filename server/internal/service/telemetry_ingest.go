@@ -0,0 +1,505 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+
+	"golang.org/x/sync/semaphore"
+)
+
+var (
+	ErrTelemetryIngestUnauthorized = errors.New("invalid telemetry ingest token")
+	ErrTelemetryIngestInvalid      = errors.New("invalid telemetry ingest request")
+	ErrTelemetryIngestTooLarge     = errors.New("telemetry ingest body is too large")
+	// ErrTelemetryIngestBusy очередь записи заполнена - клиенту стоит повторить позже
+	ErrTelemetryIngestBusy = errors.New("telemetry ingest queue is full")
+)
+
+// Форматы тела запроса
+const (
+	IngestFormatJSON   = "json"
+	IngestFormatNDJSON = "ndjson"
+	IngestFormatLine   = "line"
+)
+
+const (
+	// telemetryIngestMaxFailures после стольких неудачных попыток пачка пишется по одному показанию
+	telemetryIngestMaxFailures = 5
+	// telemetryIngestMaxBackoff предел паузы перед повтором пачки
+	telemetryIngestMaxBackoff = time.Minute
+)
+
+type TelemetryIngestConfig struct {
+	// Tokens токен доступа по имени источника (наземной станции)
+	Tokens         map[string]string
+	BatchSize      int
+	FlushInterval  time.Duration
+	QueueSize      int
+	MaxBodyBytes   int64
+	EnqueueTimeout time.Duration
+	// DeadLetterDir каталог NDJSON-файлов с показаниями, которые не удалось записать
+	DeadLetterDir string
+}
+
+type TelemetryIngestService interface {
+	// Authenticate имя источника по токену
+	Authenticate(token string) (string, error)
+	// Ingest разбирает показания и ставит корректные в очередь записи
+	Ingest(ctx context.Context, source string, req TelemetryIngestRequest) (*TelemetryIngestResult, error)
+	// Run пишет очередь в базу пачками до закрытия stop, затем дописывает остаток
+	Run(stop <-chan struct{})
+}
+
+type TelemetryIngestRequest struct {
+	ContentType     string
+	ContentEncoding string
+	// Format явный формат (json, ndjson, line); пусто - по Content-Type
+	Format string
+	// Precision единица меток времени line protocol: ns (по умолчанию), us, ms, s
+	Precision string
+	Body      io.Reader
+}
+
+type TelemetryIngestResult struct {
+	Source   string `json:"source"`
+	Format   string `json:"format"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	// QueueDepth показаний в очереди на запись после приема этого запроса
+	QueueDepth      int64               `json:"queue_depth"`
+	ErrorsTruncated bool                `json:"errors_truncated,omitempty"`
+	Errors          []TelemetryRowError `json:"errors"`
+}
+
+func (r *TelemetryIngestResult) reject(rowErr TelemetryRowError) {
+	r.Rejected++
+	if len(r.Errors) >= telemetryImportMaxErrors {
+		r.ErrorsTruncated = true
+		return
+	}
+	r.Errors = append(r.Errors, rowErr)
+}
+
+type telemetryIngestService struct {
+	repo      repository.TelemetryRepository
+	channels  TelemetryChannelService
+	anomalies TelemetryAnomalyService
+	alerts    TelemetryAlertService
+	config    TelemetryIngestConfig
+
+	// queue ограничивает число принятых, но еще не записанных показаний
+	queue   *semaphore.Weighted
+	pending atomic.Int64
+
+	mu     sync.Mutex
+	buffer []models.Telemetry
+	kick   chan struct{}
+
+	// headFailures неудачных попыток записать пачку из начала буфера, retryAt - время
+	// следующей попытки; меняются только горутиной Run
+	headFailures int
+	retryAt      time.Time
+}
+
+func NewTelemetryIngestService(repo repository.TelemetryRepository, channels TelemetryChannelService,
+	anomalies TelemetryAnomalyService, alerts TelemetryAlertService, config TelemetryIngestConfig) TelemetryIngestService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.QueueSize < config.BatchSize {
+		config.QueueSize = config.BatchSize * 100
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = 10 << 20
+	}
+	if config.EnqueueTimeout <= 0 {
+		config.EnqueueTimeout = 2 * time.Second
+	}
+	if config.DeadLetterDir == "" {
+		config.DeadLetterDir = "./data/ingest-deadletter"
+	}
+	if len(config.Tokens) == 0 {
+		log.Println("Telemetry ingest: no TELEMETRY_INGEST_TOKENS configured, all requests will be rejected")
+	}
+
+	return &telemetryIngestService{
+		repo:      repo,
+		channels:  channels,
+		anomalies: anomalies,
+		alerts:    alerts,
+		config:    config,
+		queue:     semaphore.NewWeighted(int64(config.QueueSize)),
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+func (s *telemetryIngestService) Authenticate(token string) (string, error) {
+	if token == "" {
+		return "", ErrTelemetryIngestUnauthorized
+	}
+
+	// Сравниваем со всеми токенами за постоянное время, чтобы не подсказывать совпадение
+	source := ""
+	for name, expected := range s.config.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			source = name
+		}
+	}
+	if source == "" {
+		return "", ErrTelemetryIngestUnauthorized
+	}
+	return source, nil
+}
+
+func (s *telemetryIngestService) Ingest(ctx context.Context, source string, req TelemetryIngestRequest) (*TelemetryIngestResult, error) {
+	format, err := ingestFormat(req.Format, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.readBody(req.Body, req.ContentEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := s.channels.Registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TelemetryIngestResult{
+		Source: source,
+		Format: format,
+		Errors: []TelemetryRowError{},
+	}
+
+	now := time.Now().UTC()
+	var readings []models.Telemetry
+	accept := func(row int, reading ingestReading) {
+		record, rowErr := reading.toRecord(source, now, registry)
+		if rowErr != nil {
+			rowErr.Row = row
+			result.reject(*rowErr)
+			return
+		}
+		readings = append(readings, record)
+	}
+
+	switch format {
+	case IngestFormatJSON:
+		err = parseIngestJSON(body, accept, result)
+	case IngestFormatNDJSON:
+		err = parseIngestNDJSON(body, accept, result)
+	case IngestFormatLine:
+		err = parseIngestLines(body, req.Precision, now, accept, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(readings) > s.config.QueueSize {
+		return nil, fmt.Errorf("%w: %d readings in one request, split into requests of at most %d",
+			ErrTelemetryIngestInvalid, len(readings), s.config.QueueSize)
+	}
+
+	if len(readings) > 0 {
+		if err := s.enqueue(ctx, readings); err != nil {
+			return nil, err
+		}
+	}
+	result.Accepted = len(readings)
+	result.QueueDepth = s.pending.Load()
+
+	return result, nil
+}
+
+// readBody читает тело с распаковкой gzip; предел действует на распакованный размер
+func (s *telemetryIngestService) readBody(r io.Reader, encoding string) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad gzip body: %v", ErrTelemetryIngestInvalid, err)
+		}
+		defer gz.Close()
+		r = gz
+	default:
+		return nil, fmt.Errorf("%w: unsupported Content-Encoding %q, use gzip", ErrTelemetryIngestInvalid, encoding)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.config.MaxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read body: %v", ErrTelemetryIngestInvalid, err)
+	}
+	if int64(len(data)) > s.config.MaxBodyBytes {
+		return nil, fmt.Errorf("%w: limit is %d MB", ErrTelemetryIngestTooLarge, s.config.MaxBodyBytes>>20)
+	}
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), nil
+}
+
+// ingestFormat формат по явному параметру или по Content-Type
+func ingestFormat(explicit, contentType string) (string, error) {
+	switch explicit {
+	case IngestFormatJSON, IngestFormatNDJSON, IngestFormatLine:
+		return explicit, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w: unknown format %q, use json, ndjson or line", ErrTelemetryIngestInvalid, explicit)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return IngestFormatJSON, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return IngestFormatNDJSON, nil
+	case "", "text/plain", "application/x-influx-line-protocol":
+		return IngestFormatLine, nil
+	}
+	return "", fmt.Errorf("%w: unsupported Content-Type %q", ErrTelemetryIngestInvalid, mediaType)
+}
+
+// enqueue резервирует место в очереди (все или ничего) и добавляет показания в буфер.
+// Если место не освободилось за EnqueueTimeout, запрос отклоняется - это и есть
+// обратное давление на клиентов, когда база не успевает.
+func (s *telemetryIngestService) enqueue(ctx context.Context, readings []models.Telemetry) error {
+	waitCtx, cancel := context.WithTimeout(ctx, s.config.EnqueueTimeout)
+	defer cancel()
+
+	n := int64(len(readings))
+	if err := s.queue.Acquire(waitCtx, n); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrTelemetryIngestBusy
+	}
+	s.pending.Add(n)
+
+	s.mu.Lock()
+	s.buffer = append(s.buffer, readings...)
+	full := len(s.buffer) >= s.config.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *telemetryIngestService) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(false)
+		case <-s.kick:
+			s.flush(false)
+		case <-stop:
+			s.flush(true)
+			return
+		}
+	}
+}
+
+// flush пишет буфер пачками по BatchSize. При ошибке базы пачка возвращается в начало
+// буфера, место в очереди не освобождается, а следующая попытка откладывается с растущей
+// паузой. Пачка, которая не записалась telemetryIngestMaxFailures раз, пишется по одному
+// показанию; не записанные показания сохраняются в каталог недоставленных.
+func (s *telemetryIngestService) flush(final bool) {
+	for {
+		if !final && time.Now().Before(s.retryAt) {
+			return
+		}
+
+		s.mu.Lock()
+		n := len(s.buffer)
+		if n == 0 {
+			s.mu.Unlock()
+			return
+		}
+		if n > s.config.BatchSize {
+			n = s.config.BatchSize
+		}
+		batch := make([]models.Telemetry, n)
+		copy(batch, s.buffer[:n])
+		s.buffer = s.buffer[n:]
+		s.mu.Unlock()
+
+		if err := s.writeBatch(batch); err != nil {
+			s.headFailures++
+			if s.headFailures < telemetryIngestMaxFailures {
+				backoff := s.config.FlushInterval << (s.headFailures - 1)
+				if backoff > telemetryIngestMaxBackoff {
+					backoff = telemetryIngestMaxBackoff
+				}
+				log.Printf("Telemetry ingest: failed to write %d readings (attempt %d, retry in %v): %v", n, s.headFailures, backoff, err)
+				s.mu.Lock()
+				s.buffer = append(batch, s.buffer...)
+				s.mu.Unlock()
+				if final {
+					time.Sleep(time.Second)
+					continue
+				}
+				s.retryAt = time.Now().Add(backoff)
+				return
+			}
+			log.Printf("Telemetry ingest: %d readings failed %d times, writing them one by one: %v", n, s.headFailures, err)
+			if !s.salvage(batch) && final {
+				// База недоступна: при остановке не ждем попыток по каждой пачке, сохраняем весь остаток
+				s.mu.Lock()
+				rest := s.buffer
+				s.buffer = nil
+				s.mu.Unlock()
+				if len(rest) > 0 {
+					s.saveDeadLetter(rest, err)
+					n += len(rest)
+				}
+			}
+		}
+		s.headFailures = 0
+		s.retryAt = time.Time{}
+
+		s.pending.Add(-int64(n))
+		s.queue.Release(int64(n))
+	}
+}
+
+// writeBatch пишет пачку без показаний, которые уже есть в базе или повторяются в пачке:
+// клиент, не дождавшийся ответа, присылает те же показания повторно
+func (s *telemetryIngestService) writeBatch(batch []models.Telemetry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Копия: ID, назначенные в неудачной транзакции, не должны попасть в повтор
+	records := append([]models.Telemetry(nil), batch...)
+	created, err := s.repo.BatchCreate(ctx, records)
+	if err != nil {
+		return err
+	}
+	if skipped := len(records) - created; skipped > 0 {
+		log.Printf("Telemetry ingest: skipped %d duplicate readings", skipped)
+	}
+
+	fresh := records[:0]
+	for _, record := range records {
+		if record.ID != 0 {
+			fresh = append(fresh, record)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	// Детектор видит записанную пачку вместе с ID показаний
+	if _, err := s.anomalies.Detect(ctx, fresh); err != nil {
+		log.Printf("Telemetry ingest: failed to detect anomalies: %v", err)
+	}
+	// Правила тревоги проверяются сразу; импорт и генератор подхватит плановая проверка
+	if err := s.alerts.Evaluate(ctx, fresh); err != nil {
+		log.Printf("Telemetry ingest: failed to evaluate alert rules: %v", err)
+	}
+	return nil
+}
+
+// salvage пишет пачку по одному показанию, чтобы одно ошибочное показание (например,
+// нарушающее ограничение базы) не держало остальные. Не записанные показания уходят
+// в каталог недоставленных; после telemetryIngestMaxFailures ошибок подряд база считается
+// недоступной и туда уходит весь остаток пачки. false - не записано ни одно показание.
+func (s *telemetryIngestService) salvage(batch []models.Telemetry) bool {
+	var failed []models.Telemetry
+	var lastErr error
+	consecutive, written := 0, 0
+	for i := range batch {
+		if consecutive >= telemetryIngestMaxFailures {
+			failed = append(failed, batch[i:]...)
+			break
+		}
+		if err := s.writeBatch(batch[i : i+1]); err != nil {
+			failed = append(failed, batch[i])
+			lastErr = err
+			consecutive++
+			continue
+		}
+		consecutive = 0
+		written++
+	}
+	if len(failed) > 0 {
+		s.saveDeadLetter(failed, lastErr)
+	}
+	return written > 0
+}
+
+func (s *telemetryIngestService) saveDeadLetter(records []models.Telemetry, cause error) {
+	path, err := s.deadLetter(records)
+	if err != nil {
+		log.Printf("Telemetry ingest: LOST %d readings, failed to save dead letter file: %v (write error: %v)", len(records), err, cause)
+		return
+	}
+	log.Printf("Telemetry ingest: moved %d readings to %s: %v", len(records), path, cause)
+}
+
+// deadLetter сохраняет показания в NDJSON в формате /telemetry/ingest: после устранения
+// причины файл можно загрузить через /telemetry/import
+func (s *telemetryIngestService) deadLetter(records []models.Telemetry) (string, error) {
+	if err := os.MkdirAll(s.config.DeadLetterDir, 0755); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(s.config.DeadLetterDir, "ingest_"+time.Now().UTC().Format("20060102T150405Z")+"_*.ndjson")
+	if err != nil {
+		return "", err
+	}
+
+	buf := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buf)
+	for _, record := range records {
+		err = encoder.Encode(struct {
+			RecordedAt string                 `json:"recorded_at"`
+			Source     string                 `json:"source"`
+			Values     models.TelemetryValues `json:"values"`
+		}{
+			RecordedAt: record.RecordedAt.UTC().Format(time.RFC3339Nano),
+			Source:     record.SourceFile,
+			Values:     record.Values,
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
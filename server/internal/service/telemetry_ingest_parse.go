@@ -0,0 +1,369 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cassiopeia/internal/models"
+)
+
+// ingestReading одно показание до проверки по реестру каналов
+type ingestReading struct {
+	RecordedAt *time.Time
+	RawTime    string
+	Values     map[string]float64
+	Source     string
+}
+
+// toRecord проверяет показание по реестру каналов, как и импорт файлов.
+// Показание чужого источника отклоняется: токен дает право писать только от своего имени.
+func (r ingestReading) toRecord(source string, now time.Time, registry map[string]models.TelemetryChannel) (models.Telemetry, *TelemetryRowError) {
+	record := models.Telemetry{SourceFile: source, Values: models.TelemetryValues{}}
+
+	if r.Source != "" && r.Source != source {
+		return record, &TelemetryRowError{Column: "source", Value: r.Source,
+			Message: fmt.Sprintf("token is issued for source %q", source)}
+	}
+
+	if r.RecordedAt == nil {
+		if r.RawTime != "" {
+			return record, &TelemetryRowError{Column: "recorded_at", Value: r.RawTime,
+				Message: "invalid time, use RFC 3339 or unix seconds"}
+		}
+		return record, &TelemetryRowError{Column: "recorded_at", Message: "value is required"}
+	}
+	if r.RecordedAt.After(now.Add(telemetryFutureSkew)) {
+		return record, &TelemetryRowError{Column: "recorded_at", Value: r.RecordedAt.Format(time.RFC3339Nano),
+			Message: "time is in the future"}
+	}
+	record.RecordedAt = r.RecordedAt.UTC().Truncate(time.Microsecond)
+
+	if len(r.Values) == 0 {
+		return record, &TelemetryRowError{Message: "reading has no channel values"}
+	}
+
+	// Порядок имен фиксирован, чтобы ошибка в отчете не зависела от обхода map
+	names := make([]string, 0, len(r.Values))
+	for name := range r.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := r.Values[name]
+		channel, ok := registry[name]
+		if !ok {
+			return record, &TelemetryRowError{Column: name, Message: "unknown channel, register it in /telemetry/channels"}
+		}
+		value, problem := normalizeChannelValue(channel, v)
+		if problem != "" {
+			return record, &TelemetryRowError{Column: name, Value: strconv.FormatFloat(v, 'g', -1, 64), Message: problem}
+		}
+		record.Values[name] = value
+	}
+
+	return record, nil
+}
+
+// Служебные ключи объекта показания; остальные ключи - значения каналов
+var ingestTimeKeys = []string{"recorded_at", "time", "timestamp"}
+
+// decodeIngestObject объект показания: значения каналов - на верхнем уровне
+// или во вложенном объекте values
+func decodeIngestObject(raw []byte) (ingestReading, *TelemetryRowError) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		return ingestReading{}, &TelemetryRowError{Message: "not a JSON object"}
+	}
+
+	reading := ingestReading{Values: make(map[string]float64)}
+
+	for _, key := range ingestTimeKeys {
+		rawTime, ok := obj[key]
+		delete(obj, key)
+		if !ok || string(rawTime) == "null" || reading.RawTime != "" {
+			continue
+		}
+		reading.RawTime = string(rawTime)
+		if t, ok := parseIngestJSONTime(rawTime); ok {
+			reading.RecordedAt = &t
+		}
+	}
+
+	if rawSource, ok := obj["source"]; ok {
+		delete(obj, "source")
+		if err := json.Unmarshal(rawSource, &reading.Source); err != nil {
+			return reading, &TelemetryRowError{Column: "source", Message: "must be a string"}
+		}
+	}
+
+	// Отметки аномалий из выгрузки JSON/NDJSON не являются показаниями; канал с таким
+	// именем по-прежнему принимается, если значение не массив
+	if rawAnomalies, ok := obj["anomalies"]; ok && bytes.HasPrefix(bytes.TrimSpace(rawAnomalies), []byte("[")) {
+		delete(obj, "anomalies")
+	}
+
+	if rawValues, ok := obj["values"]; ok {
+		delete(obj, "values")
+		var nested map[string]json.RawMessage
+		if err := json.Unmarshal(rawValues, &nested); err != nil {
+			return reading, &TelemetryRowError{Column: "values", Message: "must be an object"}
+		}
+		for key, value := range nested {
+			obj[key] = value
+		}
+	}
+
+	for key, rawValue := range obj {
+		switch string(rawValue) {
+		case "null":
+			continue
+		case "true":
+			reading.Values[key] = 1
+			continue
+		case "false":
+			reading.Values[key] = 0
+			continue
+		}
+		var v float64
+		if err := json.Unmarshal(rawValue, &v); err != nil {
+			return reading, &TelemetryRowError{Column: key, Value: string(rawValue), Message: "must be a number or boolean"}
+		}
+		reading.Values[key] = v
+	}
+
+	return reading, nil
+}
+
+// parseIngestJSONTime строка в форматах импорта или число unix-секунд (дробное допускается)
+func parseIngestJSONTime(raw json.RawMessage) (time.Time, bool) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		t, err := parseTelemetryTime(strings.TrimSpace(text))
+		return t, err == nil
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(raw, &seconds); err != nil || seconds <= 0 || math.IsInf(seconds, 0) {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+}
+
+// parseIngestJSON массив объектов или один объект. Синтаксическая ошибка
+// отклоняет весь запрос; ошибки отдельных объектов - только их.
+func parseIngestJSON(body []byte, accept func(int, ingestReading), result *TelemetryIngestResult) error {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return fmt.Errorf("%w: body is empty", ErrTelemetryIngestInvalid)
+	}
+
+	var items []json.RawMessage
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return fmt.Errorf("%w: malformed JSON: %v", ErrTelemetryIngestInvalid, err)
+		}
+	case '{':
+		if !json.Valid(trimmed) {
+			return fmt.Errorf("%w: malformed JSON, send an array or use application/x-ndjson", ErrTelemetryIngestInvalid)
+		}
+		items = []json.RawMessage{trimmed}
+	default:
+		return fmt.Errorf("%w: expected a JSON array or object", ErrTelemetryIngestInvalid)
+	}
+
+	for i, item := range items {
+		reading, rowErr := decodeIngestObject(item)
+		if rowErr != nil {
+			rowErr.Row = i + 1
+			result.reject(*rowErr)
+			continue
+		}
+		accept(i+1, reading)
+	}
+	return nil
+}
+
+// parseIngestNDJSON объект на строку; номер строки ошибки - номер строки тела
+func parseIngestNDJSON(body []byte, accept func(int, ingestReading), result *TelemetryIngestResult) error {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		reading, rowErr := decodeIngestObject(text)
+		if rowErr != nil {
+			rowErr.Row = line
+			result.reject(*rowErr)
+			continue
+		}
+		accept(line, reading)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTelemetryIngestInvalid, err)
+	}
+	return nil
+}
+
+// Множители меток времени line protocol к наносекундам
+var linePrecisions = map[string]int64{
+	"":   1,
+	"ns": 1,
+	"n":  1,
+	"us": int64(time.Microsecond),
+	"u":  int64(time.Microsecond),
+	"ms": int64(time.Millisecond),
+	"s":  int64(time.Second),
+}
+
+// parseIngestLines InfluxDB line protocol:
+//
+//	measurement[,tag=value...] field=value[,field=value...] [timestamp]
+//
+// Каждое поле - значение канала реестра; тег source сверяется с токеном.
+// Без метки времени берется время приема, как это делает InfluxDB.
+func parseIngestLines(body []byte, precision string, now time.Time, accept func(int, ingestReading), result *TelemetryIngestResult) error {
+	multiplier, ok := linePrecisions[precision]
+	if !ok {
+		return fmt.Errorf("%w: unknown precision %q, use ns, us, ms or s", ErrTelemetryIngestInvalid, precision)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		reading, rowErr := parseLineProtocol(text, multiplier, now)
+		if rowErr != nil {
+			rowErr.Row = line
+			result.reject(*rowErr)
+			continue
+		}
+		accept(line, reading)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrTelemetryIngestInvalid, err)
+	}
+	return nil
+}
+
+func parseLineProtocol(text string, multiplier int64, now time.Time) (ingestReading, *TelemetryRowError) {
+	reading := ingestReading{Values: make(map[string]float64)}
+
+	parts := splitUnescaped(text, ' ')
+	if len(parts) < 2 || len(parts) > 3 {
+		return reading, &TelemetryRowError{Message: "expected: measurement[,tags] fields [timestamp]"}
+	}
+
+	// Измерение значения не имеет, из тегов интересен только source
+	for _, tag := range splitUnescaped(parts[0], ',')[1:] {
+		key, value, found := strings.Cut(tag, "=")
+		if !found {
+			return reading, &TelemetryRowError{Value: tag, Message: "tag must be key=value"}
+		}
+		if unescapeLine(key) == "source" {
+			reading.Source = unescapeLine(value)
+		}
+	}
+
+	for _, field := range splitUnescaped(parts[1], ',') {
+		key, value, found := strings.Cut(field, "=")
+		if !found || value == "" {
+			return reading, &TelemetryRowError{Value: field, Message: "field must be key=value"}
+		}
+		key = unescapeLine(key)
+
+		v, err := parseLineValue(value)
+		if err != nil {
+			return reading, &TelemetryRowError{Column: key, Value: value, Message: "must be a number or boolean"}
+		}
+		reading.Values[key] = v
+	}
+
+	if len(parts) == 3 {
+		reading.RawTime = parts[2]
+		ts, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || ts <= 0 || ts > math.MaxInt64/multiplier {
+			return reading, &TelemetryRowError{Column: "timestamp", Value: parts[2], Message: "invalid integer timestamp"}
+		}
+		t := time.Unix(0, ts*multiplier).UTC()
+		reading.RecordedAt = &t
+	} else {
+		t := now
+		reading.RecordedAt = &t
+	}
+
+	return reading, nil
+}
+
+// parseLineValue значение поля: float, целое с суффиксом i или u, логическое (t, true, f, false).
+// Строковые поля каналами быть не могут.
+func parseLineValue(value string) (float64, error) {
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		return 1, nil
+	case "f", "F", "false", "False", "FALSE":
+		return 0, nil
+	}
+	if strings.HasSuffix(value, "i") || strings.HasSuffix(value, "u") {
+		n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		return float64(n), err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// splitUnescaped делит строку по sep, пропуская экранированные \ и внутри кавычек
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescapeLine(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+// ingestRepo отклоняет пачки с показанием канала poison, как база с нарушенным ограничением;
+// down - база недоступна целиком
+type ingestRepo struct {
+	repository.TelemetryRepository
+	mu      sync.Mutex
+	down    bool
+	created []models.Telemetry
+}
+
+// BatchCreate пропускает уже записанные ключи, как уникальный индекс с ON CONFLICT DO NOTHING
+func (r *ingestRepo) BatchCreate(ctx context.Context, telemetries []models.Telemetry) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.down {
+		return 0, errors.New("connection refused")
+	}
+	for _, record := range telemetries {
+		if _, ok := record.Values["poison"]; ok {
+			return 0, errors.New("violates check constraint")
+		}
+	}
+	stored := make(map[repository.TelemetryKey]bool)
+	for _, record := range r.created {
+		stored[repository.KeyOf(record)] = true
+	}
+	created := 0
+	for i := range telemetries {
+		if key := repository.KeyOf(telemetries[i]); !stored[key] {
+			stored[key] = true
+			telemetries[i].ID = uint(len(r.created) + 1)
+			r.created = append(r.created, telemetries[i])
+			created++
+		}
+	}
+	return created, nil
+}
+
+type ingestAnomalies struct{ TelemetryAnomalyService }
+
+func (ingestAnomalies) Detect(ctx context.Context, records []models.Telemetry) ([]models.TelemetryAnomaly, error) {
+	return nil, nil
+}
+
+type ingestAlerts struct{ TelemetryAlertService }
+
+func (ingestAlerts) Evaluate(ctx context.Context, records []models.Telemetry) error {
+	return nil
+}
+
+const ingestTestQueue = 40
+
+func newIngestTestService(t *testing.T, repo *ingestRepo) *telemetryIngestService {
+	t.Helper()
+	return NewTelemetryIngestService(repo, nil, ingestAnomalies{}, ingestAlerts{}, TelemetryIngestConfig{
+		Tokens:        map[string]string{"station-1": "token"},
+		BatchSize:     4,
+		FlushInterval: time.Millisecond,
+		QueueSize:     ingestTestQueue,
+		DeadLetterDir: t.TempDir(),
+	}).(*telemetryIngestService)
+}
+
+func ingestReadings(source string, from, n int) []models.Telemetry {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	readings := make([]models.Telemetry, 0, n)
+	for i := from; i < from+n; i++ {
+		readings = append(readings, models.Telemetry{
+			RecordedAt: start.Add(time.Duration(i) * time.Second),
+			SourceFile: source,
+			Values:     models.TelemetryValues{"voltage": float64(i)},
+		})
+	}
+	return readings
+}
+
+// drain вызывает flush, как Run по тикам, пока очередь не опустеет
+func drain(t *testing.T, s *telemetryIngestService) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue not drained: %d readings pending", s.pending.Load())
+		}
+		s.flush(false)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func deadLetterLines(t *testing.T, dir string) [][]byte {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines [][]byte
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, bytes.Split(bytes.TrimSpace(data), []byte("\n"))...)
+	}
+	return lines
+}
+
+func assertQueueReleased(t *testing.T, s *telemetryIngestService) {
+	t.Helper()
+	if !s.queue.TryAcquire(ingestTestQueue) {
+		t.Fatal("queue capacity was not released")
+	}
+	s.queue.Release(ingestTestQueue)
+}
+
+func TestTelemetryIngestFlushMovesPoisonToDeadLetter(t *testing.T) {
+	repo := &ingestRepo{}
+	s := newIngestTestService(t, repo)
+	ctx := context.Background()
+
+	readings := ingestReadings("station-1", 0, 10)
+	readings[1].Values["poison"] = 1
+	if err := s.enqueue(ctx, readings); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, s)
+
+	if len(repo.created) != 9 {
+		t.Fatalf("created %d readings, want 9", len(repo.created))
+	}
+	for _, record := range repo.created {
+		if _, ok := record.Values["poison"]; ok {
+			t.Fatal("poison reading was written")
+		}
+	}
+	lines := deadLetterLines(t, s.config.DeadLetterDir)
+	if len(lines) != 1 || !bytes.Contains(lines[0], []byte(`"poison":1`)) {
+		t.Fatalf("dead letter lines %q, want the poison reading only", lines)
+	}
+	assertQueueReleased(t, s)
+
+	// Очередь не заблокирована: новые показания записываются
+	if err := s.enqueue(ctx, ingestReadings("station-1", 100, 3)); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, s)
+	if len(repo.created) != 12 {
+		t.Fatalf("created %d readings after recovery, want 12", len(repo.created))
+	}
+}
+
+func TestTelemetryIngestFlushSkipsDuplicates(t *testing.T) {
+	repo := &ingestRepo{created: ingestReadings("station-1", 0, 2)}
+	s := newIngestTestService(t, repo)
+	ctx := context.Background()
+
+	// Повтор запроса клиентом: те же показания дважды, два из них уже в базе
+	readings := ingestReadings("station-1", 0, 6)
+	if err := s.enqueue(ctx, readings); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.enqueue(ctx, readings); err != nil {
+		t.Fatal(err)
+	}
+	// Тот же момент от другого источника - не повтор
+	if err := s.enqueue(ctx, ingestReadings("station-2", 0, 1)); err != nil {
+		t.Fatal(err)
+	}
+	drain(t, s)
+
+	if len(repo.created) != 2+4+1 {
+		t.Fatalf("created %d readings, want 7", len(repo.created))
+	}
+	seen := make(map[repository.TelemetryKey]bool)
+	for _, record := range repo.created {
+		key := repository.TelemetryKey{RecordedAt: record.RecordedAt, SourceFile: record.SourceFile}
+		if seen[key] {
+			t.Fatalf("duplicate reading %v written", key)
+		}
+		seen[key] = true
+	}
+	assertQueueReleased(t, s)
+}
+
+func TestTelemetryIngestFlushBacksOffWhileDatabaseIsDown(t *testing.T) {
+	repo := &ingestRepo{down: true}
+	s := newIngestTestService(t, repo)
+	s.config.FlushInterval = 10 * time.Second
+
+	if err := s.enqueue(context.Background(), ingestReadings("station-1", 0, 3)); err != nil {
+		t.Fatal(err)
+	}
+	s.flush(false)
+	if s.headFailures != 1 || s.retryAt.Before(time.Now().Add(5*time.Second)) {
+		t.Fatalf("headFailures=%d retryAt=%v, want one failure and a delayed retry", s.headFailures, s.retryAt)
+	}
+
+	// До retryAt пачка не повторяется
+	s.flush(false)
+	if s.headFailures != 1 {
+		t.Fatalf("retried before backoff elapsed: headFailures=%d", s.headFailures)
+	}
+
+	repo.mu.Lock()
+	repo.down = false
+	repo.mu.Unlock()
+	s.retryAt = time.Time{}
+	s.flush(false)
+	if len(repo.created) != 3 || s.headFailures != 0 || s.pending.Load() != 0 {
+		t.Fatalf("created=%d headFailures=%d pending=%d after recovery", len(repo.created), s.headFailures, s.pending.Load())
+	}
+}
+
+func TestTelemetryIngestFinalFlushSavesBufferWhenDatabaseIsDown(t *testing.T) {
+	repo := &ingestRepo{down: true}
+	s := newIngestTestService(t, repo)
+
+	if err := s.enqueue(context.Background(), ingestReadings("station-1", 0, 11)); err != nil {
+		t.Fatal(err)
+	}
+	// Пачка уже исчерпала попытки до остановки
+	s.headFailures = telemetryIngestMaxFailures - 1
+	s.flush(true)
+
+	if lines := deadLetterLines(t, s.config.DeadLetterDir); len(lines) != 11 {
+		t.Fatalf("dead letter has %d readings, want 11", len(lines))
+	}
+	if s.pending.Load() != 0 {
+		t.Fatalf("%d readings still pending", s.pending.Load())
+	}
+	assertQueueReleased(t, s)
+}
+
+func TestTelemetryIngestDeadLetterReimports(t *testing.T) {
+	repo := &ingestRepo{}
+	s := newIngestTestService(t, repo)
+	readings := ingestReadings("station-1", 0, 3)
+
+	path, err := s.deadLetter(readings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	target := &roundTripRepo{}
+	importer := &telemetryService{
+		repo:      target,
+		channels:  roundTripChannels{channels: roundTripChannelList()},
+		anomalies: roundTripAnomalies{},
+	}
+	report, err := importer.ImportTelemetry(context.Background(), filepath.Base(path), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 3 {
+		t.Fatalf("imported %d readings from dead letter, errors %v", report.Imported, report.Errors)
+	}
+	for i := range readings {
+		if err := compareRoundTripRecord(target.created[i], readings[i]); err != nil {
+			t.Fatalf("reading %d: %v", i, err)
+		}
+	}
+}
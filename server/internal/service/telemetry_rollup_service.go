@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+var ErrTelemetryRollupInvalid = errors.New("invalid telemetry rollup query")
+
+// Длительность интервала каждого разрешения
+var telemetryRollupSteps = map[string]time.Duration{
+	models.RollupMinute: time.Minute,
+	models.RollupHour:   time.Hour,
+	models.RollupDay:    24 * time.Hour,
+}
+
+const (
+	// telemetryRollupMaxPoints больше точек на канал график все равно не покажет
+	telemetryRollupMaxPoints = 1500
+	// telemetryRollupMaxBatches пачек за один запуск воркера, чтобы первичный пересчет не занимал его целиком
+	telemetryRollupMaxBatches = 20
+)
+
+type TelemetryRollupConfig struct {
+	BatchSize int
+}
+
+type TelemetryRollupService interface {
+	// Refresh досчитывает сводки по новым показаниям
+	Refresh(ctx context.Context) error
+	// Query агрегаты каналов за период; при пустом Resolution разрешение выбирается по длине периода
+	Query(ctx context.Context, query TelemetryRollupQuery) (*TelemetryRollupSeries, error)
+}
+
+type TelemetryRollupQuery struct {
+	From     time.Time
+	To       time.Time
+	Channels []string
+	// Resolution 1m, 1h, 1d или пусто (auto)
+	Resolution string
+}
+
+type TelemetryRollupSeries struct {
+	Resolution string                            `json:"resolution"`
+	From       time.Time                         `json:"from"`
+	To         time.Time                         `json:"to"`
+	Channels   []TelemetryChannelView            `json:"channels"`
+	Series     map[string][]TelemetryRollupPoint `json:"series"`
+}
+
+type TelemetryRollupPoint struct {
+	Time   time.Time `json:"t"`
+	Count  int64     `json:"count"`
+	Avg    float64   `json:"avg"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	StdDev float64   `json:"stddev"`
+}
+
+type telemetryRollupService struct {
+	repo     repository.TelemetryRollupRepository
+	channels TelemetryChannelService
+	config   TelemetryRollupConfig
+}
+
+func NewTelemetryRollupService(repo repository.TelemetryRollupRepository, channels TelemetryChannelService,
+	config TelemetryRollupConfig) TelemetryRollupService {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50000
+	}
+
+	return &telemetryRollupService{
+		repo:     repo,
+		channels: channels,
+		config:   config,
+	}
+}
+
+func (s *telemetryRollupService) Refresh(ctx context.Context) error {
+	batches := 0
+	for batches < telemetryRollupMaxBatches {
+		advanced, err := s.repo.Advance(ctx, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to update telemetry rollups: %w", err)
+		}
+		if !advanced {
+			break
+		}
+		batches++
+	}
+
+	if batches == telemetryRollupMaxBatches {
+		log.Printf("Telemetry rollups: processed %d batches, the rest on the next run", batches)
+	}
+	return nil
+}
+
+func (s *telemetryRollupService) Query(ctx context.Context, query TelemetryRollupQuery) (*TelemetryRollupSeries, error) {
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+	if !query.To.After(query.From) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrTelemetryRollupInvalid)
+	}
+
+	resolution := query.Resolution
+	switch resolution {
+	case "", "auto":
+		resolution = pickRollupResolution(query.To.Sub(query.From))
+	case models.RollupMinute, models.RollupHour, models.RollupDay:
+		if points := query.To.Sub(query.From) / telemetryRollupSteps[resolution]; points > 10*telemetryRollupMaxPoints {
+			return nil, fmt.Errorf("%w: %d points per channel at %s, use a coarser resolution or a shorter range",
+				ErrTelemetryRollupInvalid, points, resolution)
+		}
+	default:
+		return nil, fmt.Errorf("%w: resolution must be 1m, 1h, 1d or auto", ErrTelemetryRollupInvalid)
+	}
+
+	selected, err := s.channels.Resolve(ctx, query.Channels)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(selected))
+	for _, channel := range selected {
+		names = append(names, channel.Name)
+	}
+
+	// Первый интервал берем целиком, даже если период начинается в его середине
+	from := query.From.UTC().Truncate(telemetryRollupSteps[resolution])
+	rollups, err := s.repo.Query(ctx, resolution, names, from, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load telemetry rollups: %w", err)
+	}
+
+	series := &TelemetryRollupSeries{
+		Resolution: resolution,
+		From:       from,
+		To:         query.To,
+		Channels:   []TelemetryChannelView{},
+		Series:     make(map[string][]TelemetryRollupPoint),
+	}
+	for _, rollup := range rollups {
+		series.Series[rollup.Channel] = append(series.Series[rollup.Channel], toTelemetryRollupPoint(rollup))
+	}
+	for i := range selected {
+		if _, ok := series.Series[selected[i].Name]; ok || len(query.Channels) > 0 {
+			series.Channels = append(series.Channels, ToTelemetryChannelView(&selected[i]))
+		}
+	}
+	return series, nil
+}
+
+// pickRollupResolution самое мелкое разрешение, при котором точек на канал не больше предела
+func pickRollupResolution(span time.Duration) string {
+	for _, resolution := range []string{models.RollupMinute, models.RollupHour} {
+		if span/telemetryRollupSteps[resolution] <= telemetryRollupMaxPoints {
+			return resolution
+		}
+	}
+	return models.RollupDay
+}
+
+func toTelemetryRollupPoint(rollup models.TelemetryRollup) TelemetryRollupPoint {
+	point := TelemetryRollupPoint{
+		Time:  rollup.Bucket.UTC(),
+		Count: rollup.Count,
+		Min:   rollup.Min,
+		Max:   rollup.Max,
+	}
+	if rollup.Count == 0 {
+		return point
+	}
+
+	n := float64(rollup.Count)
+	point.Avg = rollup.Sum / n
+	if rollup.Count > 1 {
+		// Выборочное отклонение по суммам; отрицательный остаток - ошибка округления
+		variance := (rollup.SumSquares - rollup.Sum*rollup.Sum/n) / (n - 1)
+		point.StdDev = math.Sqrt(math.Max(variance, 0))
+	}
+	return point
+}
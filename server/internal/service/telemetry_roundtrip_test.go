@@ -0,0 +1,276 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/models"
+	"cassiopeia/internal/repository"
+)
+
+// roundTripRepo отдает показания курсором пачками и запоминает записанные импортом
+type roundTripRepo struct {
+	repository.TelemetryRepository
+	records []models.Telemetry
+	created []models.Telemetry
+}
+
+func (r *roundTripRepo) StreamByDateRange(ctx context.Context, from, to time.Time, channels []string, batchSize int,
+	fn func([]models.Telemetry) error) error {
+	batch := make([]models.Telemetry, 0, batchSize)
+	for _, record := range r.records {
+		batch = append(batch, record)
+		if len(batch) == batchSize {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func (r *roundTripRepo) ChannelNames(ctx context.Context, from, to time.Time) ([]string, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, record := range r.records {
+		for name := range record.Values {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+func (r *roundTripRepo) BatchCreate(ctx context.Context, telemetries []models.Telemetry) (int, error) {
+	stored := make(map[repository.TelemetryKey]bool)
+	for _, record := range r.created {
+		stored[repository.KeyOf(record)] = true
+	}
+	created := 0
+	for i := range telemetries {
+		if key := repository.KeyOf(telemetries[i]); !stored[key] {
+			stored[key] = true
+			telemetries[i].ID = uint(len(r.created) + 1)
+			r.created = append(r.created, telemetries[i])
+			created++
+		}
+	}
+	return created, nil
+}
+
+type roundTripChannels struct {
+	TelemetryChannelService
+	channels []models.TelemetryChannel
+}
+
+func (c roundTripChannels) Resolve(ctx context.Context, names []string) ([]models.TelemetryChannel, error) {
+	return c.channels, nil
+}
+
+func (c roundTripChannels) Registry(ctx context.Context) (map[string]models.TelemetryChannel, error) {
+	registry := make(map[string]models.TelemetryChannel, len(c.channels))
+	for _, channel := range c.channels {
+		registry[channel.Name] = channel
+	}
+	return registry, nil
+}
+
+// roundTripAnomalies отмечает каждое третье показание, чтобы выгрузки содержали аномалии
+type roundTripAnomalies struct {
+	TelemetryAnomalyService
+}
+
+func (roundTripAnomalies) ForRecords(ctx context.Context, records []models.Telemetry) (map[uint][]models.TelemetryAnomaly, error) {
+	marks := make(map[uint][]models.TelemetryAnomaly)
+	for _, record := range records {
+		if record.ID%3 == 0 {
+			marks[record.ID] = []models.TelemetryAnomaly{{
+				TelemetryID: record.ID,
+				Channel:     "voltage",
+				Detector:    models.AnomalyDetectorThreshold,
+				Severity:    models.AnomalySeverityWarning,
+			}}
+		}
+	}
+	return marks, nil
+}
+
+func (roundTripAnomalies) Detect(ctx context.Context, records []models.Telemetry) ([]models.TelemetryAnomaly, error) {
+	return nil, nil
+}
+
+func roundTripChannelList() []models.TelemetryChannel {
+	return []models.TelemetryChannel{
+		{Name: "voltage", Unit: "V", Type: models.ChannelTypeFloat, Precision: 2},
+		{Name: "temperature", Unit: "C", Type: models.ChannelTypeFloat, Precision: 1},
+		{Name: "pulses", Type: models.ChannelTypeInteger},
+		{Name: "door_open", Type: models.ChannelTypeBoolean},
+	}
+}
+
+// roundTripRecords больше одной пачки курсора; значения уже с точностью канала,
+// метки времени с микросекундами, источники с символами, которые нужно экранировать
+func roundTripRecords(n int) []models.Telemetry {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	sources := []string{"station-1", `station "A", north`, "станция-2"}
+
+	records := make([]models.Telemetry, 0, n)
+	for i := 0; i < n; i++ {
+		values := models.TelemetryValues{
+			"voltage": float64(1000+i) / 100,
+			"pulses":  float64(i * 7),
+		}
+		if i%2 == 0 {
+			values["temperature"] = float64(i%400-200) / 10
+		}
+		if i%5 != 4 {
+			values["door_open"] = float64(i % 2)
+		}
+		records = append(records, models.Telemetry{
+			ID:         uint(i + 1),
+			RecordedAt: start.Add(time.Duration(i)*1500*time.Millisecond + time.Duration(i%1000)*time.Microsecond),
+			SourceFile: sources[i%len(sources)],
+			Values:     values,
+		})
+	}
+	return records
+}
+
+func TestTelemetryExportImportRoundTrip(t *testing.T) {
+	records := roundTripRecords(2*telemetryExportBatchSize + 17)
+	from := records[0].RecordedAt.Add(-time.Minute)
+	to := records[len(records)-1].RecordedAt.Add(time.Minute)
+
+	for _, format := range []string{"csv", "ndjson", "json", "xlsx"} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			channels := roundTripChannels{channels: roundTripChannelList()}
+
+			source := &telemetryService{repo: &roundTripRepo{records: records}, channels: channels, anomalies: roundTripAnomalies{}}
+			export, err := source.PrepareExport(ctx, format, from, to, nil)
+			if err != nil {
+				t.Fatalf("PrepareExport: %v", err)
+			}
+			var file bytes.Buffer
+			if err := source.WriteExport(ctx, export, &file); err != nil {
+				t.Fatalf("WriteExport: %v", err)
+			}
+
+			target := &roundTripRepo{}
+			importer := &telemetryService{repo: target, channels: channels, anomalies: roundTripAnomalies{}}
+			report, err := importer.ImportTelemetry(ctx, export.Filename, &file)
+			if err != nil {
+				t.Fatalf("ImportTelemetry: %v", err)
+			}
+
+			if report.Invalid != 0 || report.Duplicates != 0 || len(report.Errors) != 0 {
+				t.Fatalf("import rejected rows: invalid=%d duplicates=%d errors=%v", report.Invalid, report.Duplicates, report.Errors)
+			}
+			if report.Imported != len(records) || len(target.created) != len(records) {
+				t.Fatalf("imported %d (created %d), want %d", report.Imported, len(target.created), len(records))
+			}
+
+			for i, want := range records {
+				if err := compareRoundTripRecord(target.created[i], want); err != nil {
+					t.Fatalf("record %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+// Показания, уже записанные в базу (например, параллельным приемом), импорт
+// пропускает по уникальному ключу и относит к повторам
+func TestTelemetryImportSkipsStoredReadings(t *testing.T) {
+	ctx := context.Background()
+	records := roundTripRecords(30)
+	channels := roundTripChannels{channels: roundTripChannelList()}
+
+	source := &telemetryService{repo: &roundTripRepo{records: records}, channels: channels, anomalies: roundTripAnomalies{}}
+	export, err := source.PrepareExport(ctx, "ndjson", records[0].RecordedAt, records[len(records)-1].RecordedAt.Add(time.Second), nil)
+	if err != nil {
+		t.Fatalf("PrepareExport: %v", err)
+	}
+	var file bytes.Buffer
+	if err := source.WriteExport(ctx, export, &file); err != nil {
+		t.Fatalf("WriteExport: %v", err)
+	}
+
+	stored := append([]models.Telemetry(nil), records[:10]...)
+	target := &roundTripRepo{created: stored}
+	importer := &telemetryService{repo: target, channels: channels, anomalies: roundTripAnomalies{}}
+	report, err := importer.ImportTelemetry(ctx, export.Filename, &file)
+	if err != nil {
+		t.Fatalf("ImportTelemetry: %v", err)
+	}
+
+	if report.Imported != 20 || report.Duplicates != 10 || len(report.Errors) != 10 {
+		t.Fatalf("imported=%d duplicates=%d errors=%d, want 20, 10, 10", report.Imported, report.Duplicates, len(report.Errors))
+	}
+	// NDJSON: номер строки совпадает с номером показания
+	for i, rowErr := range report.Errors {
+		if rowErr.Row != i+1 || rowErr.Message != "reading is already stored" {
+			t.Fatalf("error %d: %+v", i, rowErr)
+		}
+	}
+	if len(target.created) != len(records) {
+		t.Fatalf("stored %d readings, want %d", len(target.created), len(records))
+	}
+}
+
+func compareRoundTripRecord(got, want models.Telemetry) error {
+	if !got.RecordedAt.Equal(want.RecordedAt) {
+		return fmt.Errorf("recorded_at %s, want %s", got.RecordedAt.Format(time.RFC3339Nano), want.RecordedAt.Format(time.RFC3339Nano))
+	}
+	if got.SourceFile != want.SourceFile {
+		return fmt.Errorf("source %q, want %q", got.SourceFile, want.SourceFile)
+	}
+	if len(got.Values) != len(want.Values) {
+		return fmt.Errorf("values %v, want %v", got.Values, want.Values)
+	}
+	for name, value := range want.Values {
+		if v, ok := got.Values[name]; !ok || v != value {
+			return fmt.Errorf("channel %s = %v (present %v), want %v", name, v, ok, value)
+		}
+	}
+	return nil
+}
+
+func TestTelemetryJSONExportMarksAnomalies(t *testing.T) {
+	ctx := context.Background()
+	records := roundTripRecords(4)
+	service := &telemetryService{
+		repo:      &roundTripRepo{records: records},
+		channels:  roundTripChannels{channels: roundTripChannelList()},
+		anomalies: roundTripAnomalies{},
+	}
+
+	for _, format := range []string{"ndjson", "json"} {
+		export, err := service.PrepareExport(ctx, format, records[0].RecordedAt, records[3].RecordedAt, nil)
+		if err != nil {
+			t.Fatalf("%s: PrepareExport: %v", format, err)
+		}
+		var file bytes.Buffer
+		if err := service.WriteExport(ctx, export, &file); err != nil {
+			t.Fatalf("%s: WriteExport: %v", format, err)
+		}
+
+		// Показание с ID 3 отмечено, остальные - с пустым массивом
+		mark := `"anomalies":[{"channel":"voltage","detector":"` + models.AnomalyDetectorThreshold + `","severity":"warning"}]`
+		if got := bytes.Count(file.Bytes(), []byte(mark)); got != 1 {
+			t.Errorf("%s: %d marked records, want 1:\n%s", format, got, file.String())
+		}
+		if got := bytes.Count(file.Bytes(), []byte(`"anomalies":[]`)); got != 3 {
+			t.Errorf("%s: %d unmarked records, want 3:\n%s", format, got, file.String())
+		}
+	}
+}
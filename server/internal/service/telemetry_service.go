@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"cassiopeia/internal/models"
@@ -19,15 +22,31 @@ type TelemetryService interface {
 	GenerateTelemetry(ctx context.Context) (*TelemetryBatch, error)
 	GenerateTelemetryCSV(ctx context.Context) (string, error)
 	GenerateTelemetryExcel(ctx context.Context) (string, error)
-	GetTelemetryHistory(ctx context.Context, from, to time.Time) ([]models.Telemetry, error)
-	ExportTelemetry(ctx context.Context, format string, from, to time.Time) (string, error)
+	// GetTelemetryHistory показания выбранных каналов (пусто - всех) за период
+	GetTelemetryHistory(ctx context.Context, from, to time.Time, channels []string) (*TelemetryHistory, error)
+	// PrepareExport проверяет формат и каналы выгрузки до того, как начнется ответ
+	PrepareExport(ctx context.Context, format string, from, to time.Time, channels []string) (*TelemetryExport, error)
+	// WriteExport пишет выгрузку в w по мере чтения показаний из базы
+	WriteExport(ctx context.Context, export *TelemetryExport, w io.Writer) error
+	// ImportTelemetry загружает реальные показания из CSV, XLSX, JSON или NDJSON
+	ImportTelemetry(ctx context.Context, filename string, r io.Reader) (*TelemetryImportReport, error)
 }
 
 type telemetryService struct {
 	repo      repository.TelemetryRepository
+	channels  TelemetryChannelService
+	anomalies TelemetryAnomalyService
 	outputDir string
 }
 
+// TelemetryHistory показания и описание их каналов (столбцов) в порядке реестра
+type TelemetryHistory struct {
+	Channels []TelemetryChannelView `json:"channels"`
+	Records  []models.Telemetry     `json:"telemetry"`
+	// channels каналы для выгрузки в том же порядке, что и Channels
+	channels []models.TelemetryChannel
+}
+
 type TelemetryBatch struct {
 	Filename    string             `json:"filename"`
 	Records     int                `json:"records"`
@@ -35,7 +54,8 @@ type TelemetryBatch struct {
 	Data        []models.Telemetry `json:"data,omitempty"`
 }
 
-func NewTelemetryService(repo repository.TelemetryRepository, outputDir string) TelemetryService {
+func NewTelemetryService(repo repository.TelemetryRepository, channels TelemetryChannelService,
+	anomalies TelemetryAnomalyService, outputDir string) TelemetryService {
 	if outputDir == "" {
 		outputDir = "/data/telemetry"
 	}
@@ -47,6 +67,8 @@ func NewTelemetryService(repo repository.TelemetryRepository, outputDir string)
 
 	return &telemetryService{
 		repo:      repo,
+		channels:  channels,
+		anomalies: anomalies,
 		outputDir: outputDir,
 	}
 }
@@ -59,21 +81,30 @@ func (s *telemetryService) GenerateTelemetry(ctx context.Context) (*TelemetryBat
 	filename := fmt.Sprintf("telemetry_%s.csv", timestamp)
 	filepath := filepath.Join(s.outputDir, filename)
 
-	// Генерируем тестовые данные
+	// Генерируем тестовые данные по исходным каналам
+	channels, err := s.channels.Resolve(ctx, []string{"voltage", "temperature"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sample channels: %w", err)
+	}
 	records := s.generateSampleData(100) // 100 записей
 
 	// Сохраняем в CSV
-	if err := s.saveToCSV(filepath, records); err != nil {
+	if err := s.saveToCSV(filepath, channels, records, nil); err != nil {
 		return nil, fmt.Errorf("failed to save CSV: %w", err)
 	}
 
 	// Сохраняем в БД
-	for _, record := range records {
-		if err := s.repo.Create(ctx, &record); err != nil {
+	for i := range records {
+		if err := s.repo.Create(ctx, &records[i]); err != nil {
 			log.Printf("Failed to save telemetry record to DB: %v", err)
 		}
 	}
 
+	// Несохраненные записи (без ID) детектор пропустит
+	if _, err := s.anomalies.Detect(ctx, records); err != nil {
+		log.Printf("Failed to detect telemetry anomalies: %v", err)
+	}
+
 	log.Printf("Telemetry generated: %s (%d records)", filename, len(records))
 
 	return &TelemetryBatch{
@@ -94,11 +125,13 @@ func (s *telemetryService) generateSampleData(count int) []models.Telemetry {
 		recordTime := startTime.Add(time.Duration(i) * time.Second)
 
 		record := models.Telemetry{
-			RecordedAt:  recordTime,
-			Voltage:     randFloat(3.2, 12.6),
-			Temperature: randFloat(-50.0, 80.0),
-			SourceFile:  fmt.Sprintf("telemetry_%s.csv", recordTime.Format("20060102_150405")),
-			CreatedAt:   time.Now().UTC(),
+			RecordedAt: recordTime,
+			Values: models.TelemetryValues{
+				"voltage":     math.Round(randFloat(3.2, 12.6)*100) / 100,
+				"temperature": math.Round(randFloat(-50.0, 80.0)*100) / 100,
+			},
+			SourceFile: fmt.Sprintf("telemetry_%s.csv", recordTime.Format("20060102_150405")),
+			CreatedAt:  time.Now().UTC(),
 		}
 
 		records = append(records, record)
@@ -111,7 +144,10 @@ func randFloat(min, max float64) float64 {
 	return min + rand.Float64()*(max-min)
 }
 
-func (s *telemetryService) saveToCSV(filepath string, records []models.Telemetry) error {
+// saveToCSV столбец на канал; пустая ячейка - канал в показании отсутствует.
+// Если переданы аномалии, последний столбец перечисляет их как канал:детектор:уровень.
+func (s *telemetryService) saveToCSV(filepath string, channels []models.TelemetryChannel, records []models.Telemetry,
+	anomalies map[uint][]models.TelemetryAnomaly) error {
 	file, err := os.Create(filepath)
 	if err != nil {
 		return err
@@ -121,26 +157,49 @@ func (s *telemetryService) saveToCSV(filepath string, records []models.Telemetry
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	// Записываем заголовок
-	header := []string{"recorded_at", "voltage", "temperature", "source_file"}
-	if err := writer.Write(header); err != nil {
+	if err := writer.Write(telemetryCSVHeader(channels, anomalies != nil)); err != nil {
 		return err
 	}
+	return writeTelemetryCSVRows(writer, channels, records, anomalies)
+}
+
+func telemetryCSVHeader(channels []models.TelemetryChannel, withAnomalies bool) []string {
+	header := []string{"recorded_at"}
+	for _, channel := range channels {
+		header = append(header, channel.Name)
+	}
+	header = append(header, "source_file")
+	if withAnomalies {
+		header = append(header, "anomalies")
+	}
+	return header
+}
 
-	// Записываем данные
+func writeTelemetryCSVRows(writer *csv.Writer, channels []models.TelemetryChannel, records []models.Telemetry,
+	anomalies map[uint][]models.TelemetryAnomaly) error {
 	for _, record := range records {
-		row := []string{
-			record.RecordedAt.Format("2006-01-02 15:04:05"),
-			fmt.Sprintf("%.2f", record.Voltage),
-			fmt.Sprintf("%.2f", record.Temperature),
-			record.SourceFile,
+		row := make([]string, 0, len(channels)+3)
+		row = append(row, record.RecordedAt.UTC().Format(utils.TelemetryTimeLayout))
+		for _, channel := range channels {
+			value := ""
+			if v, ok := record.Values[channel.Name]; ok {
+				value = FormatChannelValue(channel, v)
+			}
+			row = append(row, value)
+		}
+		row = append(row, record.SourceFile)
+		if anomalies != nil {
+			marks := make([]string, 0, len(anomalies[record.ID]))
+			for _, anomaly := range anomalies[record.ID] {
+				marks = append(marks, anomaly.Channel+":"+anomaly.Detector+":"+anomaly.Severity)
+			}
+			row = append(row, strings.Join(marks, ";"))
 		}
 
 		if err := writer.Write(row); err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -166,8 +225,18 @@ func (s *telemetryService) GenerateTelemetryExcel(ctx context.Context) (string,
 		time.Now().UTC().Format("20060102_150405"))
 	excelPath := filepath.Join(s.outputDir, excelFilename)
 
+	channels, err := s.channels.Resolve(ctx, []string{"voltage", "temperature"})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sample channels: %w", err)
+	}
+
 	// Используем утилиту для создания Excel
-	if err := utils.CreateExcelFile(excelPath, batch.Data); err != nil {
+	anomalies, err := s.anomalies.ForRecords(ctx, batch.Data)
+	if err != nil {
+		return "", err
+	}
+
+	if err := utils.CreateExcelFile(excelPath, channels, batch.Data, anomalies); err != nil {
 		return "", fmt.Errorf("failed to create Excel file: %w", err)
 	}
 
@@ -175,7 +244,7 @@ func (s *telemetryService) GenerateTelemetryExcel(ctx context.Context) (string,
 	return excelPath, nil
 }
 
-func (s *telemetryService) GetTelemetryHistory(ctx context.Context, from, to time.Time) ([]models.Telemetry, error) {
+func (s *telemetryService) GetTelemetryHistory(ctx context.Context, from, to time.Time, channels []string) (*TelemetryHistory, error) {
 	if from.IsZero() {
 		from = time.Now().UTC().Add(-24 * time.Hour)
 	}
@@ -189,54 +258,42 @@ func (s *telemetryService) GetTelemetryHistory(ctx context.Context, from, to tim
 		from = to.Add(-maxRange)
 	}
 
-	return s.repo.GetByDateRange(ctx, from, to)
-}
-
-func (s *telemetryService) ExportTelemetry(ctx context.Context, format string, from, to time.Time) (string, error) {
-	// Получаем данные
-	records, err := s.GetTelemetryHistory(ctx, from, to)
+	selected, err := s.channels.Resolve(ctx, channels)
 	if err != nil {
-		return "", fmt.Errorf("failed to get telemetry data: %w", err)
+		return nil, err
 	}
 
-	if len(records) == 0 {
-		return "", fmt.Errorf("no data found for the specified range")
+	records, err := s.repo.GetByDateRange(ctx, from, to, channels)
+	if err != nil {
+		return nil, err
 	}
 
-	timestamp := time.Now().UTC().Format("20060102_150405")
-
-	switch format {
-	case "csv":
-		filename := fmt.Sprintf("telemetry_export_%s.csv", timestamp)
-		filepath := filepath.Join(s.outputDir, filename)
-
-		if err := s.saveToCSV(filepath, records); err != nil {
-			return "", err
+	// Оставляем в показаниях только выбранные каналы, а в описании - только встретившиеся
+	present := make(map[string]bool)
+	for i := range records {
+		if len(channels) > 0 {
+			values := make(models.TelemetryValues, len(selected))
+			for _, channel := range selected {
+				if v, ok := records[i].Values[channel.Name]; ok {
+					values[channel.Name] = v
+				}
+			}
+			records[i].Values = values
 		}
-
-		return filepath, nil
-
-	case "excel", "xlsx":
-		filename := fmt.Sprintf("telemetry_export_%s.xlsx", timestamp)
-		filepath := filepath.Join(s.outputDir, filename)
-
-		if err := utils.CreateExcelFile(filepath, records); err != nil {
-			return "", err
+		for name := range records[i].Values {
+			present[name] = true
 		}
+	}
 
-		return filepath, nil
-
-	case "json":
-		filename := fmt.Sprintf("telemetry_export_%s.json", timestamp)
-		filepath := filepath.Join(s.outputDir, filename)
-
-		if err := utils.SaveAsJSON(filepath, records); err != nil {
-			return "", err
+	history := &TelemetryHistory{
+		Channels: []TelemetryChannelView{},
+		Records:  records,
+	}
+	for i := range selected {
+		if len(channels) > 0 || present[selected[i].Name] {
+			history.channels = append(history.channels, selected[i])
+			history.Channels = append(history.Channels, ToTelemetryChannelView(&selected[i]))
 		}
-
-		return filepath, nil
-
-	default:
-		return "", fmt.Errorf("unsupported format: %s", format)
 	}
+	return history, nil
 }
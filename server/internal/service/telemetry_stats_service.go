@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cassiopeia/internal/repository"
+)
+
+var ErrTelemetryStatsInvalid = errors.New("invalid telemetry stats query")
+
+const (
+	// telemetryStatsMaxRange процентили считаются по сырым показаниям - период ограничен
+	telemetryStatsMaxRange = 366 * 24 * time.Hour
+	// telemetryStatsMaxHourRange при группировке по часам групп не больше ~2200
+	telemetryStatsMaxHourRange = 92 * 24 * time.Hour
+	// Прошедший период меняется только импортом - кэшируем дольше, текущий пересчитывается чаще
+	telemetryStatsClosedTTL = time.Hour
+	telemetryStatsOpenTTL   = time.Minute
+)
+
+type TelemetryStatsService interface {
+	// GetStats count/avg/min/max/stddev и p50/p95/p99 каналов по группам (hour, day, source_file или без группировки)
+	GetStats(ctx context.Context, from, to time.Time, groupBy string) (*TelemetryStatsReport, error)
+}
+
+type TelemetryStatsReport struct {
+	// From и To нормализованный период [From, To): границы расширены до целых часов или суток
+	// группировки, показание ровно в To относится к следующему периоду
+	From        time.Time                        `json:"from"`
+	To          time.Time                        `json:"to"`
+	GroupBy     string                           `json:"group_by"`
+	Groups      []repository.TelemetryGroupStats `json:"groups"`
+	GeneratedAt time.Time                        `json:"generated_at"`
+}
+
+type telemetryStatsService struct {
+	repo      repository.TelemetryRepository
+	cacheRepo repository.CacheRepository
+}
+
+func NewTelemetryStatsService(repo repository.TelemetryRepository, cacheRepo repository.CacheRepository) TelemetryStatsService {
+	return &telemetryStatsService{
+		repo:      repo,
+		cacheRepo: cacheRepo,
+	}
+}
+
+func (s *telemetryStatsService) GetStats(ctx context.Context, from, to time.Time, groupBy string) (*TelemetryStatsReport, error) {
+	now := time.Now().UTC()
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+
+	// Нормализуем период по шагу группировки: одинаковые запросы дают один ключ кэша
+	step := time.Minute
+	switch groupBy {
+	case repository.TelemetryStatsByNone, repository.TelemetryStatsBySource:
+	case repository.TelemetryStatsByHour:
+		step = time.Hour
+	case repository.TelemetryStatsByDay:
+		step = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("%w: group_by must be hour, day or source_file", ErrTelemetryStatsInvalid)
+	}
+	from = from.UTC().Truncate(step)
+	if end := to.UTC().Truncate(step); end.Before(to) {
+		to = end.Add(step)
+	} else {
+		to = end
+	}
+
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrTelemetryStatsInvalid)
+	}
+	if to.Sub(from) > telemetryStatsMaxRange {
+		return nil, fmt.Errorf("%w: range is limited to 366 days, use /telemetry/rollups for longer periods", ErrTelemetryStatsInvalid)
+	}
+	if groupBy == repository.TelemetryStatsByHour && to.Sub(from) > telemetryStatsMaxHourRange {
+		return nil, fmt.Errorf("%w: group_by=hour is limited to 92 days", ErrTelemetryStatsInvalid)
+	}
+
+	cacheKey := fmt.Sprintf("telemetry:stats:%s:%d:%d", groupBy, from.Unix(), to.Unix())
+
+	var cached TelemetryStatsReport
+	if err := s.cacheRepo.GetJSON(ctx, cacheKey, &cached); err == nil && cached.Groups != nil {
+		return &cached, nil
+	}
+
+	groups, err := s.repo.GetStats(ctx, from, to, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute telemetry stats: %w", err)
+	}
+
+	report := &TelemetryStatsReport{
+		From:        from,
+		To:          to,
+		GroupBy:     groupBy,
+		Groups:      groups,
+		GeneratedAt: now,
+	}
+
+	ttl := telemetryStatsOpenTTL
+	if to.Before(now.Add(-time.Hour)) {
+		ttl = telemetryStatsClosedTTL
+	}
+	if err := s.cacheRepo.SetJSON(ctx, cacheKey, report, ttl); err != nil {
+		log.Printf("Failed to cache telemetry stats: %v", err)
+	}
+
+	return report, nil
+}
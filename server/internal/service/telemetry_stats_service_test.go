@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cassiopeia/internal/repository"
+)
+
+// statsRepo запоминает период, с которым считается статистика
+type statsRepo struct {
+	repository.TelemetryRepository
+	from, to time.Time
+}
+
+func (r *statsRepo) GetStats(ctx context.Context, from, to time.Time, groupBy string) ([]repository.TelemetryGroupStats, error) {
+	r.from, r.to = from, to
+	return []repository.TelemetryGroupStats{}, nil
+}
+
+type statsCache struct{ repository.CacheRepository }
+
+func (statsCache) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	return errors.New("cache miss")
+}
+
+func (statsCache) SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+// Период расширяется до целых интервалов группировки и передается с исключенным концом
+func TestTelemetryStatsRangeIsHalfOpen(t *testing.T) {
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		from, to time.Time
+		groupBy  string
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{"hour aligned", at(1, 10, 0), at(1, 12, 0), repository.TelemetryStatsByHour, at(1, 10, 0), at(1, 12, 0)},
+		{"hour widened", at(1, 10, 15), at(1, 11, 30), repository.TelemetryStatsByHour, at(1, 10, 0), at(1, 12, 0)},
+		{"day widened", at(1, 10, 15), at(2, 0, 1), repository.TelemetryStatsByDay, at(1, 0, 0), at(3, 0, 0)},
+		{"minute", at(1, 10, 15), at(1, 10, 45), repository.TelemetryStatsByNone, at(1, 10, 15), at(1, 10, 45)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &statsRepo{}
+			report, err := NewTelemetryStatsService(repo, statsCache{}).GetStats(context.Background(), tt.from, tt.to, tt.groupBy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !repo.from.Equal(tt.wantFrom) || !repo.to.Equal(tt.wantTo) {
+				t.Errorf("queried [%s, %s), want [%s, %s)", repo.from, repo.to, tt.wantFrom, tt.wantTo)
+			}
+			if !report.From.Equal(tt.wantFrom) || !report.To.Equal(tt.wantTo) {
+				t.Errorf("reported [%s, %s), want [%s, %s)", report.From, report.To, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
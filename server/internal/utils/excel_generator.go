@@ -1,16 +1,26 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/xuri/excelize/v2"
-	"strconv"
+	"os"
+	"strings"
 	"time"
 
 	"cassiopeia/internal/models"
 )
 
-// CreateExcelFile создает Excel файл с данными телеметрии
-func CreateExcelFile(filepath string, records []models.Telemetry) error {
+// Заливка аномальных значений по уровню
+var anomalyFills = map[string]string{
+	models.AnomalySeverityWarning:  "#FFF2CC",
+	models.AnomalySeverityCritical: "#FFCCCC",
+}
+
+// CreateExcelFile создает Excel файл с данными телеметрии: столбец на каждый канал.
+// Аномальные значения закрашиваются по уровню и перечисляются в столбце Anomalies.
+func CreateExcelFile(filepath string, channels []models.TelemetryChannel, records []models.Telemetry,
+	anomalies map[uint][]models.TelemetryAnomaly) error {
 	f := excelize.NewFile()
 	defer f.Close()
 
@@ -20,30 +30,57 @@ func CreateExcelFile(filepath string, records []models.Telemetry) error {
 		return err
 	}
 
-	// Устанавливаем заголовки
-	headers := []string{"Timestamp", "Voltage (V)", "Temperature (°C)", "Source File", "Created At"}
+	// Устанавливаем заголовки: время, каналы, источник, время записи
+	headers := []string{"Timestamp"}
+	for _, channel := range channels {
+		headers = append(headers, ChannelLabel(channel))
+	}
+	headers = append(headers, "Source File", "Created At", "Anomalies")
 	for i, header := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 		f.SetCellValue("Telemetry", cell, header)
 	}
 
+	// Стиль чисел по точности канала: обычный и с заливкой для каждого уровня аномалии
+	styles := make([]map[string]int, len(channels))
+	for i, channel := range channels {
+		styles[i] = map[string]int{"": getNumberStyle(f, channel.Precision, "")}
+		for severity, color := range anomalyFills {
+			styles[i][severity] = getNumberStyle(f, channel.Precision, color)
+		}
+	}
+
 	// Заполняем данные
 	for rowIdx, record := range records {
 		rowNum := rowIdx + 2 // Заголовок в первой строке
 
 		f.SetCellValue("Telemetry", fmt.Sprintf("A%d", rowNum),
 			record.RecordedAt.Format("2006-01-02 15:04:05"))
-		f.SetCellValue("Telemetry", fmt.Sprintf("B%d", rowNum), record.Voltage)
-		f.SetCellValue("Telemetry", fmt.Sprintf("C%d", rowNum), record.Temperature)
-		f.SetCellValue("Telemetry", fmt.Sprintf("D%d", rowNum), record.SourceFile)
-		f.SetCellValue("Telemetry", fmt.Sprintf("E%d", rowNum),
-			record.CreatedAt.Format("2006-01-02 15:04:05"))
 
-		// Форматирование чисел
-		f.SetCellStyle("Telemetry", fmt.Sprintf("B%d", rowNum), fmt.Sprintf("B%d", rowNum),
-			getNumberStyle(f, "0.00"))
-		f.SetCellStyle("Telemetry", fmt.Sprintf("C%d", rowNum), fmt.Sprintf("C%d", rowNum),
-			getNumberStyle(f, "0.00"))
+		severity, marks := anomalyMarks(anomalies[record.ID])
+
+		for i, channel := range channels {
+			v, ok := record.Values[channel.Name]
+			if !ok {
+				continue
+			}
+			cell, _ := excelize.CoordinatesToCellName(i+2, rowNum)
+			if channel.Type == models.ChannelTypeBoolean {
+				f.SetCellValue("Telemetry", cell, v != 0)
+			} else {
+				f.SetCellValue("Telemetry", cell, v)
+			}
+			f.SetCellStyle("Telemetry", cell, cell, styles[i][severity[channel.Name]])
+		}
+
+		sourceCell, _ := excelize.CoordinatesToCellName(len(channels)+2, rowNum)
+		createdCell, _ := excelize.CoordinatesToCellName(len(channels)+3, rowNum)
+		anomaliesCell, _ := excelize.CoordinatesToCellName(len(channels)+4, rowNum)
+		f.SetCellValue("Telemetry", sourceCell, record.SourceFile)
+		f.SetCellValue("Telemetry", createdCell, record.CreatedAt.Format("2006-01-02 15:04:05"))
+		if len(marks) > 0 {
+			f.SetCellValue("Telemetry", anomaliesCell, strings.Join(marks, "; "))
+		}
 	}
 
 	// Авто-ширина колонок
@@ -52,42 +89,17 @@ func CreateExcelFile(filepath string, records []models.Telemetry) error {
 		f.SetColWidth("Telemetry", colName, colName, 20)
 	}
 
-	// Добавляем условное форматирование для температуры
-	// Красный для высоких температур (> 60°C)
-	highTempRule := []excelize.ConditionalFormatOptions{
-		{
-			Type:     "cell",
-			Criteria: ">",
-			Value:    "60",
-			Format:   getConditionalFormatStyle(f, "#FFCCCC"),
-		},
-	}
-	err = f.SetConditionalFormat("Telemetry", "C2:C1000", highTempRule)
-	if err != nil {
-		return err
-	}
-
-	// Синий для низких температур (< -20°C)
-	lowTempRule := []excelize.ConditionalFormatOptions{
-		{
-			Type:     "cell",
-			Criteria: "<",
-			Value:    "-20",
-			Format:   getConditionalFormatStyle(f, "#CCE5FF"),
-		},
-	}
-	err = f.SetConditionalFormat("Telemetry", "C2:C1000", lowTempRule)
-	if err != nil {
-		return err
-	}
-
-	// Создаем график
+	// Создаем графики
 	if len(records) > 1 {
-		createChart(f, records)
+		createCharts(f, channels, len(records), len(headers))
 	}
 
 	// Создаем информационный лист
-	createInfoSheet(f, records)
+	summary := newExcelSummary()
+	for _, record := range records {
+		summary.add(record)
+	}
+	createInfoSheet(f, channels, summary)
 
 	// Устанавливаем активный лист
 	f.SetActiveSheet(index)
@@ -100,143 +112,177 @@ func CreateExcelFile(filepath string, records []models.Telemetry) error {
 	return nil
 }
 
-func getNumberStyle(f *excelize.File, format string) int {
-	formatInt, err := strconv.Atoi(format)
-	if err != nil {
-		return 0
+// anomalyMarks самый высокий уровень аномалии по каждому каналу показания и их описания
+func anomalyMarks(anomalies []models.TelemetryAnomaly) (map[string]string, []string) {
+	severity := make(map[string]string)
+	var marks []string
+	for _, anomaly := range anomalies {
+		if severity[anomaly.Channel] != models.AnomalySeverityCritical {
+			severity[anomaly.Channel] = anomaly.Severity
+		}
+		marks = append(marks, anomaly.Channel+": "+anomaly.Message)
 	}
-	style, _ := f.NewStyle(&excelize.Style{
-		NumFmt: formatInt,
-	})
-	return style
-}
-
-func createChart(f *excelize.File, records []models.Telemetry) {
-	// Создаем график температуры
-	chart := &excelize.Chart{
-		Type: excelize.Col3DClustered,
-		Series: []excelize.ChartSeries{
-			{
-				Name:       "Temperature",
-				Categories: "Telemetry!$A$2:$A$" + fmt.Sprintf("%d", len(records)+1),
-				Values:     "Telemetry!$C$2:$C$" + fmt.Sprintf("%d", len(records)+1),
-			},
-		},
-		Title: []excelize.RichTextRun{
-			{
-				Text: "Temperature Over Time",
-			},
-		},
-		XAxis: excelize.ChartAxis{
-			MajorGridLines: true,
-		},
-		YAxis: excelize.ChartAxis{
-			MajorGridLines: true,
-		},
-		Dimension: excelize.ChartDimension{
-			Width:  600,
-			Height: 400,
-		},
-	}
-
-	f.AddChart("Telemetry", "G2", chart)
+	return severity, marks
 }
 
-func createInfoSheet(f *excelize.File, records []models.Telemetry) {
-	// Создаем лист с информацией
-	f.NewSheet("Info")
-
-	// Записываем метаданные
-	metadata := map[string]interface{}{
-		"Report Generated": time.Now().Format("2006-01-02 15:04:05"),
-		"Total Records":    len(records),
-		"Time Range": fmt.Sprintf("%s to %s",
-			records[0].RecordedAt.Format("2006-01-02 15:04:05"),
-			records[len(records)-1].RecordedAt.Format("2006-01-02 15:04:05")),
-		"Voltage Range": fmt.Sprintf("%.2fV - %.2fV",
-			findMinVoltage(records), findMaxVoltage(records)),
-		"Temperature Range": fmt.Sprintf("%.2f°C - %.2f°C",
-			findMinTemperature(records), findMaxTemperature(records)),
-	}
-
-	row := 1
-	for key, value := range metadata {
-		f.SetCellValue("Info", fmt.Sprintf("A%d", row), key)
-		f.SetCellValue("Info", fmt.Sprintf("B%d", row), value)
-		row++
+// ChannelLabel заголовок столбца канала: "voltage (V)"; импорт понимает его обратно
+func ChannelLabel(channel models.TelemetryChannel) string {
+	if channel.Unit == "" {
+		return channel.Name
 	}
+	return fmt.Sprintf("%s (%s)", channel.Name, channel.Unit)
 }
 
-func findMinVoltage(records []models.Telemetry) float64 {
-	if len(records) == 0 {
-		return 0
+// getNumberStyle формат числа с точностью канала и, если задан цвет, заливка
+func getNumberStyle(f *excelize.File, precision int, color string) int {
+	format := "0"
+	if precision > 0 {
+		format += "." + strings.Repeat("0", precision)
 	}
-	min := records[0].Voltage
-	for _, r := range records {
-		if r.Voltage < min {
-			min = r.Voltage
+	style := &excelize.Style{CustomNumFmt: &format}
+	if color != "" {
+		style.Fill = excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{color},
+			Pattern: 1,
 		}
 	}
-	return min
+	id, _ := f.NewStyle(style)
+	return id
 }
 
-func findMaxVoltage(records []models.Telemetry) float64 {
-	if len(records) == 0 {
-		return 0
-	}
-	max := records[0].Voltage
-	for _, r := range records {
-		if r.Voltage > max {
-			max = r.Voltage
+// createCharts график на каждый числовой канал, друг под другом справа от таблицы
+func createCharts(f *excelize.File, channels []models.TelemetryChannel, rows, columns int) {
+	anchorCol, _ := excelize.ColumnNumberToName(columns + 2)
+	anchorRow := 2
+
+	for i, channel := range channels {
+		if channel.Type == models.ChannelTypeBoolean {
+			continue
 		}
+		colName, _ := excelize.ColumnNumberToName(i + 2)
+
+		chart := &excelize.Chart{
+			Type: excelize.Col3DClustered,
+			Series: []excelize.ChartSeries{
+				{
+					Name:       "Telemetry!$" + colName + "$1",
+					Categories: "Telemetry!$A$2:$A$" + fmt.Sprintf("%d", rows+1),
+					Values:     "Telemetry!$" + colName + "$2:$" + colName + "$" + fmt.Sprintf("%d", rows+1),
+				},
+			},
+			Title: []excelize.RichTextRun{
+				{
+					Text: ChannelLabel(channel) + " Over Time",
+				},
+			},
+			XAxis: excelize.ChartAxis{
+				MajorGridLines: true,
+			},
+			YAxis: excelize.ChartAxis{
+				MajorGridLines: true,
+			},
+			Dimension: excelize.ChartDimension{
+				Width:  600,
+				Height: 400,
+			},
+		}
+
+		f.AddChart("Telemetry", fmt.Sprintf("%s%d", anchorCol, anchorRow), chart)
+		// 400 px графика - примерно 21 строка стандартной высоты
+		anchorRow += 22
+	}
+}
+
+// excelSummary сводка для листа Info, накапливается по мере записи строк
+type excelSummary struct {
+	records int
+	first   time.Time
+	last    time.Time
+	min     map[string]float64
+	max     map[string]float64
+}
+
+func newExcelSummary() *excelSummary {
+	return &excelSummary{
+		min: make(map[string]float64),
+		max: make(map[string]float64),
 	}
-	return max
 }
 
-func findMinTemperature(records []models.Telemetry) float64 {
-	if len(records) == 0 {
-		return 0
+func (s *excelSummary) add(record models.Telemetry) {
+	if s.records == 0 || record.RecordedAt.Before(s.first) {
+		s.first = record.RecordedAt
+	}
+	if s.records == 0 || record.RecordedAt.After(s.last) {
+		s.last = record.RecordedAt
 	}
-	min := records[0].Temperature
-	for _, r := range records {
-		if r.Temperature < min {
-			min = r.Temperature
+	s.records++
+
+	for name, v := range record.Values {
+		if min, ok := s.min[name]; !ok || v < min {
+			s.min[name] = v
+		}
+		if max, ok := s.max[name]; !ok || v > max {
+			s.max[name] = v
 		}
 	}
-	return min
 }
 
-func findMaxTemperature(records []models.Telemetry) float64 {
-	if len(records) == 0 {
-		return 0
+func createInfoSheet(f *excelize.File, channels []models.TelemetryChannel, summary *excelSummary) {
+	// Создаем лист с информацией
+	f.NewSheet("Info")
+
+	// Записываем метаданные
+	rows := [][2]interface{}{
+		{"Report Generated", time.Now().Format("2006-01-02 15:04:05")},
+		{"Total Records", summary.records},
+	}
+	if summary.records > 0 {
+		rows = append(rows, [2]interface{}{"Time Range", fmt.Sprintf("%s to %s",
+			summary.first.Format("2006-01-02 15:04:05"),
+			summary.last.Format("2006-01-02 15:04:05"))})
 	}
-	max := records[0].Temperature
-	for _, r := range records {
-		if r.Temperature > max {
-			max = r.Temperature
+
+	for _, channel := range channels {
+		min, ok := summary.min[channel.Name]
+		if !ok || channel.Type == models.ChannelTypeBoolean {
+			continue
 		}
+		max := summary.max[channel.Name]
+		rows = append(rows, [2]interface{}{channel.Name + " Range", fmt.Sprintf("%.*f%s - %.*f%s",
+			channel.Precision, min, channel.Unit, channel.Precision, max, channel.Unit)})
 	}
-	return max
-}
 
-// SaveAsJSON сохраняет данные в JSON файл
-func SaveAsJSON(filepath string, data interface{}) error {
-	// Реализация сохранения в JSON
-	// (используйте encoding/json)
-	return nil
+	for i, row := range rows {
+		f.SetCellValue("Info", fmt.Sprintf("A%d", i+1), row[0])
+		f.SetCellValue("Info", fmt.Sprintf("B%d", i+1), row[1])
+	}
 }
 
-// getConditionalFormatStyle создает стиль для условного форматирования
-func getConditionalFormatStyle(f *excelize.File, color string) *int {
-	style, err := f.NewStyle(&excelize.Style{
-		Fill: excelize.Fill{
-			Type:    "pattern",
-			Color:   []string{color},
-			Pattern: 1,
-		},
-	})
+// SaveAsJSON сохраняет данные в JSON файл с отступами; файл заменяется целиком через временный
+func SaveAsJSON(filepath string, data interface{}) error {
+	tmpPath := filepath + ".tmp"
+	file, err := os.Create(tmpPath)
 	if err != nil {
-		return nil
+		return fmt.Errorf("failed to create JSON file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
-	return &style
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close JSON file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save JSON file: %w", err)
+	}
+	return nil
 }
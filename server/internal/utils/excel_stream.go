@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"cassiopeia/internal/models"
+)
+
+// TelemetryTimeLayout метка времени показания в выгрузках CSV и Excel: UTC с долями
+// секунды до микросекунд, как в базе, чтобы выгрузка импортировалась без потерь
+const TelemetryTimeLayout = "2006-01-02 15:04:05.999999"
+
+const (
+	// excelSheetRows строк данных на листе: остальное место занимает заголовок
+	excelSheetRows = excelize.TotalRows - 1
+	// excelChartMaxRows графики на больше точек Excel открывает слишком долго
+	excelChartMaxRows = 50000
+)
+
+// ExcelStream построчная запись выгрузки телеметрии через StreamWriter: строки сбрасываются
+// во временный файл excelize, поэтому память не зависит от их числа (в конце excelize
+// собирает в памяти только сжатую книгу). Показания сверх
+// предела листа переносятся на следующие листы Telemetry 2, Telemetry 3...
+type ExcelStream struct {
+	file     *excelize.File
+	writer   *excelize.StreamWriter
+	channels []models.TelemetryChannel
+	headers  []interface{}
+	styles   []map[string]int
+	summary  *excelSummary
+	sheets   int
+	row      int
+	// firstSheetRows строк данных на первом листе - для графиков
+	firstSheetRows int
+}
+
+func NewExcelStream(channels []models.TelemetryChannel) (*ExcelStream, error) {
+	f := excelize.NewFile()
+
+	// Тот же порядок столбцов, что и в CreateExcelFile
+	headers := []interface{}{"Timestamp"}
+	for _, channel := range channels {
+		headers = append(headers, ChannelLabel(channel))
+	}
+	headers = append(headers, "Source File", "Created At", "Anomalies")
+
+	styles := make([]map[string]int, len(channels))
+	for i, channel := range channels {
+		styles[i] = map[string]int{"": getNumberStyle(f, channel.Precision, "")}
+		for severity, color := range anomalyFills {
+			styles[i][severity] = getNumberStyle(f, channel.Precision, color)
+		}
+	}
+
+	stream := &ExcelStream{
+		file:     f,
+		channels: channels,
+		headers:  headers,
+		styles:   styles,
+		summary:  newExcelSummary(),
+	}
+	if err := stream.nextSheet(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return stream, nil
+}
+
+// nextSheet завершает текущий лист и начинает новый с заголовком
+func (s *ExcelStream) nextSheet() error {
+	if s.writer != nil {
+		if err := s.writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	s.sheets++
+	name := "Telemetry"
+	if s.sheets == 1 {
+		if err := s.file.SetSheetName("Sheet1", name); err != nil {
+			return err
+		}
+	} else {
+		name = fmt.Sprintf("Telemetry %d", s.sheets)
+		if _, err := s.file.NewSheet(name); err != nil {
+			return err
+		}
+	}
+
+	writer, err := s.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	// Ширину задаем до первой строки - так требует StreamWriter
+	if err := writer.SetColWidth(1, len(s.headers), 20); err != nil {
+		return err
+	}
+	if err := writer.SetRow("A1", s.headers); err != nil {
+		return err
+	}
+
+	s.writer = writer
+	s.row = 1
+	return nil
+}
+
+// WriteRecords дописывает показания; аномалии закрашиваются и перечисляются, как в CreateExcelFile
+func (s *ExcelStream) WriteRecords(records []models.Telemetry, anomalies map[uint][]models.TelemetryAnomaly) error {
+	for _, record := range records {
+		if s.row > excelSheetRows {
+			if err := s.nextSheet(); err != nil {
+				return err
+			}
+		}
+		s.row++
+		if s.sheets == 1 {
+			s.firstSheetRows++
+		}
+		s.summary.add(record)
+
+		severity, marks := anomalyMarks(anomalies[record.ID])
+
+		values := make([]interface{}, len(s.headers))
+		values[0] = record.RecordedAt.UTC().Format(TelemetryTimeLayout)
+		for i, channel := range s.channels {
+			v, ok := record.Values[channel.Name]
+			if !ok {
+				continue
+			}
+			var value interface{} = v
+			if channel.Type == models.ChannelTypeBoolean {
+				value = v != 0
+			}
+			values[i+1] = excelize.Cell{StyleID: s.styles[i][severity[channel.Name]], Value: value}
+		}
+		values[len(s.channels)+1] = record.SourceFile
+		values[len(s.channels)+2] = record.CreatedAt.Format("2006-01-02 15:04:05")
+		if len(marks) > 0 {
+			values[len(s.channels)+3] = strings.Join(marks, "; ")
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(1, s.row)
+		if err := s.writer.SetRow(cell, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo завершает книгу (графики, лист Info) и пишет ее в w
+func (s *ExcelStream) WriteTo(w io.Writer) (int64, error) {
+	if err := s.writer.Flush(); err != nil {
+		return 0, err
+	}
+
+	if s.firstSheetRows > 1 && s.firstSheetRows <= excelChartMaxRows {
+		createCharts(s.file, s.channels, s.firstSheetRows, len(s.headers))
+	}
+	// Активным остается первый лист; SetActiveSheet перечитал бы уже выгруженные листы
+	createInfoSheet(s.file, s.channels, s.summary)
+
+	return s.file.WriteTo(w)
+}
+
+// Close удаляет временные файлы excelize
+func (s *ExcelStream) Close() error {
+	return s.file.Close()
+}
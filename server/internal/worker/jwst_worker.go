@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cassiopeia/internal/service"
+)
+
+type JWSTWorker struct {
+	service  service.JWSTService
+	interval time.Duration
+	stopChan chan struct{}
+	running  bool
+}
+
+func NewJWSTWorker(service service.JWSTService, interval time.Duration) *JWSTWorker {
+	return &JWSTWorker{
+		service:  service,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (w *JWSTWorker) Start() {
+	if w.running {
+		return
+	}
+
+	w.running = true
+	log.Printf("JWST Worker started with interval %v", w.interval)
+
+	go w.run()
+}
+
+func (w *JWSTWorker) Stop() {
+	if !w.running {
+		return
+	}
+
+	close(w.stopChan)
+	w.running = false
+	log.Println("JWST Worker stopped")
+}
+
+func (w *JWSTWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// Первый обход сразу
+	w.syncCatalog()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.syncCatalog()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *JWSTWorker) syncCatalog() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := w.service.SyncCatalog(ctx); err != nil {
+		log.Printf("JWST Worker error: %v", err)
+	} else {
+		log.Println("JWST Worker: catalog synced")
+	}
+}
@@ -89,5 +89,12 @@ func (w *NASAWorker) syncNASA() {
 		log.Println("NASA Worker: NEO data updated")
 	}
 
+	// 4. Сохраняем события космической погоды DONKI
+	if err := w.service.FetchAndStoreDONKI(ctx); err != nil {
+		log.Printf("NASA Worker DONKI error: %v", err)
+	} else {
+		log.Println("NASA Worker: DONKI events stored")
+	}
+
 	log.Println("NASA Worker: Sync completed")
 }
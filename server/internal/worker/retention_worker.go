@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cassiopeia/internal/service"
+)
+
+// RetentionWorker по расписанию архивирует и удаляет данные старше сроков хранения
+type RetentionWorker struct {
+	service  service.RetentionService
+	interval time.Duration
+	stopChan chan struct{}
+	done     chan struct{}
+	running  bool
+}
+
+func NewRetentionWorker(service service.RetentionService, interval time.Duration) *RetentionWorker {
+	return &RetentionWorker{
+		service:  service,
+		interval: interval,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (w *RetentionWorker) Start() {
+	if w.running {
+		return
+	}
+
+	w.running = true
+	log.Printf("Retention Worker started with interval %v", w.interval)
+
+	go w.run()
+}
+
+func (w *RetentionWorker) Stop() {
+	if !w.running {
+		return
+	}
+
+	close(w.stopChan)
+	w.running = false
+
+	// Текущие сутки дописываются или откатываются транзакцией - ждем недолго
+	select {
+	case <-w.done:
+		log.Println("Retention Worker stopped")
+	case <-time.After(5 * time.Second):
+		log.Println("Retention Worker stop timeout")
+	}
+}
+
+func (w *RetentionWorker) run() {
+	defer close(w.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-w.stopChan
+		cancel()
+	}()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.apply(ctx)
+	for {
+		select {
+		case <-ticker.C:
+			w.apply(ctx)
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *RetentionWorker) apply(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+
+	report, err := w.service.Run(ctx, false)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Printf("Retention Worker error: %v", err)
+		}
+		if report == nil {
+			return
+		}
+	}
+
+	mode := ""
+	if report.DryRun {
+		mode = " (dry run)"
+	}
+	for _, table := range report.Tables {
+		if table.Rows > 0 || table.Pending {
+			log.Printf("Retention Worker%s: %s - %d rows in %d days before %s, pending: %v",
+				mode, table.Table, table.Rows, len(table.Days), table.Cutoff.Format("2006-01-02"), table.Pending)
+		}
+	}
+	if report.Exports != nil && report.Exports.Files > 0 {
+		log.Printf("Retention Worker%s: %d export files (%d bytes) removed from %s",
+			mode, report.Exports.Files, report.Exports.Bytes, report.Exports.Dir)
+	}
+}
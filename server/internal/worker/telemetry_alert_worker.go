@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"cassiopeia/internal/service"
+)
+
+// TelemetryAlertWorker плановая проверка правил тревоги по показаниям в базе
+type TelemetryAlertWorker struct {
+	service  service.TelemetryAlertService
+	stopChan chan struct{}
+	done     chan struct{}
+	running  bool
+}
+
+func NewTelemetryAlertWorker(service service.TelemetryAlertService) *TelemetryAlertWorker {
+	return &TelemetryAlertWorker{
+		service:  service,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (w *TelemetryAlertWorker) Start() {
+	if w.running {
+		return
+	}
+
+	w.running = true
+	log.Println("Telemetry Alert Worker started")
+
+	go func() {
+		defer close(w.done)
+		w.service.Run(w.stopChan)
+	}()
+}
+
+func (w *TelemetryAlertWorker) Stop() {
+	if !w.running {
+		return
+	}
+
+	close(w.stopChan)
+	w.running = false
+
+	// Ждем отправки уже поставленных уведомлений
+	select {
+	case <-w.done:
+		log.Println("Telemetry Alert Worker stopped")
+	case <-time.After(15 * time.Second):
+		log.Println("Telemetry Alert Worker stop timeout, pending notifications are lost")
+	}
+}
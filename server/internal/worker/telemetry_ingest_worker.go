@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"cassiopeia/internal/service"
+)
+
+// TelemetryIngestWorker пишет в базу очередь показаний, принятых через /telemetry/ingest
+type TelemetryIngestWorker struct {
+	service  service.TelemetryIngestService
+	stopChan chan struct{}
+	done     chan struct{}
+	running  bool
+}
+
+func NewTelemetryIngestWorker(service service.TelemetryIngestService) *TelemetryIngestWorker {
+	return &TelemetryIngestWorker{
+		service:  service,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (w *TelemetryIngestWorker) Start() {
+	if w.running {
+		return
+	}
+
+	w.running = true
+	log.Println("Telemetry Ingest Worker started")
+
+	go func() {
+		defer close(w.done)
+		w.service.Run(w.stopChan)
+	}()
+}
+
+func (w *TelemetryIngestWorker) Stop() {
+	if !w.running {
+		return
+	}
+
+	close(w.stopChan)
+	w.running = false
+
+	// Ждем, пока остаток очереди запишется в базу
+	select {
+	case <-w.done:
+		log.Println("Telemetry Ingest Worker stopped")
+	case <-time.After(30 * time.Second):
+		log.Println("Telemetry Ingest Worker stop timeout, unsaved readings are lost")
+	}
+}
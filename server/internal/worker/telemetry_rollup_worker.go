@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cassiopeia/internal/service"
+)
+
+// TelemetryRollupWorker досчитывает сводки 1m/1h/1d по новым показаниям
+type TelemetryRollupWorker struct {
+	service  service.TelemetryRollupService
+	interval time.Duration
+	stopChan chan struct{}
+	running  bool
+}
+
+func NewTelemetryRollupWorker(service service.TelemetryRollupService, interval time.Duration) *TelemetryRollupWorker {
+	return &TelemetryRollupWorker{
+		service:  service,
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+func (w *TelemetryRollupWorker) Start() {
+	if w.running {
+		return
+	}
+
+	w.running = true
+	log.Printf("Telemetry Rollup Worker started with interval %v", w.interval)
+
+	// Сразу догоняем показания, пришедшие, пока сервис не работал
+	w.refresh()
+
+	go w.run()
+}
+
+func (w *TelemetryRollupWorker) Stop() {
+	if !w.running {
+		return
+	}
+
+	close(w.stopChan)
+	w.running = false
+	log.Println("Telemetry Rollup Worker stopped")
+}
+
+func (w *TelemetryRollupWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.refresh()
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+func (w *TelemetryRollupWorker) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := w.service.Refresh(ctx); err != nil {
+		log.Printf("Telemetry Rollup Worker error: %v", err)
+	}
+}
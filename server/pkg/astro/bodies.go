@@ -0,0 +1,164 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Body тело Солнечной системы
+type Body string
+
+const (
+	Sun     Body = "sun"
+	Moon    Body = "moon"
+	Mercury Body = "mercury"
+	Venus   Body = "venus"
+	Mars    Body = "mars"
+	Jupiter Body = "jupiter"
+	Saturn  Body = "saturn"
+	Uranus  Body = "uranus"
+	Neptune Body = "neptune"
+
+	// earth барицентр Земля-Луна, используется только для геоцентрических векторов
+	earth Body = "earth"
+)
+
+// Bodies тела в порядке вывода
+var Bodies = []Body{Sun, Moon, Mercury, Venus, Mars, Jupiter, Saturn, Uranus, Neptune}
+
+var bodyNames = map[Body]string{
+	Sun: "Sun", Moon: "Moon", Mercury: "Mercury", Venus: "Venus", Mars: "Mars",
+	Jupiter: "Jupiter", Saturn: "Saturn", Uranus: "Uranus", Neptune: "Neptune",
+}
+
+// Name название тела для отображения
+func (b Body) Name() string {
+	return bodyNames[b]
+}
+
+// planetHorizon высота центра точечного объекта при восходе (рефракция), градусы
+const planetHorizon = -0.5667
+
+// BodyPosition положение тела для наблюдателя в момент времени
+type BodyPosition struct {
+	Body Body   `json:"body"`
+	Name string `json:"name"`
+	// Экваториальные координаты даты, градусы
+	RA  float64 `json:"ra"`
+	Dec float64 `json:"dec"`
+	// Эклиптические координаты даты, градусы
+	EclipticLon float64 `json:"ecliptic_lon"`
+	EclipticLat float64 `json:"ecliptic_lat"`
+	Altitude    float64 `json:"altitude"`
+	Azimuth     float64 `json:"azimuth"`
+	// DistanceAU геоцентрическое расстояние, а.е.
+	DistanceAU    float64 `json:"distance_au"`
+	Magnitude     float64 `json:"magnitude"`
+	Constellation string  `json:"constellation"`
+	// Elongation угловое расстояние от Солнца, градусы
+	Elongation float64 `json:"elongation"`
+	// Illumination освещенная доля диска
+	Illumination float64 `json:"illumination"`
+}
+
+// Position вычисляет положение тела в момент t для наблюдателя на lat/lon
+func Position(body Body, t time.Time, lat, lon float64) BodyPosition {
+	jd := JulianDay(t)
+	tc := julianCenturies(jd)
+	sun := SunPosition(jd)
+
+	pos := BodyPosition{Body: body, Name: body.Name()}
+
+	switch body {
+	case Sun:
+		pos.EclipticLon = sun.Longitude
+		pos.DistanceAU = sun.Distance
+		pos.Magnitude = -26.74
+		pos.Illumination = 1
+
+	case Moon:
+		moon := MoonPosition(jd)
+		pos.EclipticLon, pos.EclipticLat = moon.Longitude, moon.Latitude
+		pos.DistanceAU = moon.Distance / auKm
+		pos.Illumination = Illumination(jd)
+		// Фазовый угол из освещенности: k = (1 + cos i) / 2
+		i := math.Acos(2*pos.Illumination-1) * rad
+		pos.Magnitude = -12.73 + 0.026*math.Abs(i) + 4e-9*math.Pow(i, 4)
+
+	default:
+		g := planetPosition(body, jd)
+		pos.EclipticLon, pos.EclipticLat = g.lon, g.lat
+		pos.DistanceAU = g.delta
+		pos.Magnitude = planetMagnitude(body, g, tc)
+		pos.Illumination = (1 + math.Cos(g.phaseAngle()*deg)) / 2
+	}
+
+	eq := EclipticToEquatorial(pos.EclipticLon, pos.EclipticLat, jd)
+	if body == Sun {
+		eq = sun.Equatorial
+	}
+	pos.RA, pos.Dec = eq.RA, eq.Dec
+
+	horizontal := ToHorizontal(eq, jd, lat, lon)
+	if body == Moon {
+		// Суточный параллакс Луны заметно понижает ее над горизонтом
+		parallax := math.Asin(earthRadiusKm/(pos.DistanceAU*auKm)) * rad
+		horizontal.Altitude -= parallax * math.Cos(horizontal.Altitude*deg)
+	}
+	pos.Altitude, pos.Azimuth = horizontal.Altitude, horizontal.Azimuth
+
+	if body != Sun {
+		pos.Elongation = AngularSeparation(eq, sun.Equatorial)
+	}
+
+	// Границы созвездий заданы для эпохи J2000 - убираем прецессию с начала эпохи
+	pos.Constellation = EclipticConstellation(pos.EclipticLon - 1.396971*tc)
+
+	return pos
+}
+
+// RiseTransitSet восход, верхняя кульминация и заход тела за сутки
+type RiseTransitSet struct {
+	Rise       *time.Time `json:"rise,omitempty"`
+	Transit    *time.Time `json:"transit,omitempty"`
+	Set        *time.Time `json:"set,omitempty"`
+	AlwaysUp   bool       `json:"always_up,omitempty"`
+	AlwaysDown bool       `json:"always_down,omitempty"`
+}
+
+// BodyEvents считает восход, кульминацию и заход тела в сутки, содержащие date
+// (границы суток - в часовом поясе date)
+func BodyEvents(body Body, date time.Time, lat, lon float64) RiseTransitSet {
+	start, end := dayBounds(date)
+
+	equatorial := func(jd float64) Equatorial {
+		switch body {
+		case Sun:
+			return SunPosition(jd).Equatorial
+		case Moon:
+			return MoonPosition(jd).Equatorial
+		}
+		g := planetPosition(body, jd)
+		return EclipticToEquatorial(g.lon, g.lat, jd)
+	}
+
+	position := func(jd float64) (Equatorial, float64) {
+		switch body {
+		case Sun:
+			return SunPosition(jd).Equatorial, SunriseAltitude
+		case Moon:
+			moon := MoonPosition(jd)
+			return moon.Equatorial, moonHorizon(moon.Distance)
+		}
+		return equatorial(jd), planetHorizon
+	}
+
+	rise, set, up, down := riseSet(position, start, end, lat, lon, 0)
+	return RiseTransitSet{
+		Rise:       inLocation(rise, date),
+		Transit:    inLocation(transit(equatorial, start, end, lon), date),
+		Set:        inLocation(set, date),
+		AlwaysUp:   up,
+		AlwaysDown: down,
+	}
+}
@@ -0,0 +1,38 @@
+package astro
+
+// Границы созвездий вдоль эклиптики: эклиптическая долгота J2000, с которой
+// начинается созвездие (по границам МАС). Для тел Солнечной системы, которые
+// держатся в нескольких градусах от эклиптики, этого достаточно; вблизи углов
+// границ (Кит, Орион, Секстант) результат может отличаться от точного.
+var eclipticBoundaries = []struct {
+	start float64
+	name  string
+}{
+	{29.09, "Aries"},
+	{53.47, "Taurus"},
+	{90.43, "Gemini"},
+	{118.26, "Cancer"},
+	{138.18, "Leo"},
+	{174.15, "Virgo"},
+	{218.00, "Libra"},
+	{241.12, "Scorpius"},
+	{247.68, "Ophiuchus"},
+	{266.28, "Sagittarius"},
+	{299.66, "Capricornus"},
+	{327.48, "Aquarius"},
+	{351.57, "Pisces"},
+}
+
+// EclipticConstellation созвездие зодиакального пояса по эклиптической долготе J2000
+func EclipticConstellation(lon float64) string {
+	lon = normalizeDegrees(lon)
+
+	// Pisces переходит через 0°
+	name := "Pisces"
+	for _, boundary := range eclipticBoundaries {
+		if lon >= boundary.start {
+			name = boundary.name
+		}
+	}
+	return name
+}
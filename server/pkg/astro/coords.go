@@ -0,0 +1,119 @@
+// Package astro вычисляет положения Солнца и Луны, восходы, заходы, сумерки
+// и фазы Луны без обращения к внешним API. Формулы - упрощенные ряды из
+// "Astronomical Algorithms" Ж. Меёса; точность порядка минуты времени для
+// восходов и заходов, чего достаточно для дашборда и планирования наблюдений.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	deg = math.Pi / 180
+	rad = 180 / math.Pi
+
+	// j2000 юлианская дата эпохи J2000.0
+	j2000 = 2451545.0
+	// SynodicMonth средняя продолжительность синодического месяца, сутки
+	SynodicMonth = 29.530588853
+)
+
+// Equatorial экваториальные координаты, градусы
+type Equatorial struct {
+	RA  float64
+	Dec float64
+}
+
+// Horizontal горизонтальные координаты, градусы; азимут от севера через восток
+type Horizontal struct {
+	Altitude float64
+	Azimuth  float64
+}
+
+// JulianDay юлианская дата момента t
+func JulianDay(t time.Time) float64 {
+	return float64(t.UTC().UnixNano())/86400e9 + 2440587.5
+}
+
+// TimeFromJulianDay обратное преобразование юлианской даты
+func TimeFromJulianDay(jd float64) time.Time {
+	return time.Unix(0, int64((jd-2440587.5)*86400e9)).UTC()
+}
+
+// julianCenturies время от J2000.0 в юлианских столетиях
+func julianCenturies(jd float64) float64 {
+	return (jd - j2000) / 36525
+}
+
+// normalizeDegrees приводит угол к диапазону [0, 360)
+func normalizeDegrees(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// normalizeSigned приводит угол к диапазону [-180, 180)
+func normalizeSigned(a float64) float64 {
+	a = normalizeDegrees(a)
+	if a >= 180 {
+		a -= 360
+	}
+	return a
+}
+
+// obliquity наклон эклиптики с поправкой на нутацию, градусы
+func obliquity(t float64) float64 {
+	omega := 125.04 - 1934.136*t
+	return 23.439291 - 0.0130042*t + 0.00256*math.Cos(omega*deg)
+}
+
+// nutationLongitude главный член нутации по долготе, градусы
+func nutationLongitude(t float64) float64 {
+	omega := 125.04 - 1934.136*t
+	return -0.00478 * math.Sin(omega*deg)
+}
+
+// EclipticToEquatorial переводит эклиптические координаты даты в экваториальные
+func EclipticToEquatorial(lon, lat, jd float64) Equatorial {
+	eps := obliquity(julianCenturies(jd)) * deg
+	l, b := lon*deg, lat*deg
+
+	ra := math.Atan2(math.Sin(l)*math.Cos(eps)-math.Tan(b)*math.Sin(eps), math.Cos(l))
+	dec := math.Asin(math.Sin(b)*math.Cos(eps) + math.Cos(b)*math.Sin(eps)*math.Sin(l))
+
+	return Equatorial{RA: normalizeDegrees(ra * rad), Dec: dec * rad}
+}
+
+// SiderealTime среднее звездное время Гринвича, градусы
+func SiderealTime(jd float64) float64 {
+	t := julianCenturies(jd)
+	return normalizeDegrees(280.46061837 + 360.98564736629*(jd-j2000) +
+		0.000387933*t*t - t*t*t/38710000)
+}
+
+// HourAngle местный часовой угол объекта в диапазоне [-180, 180), градусы
+func HourAngle(eq Equatorial, jd, lon float64) float64 {
+	return normalizeSigned(SiderealTime(jd) + lon - eq.RA)
+}
+
+// ToHorizontal переводит экваториальные координаты в горизонтальные
+// для наблюдателя на широте lat и долготе lon (восточная положительна)
+func ToHorizontal(eq Equatorial, jd, lat, lon float64) Horizontal {
+	h := HourAngle(eq, jd, lon) * deg
+	phi, dec := lat*deg, eq.Dec*deg
+
+	alt := math.Asin(math.Sin(phi)*math.Sin(dec) + math.Cos(phi)*math.Cos(dec)*math.Cos(h))
+	az := math.Atan2(-math.Cos(dec)*math.Sin(h), math.Sin(dec)*math.Cos(phi)-math.Cos(dec)*math.Sin(phi)*math.Cos(h))
+
+	return Horizontal{Altitude: alt * rad, Azimuth: normalizeDegrees(az * rad)}
+}
+
+// AngularSeparation угловое расстояние между двумя точками неба, градусы
+func AngularSeparation(a, b Equatorial) float64 {
+	d1, d2 := a.Dec*deg, b.Dec*deg
+	cos := math.Sin(d1)*math.Sin(d2) + math.Cos(d1)*math.Cos(d2)*math.Cos((a.RA-b.RA)*deg)
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * rad
+}
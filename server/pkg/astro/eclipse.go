@@ -0,0 +1,430 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	sunRadiusKm  = 696000.0
+	moonRadiusKm = 1737.4
+	// Шаг по синодическим месяцам и окно поиска максимума вокруг сизигии
+	eclipseWindow = 5 * time.Hour
+)
+
+// Типы затмений
+const (
+	EclipsePenumbral = "penumbral"
+	EclipsePartial   = "partial"
+	EclipseTotal     = "total"
+	EclipseAnnular   = "annular"
+	EclipseHybrid    = "hybrid"
+)
+
+// EclipseContact момент фазы затмения; у локальных обстоятельств - с высотой тела
+type EclipseContact struct {
+	// Event penumbral_start, partial_start, total_start, greatest, total_end, ...
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	// Altitude высота Луны (лунное затмение) или Солнца (солнечное), градусы
+	Altitude     float64 `json:"altitude"`
+	AboveHorizon bool    `json:"above_horizon"`
+}
+
+// LunarEclipse лунное затмение. Видно сразу со всей ночной стороны Земли,
+// поэтому моменты фаз общие, а от наблюдателя зависит только высота Луны.
+type LunarEclipse struct {
+	Type     string    `json:"type"`
+	Greatest time.Time `json:"greatest"`
+	// Gamma расстояние центра Луны от оси тени в радиусах Земли, со знаком широты
+	Gamma              float64          `json:"gamma"`
+	PenumbralMagnitude float64          `json:"penumbral_magnitude"`
+	UmbralMagnitude    float64          `json:"umbral_magnitude"`
+	Contacts           []EclipseContact `json:"contacts"`
+}
+
+// SolarEclipse глобальные обстоятельства солнечного затмения
+type SolarEclipse struct {
+	Type     string    `json:"type"`
+	Greatest time.Time `json:"greatest"`
+	// Gamma расстояние оси тени Луны от центра Земли в экваториальных радиусах
+	Gamma float64 `json:"gamma"`
+	// Magnitude доля диаметра Солнца, закрытая в точке наибольшей фазы (для
+	// центральных - отношение видимых диаметров Луны и Солнца)
+	Magnitude float64 `json:"magnitude"`
+	Central   bool    `json:"central"`
+}
+
+// LocalSolarEclipse солнечное затмение в месте наблюдения
+type LocalSolarEclipse struct {
+	Type        string           `json:"type"`
+	Magnitude   float64          `json:"magnitude"`
+	Obscuration float64          `json:"obscuration"`
+	Visible     bool             `json:"visible"`
+	Contacts    []EclipseContact `json:"contacts"`
+}
+
+// equatorialVector геоцентрический вектор в экваториальной системе даты, км
+func equatorialVector(eq Equatorial, distance float64) vector3 {
+	ra, dec := eq.RA*deg, eq.Dec*deg
+	return vector3{
+		x: distance * math.Cos(dec) * math.Cos(ra),
+		y: distance * math.Cos(dec) * math.Sin(ra),
+		z: distance * math.Sin(dec),
+	}
+}
+
+func dot(a, b vector3) float64 { return a.x*b.x + a.y*b.y + a.z*b.z }
+
+func (v vector3) scale(k float64) vector3 { return vector3{v.x * k, v.y * k, v.z * k} }
+
+// angleBetween угол между векторами, градусы
+func angleBetween(a, b vector3) float64 {
+	cos := dot(a, b) / (a.length() * b.length())
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * rad
+}
+
+// minimize золотое сечение: момент минимума унимодальной f на [a, b] с точностью до секунды
+func minimize(f func(time.Time) float64, a, b time.Time) time.Time {
+	for b.Sub(a) > time.Second {
+		m1 := a.Add(b.Sub(a) / 3)
+		m2 := b.Add(-b.Sub(a) / 3)
+		if f(m1) < f(m2) {
+			b = m2
+		} else {
+			a = m1
+		}
+	}
+	return a.Add(b.Sub(a) / 2).Truncate(time.Second)
+}
+
+// lunarShadow геометрия тени Земли в момент t (UT): расстояние Луны от оси тени,
+// радиусы полутени и тени и полудиаметр Луны, градусы
+type lunarShadow struct {
+	separation, penumbra, umbra, moonRadius float64
+	gamma                                   float64
+}
+
+func lunarShadowAt(t time.Time) lunarShadow {
+	jde := dynamicalDay(t)
+	moon := MoonPosition(jde)
+	sun := SunPosition(jde)
+
+	// Центр тени - точка, противоположная Солнцу; широта Солнца принята нулевой
+	cos := math.Cos(moon.Latitude*deg) * math.Cos((moon.Longitude-sun.Longitude-180)*deg)
+	separation := math.Acos(math.Max(-1, math.Min(1, cos))) * rad
+
+	moonParallax := math.Asin(earthRadiusKm/moon.Distance) * rad
+	sunParallax := 8.794 / 3600 / sun.Distance
+	sunRadius := 959.63 / 3600 / sun.Distance
+
+	// Метод Данжона: атмосфера увеличивает тень примерно на 1/85 радиуса Земли
+	parallax := 1.01 * 0.998340 * moonParallax
+	sign := 1.0
+	if moon.Latitude < 0 {
+		sign = -1
+	}
+
+	return lunarShadow{
+		separation: separation,
+		penumbra:   parallax + sunParallax + sunRadius,
+		umbra:      parallax + sunParallax - sunRadius,
+		moonRadius: math.Asin(moonRadiusKm/moon.Distance) * rad,
+		gamma:      sign * math.Sin(separation*deg) * moon.Distance / earthRadiusKm,
+	}
+}
+
+// LunarEclipses лунные затмения с максимумом в интервале [from, to)
+func LunarEclipses(from, to time.Time) []LunarEclipse {
+	var eclipses []LunarEclipse
+	for full := NextPhase(from.Add(-eclipseWindow), FullMoon); full.Before(to.Add(eclipseWindow)); full = NextPhase(full.Add(24*time.Hour), FullMoon) {
+		eclipse, ok := lunarEclipseNear(full)
+		if ok && !eclipse.Greatest.Before(from) && eclipse.Greatest.Before(to) {
+			eclipses = append(eclipses, eclipse)
+		}
+	}
+	return eclipses
+}
+
+func lunarEclipseNear(full time.Time) (LunarEclipse, bool) {
+	greatest := minimize(func(t time.Time) float64 {
+		return lunarShadowAt(t).separation
+	}, full.Add(-eclipseWindow), full.Add(eclipseWindow))
+
+	g := lunarShadowAt(greatest)
+	penumbral := (g.penumbra + g.moonRadius - g.separation) / (2 * g.moonRadius)
+	if penumbral <= 0 {
+		return LunarEclipse{}, false
+	}
+	umbral := (g.umbra + g.moonRadius - g.separation) / (2 * g.moonRadius)
+
+	eclipse := LunarEclipse{
+		Type:               EclipsePenumbral,
+		Greatest:           greatest,
+		Gamma:              round4(g.gamma),
+		PenumbralMagnitude: round4(penumbral),
+		UmbralMagnitude:    round4(umbral),
+	}
+	switch {
+	case umbral >= 1:
+		eclipse.Type = EclipseTotal
+	case umbral > 0:
+		eclipse.Type = EclipsePartial
+	}
+
+	// contact касание, когда расстояние от оси тени равно radius(shadow)
+	contact := func(radius func(lunarShadow) float64) (time.Time, time.Time) {
+		f := func(t time.Time) float64 {
+			s := lunarShadowAt(t)
+			return s.separation - radius(s)
+		}
+		return bisect(f, greatest.Add(-eclipseWindow), greatest, false),
+			bisect(f, greatest, greatest.Add(eclipseWindow), true)
+	}
+
+	p1, p4 := contact(func(s lunarShadow) float64 { return s.penumbra + s.moonRadius })
+	contacts := []EclipseContact{{Event: "penumbral_start", Time: p1}}
+	var tail []EclipseContact
+	if umbral > 0 {
+		u1, u4 := contact(func(s lunarShadow) float64 { return s.umbra + s.moonRadius })
+		contacts = append(contacts, EclipseContact{Event: "partial_start", Time: u1})
+		tail = append(tail, EclipseContact{Event: "partial_end", Time: u4})
+	}
+	if umbral >= 1 {
+		u2, u3 := contact(func(s lunarShadow) float64 { return s.umbra - s.moonRadius })
+		contacts = append(contacts, EclipseContact{Event: "total_start", Time: u2})
+		tail = append([]EclipseContact{{Event: "total_end", Time: u3}}, tail...)
+	}
+	contacts = append(contacts, EclipseContact{Event: "greatest", Time: greatest})
+	contacts = append(contacts, tail...)
+	eclipse.Contacts = append(contacts, EclipseContact{Event: "penumbral_end", Time: p4})
+
+	return eclipse, true
+}
+
+// Local высота Луны в моменты фаз для наблюдателя; затмение видно,
+// если Луна над горизонтом хотя бы в одной из фаз
+func (e LunarEclipse) Local(lat, lon float64) ([]EclipseContact, bool) {
+	contacts := make([]EclipseContact, len(e.Contacts))
+	visible := false
+	for i, c := range e.Contacts {
+		altitude := MoonAltitude(c.Time, lat, lon)
+		c.Altitude = round1(altitude)
+		c.AboveHorizon = altitude > 0
+		visible = visible || c.AboveHorizon
+		contacts[i] = c
+	}
+	return contacts, visible
+}
+
+// solarAxis геометрия оси тени Луны в момент t (UT)
+type solarAxis struct {
+	sun, moon vector3
+	// gamma расстояние оси от центра Земли, км (со знаком: плюс - ось севернее центра)
+	gamma float64
+	// planeDistance расстояние от Луны до фундаментальной плоскости вдоль оси, км
+	planeDistance float64
+	tanPenumbra   float64
+	tanUmbra      float64
+}
+
+func solarAxisAt(t time.Time) solarAxis {
+	jde := dynamicalDay(t)
+	moonPos := MoonPosition(jde)
+	sunPos := SunPosition(jde)
+	moon := equatorialVector(moonPos.Equatorial, moonPos.Distance)
+	sun := equatorialVector(sunPos.Equatorial, sunPos.Distance*auKm)
+
+	sunMoon := moon.sub(sun)
+	d := sunMoon.scale(1 / sunMoon.length())
+	along := dot(moon, d)
+	closest := moon.sub(d.scale(along))
+
+	gamma := closest.length()
+	if closest.z < 0 {
+		gamma = -gamma
+	}
+
+	return solarAxis{
+		sun:           sun,
+		moon:          moon,
+		gamma:         gamma,
+		planeDistance: -along,
+		tanPenumbra:   math.Tan(math.Asin((sunRadiusKm + moonRadiusKm) / sunMoon.length())),
+		tanUmbra:      math.Tan(math.Asin((sunRadiusKm - moonRadiusKm) / sunMoon.length())),
+	}
+}
+
+// umbraRadius радиус тени на расстоянии s от Луны вдоль оси; отрицательный - антитень
+func (a solarAxis) umbraRadius(s float64) float64 {
+	return moonRadiusKm - s*a.tanUmbra
+}
+
+// SolarEclipses солнечные затмения с наибольшей фазой в интервале [from, to)
+func SolarEclipses(from, to time.Time) []SolarEclipse {
+	var eclipses []SolarEclipse
+	for newMoon := NextPhase(from.Add(-eclipseWindow), NewMoon); newMoon.Before(to.Add(eclipseWindow)); newMoon = NextPhase(newMoon.Add(24*time.Hour), NewMoon) {
+		eclipse, ok := solarEclipseNear(newMoon)
+		if ok && !eclipse.Greatest.Before(from) && eclipse.Greatest.Before(to) {
+			eclipses = append(eclipses, eclipse)
+		}
+	}
+	return eclipses
+}
+
+func solarEclipseNear(newMoon time.Time) (SolarEclipse, bool) {
+	// Наибольшая фаза - момент, когда ось тени ближе всего к центру Земли
+	greatest := minimize(func(t time.Time) float64 {
+		return math.Abs(solarAxisAt(t).gamma)
+	}, newMoon.Add(-eclipseWindow), newMoon.Add(eclipseWindow))
+
+	a := solarAxisAt(greatest)
+	gamma := math.Abs(a.gamma) / wgs84RadiusKm
+	penumbra := (moonRadiusKm + a.planeDistance*a.tanPenumbra) / wgs84RadiusKm
+	// u в обозначениях Меёса: радиус тени на фундаментальной плоскости, плюс - антитень
+	u := -a.umbraRadius(a.planeDistance) / wgs84RadiusKm
+
+	// 0.9972 - полярный радиус с учетом сжатия Земли
+	if gamma >= 0.9972+penumbra {
+		return SolarEclipse{}, false
+	}
+
+	eclipse := SolarEclipse{
+		Type:     EclipsePartial,
+		Greatest: greatest,
+		Gamma:    round4(a.gamma / wgs84RadiusKm),
+	}
+
+	switch {
+	case gamma < 0.9972:
+		eclipse.Central = true
+		// Точка поверхности на оси ближе к Луне, чем фундаментальная плоскость
+		surface := a.planeDistance - wgs84RadiusKm*math.Sqrt(1-gamma*gamma)
+		sunDistance := a.moon.sub(a.sun).length() + surface
+		eclipse.Magnitude = round4((moonRadiusKm / surface) / (sunRadiusKm / sunDistance))
+
+		switch {
+		case a.umbraRadius(surface) <= 0:
+			eclipse.Type = EclipseAnnular
+		case a.umbraRadius(a.planeDistance) > 0:
+			eclipse.Type = EclipseTotal
+		default:
+			// Полное в середине полосы, кольцеобразное у ее концов
+			eclipse.Type = EclipseHybrid
+		}
+	case gamma < 0.9972+math.Abs(u):
+		// Нецентральное: край тени задевает полярную область
+		eclipse.Type = EclipseAnnular
+		if u < 0 {
+			eclipse.Type = EclipseTotal
+		}
+		eclipse.Magnitude = round4((1.5433 + u - gamma) / (0.5461 + 2*u))
+	default:
+		eclipse.Magnitude = round4((1.5433 + u - gamma) / (0.5461 + 2*u))
+	}
+
+	return eclipse, true
+}
+
+// solarDisks топоцентрическое расстояние между центрами Луны и Солнца и их полудиаметры, градусы
+func solarDisks(t time.Time, lat, lon, elevation float64) (separation, sunRadius, moonRadius float64) {
+	a := solarAxisAt(t)
+	observer := observerECI(JulianDay(t), lat, lon, elevation)
+	sun := a.sun.sub(observer)
+	moon := a.moon.sub(observer)
+
+	return angleBetween(sun, moon),
+		math.Asin(sunRadiusKm/sun.length()) * rad,
+		math.Asin(moonRadiusKm/moon.length()) * rad
+}
+
+// Local обстоятельства затмения для наблюдателя; nil, если из этого места
+// Луна не закрывает Солнце ни в один момент (днем или ночью)
+func (e SolarEclipse) Local(lat, lon, elevation float64) *LocalSolarEclipse {
+	from, to := e.Greatest.Add(-eclipseWindow), e.Greatest.Add(eclipseWindow)
+	separation := func(t time.Time) float64 {
+		s, _, _ := solarDisks(t, lat, lon, elevation)
+		return s
+	}
+
+	// Сначала грубый минимум с шагом 5 минут, затем уточнение
+	best := from
+	bestSep := separation(from)
+	for t := from.Add(5 * time.Minute); !t.After(to); t = t.Add(5 * time.Minute) {
+		if s := separation(t); s < bestSep {
+			best, bestSep = t, s
+		}
+	}
+	greatest := minimize(separation, best.Add(-5*time.Minute), best.Add(5*time.Minute))
+
+	sep, sunR, moonR := solarDisks(greatest, lat, lon, elevation)
+	if sep >= sunR+moonR {
+		return nil
+	}
+
+	local := &LocalSolarEclipse{
+		Type:        EclipsePartial,
+		Magnitude:   round4((sunR + moonR - sep) / (2 * sunR)),
+		Obscuration: round4(diskOverlap(sep, sunR, moonR)),
+	}
+	switch {
+	case sep <= moonR-sunR:
+		local.Type = EclipseTotal
+		local.Magnitude = round4(moonR / sunR)
+	case sep <= sunR-moonR:
+		local.Type = EclipseAnnular
+		local.Magnitude = round4(moonR / sunR)
+	}
+
+	contact := func(inner bool) (time.Time, time.Time) {
+		f := func(t time.Time) float64 {
+			s, sr, mr := solarDisks(t, lat, lon, elevation)
+			if inner {
+				return s - math.Abs(mr-sr)
+			}
+			return s - (sr + mr)
+		}
+		return bisect(f, from, greatest, false), bisect(f, greatest, to, true)
+	}
+
+	c1, c4 := contact(false)
+	contacts := []EclipseContact{{Event: "partial_start", Time: c1}}
+	var tail []EclipseContact
+	if local.Type != EclipsePartial {
+		c2, c3 := contact(true)
+		contacts = append(contacts, EclipseContact{Event: local.Type + "_start", Time: c2})
+		tail = append(tail, EclipseContact{Event: local.Type + "_end", Time: c3})
+	}
+	contacts = append(contacts, EclipseContact{Event: "greatest", Time: greatest})
+	contacts = append(contacts, tail...)
+	contacts = append(contacts, EclipseContact{Event: "partial_end", Time: c4})
+
+	for i := range contacts {
+		altitude := SunAltitude(contacts[i].Time, lat, lon)
+		contacts[i].Altitude = round1(altitude)
+		contacts[i].AboveHorizon = altitude > SunriseAltitude
+		local.Visible = local.Visible || contacts[i].AboveHorizon
+	}
+	local.Contacts = contacts
+
+	return local
+}
+
+// diskOverlap доля площади диска Солнца, закрытая диском Луны
+func diskOverlap(d, r1, r2 float64) float64 {
+	switch {
+	case d >= r1+r2:
+		return 0
+	case d <= math.Abs(r1-r2):
+		return math.Min(1, (r2*r2)/(r1*r1))
+	}
+
+	a1 := math.Acos((d*d + r1*r1 - r2*r2) / (2 * d * r1))
+	a2 := math.Acos((d*d + r2*r2 - r1*r1) / (2 * d * r2))
+	area := r1*r1*(a1-math.Sin(2*a1)/2) + r2*r2*(a2-math.Sin(2*a2)/2)
+	return area / (math.Pi * r1 * r1)
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
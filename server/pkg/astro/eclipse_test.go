@@ -0,0 +1,220 @@
+package astro
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Эталоны - обстоятельства затмений из каталогов NASA (F. Espenak), время в UT.
+// Допуски отражают точность упрощенной теории Солнца (глава 25 Меёса, около 0.01°):
+// она сдвигает моменты затмений на десятки секунд, gamma и фазу - в третьем знаке.
+const (
+	eclipseTimeTolerance      = time.Minute
+	eclipseGammaTolerance     = 0.002
+	eclipseMagnitudeTolerance = 0.005
+)
+
+func TestSolarEclipsesMatchNASA(t *testing.T) {
+	tests := []struct {
+		name      string
+		greatest  time.Time
+		kind      string
+		gamma     float64
+		magnitude float64
+	}{
+		{"total 2024-04-08", time.Date(2024, 4, 8, 18, 17, 16, 0, time.UTC), EclipseTotal, 0.3431, 1.0566},
+		{"annular 2024-10-02", time.Date(2024, 10, 2, 18, 45, 0, 0, time.UTC), EclipseAnnular, -0.3509, 0.9326},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eclipses := SolarEclipses(tt.greatest.Add(-24*time.Hour), tt.greatest.Add(24*time.Hour))
+			if len(eclipses) != 1 {
+				t.Fatalf("found %d eclipses, want 1", len(eclipses))
+			}
+			e := eclipses[0]
+			if e.Type != tt.kind || !e.Central {
+				t.Errorf("type %s central=%v, want central %s", e.Type, e.Central, tt.kind)
+			}
+			if d := e.Greatest.Sub(tt.greatest); d < -eclipseTimeTolerance || d > eclipseTimeTolerance {
+				t.Errorf("greatest %s, want %s ± %s", e.Greatest.Format(time.TimeOnly), tt.greatest.Format(time.TimeOnly), eclipseTimeTolerance)
+			}
+			if math.Abs(e.Gamma-tt.gamma) > eclipseGammaTolerance {
+				t.Errorf("gamma %.4f, want %.4f ± %g", e.Gamma, tt.gamma, eclipseGammaTolerance)
+			}
+			if math.Abs(e.Magnitude-tt.magnitude) > eclipseMagnitudeTolerance {
+				t.Errorf("magnitude %.4f, want %.4f ± %g", e.Magnitude, tt.magnitude, eclipseMagnitudeTolerance)
+			}
+		})
+	}
+}
+
+func TestLunarEclipseMatchesNASA(t *testing.T) {
+	// Полное лунное затмение 2025-03-14
+	want := time.Date(2025, 3, 14, 6, 58, 43, 0, time.UTC)
+	eclipses := LunarEclipses(want.Add(-24*time.Hour), want.Add(24*time.Hour))
+	if len(eclipses) != 1 {
+		t.Fatalf("found %d eclipses, want 1", len(eclipses))
+	}
+	e := eclipses[0]
+
+	if e.Type != EclipseTotal {
+		t.Errorf("type %s, want %s", e.Type, EclipseTotal)
+	}
+	if d := e.Greatest.Sub(want); d < -eclipseTimeTolerance || d > eclipseTimeTolerance {
+		t.Errorf("greatest %s, want %s ± %s", e.Greatest.Format(time.TimeOnly), want.Format(time.TimeOnly), eclipseTimeTolerance)
+	}
+	if math.Abs(e.Gamma-0.3485) > eclipseGammaTolerance {
+		t.Errorf("gamma %.4f, want 0.3485 ± %g", e.Gamma, eclipseGammaTolerance)
+	}
+	if math.Abs(e.UmbralMagnitude-1.1784) > eclipseMagnitudeTolerance {
+		t.Errorf("umbral magnitude %.4f, want 1.1784 ± %g", e.UmbralMagnitude, eclipseMagnitudeTolerance)
+	}
+	if math.Abs(e.PenumbralMagnitude-2.2595) > eclipseMagnitudeTolerance {
+		t.Errorf("penumbral magnitude %.4f, want 2.2595 ± %g", e.PenumbralMagnitude, eclipseMagnitudeTolerance)
+	}
+}
+
+func TestEclipseSearchFindsNoExtraEclipses(t *testing.T) {
+	from, to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// В 2024 году два солнечных затмения и два лунных: полутеневое 25 марта и частное 18 сентября
+	if n := len(SolarEclipses(from, to)); n != 2 {
+		t.Errorf("%d solar eclipses in 2024, want 2", n)
+	}
+	lunar := LunarEclipses(from, to)
+	if len(lunar) != 2 || lunar[0].Type != EclipsePenumbral || lunar[1].Type != EclipsePartial {
+		t.Errorf("lunar eclipses in 2024 %+v, want penumbral and partial", lunar)
+	}
+}
+
+// assertContacts сверяет моменты фаз с эталоном; события без эталона не проверяются
+func assertContacts(t *testing.T, contacts []EclipseContact, want map[string]time.Time) {
+	t.Helper()
+	found := make(map[string]bool)
+	for _, c := range contacts {
+		w, ok := want[c.Event]
+		if !ok {
+			continue
+		}
+		found[c.Event] = true
+		if d := c.Time.Sub(w); d < -eclipseTimeTolerance || d > eclipseTimeTolerance {
+			t.Errorf("%s %s, want %s ± %s", c.Event, c.Time.Format(time.TimeOnly), w.Format(time.TimeOnly), eclipseTimeTolerance)
+		}
+	}
+	for event := range want {
+		if !found[event] {
+			t.Errorf("no %s contact", event)
+		}
+	}
+}
+
+func TestSolarEclipseLocalCircumstances(t *testing.T) {
+	at := func(hour, min, sec int) time.Time { return time.Date(2024, 4, 8, hour, min, sec, 0, time.UTC) }
+	eclipses := SolarEclipses(at(0, 0, 0), at(23, 59, 59))
+	if len(eclipses) != 1 {
+		t.Fatalf("found %d eclipses, want 1", len(eclipses))
+	}
+	eclipse := eclipses[0]
+
+	t.Run("Dallas total", func(t *testing.T) {
+		local := eclipse.Local(32.78, -96.80, 139)
+		if local == nil || local.Type != EclipseTotal || !local.Visible {
+			t.Fatalf("local %+v, want a visible total eclipse", local)
+		}
+		assertContacts(t, local.Contacts, map[string]time.Time{
+			"partial_start": at(17, 23, 22),
+			"total_start":   at(18, 40, 43),
+			"greatest":      at(18, 42, 38),
+			"total_end":     at(18, 44, 34),
+			"partial_end":   at(20, 2, 39),
+		})
+
+		// Полная фаза 3m51s: ошибка положения тени дает разницу длительности меньше, чем моментов
+		var start, end time.Time
+		for _, c := range local.Contacts {
+			switch c.Event {
+			case "total_start":
+				start = c.Time
+			case "total_end":
+				end = c.Time
+			}
+		}
+		const want, tolerance = 231 * time.Second, 20 * time.Second
+		if d := end.Sub(start); d < want-tolerance || d > want+tolerance {
+			t.Errorf("totality %s, want %s ± %s", d, want, tolerance)
+		}
+	})
+
+	t.Run("New York partial", func(t *testing.T) {
+		// Нью-Йорк вне полосы полной фазы: частное затмение с фазой около 0.90
+		local := eclipse.Local(40.71, -74.01, 10)
+		if local == nil || local.Type != EclipsePartial || !local.Visible {
+			t.Fatalf("local %+v, want a visible partial eclipse", local)
+		}
+		if math.Abs(local.Magnitude-0.90) > 0.02 {
+			t.Errorf("magnitude %.4f, want 0.90 ± 0.02", local.Magnitude)
+		}
+		for _, c := range local.Contacts {
+			if strings.HasPrefix(c.Event, EclipseTotal) {
+				t.Errorf("unexpected %s contact outside the path of totality", c.Event)
+			}
+		}
+	})
+
+	t.Run("Moscow night side", func(t *testing.T) {
+		// Полутень задевает Россию, но в Москве Солнце уже под горизонтом
+		if local := eclipse.Local(55.75, 37.62, 150); local != nil && local.Visible {
+			t.Errorf("eclipse visible in Moscow: %+v", local)
+		}
+	})
+}
+
+func TestLunarEclipseLocalVisibility(t *testing.T) {
+	at := func(hour, min, sec int) time.Time { return time.Date(2025, 3, 14, hour, min, sec, 0, time.UTC) }
+	eclipses := LunarEclipses(at(0, 0, 0), at(23, 59, 59))
+	if len(eclipses) != 1 {
+		t.Fatalf("found %d eclipses, want 1", len(eclipses))
+	}
+	eclipse := eclipses[0]
+
+	assertContacts(t, eclipse.Contacts, map[string]time.Time{
+		"penumbral_start": at(3, 57, 28),
+		"partial_start":   at(5, 9, 40),
+		"total_start":     at(6, 26, 6),
+		"total_end":       at(7, 31, 26),
+		"partial_end":     at(8, 47, 56),
+		"penumbral_end":   at(10, 0, 9),
+	})
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		// above фазы, в которые Луна над горизонтом
+		above map[string]bool
+	}{
+		// Ночь в Северной Америке - видно все затмение
+		{"New York", 40.71, -74.01, map[string]bool{
+			"penumbral_start": true, "partial_start": true, "total_start": true, "greatest": true,
+			"total_end": true, "partial_end": true, "penumbral_end": true,
+		}},
+		// Полдень в Индии - Луна под горизонтом все затмение
+		{"New Delhi", 28.61, 77.21, map[string]bool{}},
+		// Вечер в Сиднее - Луна восходит после полной фазы
+		{"Sydney", -33.87, 151.21, map[string]bool{"partial_end": true, "penumbral_end": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacts, visible := eclipse.Local(tt.lat, tt.lon)
+			if visible != (len(tt.above) > 0) {
+				t.Errorf("visible=%v, want %v", visible, len(tt.above) > 0)
+			}
+			for _, c := range contacts {
+				if c.AboveHorizon != tt.above[c.Event] {
+					t.Errorf("%s: Moon above horizon %v (altitude %.1f), want %v", c.Event, c.AboveHorizon, c.Altitude, tt.above[c.Event])
+				}
+			}
+		})
+	}
+}
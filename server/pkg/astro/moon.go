@@ -0,0 +1,238 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const earthRadiusKm = 6378.14
+
+// MoonCoordinates видимое геоцентрическое положение Луны
+type MoonCoordinates struct {
+	Longitude float64
+	Latitude  float64
+	Equatorial
+	// Distance расстояние между центрами Земли и Луны, км
+	Distance float64
+}
+
+// Члены рядов Меёса (глава 47): множители D, M, M', F и амплитуды
+// для долготы (1e-6 градуса) и расстояния (1e-3 км)
+var moonLonDistTerms = [][6]float64{
+	{0, 0, 1, 0, 6288774, -20905355},
+	{2, 0, -1, 0, 1274027, -3699111},
+	{2, 0, 0, 0, 658314, -2955968},
+	{0, 0, 2, 0, 213618, -569925},
+	{0, 1, 0, 0, -185116, 48888},
+	{0, 0, 0, 2, -114332, -3149},
+	{2, 0, -2, 0, 58793, 246158},
+	{2, -1, -1, 0, 57066, -152138},
+	{2, 0, 1, 0, 53322, -170733},
+	{2, -1, 0, 0, 45758, -204586},
+	{0, 1, -1, 0, -40923, -129620},
+	{1, 0, 0, 0, -34720, 108743},
+	{0, 1, 1, 0, -30383, 104755},
+	{2, 0, 0, -2, 15327, 10321},
+	{0, 0, 1, 2, -12528, 0},
+	{0, 0, 1, -2, 10980, 79661},
+	{4, 0, -1, 0, 10675, -34782},
+	{0, 0, 3, 0, 10034, -23210},
+	{4, 0, -2, 0, 8548, -21636},
+	{2, 1, -1, 0, -7888, 24208},
+	{2, 1, 0, 0, -6766, 30824},
+	{1, 0, -1, 0, -5163, -8379},
+	{1, 1, 0, 0, 4987, -16675},
+	{2, -1, 1, 0, 4036, -12831},
+	{2, 0, 2, 0, 3994, -10445},
+	{4, 0, 0, 0, 3861, -11650},
+	{2, 0, -3, 0, 3665, 14403},
+	{0, 1, -2, 0, -2689, -7003},
+	{2, 0, -1, 2, -2602, 0},
+	{2, -1, -2, 0, 2390, 10056},
+	{1, 0, 1, 0, -2348, 6322},
+	{2, -2, 0, 0, 2236, -9884},
+	{0, 1, 2, 0, -2120, 5751},
+	{0, 2, 0, 0, -2069, 0},
+	{2, -2, -1, 0, 2048, -4950},
+	{2, 0, 1, -2, -1773, 4130},
+	{2, 0, 0, 2, -1595, 0},
+	{4, -1, -1, 0, 1215, -3958},
+	{0, 0, 2, 2, -1110, 0},
+	{3, 0, -1, 0, -892, 3258},
+	{2, 1, 1, 0, -810, 2616},
+	{4, -1, -2, 0, 759, -1897},
+	{0, 2, -1, 0, -713, -2117},
+	{2, 2, -1, 0, -700, 2354},
+	{2, 1, -2, 0, 691, 0},
+	{2, -1, 0, -2, 596, 0},
+	{4, 0, 1, 0, 549, -1423},
+	{0, 0, 4, 0, 537, -1117},
+	{4, -1, 0, 0, 520, -1571},
+	{1, 0, -2, 0, -487, -1739},
+	{2, 1, 0, -2, -399, 0},
+	{0, 0, 2, -2, -381, -4421},
+	{1, 1, 1, 0, 351, 0},
+	{3, 0, -2, 0, -340, 0},
+	{4, 0, -3, 0, 330, 0},
+	{2, -1, 2, 0, 327, 0},
+	{0, 2, 1, 0, -323, 1165},
+	{1, 1, -1, 0, 299, 0},
+	{2, 0, 3, 0, 294, 0},
+	{2, 0, -1, -2, 0, 8752},
+}
+
+// Члены для широты (1e-6 градуса)
+var moonLatTerms = [][5]float64{
+	{0, 0, 0, 1, 5128122},
+	{0, 0, 1, 1, 280602},
+	{0, 0, 1, -1, 277693},
+	{2, 0, 0, -1, 173237},
+	{2, 0, -1, 1, 55413},
+	{2, 0, -1, -1, 46271},
+	{2, 0, 0, 1, 32573},
+	{0, 0, 2, 1, 17198},
+	{2, 0, 1, -1, 9266},
+	{0, 0, 2, -1, 8822},
+	{2, -1, 0, -1, 8216},
+	{2, 0, -2, -1, 4324},
+	{2, 0, 1, 1, 4200},
+	{2, 1, 0, -1, -3359},
+	{2, -1, -1, 1, 2463},
+	{2, -1, 0, 1, 2211},
+	{2, -1, -1, -1, 2065},
+	{0, 1, -1, -1, -1870},
+	{4, 0, -1, -1, 1828},
+	{0, 1, 0, 1, -1794},
+	{0, 0, 0, 3, -1749},
+	{0, 1, -1, 1, -1565},
+	{1, 0, 0, 1, -1491},
+	{0, 1, 1, 1, -1475},
+	{0, 1, 1, -1, -1410},
+	{0, 1, 0, -1, -1344},
+	{1, 0, 0, -1, -1335},
+	{0, 0, 3, 1, 1107},
+	{4, 0, 0, -1, 1021},
+	{4, 0, -1, 1, 833},
+	{0, 0, 1, -3, 777},
+	{4, 0, -2, 1, 671},
+	{2, 0, 0, -3, 607},
+	{2, 0, 2, -1, 596},
+	{2, -1, 1, -1, 491},
+	{2, 0, -2, 1, -451},
+	{0, 0, 3, -1, 439},
+	{2, 0, 2, 1, 422},
+	{2, 0, -3, -1, 421},
+	{2, 1, -1, 1, -366},
+	{2, 1, 0, 1, -351},
+	{4, 0, 0, 1, 331},
+	{2, -1, 1, 1, 315},
+	{2, -2, 0, -1, 302},
+	{0, 0, 1, 3, -283},
+	{2, 1, 1, -1, -229},
+	{1, 1, 0, -1, 223},
+	{1, 1, 0, 1, 223},
+	{0, 1, -2, -1, -220},
+	{2, 1, -1, -1, -220},
+	{1, 0, 1, 1, -185},
+	{2, -1, -2, -1, 181},
+	{0, 1, 2, 1, -177},
+	{4, 0, -2, -1, 176},
+	{4, -1, -1, -1, 166},
+	{1, 0, 1, -1, -164},
+	{4, 0, 1, -1, 132},
+	{1, 0, -1, -1, -119},
+	{4, -1, 0, -1, 115},
+	{2, -2, 0, 1, 107},
+}
+
+// MoonPosition вычисляет положение Луны по сокращенной теории ELP2000 (Меёс, глава 47)
+func MoonPosition(jd float64) MoonCoordinates {
+	t := julianCenturies(jd)
+
+	lp := normalizeDegrees(218.3164477 + 481267.88123421*t - 0.0015786*t*t + t*t*t/538841 - t*t*t*t/65194000)
+	d := normalizeDegrees(297.8501921 + 445267.1114034*t - 0.0018819*t*t + t*t*t/545868 - t*t*t*t/113065000)
+	m := normalizeDegrees(357.5291092 + 35999.0502909*t - 0.0001536*t*t + t*t*t/24490000)
+	mp := normalizeDegrees(134.9633964 + 477198.8675055*t + 0.0087414*t*t + t*t*t/69699 - t*t*t*t/14712000)
+	f := normalizeDegrees(93.2720950 + 483202.0175233*t - 0.0036539*t*t - t*t*t/3526000 + t*t*t*t/863310000)
+
+	a1 := normalizeDegrees(119.75 + 131.849*t)
+	a2 := normalizeDegrees(53.09 + 479264.290*t)
+	a3 := normalizeDegrees(313.45 + 481266.484*t)
+	e := 1 - 0.002516*t - 0.0000074*t*t
+
+	// Члены с аномалией Солнца ослабевают с уменьшением эксцентриситета орбиты Земли
+	eccentricity := func(mCoeff float64) float64 {
+		switch math.Abs(mCoeff) {
+		case 1:
+			return e
+		case 2:
+			return e * e
+		}
+		return 1
+	}
+
+	var sumL, sumR, sumB float64
+	for _, term := range moonLonDistTerms {
+		arg := (term[0]*d + term[1]*m + term[2]*mp + term[3]*f) * deg
+		k := eccentricity(term[1])
+		sumL += term[4] * k * math.Sin(arg)
+		sumR += term[5] * k * math.Cos(arg)
+	}
+	for _, term := range moonLatTerms {
+		arg := (term[0]*d + term[1]*m + term[2]*mp + term[3]*f) * deg
+		sumB += term[4] * eccentricity(term[1]) * math.Sin(arg)
+	}
+
+	sumL += 3958*math.Sin(a1*deg) + 1962*math.Sin((lp-f)*deg) + 318*math.Sin(a2*deg)
+	sumB += -2235*math.Sin(lp*deg) + 382*math.Sin(a3*deg) + 175*math.Sin((a1-f)*deg) +
+		175*math.Sin((a1+f)*deg) + 127*math.Sin((lp-mp)*deg) - 115*math.Sin((lp+mp)*deg)
+
+	lon := normalizeDegrees(lp + sumL/1e6 + nutationLongitude(t))
+	lat := sumB / 1e6
+
+	return MoonCoordinates{
+		Longitude:  lon,
+		Latitude:   lat,
+		Equatorial: EclipticToEquatorial(lon, lat, jd),
+		Distance:   385000.56 + sumR/1000,
+	}
+}
+
+// moonHorizon высота центра Луны при восходе: рефракция, полудиаметр и параллакс
+func moonHorizon(distance float64) float64 {
+	parallax := math.Asin(earthRadiusKm/distance) * rad
+	return 0.7275*parallax - 0.5667
+}
+
+// MoonTimes восход и заход Луны за сутки
+type MoonTimes struct {
+	Moonrise   *time.Time `json:"moonrise,omitempty"`
+	Moonset    *time.Time `json:"moonset,omitempty"`
+	Transit    *time.Time `json:"transit,omitempty"`
+	AlwaysUp   bool       `json:"always_up,omitempty"`
+	AlwaysDown bool       `json:"always_down,omitempty"`
+}
+
+// MoonEvents считает восход, заход и кульминацию Луны для суток, содержащих date
+func MoonEvents(date time.Time, lat, lon float64) MoonTimes {
+	start, end := dayBounds(date)
+	position := func(jd float64) (Equatorial, float64) {
+		moon := MoonPosition(jd)
+		return moon.Equatorial, moonHorizon(moon.Distance)
+	}
+
+	rise, set, up, down := riseSet(position, start, end, lat, lon, 0)
+	return MoonTimes{
+		Moonrise:   inLocation(rise, date),
+		Moonset:    inLocation(set, date),
+		Transit:    inLocation(transit(func(jd float64) Equatorial { return MoonPosition(jd).Equatorial }, start, end, lon), date),
+		AlwaysUp:   up,
+		AlwaysDown: down,
+	}
+}
+
+// MoonAltitude высота Луны над горизонтом в момент t (без поправки на параллакс)
+func MoonAltitude(t time.Time, lat, lon float64) float64 {
+	jd := JulianDay(t)
+	return ToHorizontal(MoonPosition(jd).Equatorial, jd, lat, lon).Altitude
+}
@@ -0,0 +1,186 @@
+package astro
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const auKm = 149597870.7
+
+// Элонгации Луны для главных фаз, градусы
+const (
+	NewMoon      = 0.0
+	FirstQuarter = 90.0
+	FullMoon     = 180.0
+	LastQuarter  = 270.0
+)
+
+// MoonPhase состояние Луны в момент времени
+type MoonPhase struct {
+	Time time.Time `json:"time"`
+	// Phase доля синодического цикла: 0 - новолуние, 0.5 - полнолуние
+	Phase float64 `json:"phase"`
+	// Illumination освещенная доля диска от 0 до 1
+	Illumination float64 `json:"illumination"`
+	// Elongation разность эклиптических долгот Луны и Солнца, градусы
+	Elongation float64 `json:"elongation"`
+	AgeDays    float64 `json:"age_days"`
+	Name       string  `json:"name"`
+	Waxing     bool    `json:"waxing"`
+	// DistanceKm расстояние до Луны, км
+	DistanceKm       float64   `json:"distance_km"`
+	NextNewMoon      time.Time `json:"next_new_moon"`
+	NextFirstQuarter time.Time `json:"next_first_quarter"`
+	NextFullMoon     time.Time `json:"next_full_moon"`
+	NextLastQuarter  time.Time `json:"next_last_quarter"`
+}
+
+// PhaseEvent момент наступления главной фазы
+type PhaseEvent struct {
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// elongation разность видимых долгот Луны и Солнца в [0, 360)
+func elongation(jd float64) float64 {
+	return normalizeDegrees(MoonPosition(jd).Longitude - SunPosition(jd).Longitude)
+}
+
+// Illumination освещенная доля диска Луны по фазовому углу (Меёс, глава 48)
+func Illumination(jd float64) float64 {
+	moon := MoonPosition(jd)
+	sun := SunPosition(jd)
+
+	cosPsi := math.Cos(moon.Latitude*deg) * math.Cos((moon.Longitude-sun.Longitude)*deg)
+	psi := math.Acos(math.Max(-1, math.Min(1, cosPsi)))
+	r := sun.Distance * auKm
+	i := math.Atan2(r*math.Sin(psi), moon.Distance-r*math.Cos(psi))
+
+	return (1 + math.Cos(i)) / 2
+}
+
+// PhaseAt возвращает фазу, освещенность и даты ближайших главных фаз
+func PhaseAt(t time.Time) MoonPhase {
+	jd := JulianDay(t)
+	e := elongation(jd)
+	phase := e / 360
+
+	return MoonPhase{
+		Time:             t,
+		Phase:            phase,
+		Illumination:     Illumination(jd),
+		Elongation:       e,
+		AgeDays:          phase * SynodicMonth,
+		Name:             PhaseName(phase),
+		Waxing:           e < 180,
+		DistanceKm:       MoonPosition(jd).Distance,
+		NextNewMoon:      NextPhase(t, NewMoon),
+		NextFirstQuarter: NextPhase(t, FirstQuarter),
+		NextFullMoon:     NextPhase(t, FullMoon),
+		NextLastQuarter:  NextPhase(t, LastQuarter),
+	}
+}
+
+// PhaseName название фазы по доле цикла; главные фазы занимают ±1 сутки
+func PhaseName(phase float64) string {
+	window := 1 / SynodicMonth
+	near := func(target float64) bool {
+		d := math.Abs(phase - target)
+		return d < window || 1-d < window
+	}
+
+	switch {
+	case near(0):
+		return "New Moon"
+	case near(0.25):
+		return "First Quarter"
+	case near(0.5):
+		return "Full Moon"
+	case near(0.75):
+		return "Last Quarter"
+	case phase < 0.25:
+		return "Waxing Crescent"
+	case phase < 0.5:
+		return "Waxing Gibbous"
+	case phase < 0.75:
+		return "Waning Gibbous"
+	default:
+		return "Waning Crescent"
+	}
+}
+
+// NextPhase момент, когда элонгация Луны впервые после after достигнет angle
+func NextPhase(after time.Time, angle float64) time.Time {
+	jd := JulianDay(after)
+	diff := normalizeDegrees(angle - elongation(jd))
+	if diff < 1e-6 {
+		diff = 360
+	}
+
+	// Начальное приближение по средней скорости, затем уточнение по истинной элонгации
+	target := jd + diff/360*SynodicMonth
+	for i := 0; i < 6; i++ {
+		delta := normalizeSigned(elongation(target) - angle)
+		target -= delta / 360 * SynodicMonth
+		if math.Abs(delta) < 1e-5 {
+			break
+		}
+	}
+
+	return TimeFromJulianDay(target).Truncate(time.Second)
+}
+
+// PhasesBetween главные фазы Луны в интервале [from, to)
+func PhasesBetween(from, to time.Time) []PhaseEvent {
+	names := map[float64]string{
+		NewMoon:      "New Moon",
+		FirstQuarter: "First Quarter",
+		FullMoon:     "Full Moon",
+		LastQuarter:  "Last Quarter",
+	}
+
+	var events []PhaseEvent
+	for _, angle := range []float64{NewMoon, FirstQuarter, FullMoon, LastQuarter} {
+		for t := NextPhase(from.Add(-time.Second), angle); t.Before(to); t = NextPhase(t.Add(time.Hour), angle) {
+			events = append(events, PhaseEvent{Name: names[angle], Time: t})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// Пороги расстояния полнолуния для суперлуния и микролуния, км
+const (
+	SupermoonDistanceKm = 360000
+	MicromoonDistanceKm = 405000
+)
+
+// FullMoonExtreme полнолуние у перигея (суперлуние) или апогея (микролуние)
+type FullMoonExtreme struct {
+	// Type supermoon или micromoon
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	DistanceKm float64   `json:"distance_km"`
+}
+
+// FullMoonExtremes суперлуния и микролуния в интервале [from, to)
+func FullMoonExtremes(from, to time.Time) []FullMoonExtreme {
+	var events []FullMoonExtreme
+	for _, phase := range PhasesBetween(from, to) {
+		if phase.Name != "Full Moon" {
+			continue
+		}
+		distance := MoonPosition(dynamicalDay(phase.Time)).Distance
+		switch {
+		case distance <= SupermoonDistanceKm:
+			events = append(events, FullMoonExtreme{Type: "supermoon", Time: phase.Time, DistanceKm: math.Round(distance)})
+		case distance >= MicromoonDistanceKm:
+			events = append(events, FullMoonExtreme{Type: "micromoon", Time: phase.Time, DistanceKm: math.Round(distance)})
+		}
+	}
+	return events
+}
@@ -0,0 +1,151 @@
+package astro
+
+import (
+	"math"
+)
+
+// orbitalElements кеплеровы элементы орбиты на J2000 и их изменение за столетие
+// (E. M. Standish, "Keplerian Elements for Approximate Positions of the Major
+// Planets", интервал 1800-2050). Точность положений - порядка угловой минуты.
+type orbitalElements struct {
+	a, aRate       float64 // большая полуось, а.е.
+	e, eRate       float64 // эксцентриситет
+	i, iRate       float64 // наклон, градусы
+	l, lRate       float64 // средняя долгота, градусы
+	peri, periRate float64 // долгота перигелия, градусы
+	node, nodeRate float64 // долгота восходящего узла, градусы
+}
+
+var planetElements = map[Body]orbitalElements{
+	Mercury: {0.38709927, 0.00000037, 0.20563593, 0.00001906, 7.00497902, -0.00594749,
+		252.25032350, 149472.67411175, 77.45779628, 0.16047689, 48.33076593, -0.12534081},
+	Venus: {0.72333566, 0.00000390, 0.00677672, -0.00004107, 3.39467605, -0.00078890,
+		181.97909950, 58517.81538729, 131.60246718, 0.00268329, 76.67984255, -0.27769418},
+	earth: {1.00000261, 0.00000562, 0.01671123, -0.00004392, -0.00001531, -0.01294668,
+		100.46457166, 35999.37244981, 102.93768193, 0.32327364, 0, 0},
+	Mars: {1.52371034, 0.00001847, 0.09339410, 0.00007882, 1.84969142, -0.00813131,
+		-4.55343205, 19140.30268499, -23.94362959, 0.44441088, 49.55953891, -0.29257343},
+	Jupiter: {5.20288700, -0.00011607, 0.04838624, -0.00013253, 1.30439695, -0.00183714,
+		34.39644051, 3034.74612775, 14.72847983, 0.21252668, 100.47390909, 0.20469106},
+	Saturn: {9.53667594, -0.00125060, 0.05386179, -0.00050991, 2.48599187, 0.00193609,
+		49.95424423, 1222.49362201, 92.59887831, -0.41897216, 113.66242448, -0.28867794},
+	Uranus: {19.18916464, -0.00196176, 0.04725744, -0.00004397, 0.77263783, -0.00242939,
+		313.23810451, 428.48202785, 170.95427630, 0.40805281, 74.01692503, 0.04240589},
+	Neptune: {30.06992276, 0.00026291, 0.00859048, 0.00005105, 1.77004347, 0.00035372,
+		-55.12002969, 218.45945325, 44.96476227, -0.32241464, 131.78422574, -0.00508664},
+}
+
+// vector3 прямоугольные координаты, а.е.
+type vector3 struct{ x, y, z float64 }
+
+func (v vector3) sub(o vector3) vector3 { return vector3{v.x - o.x, v.y - o.y, v.z - o.z} }
+func (v vector3) length() float64       { return math.Sqrt(v.x*v.x + v.y*v.y + v.z*v.z) }
+
+// heliocentric гелиоцентрические эклиптические координаты J2000
+func heliocentric(el orbitalElements, t float64) vector3 {
+	a := el.a + el.aRate*t
+	e := el.e + el.eRate*t
+	i := (el.i + el.iRate*t) * deg
+	l := el.l + el.lRate*t
+	peri := el.peri + el.periRate*t
+	node := el.node + el.nodeRate*t
+
+	omega := (peri - node) * deg
+	m := normalizeSigned(l-peri) * deg
+	node *= deg
+
+	// Уравнение Кеплера методом Ньютона
+	ecc := m + e*math.Sin(m)
+	for k := 0; k < 10; k++ {
+		delta := (ecc - e*math.Sin(ecc) - m) / (1 - e*math.Cos(ecc))
+		ecc -= delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+
+	xp := a * (math.Cos(ecc) - e)
+	yp := a * math.Sqrt(1-e*e) * math.Sin(ecc)
+
+	cw, sw := math.Cos(omega), math.Sin(omega)
+	cn, sn := math.Cos(node), math.Sin(node)
+	ci, si := math.Cos(i), math.Sin(i)
+
+	return vector3{
+		x: (cw*cn-sw*sn*ci)*xp + (-sw*cn-cw*sn*ci)*yp,
+		y: (cw*sn+sw*cn*ci)*xp + (-sw*sn+cw*cn*ci)*yp,
+		z: sw*si*xp + cw*si*yp,
+	}
+}
+
+// planetGeometry геоцентрическое положение планеты с учетом времени распространения света
+type planetGeometry struct {
+	lon, lat float64 // видимые эклиптические координаты даты, градусы
+	r        float64 // расстояние до Солнца, а.е.
+	delta    float64 // расстояние до Земли, а.е.
+	earthSun float64 // расстояние Земля-Солнце, а.е.
+}
+
+func planetPosition(body Body, jd float64) planetGeometry {
+	el := planetElements[body]
+	t := julianCenturies(jd)
+	earthPos := heliocentric(planetElements[earth], t)
+
+	planet := heliocentric(el, t)
+	geo := planet.sub(earthPos)
+
+	// Свет идет от планеты Δ·0.0057755 суток - берем положение на момент излучения
+	tau := 0.0057755183 * geo.length()
+	planet = heliocentric(el, julianCenturies(jd-tau))
+	geo = planet.sub(earthPos)
+
+	lon := math.Atan2(geo.y, geo.x) * rad
+	lat := math.Atan2(geo.z, math.Hypot(geo.x, geo.y)) * rad
+
+	// Переход от эклиптики J2000 к эклиптике даты: общая прецессия по долготе и нутация
+	lon = normalizeDegrees(lon + 1.396971*t + nutationLongitude(t))
+
+	return planetGeometry{
+		lon:      lon,
+		lat:      lat,
+		r:        planet.length(),
+		delta:    geo.length(),
+		earthSun: earthPos.length(),
+	}
+}
+
+// phaseAngle угол Солнце-планета-Земля, градусы
+func (g planetGeometry) phaseAngle() float64 {
+	cos := (g.r*g.r + g.delta*g.delta - g.earthSun*g.earthSun) / (2 * g.r * g.delta)
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * rad
+}
+
+// planetMagnitude видимая звездная величина (Меёс, глава 41)
+func planetMagnitude(body Body, g planetGeometry, t float64) float64 {
+	i := g.phaseAngle()
+	base := 5 * math.Log10(g.r*g.delta)
+
+	switch body {
+	case Mercury:
+		return -0.42 + base + 0.0380*i - 0.000273*i*i + 0.000002*i*i*i
+	case Venus:
+		return -4.40 + base + 0.0009*i + 0.000239*i*i - 0.00000065*i*i*i
+	case Mars:
+		return -1.52 + base + 0.016*i
+	case Jupiter:
+		return -9.40 + base + 0.005*i
+	case Saturn:
+		// Кольца добавляют блеск в зависимости от их раскрытия B (глава 45)
+		ringIncl := (28.075216 - 0.012998*t) * deg
+		ringNode := (169.508470 + 1.394681*t) * deg
+		lon, lat := g.lon*deg, g.lat*deg
+		sinB := math.Sin(ringIncl)*math.Cos(lat)*math.Sin(lon-ringNode) - math.Cos(ringIncl)*math.Sin(lat)
+		b := math.Abs(math.Asin(sinB))
+		return -8.88 + base + 0.044*i - 2.60*math.Sin(b) + 1.25*math.Sin(b)*math.Sin(b)
+	case Uranus:
+		return -7.19 + base
+	case Neptune:
+		return -6.87 + base
+	}
+	return 0
+}
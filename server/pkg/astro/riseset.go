@@ -0,0 +1,113 @@
+package astro
+
+import "time"
+
+// Шаг перебора при поиске восходов и заходов. Десяти минут хватает, чтобы не
+// пропустить короткое появление Луны над горизонтом в высоких широтах.
+const searchStep = 10 * time.Minute
+
+// positionFunc возвращает координаты объекта и высоту горизонта для восхода/захода
+type positionFunc func(jd float64) (Equatorial, float64)
+
+// dayBounds начало и конец календарных суток date в ее часовом поясе
+func dayBounds(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// riseSet ищет первый восход и первый заход объекта в интервале [start, end).
+// Если пересечений горизонта нет, сообщает, над горизонтом объект или под ним.
+func riseSet(position positionFunc, start, end time.Time, lat, lon float64, step time.Duration) (rise, set *time.Time, alwaysUp, alwaysDown bool) {
+	if step <= 0 {
+		step = searchStep
+	}
+
+	f := func(t time.Time) float64 {
+		jd := JulianDay(t)
+		eq, horizon := position(jd)
+		return ToHorizontal(eq, jd, lat, lon).Altitude - horizon
+	}
+
+	prevT := start
+	prev := f(prevT)
+	initial := prev
+
+	for t := start.Add(step); !prevT.After(end) && (rise == nil || set == nil); t = t.Add(step) {
+		if t.After(end) {
+			t = end
+		}
+		cur := f(t)
+
+		if prev < 0 && cur >= 0 && rise == nil {
+			crossing := bisect(f, prevT, t, true)
+			rise = &crossing
+		} else if prev >= 0 && cur < 0 && set == nil {
+			crossing := bisect(f, prevT, t, false)
+			set = &crossing
+		}
+
+		if !t.Before(end) {
+			break
+		}
+		prevT, prev = t, cur
+	}
+
+	if rise == nil && set == nil {
+		alwaysUp = initial >= 0
+		alwaysDown = !alwaysUp
+	}
+	return rise, set, alwaysUp, alwaysDown
+}
+
+// bisect уточняет момент смены знака f на интервале [a, b] до секунды
+func bisect(f func(time.Time) float64, a, b time.Time, rising bool) time.Time {
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2)
+		above := f(mid) >= 0
+		if above == rising {
+			b = mid
+		} else {
+			a = mid
+		}
+	}
+	return a.Add(b.Sub(a) / 2).Truncate(time.Second)
+}
+
+// transit ищет верхнюю кульминацию (часовой угол проходит через ноль) в [start, end)
+func transit(position func(jd float64) Equatorial, start, end time.Time, lon float64) *time.Time {
+	ha := func(t time.Time) float64 {
+		jd := JulianDay(t)
+		return HourAngle(position(jd), jd, lon)
+	}
+
+	prevT := start
+	prev := ha(prevT)
+	for t := start.Add(searchStep); t.Before(end.Add(searchStep)); t = t.Add(searchStep) {
+		if t.After(end) {
+			t = end
+		}
+		cur := ha(t)
+
+		// Переход через -180/180 - нижняя кульминация, его пропускаем
+		if prev < 0 && cur >= 0 && cur-prev < 90 {
+			f := func(t time.Time) float64 { return ha(t) }
+			crossing := bisect(f, prevT, t, true)
+			return &crossing
+		}
+
+		if !t.Before(end) {
+			break
+		}
+		prevT, prev = t, cur
+	}
+	return nil
+}
+
+// inLocation переводит найденный момент в часовой пояс запроса
+func inLocation(t *time.Time, date time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.In(date.Location())
+	return &local
+}
@@ -0,0 +1,328 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Параметры Земли (WGS-72 для элементов TLE, WGS-84 для наблюдателя)
+const (
+	earthMu         = 398600.8 // км³/с²
+	earthEquatorKm  = 6378.135
+	earthJ2         = 1.082616e-3
+	wgs84RadiusKm   = 6378.137
+	wgs84Flattening = 1 / 298.257223563
+)
+
+// Условия видимости пролета: спутник выше 10° над горизонтом и освещен Солнцем,
+// а у наблюдателя хотя бы гражданские сумерки
+const (
+	passMinAltitude   = 10.0
+	passMaxSunAlt     = CivilTwilight
+	passSearchStep    = 20 * time.Second
+	passRefinePrecise = time.Second
+)
+
+// SatellitePass видимый пролет спутника
+type SatellitePass struct {
+	Start        time.Time `json:"start"`
+	StartAzimuth float64   `json:"start_azimuth"`
+	Max          time.Time `json:"max"`
+	MaxAltitude  float64   `json:"max_altitude"`
+	MaxAzimuth   float64   `json:"max_azimuth"`
+	End          time.Time `json:"end"`
+	EndAzimuth   float64   `json:"end_azimuth"`
+	// Magnitude блеск в самой яркой точке пролета (оценка для диффузной сферы)
+	Magnitude       float64 `json:"magnitude"`
+	DurationSeconds int     `json:"duration_seconds"`
+	// Orbit номер витка, на котором виден пролет; не меняется при уточнении TLE,
+	// поэтому вместе с номером спутника и местом однозначно задает пролет
+	Orbit int `json:"orbit"`
+}
+
+// meanElements средние элементы с вековыми возмущениями от J2
+type meanElements struct {
+	epoch      time.Time
+	a          float64 // большая полуось, км
+	e          float64
+	i          float64 // радианы
+	raan0      float64
+	argp0      float64
+	m0         float64
+	n          float64 // среднее движение, рад/мин
+	raanRate   float64 // рад/мин
+	argpRate   float64
+	mRate      float64
+	mDragAccel float64 // рад/мин², ускорение из-за торможения в атмосфере
+	revolution int     // номер витка на эпоху
+}
+
+// newMeanElements восстанавливает брауэровские среднее движение и полуось
+// (как при инициализации SGP4) и вековые скорости узла, перигея и аномалии.
+// Короткопериодические члены и модель торможения SGP4 опущены: для МКС
+// ошибка - километры в первые сутки после эпохи, моменты пролетов - до минуты
+// на горизонте в неделю.
+func newMeanElements(tle *TLE) meanElements {
+	ke := math.Sqrt(earthMu*3600) / math.Pow(earthEquatorKm, 1.5) // √μ в ER^1.5/мин
+	n0 := tle.meanMotion * 2 * math.Pi / 1440
+	e := tle.eccentricity
+	i := tle.inclination * deg
+
+	cosI := math.Cos(i)
+	beta0 := math.Sqrt(1 - e*e)
+	k2 := 0.5 * earthJ2
+	x3thm1 := 3*cosI*cosI - 1
+
+	a1 := math.Pow(ke/n0, 2.0/3)
+	del1 := 1.5 * k2 * x3thm1 / (a1 * a1 * beta0 * beta0 * beta0)
+	a0 := a1 * (1 - del1/3 - del1*del1 - 134.0/81*del1*del1*del1)
+	del0 := 1.5 * k2 * x3thm1 / (a0 * a0 * beta0 * beta0 * beta0)
+	n := n0 / (1 + del0)
+	a := a0 / (1 - del0)
+
+	p := a * (1 - e*e)
+	factor := 0.75 * earthJ2 * n / (p * p)
+
+	return meanElements{
+		epoch:      tle.Epoch,
+		a:          a * earthEquatorKm,
+		e:          e,
+		i:          i,
+		raan0:      tle.raan * deg,
+		argp0:      tle.argPerigee * deg,
+		m0:         tle.meanAnomaly * deg,
+		n:          n,
+		raanRate:   -2 * factor * cosI,
+		argpRate:   factor * (5*cosI*cosI - 1),
+		mRate:      n + factor*beta0*x3thm1,
+		mDragAccel: tle.meanMotionDot * 2 * math.Pi / (1440 * 1440),
+		revolution: tle.revolution,
+	}
+}
+
+// orbit номер витка в момент t. Граница витков перенесена из восходящего узла в точку орбиты,
+// самую далекую от широты наблюдателя (самую южную для северного полушария): пролеты над
+// наблюдателем далеки от границы, и сдвиг кульминации на минуты после уточнения TLE номер не меняет.
+func (el meanElements) orbit(t time.Time, lat float64) int {
+	dt := t.Sub(el.epoch).Minutes()
+	// Средний аргумент широты на эпоху и его приращение, в витках
+	start := normalizeDegrees((el.argp0+el.m0)*rad) / 360
+	progress := ((el.argpRate+el.mRate)*dt + el.mDragAccel*dt*dt) / (2 * math.Pi)
+
+	boundary := 0.75 // аргумент широты 270°
+	if lat < 0 {
+		boundary = 0.25
+	}
+	return int(math.Floor(float64(el.revolution) + start + progress - boundary))
+}
+
+// position положение в инерциальной системе TEME, км
+func (el meanElements) position(t time.Time) vector3 {
+	dt := t.Sub(el.epoch).Minutes()
+
+	raan := el.raan0 + el.raanRate*dt
+	argp := el.argp0 + el.argpRate*dt
+	m := math.Mod(el.m0+el.mRate*dt+el.mDragAccel*dt*dt, 2*math.Pi)
+
+	ecc := m
+	for k := 0; k < 10; k++ {
+		delta := (ecc - el.e*math.Sin(ecc) - m) / (1 - el.e*math.Cos(ecc))
+		ecc -= delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+
+	xp := el.a * (math.Cos(ecc) - el.e)
+	yp := el.a * math.Sqrt(1-el.e*el.e) * math.Sin(ecc)
+
+	cw, sw := math.Cos(argp), math.Sin(argp)
+	cn, sn := math.Cos(raan), math.Sin(raan)
+	ci, si := math.Cos(el.i), math.Sin(el.i)
+
+	return vector3{
+		x: (cw*cn-sw*sn*ci)*xp + (-sw*cn-cw*sn*ci)*yp,
+		y: (cw*sn+sw*cn*ci)*xp + (-sw*sn+cw*cn*ci)*yp,
+		z: sw*si*xp + cw*si*yp,
+	}
+}
+
+// observerECI положение наблюдателя в инерциальной системе, км (эллипсоид WGS-84)
+func observerECI(jd, lat, lon, elevation float64) vector3 {
+	phi := lat * deg
+	theta := (SiderealTime(jd) + lon) * deg
+	e2 := wgs84Flattening * (2 - wgs84Flattening)
+	h := elevation / 1000
+
+	n := wgs84RadiusKm / math.Sqrt(1-e2*math.Sin(phi)*math.Sin(phi))
+	r := (n + h) * math.Cos(phi)
+
+	return vector3{
+		x: r * math.Cos(theta),
+		y: r * math.Sin(theta),
+		z: (n*(1-e2) + h) * math.Sin(phi),
+	}
+}
+
+// satelliteLook положение спутника для наблюдателя
+type satelliteLook struct {
+	altitude, azimuth float64
+	rangeKm           float64
+	sunlit            bool
+	sunAltitude       float64
+	phaseAngle        float64 // угол Солнце-спутник-наблюдатель, градусы
+}
+
+func look(el meanElements, t time.Time, lat, lon, elevation float64) satelliteLook {
+	jd := JulianDay(t)
+	sat := el.position(t)
+	obs := observerECI(jd, lat, lon, elevation)
+	d := sat.sub(obs)
+	rng := d.length()
+
+	// Топоцентрические координаты по местной вертикали
+	phi := lat * deg
+	theta := (SiderealTime(jd) + lon) * deg
+	south := math.Sin(phi)*math.Cos(theta)*d.x + math.Sin(phi)*math.Sin(theta)*d.y - math.Cos(phi)*d.z
+	east := -math.Sin(theta)*d.x + math.Cos(theta)*d.y
+	zenith := math.Cos(phi)*math.Cos(theta)*d.x + math.Cos(phi)*math.Sin(theta)*d.y + math.Sin(phi)*d.z
+
+	sun := SunPosition(jd)
+	ra, dec := sun.RA*deg, sun.Dec*deg
+	sunDir := vector3{math.Cos(dec) * math.Cos(ra), math.Cos(dec) * math.Sin(ra), math.Sin(dec)}
+
+	// Цилиндрическая тень Земли: спутник позади Земли и ближе радиуса к оси тени
+	along := sat.x*sunDir.x + sat.y*sunDir.y + sat.z*sunDir.z
+	perp := vector3{sat.x - along*sunDir.x, sat.y - along*sunDir.y, sat.z - along*sunDir.z}
+	sunlit := along > 0 || perp.length() > wgs84RadiusKm
+
+	// Фазовый угол: между направлениями спутник->Солнце и спутник->наблюдатель
+	cosPhase := -(d.x*sunDir.x + d.y*sunDir.y + d.z*sunDir.z) / rng
+
+	return satelliteLook{
+		altitude:    math.Asin(zenith/rng) * rad,
+		azimuth:     normalizeDegrees(math.Atan2(east, -south) * rad),
+		rangeKm:     rng,
+		sunlit:      sunlit,
+		sunAltitude: ToHorizontal(sun.Equatorial, jd, lat, lon).Altitude,
+		phaseAngle:  math.Acos(math.Max(-1, math.Min(1, cosPhase))) * rad,
+	}
+}
+
+func (l satelliteLook) visible() bool {
+	return l.altitude >= passMinAltitude && l.sunlit && l.sunAltitude < passMaxSunAlt
+}
+
+// magnitude блеск диффузной сферы; стандартная величина задана на 1000 км при фазе 90°
+func (l satelliteLook) magnitude(standard float64) float64 {
+	phase := l.phaseAngle * deg
+	f := math.Sin(phase) + (math.Pi-phase)*math.Cos(phase)
+	if f <= 1e-6 {
+		f = 1e-6
+	}
+	return standard + 5*math.Log10(l.rangeKm/1000) - 2.5*math.Log10(f)
+}
+
+// VisiblePasses ищет видимые пролеты спутника в интервале [from, to) для наблюдателя
+// на lat/lon и высоте elevation (м). standardMagnitude - блеск на 1000 км при фазе 90°
+// (для МКС около -1.8). Моменты возвращаются в часовом поясе from.
+func VisiblePasses(tle *TLE, from, to time.Time, lat, lon, elevation, standardMagnitude float64) []SatellitePass {
+	el := newMeanElements(tle)
+	at := func(t time.Time) satelliteLook { return look(el, t, lat, lon, elevation) }
+
+	// edge уточняет момент смены видимости между a (значение was) и b
+	edge := func(a, b time.Time, was bool) time.Time {
+		for b.Sub(a) > passRefinePrecise {
+			mid := a.Add(b.Sub(a) / 2)
+			if at(mid).visible() == was {
+				a = mid
+			} else {
+				b = mid
+			}
+		}
+		return b
+	}
+
+	var passes []SatellitePass
+	var current *SatellitePass
+	prev := from
+	wasVisible := at(from).visible()
+	if wasVisible {
+		current = &SatellitePass{Start: from}
+	}
+
+	for t := from.Add(passSearchStep); t.Before(to); t = t.Add(passSearchStep) {
+		l := at(t)
+		visible := l.visible()
+
+		switch {
+		case visible && !wasVisible:
+			current = &SatellitePass{Start: edge(prev, t, false)}
+		case !visible && wasVisible && current != nil:
+			current.End = edge(prev, t, true)
+			passes = append(passes, finishPass(*current, at, standardMagnitude))
+			current = nil
+		}
+
+		if visible && current != nil && l.altitude > current.MaxAltitude {
+			current.Max, current.MaxAltitude = t, l.altitude
+		}
+
+		prev, wasVisible = t, visible
+	}
+	if current != nil {
+		current.End = prev
+		passes = append(passes, finishPass(*current, at, standardMagnitude))
+	}
+
+	loc := from.Location()
+	for i := range passes {
+		passes[i].Orbit = el.orbit(passes[i].Max, lat)
+		passes[i].Start = passes[i].Start.In(loc)
+		passes[i].Max = passes[i].Max.In(loc)
+		passes[i].End = passes[i].End.In(loc)
+	}
+	return passes
+}
+
+// finishPass уточняет кульминацию и считает азимуты и блеск
+func finishPass(pass SatellitePass, at func(time.Time) satelliteLook, standardMagnitude float64) SatellitePass {
+	if pass.Max.IsZero() {
+		pass.Max = pass.Start
+	}
+
+	// Золотое сечение на окрестности грубой кульминации, ограниченной самим пролетом
+	a, b := pass.Max.Add(-passSearchStep), pass.Max.Add(passSearchStep)
+	if a.Before(pass.Start) {
+		a = pass.Start
+	}
+	if b.After(pass.End) {
+		b = pass.End
+	}
+	for b.Sub(a) > passRefinePrecise {
+		m1 := a.Add(b.Sub(a) / 3)
+		m2 := b.Add(-b.Sub(a) / 3)
+		if at(m1).altitude < at(m2).altitude {
+			a = m1
+		} else {
+			b = m2
+		}
+	}
+	pass.Max = a.Add(b.Sub(a) / 2).Truncate(time.Second)
+	pass.Start = pass.Start.Truncate(time.Second)
+	pass.End = pass.End.Truncate(time.Second)
+
+	start, peak, end := at(pass.Start), at(pass.Max), at(pass.End)
+	pass.StartAzimuth = round1(start.azimuth)
+	pass.MaxAltitude = round1(peak.altitude)
+	pass.MaxAzimuth = round1(peak.azimuth)
+	pass.EndAzimuth = round1(end.azimuth)
+	pass.Magnitude = round1(peak.magnitude(standardMagnitude))
+	pass.DurationSeconds = int(pass.End.Sub(pass.Start).Seconds())
+
+	return pass
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
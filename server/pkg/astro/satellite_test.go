@@ -0,0 +1,56 @@
+package astro
+
+import (
+	"testing"
+	"time"
+)
+
+// Пример TLE МКС из описания формата NORAD; номер витка на эпоху 56353
+const issTestTLE = `ISS (ZARYA)
+1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927
+2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537`
+
+func TestVisiblePassOrbitToleratesShifts(t *testing.T) {
+	tle, err := ParseTLE(issTestTLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tle.revolution != 56353 {
+		t.Fatalf("revolution %d, want 56353", tle.revolution)
+	}
+	el := newMeanElements(tle)
+
+	// Новый TLE сдвигает кульминацию не больше чем на минуты; берем запас в 10 минут
+	const shift = 10 * time.Minute
+	observers := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"Moscow", 55.75, 37.62},
+		{"New York", 40.71, -74.01},
+		{"Cape Town", -33.92, 18.42},
+	}
+	for _, o := range observers {
+		t.Run(o.name, func(t *testing.T) {
+			passes := VisiblePasses(tle, tle.Epoch, tle.Epoch.Add(5*24*time.Hour), o.lat, o.lon, 0, -1.8)
+			if len(passes) == 0 {
+				t.Fatal("no visible passes")
+			}
+			seen := make(map[int]bool)
+			for _, p := range passes {
+				if p.Orbit < tle.revolution {
+					t.Errorf("pass at %s on orbit %d before the epoch revolution", p.Max, p.Orbit)
+				}
+				if seen[p.Orbit] {
+					t.Errorf("two passes on orbit %d", p.Orbit)
+				}
+				seen[p.Orbit] = true
+				for _, at := range []time.Time{p.Max.Add(-shift), p.Max.Add(shift)} {
+					if orbit := el.orbit(at, o.lat); orbit != p.Orbit {
+						t.Errorf("pass at %s: orbit %d at %s, want %d", p.Max, orbit, at, p.Orbit)
+					}
+				}
+			}
+		})
+	}
+}
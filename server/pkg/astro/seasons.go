@@ -0,0 +1,106 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Season равноденствие или солнцестояние
+type Season struct {
+	// Type march_equinox, june_solstice, september_equinox, december_solstice
+	Type string    `json:"type"`
+	Name string    `json:"name"`
+	Time time.Time `json:"time"`
+}
+
+// Средние моменты сезонов для 1000-3000 гг., JDE (Меёс, таблица 27.B)
+var seasonPolynomials = []struct {
+	kind, name string
+	c          [5]float64
+}{
+	{"march_equinox", "March equinox", [5]float64{2451623.80984, 365242.37404, 0.05169, -0.00411, -0.00057}},
+	{"june_solstice", "June solstice", [5]float64{2451716.56767, 365241.62603, 0.00325, 0.00888, -0.00030}},
+	{"september_equinox", "September equinox", [5]float64{2451810.21715, 365242.01767, -0.11575, 0.00337, 0.00078}},
+	{"december_solstice", "December solstice", [5]float64{2451900.05952, 365242.74049, -0.06223, -0.00823, 0.00032}},
+}
+
+// Периодические члены A·cos(B + C·T) (Меёс, таблица 27.C)
+var seasonTerms = [][3]float64{
+	{485, 324.96, 1934.136}, {203, 337.23, 32964.467}, {199, 342.08, 20.186},
+	{182, 27.85, 445267.112}, {156, 73.14, 45036.886}, {136, 171.52, 22518.443},
+	{77, 222.54, 65928.934}, {74, 296.72, 3034.906}, {70, 243.58, 9037.513},
+	{58, 119.81, 33718.147}, {52, 297.17, 150.678}, {50, 21.02, 2281.226},
+	{45, 247.54, 29929.562}, {44, 325.15, 31555.956}, {29, 60.93, 4443.417},
+	{18, 155.12, 67555.328}, {17, 288.79, 4562.452}, {16, 198.04, 62894.029},
+	{14, 199.76, 31436.921}, {12, 95.39, 14577.848}, {12, 287.11, 31931.756},
+	{12, 320.81, 34777.259}, {9, 227.73, 1222.114}, {8, 15.45, 16859.074},
+}
+
+// Seasons равноденствия и солнцестояния года по Меёсу (глава 27), точность около минуты
+func Seasons(year int) []Season {
+	y := float64(year-2000) / 1000
+	seasons := make([]Season, 0, len(seasonPolynomials))
+
+	for _, p := range seasonPolynomials {
+		jde0 := p.c[0] + p.c[1]*y + p.c[2]*y*y + p.c[3]*y*y*y + p.c[4]*y*y*y*y
+		t := julianCenturies(jde0)
+		w := (35999.373*t - 2.47) * deg
+		dl := 1 + 0.0334*math.Cos(w) + 0.0007*math.Cos(2*w)
+
+		var s float64
+		for _, term := range seasonTerms {
+			s += term[0] * math.Cos((term[1]+term[2]*t)*deg)
+		}
+
+		jde := jde0 + 0.00001*s/dl
+		seasons = append(seasons, Season{
+			Type: p.kind,
+			Name: p.name,
+			Time: fromDynamicalTime(jde).Truncate(time.Second),
+		})
+	}
+	return seasons
+}
+
+// DeltaT разность TT-UT в секундах (полиномы Эспенака и Меёса для 1900-2150 гг.)
+func DeltaT(t time.Time) float64 {
+	y := float64(t.Year()) + (float64(t.YearDay())-0.5)/365.25
+
+	switch {
+	case y < 1920:
+		u := y - 1900
+		return -2.79 + 1.494119*u - 0.0598939*u*u + 0.0061966*u*u*u - 0.000197*u*u*u*u
+	case y < 1941:
+		u := y - 1920
+		return 21.20 + 0.84493*u - 0.076100*u*u + 0.0020936*u*u*u
+	case y < 1961:
+		u := y - 1950
+		return 29.07 + 0.407*u - u*u/233 + u*u*u/2547
+	case y < 1986:
+		u := y - 1975
+		return 45.45 + 1.067*u - u*u/260 - u*u*u/718
+	case y < 2005:
+		u := y - 2000
+		return 63.86 + 0.3345*u - 0.060374*u*u + 0.0017275*u*u*u + 0.000651814*u*u*u*u + 0.00002373599*u*u*u*u*u
+	case y < 2050:
+		u := y - 2000
+		return 62.92 + 0.32217*u + 0.005589*u*u
+	case y < 2150:
+		u := (y - 1820) / 100
+		return -20 + 32*u*u - 0.5628*(2150-y)
+	default:
+		u := (y - 1820) / 100
+		return -20 + 32*u*u
+	}
+}
+
+// dynamicalDay юлианская дата в шкале TT для момента t (UT)
+func dynamicalDay(t time.Time) float64 {
+	return JulianDay(t) + DeltaT(t)/86400
+}
+
+// fromDynamicalTime момент UT по юлианской дате в шкале TT
+func fromDynamicalTime(jde float64) time.Time {
+	approx := TimeFromJulianDay(jde)
+	return TimeFromJulianDay(jde - DeltaT(approx)/86400)
+}
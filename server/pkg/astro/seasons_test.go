@@ -0,0 +1,36 @@
+package astro
+
+import (
+	"testing"
+	"time"
+)
+
+// Эталон - таблица USNO "Earth's Seasons" для 2024 года (UT, с точностью до минуты)
+func TestSeasons2024MatchUSNO(t *testing.T) {
+	// Допуск - минута: округление таблицы плюс точность метода Меёса
+	const tolerance = time.Minute
+	want := []struct {
+		kind string
+		time time.Time
+	}{
+		{"march_equinox", time.Date(2024, 3, 20, 3, 6, 0, 0, time.UTC)},
+		{"june_solstice", time.Date(2024, 6, 20, 20, 51, 0, 0, time.UTC)},
+		{"september_equinox", time.Date(2024, 9, 22, 12, 44, 0, 0, time.UTC)},
+		{"december_solstice", time.Date(2024, 12, 21, 9, 21, 0, 0, time.UTC)},
+	}
+
+	seasons := Seasons(2024)
+	if len(seasons) != len(want) {
+		t.Fatalf("%d seasons, want %d", len(seasons), len(want))
+	}
+	for i, w := range want {
+		s := seasons[i]
+		if s.Type != w.kind {
+			t.Errorf("season %d is %s, want %s", i, s.Type, w.kind)
+			continue
+		}
+		if d := s.Time.Sub(w.time); d < -tolerance || d > tolerance {
+			t.Errorf("%s %s, want %s ± %s", s.Type, s.Time.Format(time.DateTime), w.time.Format(time.DateTime), tolerance)
+		}
+	}
+}
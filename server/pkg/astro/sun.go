@@ -0,0 +1,111 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Высоты центра Солнца для восхода/захода и сумерек, градусы
+const (
+	SunriseAltitude      = -0.8333
+	CivilTwilight        = -6.0
+	NauticalTwilight     = -12.0
+	AstronomicalTwilight = -18.0
+)
+
+// SunCoordinates видимое положение Солнца: эклиптическая долгота,
+// экваториальные координаты и расстояние в а.е.
+type SunCoordinates struct {
+	Longitude float64
+	Equatorial
+	Distance float64
+}
+
+// SunPosition вычисляет положение Солнца по алгоритму Меёса (глава 25, низкая точность)
+func SunPosition(jd float64) SunCoordinates {
+	t := julianCenturies(jd)
+
+	l0 := normalizeDegrees(280.46646 + 36000.76983*t + 0.0003032*t*t)
+	m := normalizeDegrees(357.52911 + 35999.05029*t - 0.0001537*t*t)
+	e := 0.016708634 - 0.000042037*t - 0.0000001267*t*t
+
+	mr := m * deg
+	c := (1.914602-0.004817*t-0.000014*t*t)*math.Sin(mr) +
+		(0.019993-0.000101*t)*math.Sin(2*mr) +
+		0.000289*math.Sin(3*mr)
+
+	trueLon := l0 + c
+	anomaly := (m + c) * deg
+	distance := 1.000001018 * (1 - e*e) / (1 + e*math.Cos(anomaly))
+
+	// Видимая долгота: нутация и аберрация
+	lon := normalizeDegrees(trueLon - 0.00569 + nutationLongitude(t))
+
+	return SunCoordinates{
+		Longitude:  lon,
+		Equatorial: EclipticToEquatorial(lon, 0, jd),
+		Distance:   distance,
+	}
+}
+
+// SunTimes восход, заход, кульминация и границы сумерек за сутки.
+// Поля равны nil, если событие в эти сутки не происходит (полярный день или ночь).
+type SunTimes struct {
+	Sunrise          *time.Time `json:"sunrise,omitempty"`
+	Sunset           *time.Time `json:"sunset,omitempty"`
+	SolarNoon        *time.Time `json:"solar_noon,omitempty"`
+	CivilDawn        *time.Time `json:"civil_dawn,omitempty"`
+	CivilDusk        *time.Time `json:"civil_dusk,omitempty"`
+	NauticalDawn     *time.Time `json:"nautical_dawn,omitempty"`
+	NauticalDusk     *time.Time `json:"nautical_dusk,omitempty"`
+	AstronomicalDawn *time.Time `json:"astronomical_dawn,omitempty"`
+	AstronomicalDusk *time.Time `json:"astronomical_dusk,omitempty"`
+	DayLength        float64    `json:"day_length_hours"`
+	AlwaysUp         bool       `json:"always_up,omitempty"`
+	AlwaysDown       bool       `json:"always_down,omitempty"`
+}
+
+// SunEvents считает события Солнца для местных суток, которые содержат date.
+// Границы суток берутся в часовом поясе date.Location(); результаты - в нем же.
+func SunEvents(date time.Time, lat, lon float64) SunTimes {
+	start, end := dayBounds(date)
+	position := func(jd float64) (Equatorial, float64) {
+		return SunPosition(jd).Equatorial, SunriseAltitude
+	}
+
+	var times SunTimes
+	rise, set, up, down := riseSet(position, start, end, lat, lon, 0)
+	times.Sunrise, times.Sunset = inLocation(rise, date), inLocation(set, date)
+	times.AlwaysUp, times.AlwaysDown = up, down
+	times.SolarNoon = inLocation(transit(func(jd float64) Equatorial { return SunPosition(jd).Equatorial }, start, end, lon), date)
+
+	twilight := func(altitude float64) (*time.Time, *time.Time) {
+		fixed := func(jd float64) (Equatorial, float64) {
+			return SunPosition(jd).Equatorial, altitude
+		}
+		dawn, dusk, _, _ := riseSet(fixed, start, end, lat, lon, 0)
+		return inLocation(dawn, date), inLocation(dusk, date)
+	}
+	times.CivilDawn, times.CivilDusk = twilight(CivilTwilight)
+	times.NauticalDawn, times.NauticalDusk = twilight(NauticalTwilight)
+	times.AstronomicalDawn, times.AstronomicalDusk = twilight(AstronomicalTwilight)
+
+	switch {
+	case times.AlwaysUp:
+		times.DayLength = 24
+	case times.Sunrise != nil && times.Sunset != nil && times.Sunset.After(*times.Sunrise):
+		times.DayLength = times.Sunset.Sub(*times.Sunrise).Hours()
+	case times.Sunrise != nil:
+		times.DayLength = end.Sub(*times.Sunrise).Hours()
+	case times.Sunset != nil:
+		times.DayLength = times.Sunset.Sub(start).Hours()
+	}
+
+	return times
+}
+
+// SunAltitude высота Солнца над горизонтом в момент t
+func SunAltitude(t time.Time, lat, lon float64) float64 {
+	jd := JulianDay(t)
+	return ToHorizontal(SunPosition(jd).Equatorial, jd, lat, lon).Altitude
+}
@@ -0,0 +1,142 @@
+package astro
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTLE = errors.New("invalid TLE")
+
+// TLE двухстрочный набор элементов орбиты спутника (формат NORAD)
+type TLE struct {
+	Name    string    `json:"name"`
+	Line1   string    `json:"line1"`
+	Line2   string    `json:"line2"`
+	NoradID int       `json:"norad_id"`
+	Epoch   time.Time `json:"epoch"`
+
+	inclination  float64 // градусы
+	raan         float64 // долгота восходящего узла, градусы
+	eccentricity float64
+	argPerigee   float64 // градусы
+	meanAnomaly  float64 // градусы
+	meanMotion   float64 // оборотов в сутки
+	// meanMotionDot половина первой производной среднего движения, об/сут²
+	meanMotionDot float64
+	// revolution номер витка на эпоху (витки отсчитываются от восходящего узла)
+	revolution int
+}
+
+// ParseTLE разбирает TLE из текста: две строки элементов, перед ними
+// может стоять строка с названием (формат CelesTrak)
+func ParseTLE(text string) (*TLE, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		if line = strings.TrimRight(line, " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var name string
+	switch {
+	case len(lines) >= 3 && strings.HasPrefix(lines[1], "1 ") && strings.HasPrefix(lines[2], "2 "):
+		name = strings.TrimSpace(strings.TrimPrefix(lines[0], "0 "))
+		lines = lines[1:3]
+	case len(lines) >= 2 && strings.HasPrefix(lines[0], "1 ") && strings.HasPrefix(lines[1], "2 "):
+		lines = lines[:2]
+	default:
+		return nil, fmt.Errorf("%w: expected two element lines", ErrInvalidTLE)
+	}
+
+	line1, line2 := lines[0], lines[1]
+	for i, line := range lines {
+		if len(line) < 69 {
+			return nil, fmt.Errorf("%w: line %d is too short", ErrInvalidTLE, i+1)
+		}
+		if !tleChecksumOK(line) {
+			return nil, fmt.Errorf("%w: checksum mismatch on line %d", ErrInvalidTLE, i+1)
+		}
+	}
+
+	tle := &TLE{Name: name, Line1: line1, Line2: line2}
+	var err error
+	field := func(line string, from, to int) string {
+		return strings.TrimSpace(line[from-1 : to])
+	}
+	number := func(line string, from, to int) float64 {
+		if err != nil {
+			return 0
+		}
+		var v float64
+		v, err = strconv.ParseFloat(field(line, from, to), 64)
+		return v
+	}
+
+	tle.NoradID, err = strconv.Atoi(field(line1, 3, 7))
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad catalog number", ErrInvalidTLE)
+	}
+
+	year := int(number(line1, 19, 20))
+	day := number(line1, 21, 32)
+	tle.meanMotionDot = number(line1, 34, 43)
+	tle.inclination = number(line2, 9, 16)
+	tle.raan = number(line2, 18, 25)
+	tle.eccentricity = number(line2, 27, 33) / 1e7
+	tle.argPerigee = number(line2, 35, 42)
+	tle.meanAnomaly = number(line2, 44, 51)
+	tle.meanMotion = number(line2, 53, 63)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTLE, err)
+	}
+	// Номер витка в старых наборах бывает пустым - тогда считаем от нуля
+	if rev := field(line2, 64, 68); rev != "" {
+		if tle.revolution, err = strconv.Atoi(rev); err != nil {
+			return nil, fmt.Errorf("%w: bad revolution number", ErrInvalidTLE)
+		}
+	}
+	if tle.meanMotion <= 0 || tle.eccentricity >= 1 {
+		return nil, fmt.Errorf("%w: orbit elements out of range", ErrInvalidTLE)
+	}
+
+	// Двузначный год: 57-99 - XX век, 00-56 - XXI
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	tle.Epoch = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration((day - 1) * 24 * float64(time.Hour)))
+
+	return tle, nil
+}
+
+// tleChecksumOK контрольная сумма по модулю 10: цифры плюс единица за каждый минус
+func tleChecksumOK(line string) bool {
+	sum := 0
+	for _, ch := range line[:68] {
+		switch {
+		case ch >= '0' && ch <= '9':
+			sum += int(ch - '0')
+		case ch == '-':
+			sum++
+		}
+	}
+	return int(line[68]-'0') == sum%10
+}
+
+// Age возраст элементов на момент t; прогноз по элементам старше пары недель ненадежен
+func (t *TLE) Age(at time.Time) time.Duration {
+	return at.Sub(t.Epoch)
+}
+
+// String название и номер спутника
+func (t *TLE) String() string {
+	if t.Name != "" {
+		return fmt.Sprintf("%s (%d)", t.Name, t.NoradID)
+	}
+	return strconv.Itoa(t.NoradID)
+}
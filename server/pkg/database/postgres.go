@@ -66,12 +66,39 @@ func Migrate(db *gorm.DB) error {
 		&models.ISSLog{},
 		&models.OSDRItem{},
 		&models.Telemetry{},
+		&models.TelemetryChannel{},
+		&models.TelemetryAnomaly{},
+		&models.TelemetryAlertRule{},
+		&models.TelemetryAlert{},
+		&models.TelemetryRollup{},
+		&models.TelemetryRollupCursor{},
 		&models.SpaceCache{},
+		&models.SpaceWeatherEvent{},
+		&models.JWSTImage{},
+		&models.Collection{},
+		&models.CollectionItem{},
+		&models.ObserverLocation{},
+		&models.RetentionArchive{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate models: %w", err)
 	}
 
+	// Реестр каналов и перенос показаний из прежних столбцов voltage/temperature
+	if err := migrateTelemetryChannels(db); err != nil {
+		return fmt.Errorf("failed to migrate telemetry channels: %w", err)
+	}
+
+	// Транзакция показания для курсора сводок
+	if err := migrateTelemetryTxID(db); err != nil {
+		return fmt.Errorf("failed to add telemetry transaction id: %w", err)
+	}
+
+	// Повторы показаний, записанные до уникального ключа
+	if err := dedupTelemetries(db); err != nil {
+		return fmt.Errorf("failed to remove duplicate telemetry: %w", err)
+	}
+
 	// Создаем индексы
 	if err := createIndexes(db); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
@@ -102,11 +129,122 @@ func createIndexes(db *gorm.DB) error {
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_telemetry_created_at ON telemetries(created_at DESC)").Error; err != nil {
 		return err
 	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_telemetry_channel_values ON telemetries USING gin(channel_values)").Error; err != nil {
+		return err
+	}
+	// Естественный ключ показания: импорт и прием пишут с ON CONFLICT DO NOTHING.
+	// Заменяет прежний неуникальный индекс по тем же столбцам
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_telemetry_source_recorded_key ON telemetries(source_file, recorded_at)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DROP INDEX IF EXISTS idx_telemetry_source_recorded").Error; err != nil {
+		return err
+	}
+	// Курсор сводок телеметрии
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_telemetry_tx ON telemetries(tx_id, id)").Error; err != nil {
+		return err
+	}
+
+	// Не больше одной активной тревоги на правило и источник
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_telemetry_alert_open ON telemetry_alerts(rule_id, source_file) WHERE state = 'firing'").Error; err != nil {
+		return err
+	}
 
 	// Индексы для SpaceCache
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_space_cache_source_fetched ON space_caches(source, fetched_at DESC)").Error; err != nil {
 		return err
 	}
+	// Очистка по сроку хранения идет по всем источникам сразу
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_space_cache_fetched_at ON space_caches(fetched_at)").Error; err != nil {
+		return err
+	}
+
+	// Индексы для SpaceWeatherEvent
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_space_weather_type_start ON space_weather_events(event_type, start_time DESC)").Error; err != nil {
+		return err
+	}
+
+	// Индексы для JWSTImage
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_jwst_images_instruments ON jwst_images USING gin(instruments)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_jwst_images_caption ON jwst_images USING gin(caption gin_trgm_ops)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_jwst_images_target ON jwst_images USING gin(target_name gin_trgm_ops)").Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// migrateTelemetryChannels регистрирует исходные каналы voltage и temperature и переносит
+// их значения из прежних столбцов numeric(6,2) в channel_values. Прежние столбцы
+// остаются в таблице (без NOT NULL), их можно удалить вручную после проверки переноса.
+func migrateTelemetryChannels(db *gorm.DB) error {
+	// Пороги температуры - те же, что раньше подсвечивала выгрузка в Excel
+	err := db.Exec(`INSERT INTO telemetry_channels (name, unit, type, min_value, max_value, "precision", warn_min, warn_max, description, created_at, updated_at)
+		VALUES
+			('voltage', 'V', 'float', -9999.99, 9999.99, 2, NULL, NULL, 'Supply voltage', now(), now()),
+			('temperature', '°C', 'float', -273.15, 9999.99, 2, -20, 60, 'Temperature', now(), now())
+		ON CONFLICT (name) DO NOTHING`).Error
+	if err != nil {
+		return err
+	}
 
+	for _, column := range []string{"voltage", "temperature"} {
+		if !db.Migrator().HasColumn("telemetries", column) {
+			continue
+		}
+
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE telemetries ALTER COLUMN %s DROP NOT NULL", column)).Error; err != nil {
+			return err
+		}
+
+		// Переносим только строки, где канала еще нет - повторный запуск ничего не меняет
+		result := db.Exec(fmt.Sprintf(`UPDATE telemetries
+			SET channel_values = channel_values || jsonb_build_object('%[1]s', %[1]s::float8)
+			WHERE %[1]s IS NOT NULL AND NOT jsonb_exists(channel_values, '%[1]s')`, column))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Telemetry migration: moved %d %s values to channel_values", result.RowsAffected, column)
+		}
+	}
+
+	return nil
+}
+
+// dedupTelemetries удаляет повторы (recorded_at, source_file), оставляя первое записанное
+// показание, - без этого уникальный индекс не построится. После появления индекса не нужен.
+// Аномалии удаленных повторов остаются в журнале событий.
+func dedupTelemetries(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.Telemetry{}, "idx_telemetry_source_recorded_key") {
+		return nil
+	}
+
+	result := db.Exec(`DELETE FROM telemetries t
+		USING telemetries d
+		WHERE t.source_file = d.source_file AND t.recorded_at = d.recorded_at AND t.id > d.id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Telemetry migration: removed %d duplicate readings", result.RowsAffected)
+	}
 	return nil
 }
+
+// migrateTelemetryTxID добавляет telemetries.tx_id - транзакцию, записавшую показание:
+// курсор сводок останавливается на незавершенных транзакциях. Прежние показания получают 0,
+// курсор с их ID продолжает с того же места.
+func migrateTelemetryTxID(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("telemetries", "tx_id") {
+		// Постоянное значение по умолчанию не переписывает таблицу
+		if err := db.Exec("ALTER TABLE telemetries ADD COLUMN tx_id xid8 NOT NULL DEFAULT '0'").Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec("ALTER TABLE telemetries ALTER COLUMN tx_id SET DEFAULT pg_current_xact_id()").Error
+}
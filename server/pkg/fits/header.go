@@ -0,0 +1,242 @@
+// Package fits разбирает заголовки FITS (Flexible Image Transport System)
+// без зависимостей от C-библиотек. Поддерживаются карточки фиксированного
+// формата и длинные строки по соглашению CONTINUE.
+package fits
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// BlockSize размер логической записи FITS
+	BlockSize = 2880
+	// CardSize длина одной карточки заголовка
+	CardSize = 80
+)
+
+var (
+	ErrNotFITS        = errors.New("fits: stream does not start with SIMPLE or XTENSION")
+	ErrHeaderTooLarge = errors.New("fits: END card not found within size limit")
+)
+
+// Card одна карточка заголовка. Value содержит string, bool, int64, float64 или nil.
+type Card struct {
+	Key     string
+	Value   interface{}
+	Comment string
+}
+
+// Header разобранный заголовок HDU
+type Header struct {
+	Cards []Card
+	index map[string]int
+	// Size число прочитанных байт заголовка (кратно BlockSize)
+	Size int
+}
+
+// ParseHeader читает блоки из r до карточки END. maxBytes ограничивает объем
+// заголовка (0 - без ограничения).
+func ParseHeader(r io.Reader, maxBytes int) (*Header, error) {
+	header := &Header{index: make(map[string]int)}
+	block := make([]byte, BlockSize)
+
+	for {
+		if maxBytes > 0 && header.Size+BlockSize > maxBytes {
+			return nil, ErrHeaderTooLarge
+		}
+
+		if _, err := io.ReadFull(r, block); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("fits: truncated header: %w", io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+
+		if header.Size == 0 && !bytes.HasPrefix(block, []byte("SIMPLE  ")) && !bytes.HasPrefix(block, []byte("XTENSION")) {
+			return nil, ErrNotFITS
+		}
+		header.Size += BlockSize
+
+		for offset := 0; offset < BlockSize; offset += CardSize {
+			raw := string(block[offset : offset+CardSize])
+			key := strings.TrimRight(raw[:8], " ")
+
+			if key == "END" {
+				return header, nil
+			}
+
+			if err := header.addCard(key, raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (h *Header) addCard(key, raw string) error {
+	switch key {
+	case "", "COMMENT", "HISTORY":
+		return nil
+	case "CONTINUE":
+		// Продолжение длинной строки предыдущей карточки
+		if len(h.Cards) == 0 {
+			return nil
+		}
+		prev := &h.Cards[len(h.Cards)-1]
+		prevValue, ok := prev.Value.(string)
+		if !ok || !strings.HasSuffix(prevValue, "&") {
+			return nil
+		}
+		value, comment, err := parseValue(raw[8:])
+		if err != nil {
+			return fmt.Errorf("fits: CONTINUE after %s: %w", prev.Key, err)
+		}
+		if str, ok := value.(string); ok {
+			prev.Value = strings.TrimSuffix(prevValue, "&") + str
+			if comment != "" {
+				prev.Comment = comment
+			}
+		}
+		return nil
+	}
+
+	// Карточки без "= " в 9-10 колонках не несут значения
+	if raw[8:10] != "= " {
+		return nil
+	}
+
+	value, comment, err := parseValue(raw[10:])
+	if err != nil {
+		return fmt.Errorf("fits: card %s: %w", key, err)
+	}
+
+	if _, exists := h.index[key]; !exists {
+		h.index[key] = len(h.Cards)
+	}
+	h.Cards = append(h.Cards, Card{Key: key, Value: value, Comment: comment})
+	return nil
+}
+
+// parseValue разбирает поле значения карточки и комментарий после "/"
+func parseValue(field string) (interface{}, string, error) {
+	trimmed := strings.TrimLeft(field, " ")
+	if trimmed == "" {
+		return nil, "", nil
+	}
+
+	if trimmed[0] == '\'' {
+		var sb strings.Builder
+		i := 1
+		for ; i < len(trimmed); i++ {
+			if trimmed[i] == '\'' {
+				// Две кавычки подряд - экранированная кавычка
+				if i+1 < len(trimmed) && trimmed[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			sb.WriteByte(trimmed[i])
+		}
+		if i >= len(trimmed) {
+			return nil, "", errors.New("unterminated string value")
+		}
+		return strings.TrimRight(sb.String(), " "), extractComment(trimmed[i+1:]), nil
+	}
+
+	valuePart := trimmed
+	comment := ""
+	if idx := strings.IndexByte(trimmed, '/'); idx >= 0 {
+		valuePart = trimmed[:idx]
+		comment = strings.TrimSpace(trimmed[idx+1:])
+	}
+	valuePart = strings.TrimSpace(valuePart)
+
+	switch valuePart {
+	case "":
+		return nil, comment, nil
+	case "T":
+		return true, comment, nil
+	case "F":
+		return false, comment, nil
+	}
+
+	if i, err := strconv.ParseInt(valuePart, 10, 64); err == nil {
+		return i, comment, nil
+	}
+
+	// Fortran-нотация экспоненты (1.0D+03)
+	normalized := strings.NewReplacer("D", "E", "d", "e").Replace(valuePart)
+	if f, err := strconv.ParseFloat(normalized, 64); err == nil {
+		return f, comment, nil
+	}
+
+	// Комплексные числа и прочие редкие значения сохраняем как есть
+	return valuePart, comment, nil
+}
+
+func extractComment(rest string) string {
+	if idx := strings.IndexByte(rest, '/'); idx >= 0 {
+		return strings.TrimSpace(rest[idx+1:])
+	}
+	return ""
+}
+
+// Get возвращает значение первой карточки с ключом key
+func (h *Header) Get(key string) (interface{}, bool) {
+	idx, ok := h.index[strings.ToUpper(key)]
+	if !ok {
+		return nil, false
+	}
+	return h.Cards[idx].Value, true
+}
+
+// String возвращает строковое значение карточки
+func (h *Header) String(key string) (string, bool) {
+	value, ok := h.Get(key)
+	if !ok {
+		return "", false
+	}
+	str, ok := value.(string)
+	return str, ok
+}
+
+// Float возвращает числовое значение карточки (целые приводятся к float64)
+func (h *Header) Float(key string) (float64, bool) {
+	value, ok := h.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Int возвращает целое значение карточки
+func (h *Header) Int(key string) (int64, bool) {
+	value, ok := h.Get(key)
+	if !ok {
+		return 0, false
+	}
+	i, ok := value.(int64)
+	return i, ok
+}
+
+// Bool возвращает логическое значение карточки
+func (h *Header) Bool(key string) (bool, bool) {
+	value, ok := h.Get(key)
+	if !ok {
+		return false, false
+	}
+	b, ok := value.(bool)
+	return b, ok
+}
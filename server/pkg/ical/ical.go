@@ -0,0 +1,181 @@
+// Package ical формирует календари iCalendar (RFC 5545) для подписки
+// в Google Calendar, Apple Calendar, Thunderbird и т.п.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Длина строки без CRLF; длинные строки переносятся (раздел 3.1)
+const maxLineOctets = 75
+
+const (
+	utcLayout  = "20060102T150405Z"
+	dateLayout = "20060102"
+)
+
+// Calendar календарь VCALENDAR с событиями
+type Calendar struct {
+	ProdID      string
+	Name        string
+	Description string
+	// Timezone пояс IANA для отображения (X-WR-TIMEZONE); сами времена пишутся в UTC
+	Timezone string
+	// RefreshInterval как часто клиентам перечитывать подписку
+	RefreshInterval time.Duration
+	// Stamp момент формирования (DTSTAMP событий)
+	Stamp  time.Time
+	Events []Event
+}
+
+// Event событие VEVENT. End может быть нулевым для мгновенных событий.
+type Event struct {
+	// UID должен быть стабилен между выгрузками, иначе клиенты задвоят событие
+	UID         string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+	Categories  []string
+	URL         string
+	// Geo широта и долгота места события
+	Geo *[2]float64
+}
+
+// Encode пишет календарь в w
+func (c *Calendar) Encode(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	stamp := c.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", c.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	if c.Description != "" {
+		line("X-WR-CALDESC", escapeText(c.Description))
+	}
+	if c.Timezone != "" {
+		line("X-WR-TIMEZONE", c.Timezone)
+	}
+	if c.RefreshInterval > 0 {
+		duration := formatDuration(c.RefreshInterval)
+		line("REFRESH-INTERVAL;VALUE=DURATION", duration)
+		line("X-PUBLISHED-TTL", duration)
+	}
+
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp.UTC().Format(utcLayout))
+		if e.AllDay {
+			// Для событий на весь день DTEND - следующий день (не включительно)
+			line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+			end := e.End
+			if !end.After(e.Start) {
+				end = e.Start.AddDate(0, 0, 1)
+			}
+			line("DTEND;VALUE=DATE", end.Format(dateLayout))
+		} else {
+			line("DTSTART", e.Start.UTC().Format(utcLayout))
+			if e.End.After(e.Start) {
+				line("DTEND", e.End.UTC().Format(utcLayout))
+			}
+		}
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escapeText(e.Location))
+		}
+		if e.Geo != nil {
+			line("GEO", fmt.Sprintf("%.6f;%.6f", e.Geo[0], e.Geo[1]))
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				escaped[i] = escapeText(category)
+			}
+			line("CATEGORIES", strings.Join(escaped, ","))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		// Астрономические события не занимают время в расписании
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText экранирует значение типа TEXT (раздел 3.3.11)
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// writeFolded пишет строку содержимого, перенося ее по 75 октетов
+// без разрыва многобайтовых символов UTF-8
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// Продолжение начинается с пробела, который тоже занимает октет
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+// formatDuration длительность в формате DURATION (PT6H, PT30M, P1D)
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d <= 0 {
+		return "PT1M"
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	}
+
+	var b strings.Builder
+	b.WriteString("PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m := (d % time.Hour) / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	return b.String()
+}
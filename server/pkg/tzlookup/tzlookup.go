@@ -0,0 +1,196 @@
+// Package tzlookup подсказывает часовой пояс IANA по координатам без внешних
+// сервисов: пояса ближайших опорных городов, а в открытом океане - морской пояс
+// Etc/GMT±N по долготе. Границ поясов в пакете нет, поэтому вблизи них подсказка
+// ошибается (точка в одном поясе, ближайший город - в соседнем). Результат - только
+// предложение пользователю; для расчетов нужен пояс, подтвержденный им явно.
+package tzlookup
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	// Встроенная база tzdata: пояса доступны и в минимальных контейнерах
+	_ "time/tzdata"
+)
+
+const (
+	// Дальше этого расстояния от опорных городов считаем точку открытым океаном
+	maxCityDistanceKm = 1000
+	// Пояса городов в этом радиусе предлагаются как альтернативы ближайшему
+	maxSuggestDistanceKm = 1500
+	maxSuggestions       = 3
+)
+
+type city struct {
+	lat, lon float64
+	zone     string
+}
+
+var cities = []city{
+	// Европа
+	{51.51, -0.13, "Europe/London"}, {53.35, -6.26, "Europe/Dublin"}, {38.72, -9.14, "Europe/Lisbon"},
+	{40.42, -3.70, "Europe/Madrid"}, {41.39, 2.17, "Europe/Madrid"}, {48.86, 2.35, "Europe/Paris"},
+	{50.85, 4.35, "Europe/Brussels"}, {52.37, 4.90, "Europe/Amsterdam"}, {52.52, 13.40, "Europe/Berlin"},
+	{48.14, 11.58, "Europe/Berlin"}, {47.37, 8.54, "Europe/Zurich"}, {41.90, 12.50, "Europe/Rome"},
+	{45.46, 9.19, "Europe/Rome"}, {48.21, 16.37, "Europe/Vienna"}, {50.08, 14.44, "Europe/Prague"},
+	{52.23, 21.01, "Europe/Warsaw"}, {55.68, 12.57, "Europe/Copenhagen"}, {59.91, 10.75, "Europe/Oslo"},
+	{59.33, 18.07, "Europe/Stockholm"}, {60.17, 24.94, "Europe/Helsinki"}, {59.44, 24.75, "Europe/Tallinn"},
+	{56.95, 24.11, "Europe/Riga"}, {54.69, 25.28, "Europe/Vilnius"}, {53.90, 27.57, "Europe/Minsk"},
+	{50.45, 30.52, "Europe/Kyiv"}, {44.43, 26.10, "Europe/Bucharest"}, {42.70, 23.32, "Europe/Sofia"},
+	{37.98, 23.73, "Europe/Athens"}, {41.01, 28.98, "Europe/Istanbul"}, {47.50, 19.04, "Europe/Budapest"},
+	{44.79, 20.45, "Europe/Belgrade"}, {47.01, 28.86, "Europe/Chisinau"}, {64.15, -21.94, "Atlantic/Reykjavik"},
+	{28.12, -15.43, "Atlantic/Canary"}, {37.74, -25.67, "Atlantic/Azores"},
+
+	// Россия
+	{54.71, 20.51, "Europe/Kaliningrad"}, {55.76, 37.62, "Europe/Moscow"}, {59.93, 30.34, "Europe/Moscow"},
+	{68.97, 33.07, "Europe/Moscow"}, {64.54, 40.54, "Europe/Moscow"}, {56.33, 44.00, "Europe/Moscow"},
+	{55.79, 49.12, "Europe/Moscow"}, {47.24, 39.71, "Europe/Moscow"}, {43.60, 39.73, "Europe/Moscow"},
+	{48.71, 44.51, "Europe/Volgograd"}, {53.20, 50.15, "Europe/Samara"}, {51.53, 46.03, "Europe/Saratov"},
+	{46.35, 48.04, "Europe/Astrakhan"}, {54.32, 48.40, "Europe/Ulyanovsk"}, {58.60, 49.66, "Europe/Kirov"},
+	{56.84, 60.61, "Asia/Yekaterinburg"}, {58.01, 56.25, "Asia/Yekaterinburg"}, {54.74, 55.97, "Asia/Yekaterinburg"},
+	{55.16, 61.40, "Asia/Yekaterinburg"}, {57.15, 65.53, "Asia/Yekaterinburg"}, {54.99, 73.37, "Asia/Omsk"},
+	{55.03, 82.92, "Asia/Novosibirsk"}, {53.35, 83.78, "Asia/Barnaul"}, {56.50, 84.97, "Asia/Tomsk"},
+	{53.76, 87.11, "Asia/Novokuznetsk"}, {56.01, 92.85, "Asia/Krasnoyarsk"}, {69.35, 88.20, "Asia/Krasnoyarsk"},
+	{52.29, 104.28, "Asia/Irkutsk"}, {52.03, 113.50, "Asia/Chita"}, {62.03, 129.73, "Asia/Yakutsk"},
+	{43.12, 131.89, "Asia/Vladivostok"}, {48.48, 135.08, "Asia/Vladivostok"}, {59.56, 150.80, "Asia/Magadan"},
+	{46.96, 142.73, "Asia/Sakhalin"}, {67.45, 153.71, "Asia/Srednekolymsk"}, {53.04, 158.65, "Asia/Kamchatka"},
+	{64.73, 177.51, "Asia/Anadyr"},
+
+	// Кавказ, Центральная Азия, Ближний Восток
+	{41.72, 44.79, "Asia/Tbilisi"}, {40.18, 44.51, "Asia/Yerevan"}, {40.41, 49.87, "Asia/Baku"},
+	{51.17, 71.45, "Asia/Almaty"}, {43.24, 76.89, "Asia/Almaty"}, {50.28, 57.21, "Asia/Aqtobe"},
+	{41.30, 69.24, "Asia/Tashkent"}, {42.87, 74.59, "Asia/Bishkek"}, {38.56, 68.79, "Asia/Dushanbe"},
+	{37.96, 58.33, "Asia/Ashgabat"}, {47.89, 106.91, "Asia/Ulaanbaatar"}, {35.69, 51.39, "Asia/Tehran"},
+	{33.31, 44.36, "Asia/Baghdad"}, {24.71, 46.68, "Asia/Riyadh"}, {25.20, 55.27, "Asia/Dubai"},
+	{31.77, 35.21, "Asia/Jerusalem"}, {24.86, 67.01, "Asia/Karachi"}, {34.56, 69.21, "Asia/Kabul"},
+
+	// Южная и Восточная Азия
+	{28.61, 77.21, "Asia/Kolkata"}, {19.08, 72.88, "Asia/Kolkata"}, {22.57, 88.36, "Asia/Kolkata"},
+	{13.08, 80.27, "Asia/Kolkata"}, {27.72, 85.32, "Asia/Kathmandu"}, {23.81, 90.41, "Asia/Dhaka"},
+	{16.87, 96.20, "Asia/Yangon"}, {13.76, 100.50, "Asia/Bangkok"}, {21.03, 105.85, "Asia/Ho_Chi_Minh"},
+	{1.35, 103.82, "Asia/Singapore"}, {3.14, 101.69, "Asia/Kuala_Lumpur"}, {-6.21, 106.85, "Asia/Jakarta"},
+	{-5.15, 119.43, "Asia/Makassar"}, {-2.53, 140.72, "Asia/Jayapura"}, {14.60, 120.98, "Asia/Manila"},
+	{22.32, 114.17, "Asia/Hong_Kong"}, {31.23, 121.47, "Asia/Shanghai"}, {39.90, 116.41, "Asia/Shanghai"},
+	{30.57, 104.07, "Asia/Shanghai"}, {43.83, 87.62, "Asia/Shanghai"}, {29.65, 91.17, "Asia/Shanghai"},
+	{25.03, 121.57, "Asia/Taipei"}, {37.57, 126.98, "Asia/Seoul"}, {35.68, 139.69, "Asia/Tokyo"},
+	{43.06, 141.35, "Asia/Tokyo"},
+
+	// Австралия и Океания
+	{-31.95, 115.86, "Australia/Perth"}, {-12.46, 130.84, "Australia/Darwin"}, {-34.93, 138.60, "Australia/Adelaide"},
+	{-27.47, 153.03, "Australia/Brisbane"}, {-33.87, 151.21, "Australia/Sydney"}, {-37.81, 144.96, "Australia/Melbourne"},
+	{-42.88, 147.33, "Australia/Hobart"}, {-36.85, 174.76, "Pacific/Auckland"}, {-9.44, 147.18, "Pacific/Port_Moresby"},
+	{-18.14, 178.44, "Pacific/Fiji"}, {21.31, -157.86, "Pacific/Honolulu"}, {19.82, -155.47, "Pacific/Honolulu"},
+
+	// Африка
+	{30.04, 31.24, "Africa/Cairo"}, {33.57, -7.59, "Africa/Casablanca"}, {36.75, 3.06, "Africa/Algiers"},
+	{36.81, 10.18, "Africa/Tunis"}, {32.89, 13.19, "Africa/Tripoli"}, {6.52, 3.38, "Africa/Lagos"},
+	{5.60, -0.19, "Africa/Accra"}, {14.72, -17.47, "Africa/Dakar"}, {15.50, 32.56, "Africa/Khartoum"},
+	{9.03, 38.74, "Africa/Addis_Ababa"}, {-1.29, 36.82, "Africa/Nairobi"}, {-4.44, 15.27, "Africa/Kinshasa"},
+	{-11.66, 27.48, "Africa/Lubumbashi"}, {-8.84, 13.23, "Africa/Luanda"}, {-26.20, 28.05, "Africa/Johannesburg"},
+	{-33.92, 18.42, "Africa/Johannesburg"}, {-25.97, 32.57, "Africa/Maputo"}, {-18.88, 47.51, "Indian/Antananarivo"},
+	{-22.56, 17.08, "Africa/Windhoek"},
+
+	// Северная Америка
+	{61.22, -149.90, "America/Anchorage"}, {49.28, -123.12, "America/Vancouver"}, {47.61, -122.33, "America/Los_Angeles"},
+	{37.77, -122.42, "America/Los_Angeles"}, {34.05, -118.24, "America/Los_Angeles"}, {33.45, -112.07, "America/Phoenix"},
+	{31.96, -111.60, "America/Phoenix"}, {39.74, -104.99, "America/Denver"}, {40.76, -111.89, "America/Denver"},
+	{51.05, -114.07, "America/Edmonton"}, {53.55, -113.49, "America/Edmonton"}, {49.90, -97.14, "America/Winnipeg"},
+	{50.45, -104.61, "America/Regina"}, {32.78, -96.80, "America/Chicago"}, {29.76, -95.37, "America/Chicago"},
+	{41.88, -87.63, "America/Chicago"}, {44.98, -93.27, "America/Chicago"}, {33.75, -84.39, "America/New_York"},
+	{35.15, -90.05, "America/Chicago"}, {39.77, -86.16, "America/Indiana/Indianapolis"},
+	{38.25, -85.76, "America/Kentucky/Louisville"}, {31.76, -106.49, "America/Denver"},
+	{25.76, -80.19, "America/New_York"}, {40.71, -74.01, "America/New_York"}, {42.33, -83.05, "America/Detroit"},
+	{43.65, -79.38, "America/Toronto"}, {45.50, -73.57, "America/Toronto"}, {44.65, -63.57, "America/Halifax"},
+	{47.56, -52.71, "America/St_Johns"}, {64.18, -51.72, "America/Nuuk"}, {19.43, -99.13, "America/Mexico_City"},
+	{32.51, -117.04, "America/Tijuana"}, {14.63, -90.51, "America/Guatemala"}, {23.11, -82.37, "America/Havana"},
+	{8.98, -79.52, "America/Panama"},
+
+	// Южная Америка
+	{4.71, -74.07, "America/Bogota"}, {10.48, -66.90, "America/Caracas"}, {-12.05, -77.04, "America/Lima"},
+	{-0.18, -78.47, "America/Guayaquil"}, {-16.50, -68.15, "America/La_Paz"}, {-33.45, -70.67, "America/Santiago"},
+	{-24.63, -70.40, "America/Santiago"}, {-34.60, -58.38, "America/Argentina/Buenos_Aires"},
+	{-34.90, -56.16, "America/Montevideo"}, {-25.26, -57.58, "America/Asuncion"}, {-23.55, -46.63, "America/Sao_Paulo"},
+	{-22.91, -43.17, "America/Sao_Paulo"}, {-15.79, -47.88, "America/Sao_Paulo"}, {-3.12, -60.02, "America/Manaus"},
+	{-8.05, -34.88, "America/Recife"}, {-15.60, -56.10, "America/Cuiaba"}, {-20.47, -54.62, "America/Campo_Grande"},
+}
+
+// Zone наиболее вероятный пояс IANA для координат - первый из Suggest
+func Zone(lat, lon float64) string {
+	return Suggest(lat, lon)[0]
+}
+
+// Suggest пояса-кандидаты по возрастанию расстояния до опорных городов (не больше трех,
+// без повторов). Вдали от городов первым идет морской пояс по долготе.
+func Suggest(lat, lon float64) []string {
+	type candidate struct {
+		zone     string
+		distance float64
+	}
+	nearest := make(map[string]float64)
+	for _, c := range cities {
+		d := distanceKm(lat, lon, c.lat, c.lon)
+		if best, ok := nearest[c.zone]; !ok || d < best {
+			nearest[c.zone] = d
+		}
+	}
+
+	var candidates []candidate
+	for zone, d := range nearest {
+		if d <= maxSuggestDistanceKm {
+			candidates = append(candidates, candidate{zone, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].zone < candidates[j].zone
+	})
+
+	var zones []string
+	if len(candidates) == 0 || candidates[0].distance > maxCityDistanceKm {
+		zones = append(zones, nauticalZone(lon))
+	}
+	for _, c := range candidates {
+		if len(zones) == maxSuggestions {
+			break
+		}
+		zones = append(zones, c.zone)
+	}
+	return zones
+}
+
+// Location возвращает загруженный пояс для координат; при сбое загрузки - морской пояс
+func Location(lat, lon float64) *time.Location {
+	if loc, err := time.LoadLocation(Zone(lat, lon)); err == nil {
+		return loc
+	}
+	offset := nauticalOffset(lon)
+	return time.FixedZone(fmt.Sprintf("UTC%+03d:00", offset), offset*3600)
+}
+
+// nauticalZone морской пояс; в базе IANA знак Etc/GMT инвертирован (Etc/GMT-3 = UTC+3)
+func nauticalZone(lon float64) string {
+	offset := nauticalOffset(lon)
+	if offset == 0 {
+		return "Etc/GMT"
+	}
+	return fmt.Sprintf("Etc/GMT%+d", -offset)
+}
+
+func nauticalOffset(lon float64) int {
+	return int(math.Round(lon / 15))
+}
+
+// distanceKm расстояние по большому кругу (гаверсинус)
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371.0
+	p1, p2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dp := p2 - p1
+	dl := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dp/2)*math.Sin(dp/2) + math.Cos(p1)*math.Cos(p2)*math.Sin(dl/2)*math.Sin(dl/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,44 @@
+package tzlookup
+
+import (
+	"slices"
+	"testing"
+)
+
+// Города у границ поясов, где ближайший опорный город лежит в соседнем поясе
+func TestSuggestIncludesZoneNearBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		zone     string
+	}{
+		{"Memphis", 35.15, -90.05, "America/Chicago"},
+		{"Indianapolis", 39.77, -86.16, "America/Indiana/Indianapolis"},
+		{"Louisville", 38.25, -85.76, "America/Kentucky/Louisville"},
+		{"El Paso", 31.76, -106.49, "America/Denver"},
+		{"Cuiaba", -15.60, -56.10, "America/Cuiaba"},
+		{"Alice Springs", -23.70, 133.88, "Australia/Darwin"},
+		{"Tucson", 32.22, -110.97, "America/Phoenix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := Suggest(tt.lat, tt.lon)
+			if !slices.Contains(suggestions, tt.zone) {
+				t.Fatalf("Suggest(%.2f, %.2f) = %v, want %s among them", tt.lat, tt.lon, suggestions, tt.zone)
+			}
+			if len(suggestions) > maxSuggestions {
+				t.Fatalf("%d suggestions, want at most %d", len(suggestions), maxSuggestions)
+			}
+		})
+	}
+}
+
+func TestSuggestOpenOceanStartsWithNauticalZone(t *testing.T) {
+	suggestions := Suggest(0, -140)
+	if len(suggestions) == 0 || suggestions[0] != "Etc/GMT+9" {
+		t.Fatalf("Suggest(0, -140) = %v, want Etc/GMT+9 first", suggestions)
+	}
+	if zone := Zone(0, -140); zone != "Etc/GMT+9" {
+		t.Fatalf("Zone(0, -140) = %s, want Etc/GMT+9", zone)
+	}
+}